@@ -3,6 +3,7 @@ package addrmanager
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"sync"
@@ -30,6 +31,7 @@ type serializedKnownAddress struct {
 	Addr      string
 	Src       string
 	PublicKey string
+	Signature string
 }
 
 type serializedAddrManager struct {
@@ -69,6 +71,7 @@ func (addrManager *AddrManager) savePeers() error {
 		fmt.Println("PeerID", len(v.PeerID.String()))
 		ska.Src = v.PeerID.Pretty()
 		ska.PublicKey = v.PublicKey
+		ska.Signature = v.AddrSignature
 
 		sam.Addresses[i] = ska
 		i++
@@ -155,6 +158,7 @@ func (addrManager *AddrManager) deserializePeers(filePath string) error {
 		peer.PeerID = peer2.ID(v.Src)
 		peer.RawAddress = v.Addr
 		peer.PublicKey = v.PublicKey
+		peer.AddrSignature = v.Signature
 
 		addrManager.addrIndex[peer.RawAddress] = peer
 
@@ -220,9 +224,47 @@ func (addrManager *AddrManager) Good(addr *peer.Peer) {
 	addrManager.mtx.Lock()
 	defer addrManager.mtx.Unlock()
 
+	if _, ok := addrManager.addrIndex[addr.RawAddress]; !ok {
+		addrManager.makeRoom()
+	}
 	addrManager.addrIndex[addr.RawAddress] = addr
 }
 
+// AddAddress adds an address learned from another peer (addr message) to the
+// address manager so it is persisted and can be used to discover peers
+// when the bootnode is not reachable. Addresses already known are kept as is.
+// A random address is evicted when the address manager is full, so the
+// first addresses which are learned don't stay forever
+func (addrManager *AddrManager) AddAddress(addr *peer.Peer) bool {
+	if addr == nil || addr.RawAddress == "" {
+		return false
+	}
+	addrManager.mtx.Lock()
+	defer addrManager.mtx.Unlock()
+
+	if _, ok := addrManager.addrIndex[addr.RawAddress]; ok {
+		return false
+	}
+	addrManager.makeRoom()
+	addrManager.addrIndex[addr.RawAddress] = addr
+	return true
+}
+
+// makeRoom evicts random addresses until a new one can be added, mtx must
+// be held
+func (addrManager *AddrManager) makeRoom() {
+	for len(addrManager.addrIndex) >= MaxAddresses {
+		evicted := rand.Intn(len(addrManager.addrIndex))
+		for rawAddress := range addrManager.addrIndex {
+			if evicted == 0 {
+				delete(addrManager.addrIndex, rawAddress)
+				break
+			}
+			evicted--
+		}
+	}
+}
+
 // AddressCache returns the current address cache.  It must be treated as
 // read-only (but since it is a copy now, this is not as dangerous).
 func (addrManager *AddrManager) AddressCache() []*peer.Peer {
@@ -0,0 +1,63 @@
+package addrmanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/peer"
+)
+
+func init() {
+	Logger.Init(common.NewBackend(nil).Logger("test", true))
+}
+
+func newTestPeer(i int) *peer.Peer {
+	return &peer.Peer{
+		RawAddress:    fmt.Sprintf("/ip4/1.2.3.4/tcp/%d/ipfs/peer%d", 9000+i, i),
+		PublicKey:     fmt.Sprintf("pubkey%d", i),
+		AddrSignature: fmt.Sprintf("sig%d", i),
+	}
+}
+
+func TestAddAddressIsBounded(t *testing.T) {
+	addrManager := New(os.TempDir())
+	for i := 0; i < MaxAddresses+100; i++ {
+		if !addrManager.AddAddress(newTestPeer(i)) {
+			t.Fatalf("new address %d should be added", i)
+		}
+	}
+	if n := len(addrManager.AddressCache()); n != MaxAddresses {
+		t.Fatalf("address manager has %d addresses, want %d", n, MaxAddresses)
+	}
+	last := newTestPeer(MaxAddresses + 99)
+	if addrManager.AddAddress(last) {
+		t.Fatalf("known address should not be added again")
+	}
+	// good addresses also make room instead of growing the address manager
+	addrManager.Good(newTestPeer(MaxAddresses + 100))
+	if n := len(addrManager.AddressCache()); n != MaxAddresses {
+		t.Fatalf("address manager has %d addresses after good, want %d", n, MaxAddresses)
+	}
+}
+
+func TestSavePeersKeepsSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addrmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addrManager := New(dir)
+	addrManager.AddAddress(newTestPeer(1))
+	if err := addrManager.savePeers(); err != nil {
+		t.Fatalf("savePeers returns err: %+v", err)
+	}
+	loaded := New(dir)
+	loaded.loadPeers()
+	peers := loaded.AddressCache()
+	if len(peers) != 1 || peers[0].AddrSignature != "sig1" || peers[0].PublicKey != "pubkey1" {
+		t.Fatalf("loaded peers don't keep signed address record: %+v", peers)
+	}
+}
@@ -8,4 +8,8 @@ const (
 	// DumpAddressInterval is the interval used to dump the address
 	// cache to disk for future use.
 	DumpAddressInterval = time.Second * 10
+
+	// MaxAddresses is the maximum number of addresses the address manager
+	// keeps, a random address is evicted for a new one beyond it.
+	MaxAddresses = 1000
 )
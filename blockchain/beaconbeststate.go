@@ -86,6 +86,12 @@ func (bestStateBeacon *BestStateBeacon) GetBestHeightOfShard(shardID byte) uint6
 	return bestStateBeacon.BestShardHeight[shardID]
 }
 
+func (bestStateBeacon *BestStateBeacon) GetBeaconCommittee() []string {
+	bestStateBeacon.lockMu.RLock()
+	defer bestStateBeacon.lockMu.RUnlock()
+	return bestStateBeacon.BeaconCommittee
+}
+
 func (bestStateBeacon *BestStateBeacon) GetAShardCommittee(shardID byte) []string {
 	bestStateBeacon.lockMu.RLock()
 	defer bestStateBeacon.lockMu.RUnlock()
@@ -13,6 +13,7 @@ import (
 	"github.com/constant-money/constant-chain/cashec"
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/common/base58"
+	"github.com/constant-money/constant-chain/database"
 	"github.com/constant-money/constant-chain/metadata"
 )
 
@@ -85,7 +86,11 @@ func (blockchain *BlockChain) InsertBeaconBlock(block *BeaconBlock, isValidated
 		return NewBlockChainError(DuplicateBlockErr, errors.New("This block has been stored already"))
 	}
 	Logger.log.Infof("Begin Insert new block %d, with hash %+v \n", block.Header.Height, *block.Hash())
-	if !isValidated {
+	bestBlockHash := &blockchain.BestState.Beacon.BestBlockHash
+	isFork := !bestBlockHash.IsEqual(&block.Header.PrevBlockHash)
+	if isFork {
+		Logger.log.Infof("Verify Pre Processing of fork %+v after best block is reverted \n", *block.Hash())
+	} else if !isValidated {
 		Logger.log.Infof("Verify Pre Processing Beacon Block %+v \n", *block.Hash())
 		if err := blockchain.VerifyPreProcessingBeaconBlock(block, false); err != nil {
 			return err
@@ -96,12 +101,46 @@ func (blockchain *BlockChain) InsertBeaconBlock(block *BeaconBlock, isValidated
 	//========Verify block with previous best state
 	// check with current final best state
 	// block can only be insert if it match the current best state
-	bestBlockHash := &blockchain.BestState.Beacon.BestBlockHash
-	if !bestBlockHash.IsEqual(&block.Header.PrevBlockHash) {
-		return NewBlockChainError(BeaconError, errors.New("beacon Block does not match with any Beacon State in cache or in Database"))
+	// a fork of best block which has more signatures replaces best block
+	// best block is reverted in a database transaction, the fork is verified
+	// with best state of its parent and the reverted database, then it is
+	// stored in the same transaction
+	var forkDB database.Transaction
+	var revertedBestState *BestStateBeacon
+	if isFork {
+		if !blockchain.isBetterBeaconFork(block) {
+			return NewBlockChainError(BeaconError, errors.New("beacon Block does not match with any Beacon State in cache or in Database"))
+		}
+		Logger.log.Infof("Beacon block %+v is a better fork of best block, verify it with best state of parent", *block.Hash())
+		db, parentBestState, err := blockchain.beginRevertBeaconBlock()
+		if err != nil {
+			return err
+		}
+		defer db.Rollback()
+		// signatures of a fork are always verified, they decide which block is kept
+		if err := parentBestState.VerifyBestStateWithBeaconBlock(block, true); err != nil {
+			return err
+		}
+		forkDB = db
+		// best state of parent is updated with the fork, best state of
+		// reverted block is restored if the fork isn't stored
+		revertedBestState = blockchain.BestState.Beacon
+		blockchain.setBestStateBeacon(parentBestState)
+		defer func() {
+			if forkDB != nil {
+				blockchain.setBestStateBeacon(revertedBestState)
+			}
+		}()
+		if !isValidated {
+			if err := blockchain.forkView(forkDB).VerifyPreProcessingBeaconBlock(block, false); err != nil {
+				return err
+			}
+		}
 	}
 	// fmt.Printf("BeaconBest state %+v \n", blockchain.BestState.Beacon)
-	if !isValidated {
+	if forkDB != nil {
+		Logger.log.Infof("Fork %+v is verified with BestState of parent \n", *block.Hash())
+	} else if !isValidated {
 		Logger.log.Infof("Verify BestState with Beacon Block %+v \n", *block.Hash())
 		// Verify block with previous best state
 		if err := blockchain.BestState.Beacon.VerifyBestStateWithBeaconBlock(block, true); err != nil {
@@ -126,98 +165,134 @@ func (blockchain *BlockChain) InsertBeaconBlock(block *BeaconBlock, isValidated
 		Logger.log.Infof("BEACON %+v | SKIP Verify Post Processing Block %+v \n", *block.Hash())
 	}
 
+	//========Store new Beaconblock and new Beacon bestState
+	if forkDB != nil {
+		if err := blockchain.storeBeaconBlock(forkDB, block); err != nil {
+			return err
+		}
+		if err := forkDB.Commit(); err != nil {
+			return NewBlockChainError(DBError, err)
+		}
+		forkDB = nil
+	} else if err := blockchain.processStoreBeaconBlock(block); err != nil {
+		return err
+	}
+
+	//=========Update cross shard pool with new cross shard next heights
+	for fromShard := range block.Body.ShardState {
+		go blockchain.config.CrossShardPool[fromShard].UpdatePool()
+	}
+	//=========Remove beacon block in pool
+	blockchain.config.BeaconPool.SetBeaconState(blockchain.BestState.Beacon.BeaconHeight)
+	blockchain.config.BeaconPool.RemoveBlock(blockchain.BestState.Beacon.BeaconHeight)
+	//=========Remove shard to beacon block in pool
+	//Logger.log.Info("Remove block from pool block with hash  ", *block.Hash(), block.Header.Height, blockchain.BestState.Beacon.BestShardHeight)
+	blockchain.config.ShardToBeaconPool.SetShardState(blockchain.BestState.Beacon.GetBestShardHeight())
+
+	Logger.log.Infof("Finish Insert new block %+v, with hash %+v \n", block.Header.Height, *block.Hash())
+	if block.Header.Height%50 == 0 {
+		fmt.Printf("[db] inserted beacon height: %d\n", block.Header.Height)
+	}
+	return nil
+}
+
+/*
+	Store All information after Insert
+	- Accepted shard to beacon blocks, committee, cross shard next heights
+	- Beacon Best State
+	- Beacon Block
+	- Bridge instructions
+	- Undo log of all of above, so the block can be reverted
+	- Pruning of old beacon blocks
+	All of them are stored in one database transaction, so a block is stored
+	completely or not at all
+*/
+func (blockchain *BlockChain) processStoreBeaconBlock(block *BeaconBlock) error {
+	db, err := blockchain.config.DataBase.Begin()
+	if err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	defer db.Rollback()
+	if err := blockchain.storeBeaconBlock(db, block); err != nil {
+		return err
+	}
+	if err := db.Commit(); err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	return nil
+}
+
+// storeBeaconBlock stores block and beacon best state in db, caller commits
+// db
+func (blockchain *BlockChain) storeBeaconBlock(db database.Transaction, block *BeaconBlock) error {
 	for shardID, shardStates := range block.Body.ShardState {
 		for _, shardState := range shardStates {
-			blockchain.config.DataBase.StoreAcceptedShardToBeacon(shardID, block.Header.Height, &shardState.Hash)
+			if err := db.StoreAcceptedShardToBeacon(shardID, block.Header.Height, &shardState.Hash); err != nil {
+				return NewBlockChainError(DBError, err)
+			}
 		}
 	}
 	// if committee of this epoch isn't store yet then store it
 	// @NOTICE: Change to height
 	Logger.log.Infof("Store Committee in Height %+v \n", block.Header.Height)
-	// res, err := blockchain.config.DataBase.HasCommitteeByEpoch(block.Header.Epoch)
-	// if res == false {
-	if err := blockchain.config.DataBase.StoreCommitteeByEpoch(block.Header.Height, blockchain.BestState.Beacon.GetShardCommittee()); err != nil {
+	if err := db.StoreCommitteeByEpoch(block.Header.Height, blockchain.BestState.Beacon.GetShardCommittee()); err != nil {
 		return err
 	}
-	// }
-	shardCommitteeByte, err := blockchain.config.DataBase.FetchCommitteeByEpoch(block.Header.Epoch)
-	if err != nil {
-		fmt.Println("No committee for this epoch")
-	}
-	shardCommittee := make(map[byte][]string)
-	if err := json.Unmarshal(shardCommitteeByte, &shardCommittee); err != nil {
-		fmt.Println("Fail to unmarshal shard committee")
-	}
-	// fmt.Println("Beacon Process/Shard Committee in Epoch ", block.Header.Epoch, shardCommittee)
 	//=========Store cross shard state ==================================
-
 	if block.Body.ShardState != nil {
+		GetBestStateBeacon().lockMu.Lock()
 		lastCrossShardState := GetBestStateBeacon().LastCrossShardState
 		for fromShard, shardBlocks := range block.Body.ShardState {
-
-			go func(fromShard byte, shardBlocks []ShardState) {
-				GetBestStateBeacon().lockMu.Lock()
-				defer GetBestStateBeacon().lockMu.Unlock()
-				for _, shardBlock := range shardBlocks {
-					for _, toShard := range shardBlock.CrossShard {
-						if fromShard == toShard {
-							continue
-						}
-						if lastCrossShardState[fromShard] == nil {
-							lastCrossShardState[fromShard] = make(map[byte]uint64)
-						}
-						lastHeight := lastCrossShardState[fromShard][toShard] // get last cross shard height from shardID  to crossShardShardID
-						waitHeight := shardBlock.Height
-						//fmt.Println("StoreCrossShardNextHeight", fromShard, toShard, lastHeight, waitHeight)
-						blockchain.config.DataBase.StoreCrossShardNextHeight(fromShard, toShard, lastHeight, waitHeight)
+			for _, shardBlock := range shardBlocks {
+				for _, toShard := range shardBlock.CrossShard {
+					if fromShard == toShard {
+						continue
+					}
+					if lastCrossShardState[fromShard] == nil {
+						lastCrossShardState[fromShard] = make(map[byte]uint64)
+					}
+					lastHeight := lastCrossShardState[fromShard][toShard] // get last cross shard height from shardID  to crossShardShardID
+					waitHeight := shardBlock.Height
+					err := db.StoreCrossShardNextHeight(fromShard, toShard, lastHeight, waitHeight)
+					if err == nil {
 						//beacon process shard_to_beacon in order so cross shard next height also will be saved in order
 						//dont care overwrite this value
-						blockchain.config.DataBase.StoreCrossShardNextHeight(fromShard, toShard, waitHeight, 0)
-						if lastCrossShardState[fromShard] == nil {
-							lastCrossShardState[fromShard] = make(map[byte]uint64)
-						}
-						lastCrossShardState[fromShard][toShard] = waitHeight //update lastHeight to waitHeight
+						err = db.StoreCrossShardNextHeight(fromShard, toShard, waitHeight, 0)
 					}
+					if err != nil {
+						GetBestStateBeacon().lockMu.Unlock()
+						return NewBlockChainError(DBError, err)
+					}
+					lastCrossShardState[fromShard][toShard] = waitHeight //update lastHeight to waitHeight
 				}
-				blockchain.config.CrossShardPool[fromShard].UpdatePool()
-			}(fromShard, shardBlocks)
+			}
 		}
+		GetBestStateBeacon().lockMu.Unlock()
 	}
 
 	// ************ Store block at last
-	//========Store new Beaconblock and new Beacon bestState in cache
 	Logger.log.Infof("Store Beacon BestState  ")
-	if err := blockchain.StoreBeaconBestState(); err != nil {
+	if err := blockchain.StoreBeaconBestState(db); err != nil {
 		return err
 	}
 
 	Logger.log.Info("Store Beacon Block ", block.Header.Height, *block.Hash())
-	if err := blockchain.config.DataBase.StoreBeaconBlock(block); err != nil {
+	if err := db.StoreBeaconBlock(block); err != nil {
 		return err
 	}
 	blockHash := block.Hash()
-	if err := blockchain.config.DataBase.StoreBeaconBlockIndex(blockHash, block.Header.Height); err != nil {
+	if err := db.StoreBeaconBlockIndex(blockHash, block.Header.Height); err != nil {
 		return err
 	}
 
-	//=========Remove beacon block in pool
-	blockchain.config.BeaconPool.SetBeaconState(blockchain.BestState.Beacon.BeaconHeight)
-	blockchain.config.BeaconPool.RemoveBlock(blockchain.BestState.Beacon.BeaconHeight)
-	//=========Remove shard to beacon block in pool
-	//Logger.log.Info("Remove block from pool block with hash  ", *block.Hash(), block.Header.Height, blockchain.BestState.Beacon.BestShardHeight)
-	blockchain.config.ShardToBeaconPool.SetShardState(blockchain.BestState.Beacon.GetBestShardHeight())
-
-	err = blockchain.processBridgeInstructions(block)
-	if err != nil {
+	if err := blockchain.processBridgeInstructions(db, block); err != nil {
 		Logger.log.Errorf("Blockchain Error %+v", NewBlockChainError(UnExpectedError, err))
 		return NewBlockChainError(UnExpectedError, err)
 	}
-
-	Logger.log.Infof("Finish Insert new block %+v, with hash %+v \n", block.Header.Height, *block.Hash())
-	if block.Header.Height%50 == 0 {
-		fmt.Printf("[db] inserted beacon height: %d\n", block.Header.Height)
+	if err := db.StoreUndoLog(block.Hash()); err != nil {
+		return NewBlockChainError(DBError, err)
 	}
-	return nil
+	return blockchain.pruneBlocks(db, true, 0, block.Header.Height)
 }
 
 /* Verify Pre-prosessing data
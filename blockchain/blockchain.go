@@ -71,6 +71,9 @@ type Config struct {
 	ChainParams               *Params
 	RelayShards               []byte
 	NodeMode                  string
+	// PruneDepth is the number of recent blocks whose bodies are kept, older
+	// block bodies are deleted. Blocks are never pruned if it is 0
+	PruneDepth uint64
 	customTokenRewardSnapshot map[string]uint64 //snapshot reward
 	ShardToBeaconPool         ShardToBeaconPool
 	CrossShardPool            map[byte]CrossShardPool
@@ -78,7 +81,7 @@ type Config struct {
 	ShardPool                 map[byte]ShardPool
 	TxPool                    TxPool
 	TempTxPool                TxPool
-	CRemovedTxs               chan metadata.Transaction
+	FeeEstimator              map[byte]FeeEstimator
 	Server                    interface {
 		BoardcastNodeState() error
 
@@ -95,6 +98,8 @@ type Config struct {
 		PushMessageGetBlockCrossShardByHash(fromShard byte, toShard byte, blksHash []common.Hash, getFromPool bool, peerID libp2p.ID) error
 		PushMessageGetBlockCrossShardBySpecificHeight(fromShard byte, toShard byte, blksHeight []uint64, getFromPool bool, peerID libp2p.ID) error
 		UpdateConsensusState(role string, userPbk string, currentShard *byte, beaconCommittee []string, shardCommittee map[byte][]string)
+		// IsArchivePeer returns true if peer keeps full history of blocks
+		IsArchivePeer(peerID libp2p.ID) bool
 	}
 	UserKeySet *cashec.KeySet
 }
@@ -135,8 +140,13 @@ func (blockchain *BlockChain) AddTempTxPool(temptxpool TxPool) {
 	blockchain.config.TempTxPool = temptxpool
 }
 
-func (blockchain *BlockChain) InitChannelBlockchain(cRemovedTxs chan metadata.Transaction) {
-	blockchain.config.CRemovedTxs = cRemovedTxs
+// AddFeeEstimator sets fee estimator of a shard, it learns fee rates from
+// blocks of the shard which are inserted or reverted
+func (blockchain *BlockChain) AddFeeEstimator(shardID byte, feeEstimator FeeEstimator) {
+	if blockchain.config.FeeEstimator == nil {
+		blockchain.config.FeeEstimator = make(map[byte]FeeEstimator)
+	}
+	blockchain.config.FeeEstimator[shardID] = feeEstimator
 }
 
 // -------------- Blockchain retriever's implementation --------------
@@ -268,7 +278,7 @@ func (blockchain *BlockChain) initBeaconState() error {
 	blockchain.BestState.Beacon.Update(initBlock, blockchain)
 
 	// Insert new block into beacon chain
-	if err := blockchain.StoreBeaconBestState(); err != nil {
+	if err := blockchain.StoreBeaconBestState(blockchain.config.DataBase); err != nil {
 		Logger.log.Error("Error Store best state for block", blockchain.BestState.Beacon.BestBlockHash, "in beacon chain")
 		return NewBlockChainError(UnExpectedError, err)
 	}
@@ -316,19 +326,129 @@ func (blockchain *BlockChain) GetBeaconBlockByHeight(height uint64) (*BeaconBloc
 }
 
 /*
-Fetch DatabaseInterface and get block data by block hash
+Fetch DatabaseInterface and get block data by block hash. A block from cache
+is copied, so callers may change it
 */
 func (blockchain *BlockChain) GetBeaconBlockByHash(hash *common.Hash) (*BeaconBlock, uint64, error) {
+	if cache, ok := blockchain.config.DataBase.(*database.CacheDB); ok {
+		value, err := cache.FetchDecodedBlock(hash, true, decodeBeaconBlock)
+		if err != nil {
+			return nil, 0, err
+		}
+		decoded := value.(decodedBlock)
+		return copyBeaconBlock(decoded.block.(*BeaconBlock)), decoded.size, nil
+	}
 	blockBytes, err := blockchain.config.DataBase.FetchBeaconBlock(hash)
 	if err != nil {
 		return nil, 0, err
 	}
-	block := BeaconBlock{}
-	err = json.Unmarshal(blockBytes, &block)
+	decoded, err := decodeBeaconBlock(blockBytes)
 	if err != nil {
 		return nil, 0, err
 	}
-	return &block, uint64(len(blockBytes)), nil
+	return decoded.(decodedBlock).block.(*BeaconBlock), decoded.(decodedBlock).size, nil
+}
+
+// decodedBlock is a block with the size of its bytes, it is the value which
+// database.CacheDB keeps for a block
+type decodedBlock struct {
+	block interface{}
+	size  uint64
+}
+
+func decodeBeaconBlock(blockBytes []byte) (interface{}, error) {
+	block := BeaconBlock{}
+	err := json.Unmarshal(blockBytes, &block)
+	if err != nil {
+		return nil, err
+	}
+	return decodedBlock{block: &block, size: uint64(len(blockBytes))}, nil
+}
+
+func decodeShardBlock(blockBytes []byte) (interface{}, error) {
+	block := ShardBlock{}
+	err := json.Unmarshal(blockBytes, &block)
+	if err != nil {
+		return nil, err
+	}
+	return decodedBlock{block: &block, size: uint64(len(blockBytes))}, nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func copyPaymentAddress(address privacy.PaymentAddress) privacy.PaymentAddress {
+	return privacy.PaymentAddress{Pk: copyBytes(address.Pk), Tk: copyBytes(address.Tk)}
+}
+
+func copyValidatorsIdx(validatorsIdx [][]int) [][]int {
+	if validatorsIdx == nil {
+		return nil
+	}
+	copied := make([][]int, len(validatorsIdx))
+	for i, idx := range validatorsIdx {
+		copied[i] = append([]int(nil), idx...)
+	}
+	return copied
+}
+
+func copyInstructions(instructions [][]string) [][]string {
+	if instructions == nil {
+		return nil
+	}
+	copied := make([][]string, len(instructions))
+	for i, inst := range instructions {
+		copied[i] = append([]string(nil), inst...)
+	}
+	return copied
+}
+
+/*
+copyShardBlock copies a decoded block which is kept in cache, so the copy can
+be changed. Transactions and output coins of cross transactions are shared,
+they are not changed after they are decoded
+*/
+func copyShardBlock(block *ShardBlock) *ShardBlock {
+	copied := *block
+	copied.ValidatorsIdx = copyValidatorsIdx(block.ValidatorsIdx)
+	copied.Header.ProducerAddress = copyPaymentAddress(block.Header.ProducerAddress)
+	copied.Header.CrossShards = copyBytes(block.Header.CrossShards)
+	copied.Body.Instructions = copyInstructions(block.Body.Instructions)
+	if block.Body.CrossTransactions != nil {
+		copied.Body.CrossTransactions = make(map[byte][]CrossTransaction, len(block.Body.CrossTransactions))
+		for shardID, crossTransactions := range block.Body.CrossTransactions {
+			copied.Body.CrossTransactions[shardID] = append([]CrossTransaction(nil), crossTransactions...)
+		}
+	}
+	if block.Body.Transactions != nil {
+		copied.Body.Transactions = append([]metadata.Transaction{}, block.Body.Transactions...)
+	}
+	return &copied
+}
+
+// copyBeaconBlock copies a decoded beacon block which is kept in cache, so
+// the copy can be changed
+func copyBeaconBlock(block *BeaconBlock) *BeaconBlock {
+	copied := *block
+	copied.ValidatorsIdx = copyValidatorsIdx(block.ValidatorsIdx)
+	copied.Header.ProducerAddress = copyPaymentAddress(block.Header.ProducerAddress)
+	copied.Body.Instructions = copyInstructions(block.Body.Instructions)
+	if block.Body.ShardState != nil {
+		copied.Body.ShardState = make(map[byte][]ShardState, len(block.Body.ShardState))
+		for shardID, states := range block.Body.ShardState {
+			copiedStates := make([]ShardState, len(states))
+			for i, state := range states {
+				copiedStates[i] = state
+				copiedStates[i].CrossShard = copyBytes(state.CrossShard)
+			}
+			copied.Body.ShardState[shardID] = copiedStates
+		}
+	}
+	return &copied
 }
 
 /*
@@ -359,34 +479,41 @@ func (blockchain *BlockChain) GetShardBlockByHeight(height uint64, shardID byte)
 }
 
 /*
-Fetch DatabaseInterface and get block data by block hash
+Fetch DatabaseInterface and get block data by block hash. A block from cache
+is copied, so callers may change it
 */
 func (blockchain *BlockChain) GetShardBlockByHash(hash *common.Hash) (*ShardBlock, uint64, error) {
+	if cache, ok := blockchain.config.DataBase.(*database.CacheDB); ok {
+		value, err := cache.FetchDecodedBlock(hash, false, decodeShardBlock)
+		if err != nil {
+			return nil, 0, err
+		}
+		decoded := value.(decodedBlock)
+		return copyShardBlock(decoded.block.(*ShardBlock)), decoded.size, nil
+	}
 	blockBytes, err := blockchain.config.DataBase.FetchBlock(hash)
 	if err != nil {
 		return nil, 0, err
 	}
-
-	block := ShardBlock{}
-	err = json.Unmarshal(blockBytes, &block)
+	decoded, err := decodeShardBlock(blockBytes)
 	if err != nil {
 		return nil, 0, err
 	}
-	return &block, uint64(len(blockBytes)), nil
+	return decoded.(decodedBlock).block.(*ShardBlock), decoded.(decodedBlock).size, nil
 }
 
 /*
 Store best state of block(best block, num of tx, ...) into Database
 */
-func (blockchain *BlockChain) StoreBeaconBestState() error {
-	return blockchain.config.DataBase.StoreBeaconBestState(blockchain.BestState.Beacon)
+func (blockchain *BlockChain) StoreBeaconBestState(db database.DatabaseInterface) error {
+	return db.StoreBeaconBestState(blockchain.BestState.Beacon)
 }
 
 /*
 Store best state of block(best block, num of tx, ...) into Database
 */
-func (blockchain *BlockChain) StoreShardBestState(shardID byte) error {
-	return blockchain.config.DataBase.StoreShardBestState(blockchain.BestState.Shard[shardID], shardID)
+func (blockchain *BlockChain) StoreShardBestState(db database.DatabaseInterface, shardID byte) error {
+	return db.StoreShardBestState(blockchain.BestState.Shard[shardID], shardID)
 }
 
 /*
@@ -402,11 +529,29 @@ func (blockchain *BlockChain) GetShardBestState(shardID byte) (*BestStateShard,
 	return &bestState, err
 }
 
+/*
+GetShardCommitteeByBeaconHeight returns committee of shard shardID which is
+stored by beacon block at beaconHeight, when it isn't stored yet committee of
+current beacon best state is returned
+*/
+func (blockchain *BlockChain) GetShardCommitteeByBeaconHeight(shardID byte, beaconHeight uint64) []string {
+	committeeBytes, err := blockchain.config.DataBase.FetchCommitteeByEpoch(beaconHeight)
+	if err == nil {
+		shardCommittee := make(map[byte][]string)
+		if err := json.Unmarshal(committeeBytes, &shardCommittee); err == nil {
+			if committee, ok := shardCommittee[shardID]; ok {
+				return committee
+			}
+		}
+	}
+	return blockchain.BestState.Beacon.GetAShardCommittee(shardID)
+}
+
 /*
 Store block into Database
 */
-func (blockchain *BlockChain) StoreShardBlock(block *ShardBlock) error {
-	return blockchain.config.DataBase.StoreShardBlock(block, block.Header.ShardID)
+func (blockchain *BlockChain) StoreShardBlock(db database.DatabaseInterface, block *ShardBlock) error {
+	return db.StoreShardBlock(block, block.Header.ShardID)
 }
 
 /*
@@ -422,21 +567,21 @@ Save index(height) of block by block hash
 and
 Save block hash by index(height) of block
 */
-func (blockchain *BlockChain) StoreShardBlockIndex(block *ShardBlock) error {
-	return blockchain.config.DataBase.StoreShardBlockIndex(block.Hash(), block.Header.Height, block.Header.ShardID)
+func (blockchain *BlockChain) StoreShardBlockIndex(db database.DatabaseInterface, block *ShardBlock) error {
+	return db.StoreShardBlockIndex(block.Hash(), block.Header.Height, block.Header.ShardID)
 }
 
-func (blockchain *BlockChain) StoreTransactionIndex(txHash *common.Hash, blockHash *common.Hash, index int) error {
-	return blockchain.config.DataBase.StoreTransactionIndex(txHash, blockHash, index)
+func (blockchain *BlockChain) StoreTransactionIndex(db database.DatabaseInterface, txHash *common.Hash, blockHash *common.Hash, index int) error {
+	return db.StoreTransactionIndex(txHash, blockHash, index)
 }
 
 /*
 Uses an existing database to update the set of used tx by saving list nullifier of privacy,
 this is a list tx-out which are used by a new tx
 */
-func (blockchain *BlockChain) StoreSerialNumbersFromTxViewPoint(view TxViewPoint) error {
+func (blockchain *BlockChain) StoreSerialNumbersFromTxViewPoint(db database.DatabaseInterface, view TxViewPoint) error {
 	for _, item1 := range view.listSerialNumbers {
-		err := blockchain.config.DataBase.StoreSerialNumbers(view.tokenID, item1, view.shardID)
+		err := db.StoreSerialNumbers(view.tokenID, item1, view.shardID)
 		if err != nil {
 			return err
 		}
@@ -448,7 +593,7 @@ func (blockchain *BlockChain) StoreSerialNumbersFromTxViewPoint(view TxViewPoint
 Uses an existing database to update the set of used tx by saving list SNDerivator of privacy,
 this is a list tx-out which are used by a new tx
 */
-func (blockchain *BlockChain) StoreSNDerivatorsFromTxViewPoint(view TxViewPoint, shardID byte) error {
+func (blockchain *BlockChain) StoreSNDerivatorsFromTxViewPoint(db database.DatabaseInterface, view TxViewPoint, shardID byte) error {
 	// commitment
 	keys := make([]string, 0, len(view.mapCommitments))
 	for k := range view.mapCommitments {
@@ -468,7 +613,7 @@ func (blockchain *BlockChain) StoreSNDerivatorsFromTxViewPoint(view TxViewPoint,
 		// if pubkeyShardID == shardID {
 		item1 := view.mapSnD[k]
 		for _, snd := range item1 {
-			err := blockchain.config.DataBase.StoreSNDerivators(view.tokenID, privacy.AddPaddingBigInt(&snd, privacy.BigIntSize), view.shardID)
+			err := db.StoreSNDerivators(view.tokenID, privacy.AddPaddingBigInt(&snd, privacy.BigIntSize), view.shardID)
 			if err != nil {
 				return err
 			}
@@ -499,7 +644,7 @@ func (blockchain *BlockChain) StoreSNDerivatorsFromTxViewPoint(view TxViewPoint,
 Uses an existing database to update the set of not used tx by saving list commitments of privacy,
 this is a list tx-in which are used by a new tx
 */
-func (blockchain *BlockChain) StoreCommitmentsFromTxViewPoint(view TxViewPoint, shardID byte) error {
+func (blockchain *BlockChain) StoreCommitmentsFromTxViewPoint(db database.DatabaseInterface, view TxViewPoint, shardID byte) error {
 
 	// commitment
 	keys := make([]string, 0, len(view.mapCommitments))
@@ -519,7 +664,7 @@ func (blockchain *BlockChain) StoreCommitmentsFromTxViewPoint(view TxViewPoint,
 		pubkeyShardID := common.GetShardIDFromLastByte(lastByte)
 		if pubkeyShardID == shardID {
 			for _, com := range item1 {
-				err = blockchain.config.DataBase.StoreCommitments(view.tokenID, pubkeyBytes, com, view.shardID)
+				err = db.StoreCommitments(view.tokenID, pubkeyBytes, com, view.shardID)
 				if err != nil {
 					return err
 				}
@@ -546,7 +691,7 @@ func (blockchain *BlockChain) StoreCommitmentsFromTxViewPoint(view TxViewPoint,
 		pubkeyShardID := common.GetShardIDFromLastByte(lastByte)
 		if pubkeyShardID == shardID {
 			for _, outcoin := range item1 {
-				err = blockchain.config.DataBase.StoreOutputCoins(view.tokenID, pubkeyBytes, outcoin.Bytes(), pubkeyShardID)
+				err = db.StoreOutputCoins(view.tokenID, pubkeyBytes, outcoin.Bytes(), pubkeyShardID)
 				if err != nil {
 					return err
 				}
@@ -559,10 +704,10 @@ func (blockchain *BlockChain) StoreCommitmentsFromTxViewPoint(view TxViewPoint,
 // CreateAndSaveTxViewPointFromBlock - fetch data from block, put into txviewpoint variable and save into db
 // @note: still storage full data of commitments, serialnumbersm snderivator to check double spend
 // @note: this function only work for transaction transfer token/constant within shard
-func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBlock) error {
+func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(db database.DatabaseInterface, block *ShardBlock) error {
 	// Fetch data from block into tx View point
 	view := NewTxViewPoint(block.Header.ShardID)
-	err := view.fetchTxViewPointFromBlock(blockchain.config.DataBase, block)
+	err := view.fetchTxViewPointFromBlock(db, block)
 	if err != nil {
 		return err
 	}
@@ -573,7 +718,7 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 		case transaction.CustomTokenInit:
 			{
 				Logger.log.Info("Store custom token when it is issued", customTokenTx.TxTokenData.PropertyID, customTokenTx.TxTokenData.PropertySymbol, customTokenTx.TxTokenData.PropertyName)
-				err = blockchain.config.DataBase.StoreCustomToken(&customTokenTx.TxTokenData.PropertyID, customTokenTx.Hash()[:])
+				err = db.StoreCustomToken(&customTokenTx.TxTokenData.PropertyID, customTokenTx.Hash()[:])
 				if err != nil {
 					return err
 				}
@@ -581,11 +726,11 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 		case transaction.CustomTokenCrossShard:
 			{
 				// 0xsirrush updated: check existed token ID
-				existedToken := blockchain.CustomTokenIDExisted(&customTokenTx.TxTokenData.PropertyID)
+				existedToken := db.CustomTokenIDExisted(&customTokenTx.TxTokenData.PropertyID)
 				//If don't exist then create
 				if !existedToken {
 					Logger.log.Info("Store Cross Shard Custom if It's not existed in DB", customTokenTx.TxTokenData.PropertyID, customTokenTx.TxTokenData.PropertySymbol, customTokenTx.TxTokenData.PropertyName)
-					err = blockchain.config.DataBase.StoreCustomToken(&customTokenTx.TxTokenData.PropertyID, customTokenTx.Hash()[:])
+					err = db.StoreCustomToken(&customTokenTx.TxTokenData.PropertyID, customTokenTx.Hash()[:])
 					if err != nil {
 						Logger.log.Error("CreateAndSaveTxViewPointFromBlock", err)
 					}
@@ -597,7 +742,7 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 				//If don't exist then create
 				if _, ok := listCustomToken[customTokenTx.TxTokenData.PropertyID]; !ok {
 					Logger.log.Info("Store Cross Shard Custom if It's not existed in DB", customTokenTx.TxTokenData.PropertyID, customTokenTx.TxTokenData.PropertySymbol, customTokenTx.TxTokenData.PropertyName)
-					err = blockchain.config.DataBase.StoreCustomToken(&customTokenTx.TxTokenData.PropertyID, customTokenTx.Hash()[:])
+					err = db.StoreCustomToken(&customTokenTx.TxTokenData.PropertyID, customTokenTx.Hash()[:])
 				}*/
 			}
 		case transaction.CustomTokenTransfer:
@@ -609,12 +754,12 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 		// Reject Double spend UTXO before enter this state
 		//fmt.Printf("StoreCustomTokenPaymentAddresstHistory/CustomTokenTx: \n VIN %+v VOUT %+v \n", customTokenTx.TxTokenData.Vins, customTokenTx.TxTokenData.Vouts)
 		Logger.log.Info("Store Custom Token History")
-		err = blockchain.StoreCustomTokenPaymentAddresstHistory(customTokenTx, block.Header.ShardID)
+		err = blockchain.StoreCustomTokenPaymentAddresstHistory(db, customTokenTx, block.Header.ShardID)
 		if err != nil {
 			// Skip double spend
 			return err
 		}
-		err = blockchain.config.DataBase.StoreCustomTokenTx(&customTokenTx.TxTokenData.PropertyID, block.Header.ShardID, block.Header.Height, indexTx, customTokenTx.Hash()[:])
+		err = db.StoreCustomTokenTx(&customTokenTx.TxTokenData.PropertyID, block.Header.ShardID, block.Header.Height, indexTx, customTokenTx.Hash()[:])
 		if err != nil {
 			return err
 		}
@@ -622,7 +767,7 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 		// replace 1000 with proper value for snapshot
 		if block.Header.Height%1000 == 0 {
 			// list of unreward-utxo
-			blockchain.config.customTokenRewardSnapshot, err = blockchain.config.DataBase.GetCustomTokenPaymentAddressesBalance(&customTokenTx.TxTokenData.PropertyID)
+			blockchain.config.customTokenRewardSnapshot, err = db.GetCustomTokenPaymentAddressesBalance(&customTokenTx.TxTokenData.PropertyID)
 			if err != nil {
 				return err
 			}
@@ -639,7 +784,7 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 		case transaction.CustomTokenInit:
 			{
 				Logger.log.Info("Store custom token when it is issued", privacyCustomTokenTx.TxTokenPrivacyData.PropertyID, privacyCustomTokenTx.TxTokenPrivacyData.PropertySymbol, privacyCustomTokenTx.TxTokenPrivacyData.PropertyName)
-				err = blockchain.config.DataBase.StorePrivacyCustomToken(&privacyCustomTokenTx.TxTokenPrivacyData.PropertyID, privacyCustomTokenTx.Hash()[:])
+				err = db.StorePrivacyCustomToken(&privacyCustomTokenTx.TxTokenPrivacyData.PropertyID, privacyCustomTokenTx.Hash()[:])
 				if err != nil {
 					return err
 				}
@@ -649,22 +794,22 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 				Logger.log.Info("Transfer custom token %+v", privacyCustomTokenTx)
 			}
 		}
-		err = blockchain.config.DataBase.StorePrivacyCustomTokenTx(&privacyCustomTokenTx.TxTokenPrivacyData.PropertyID, block.Header.ShardID, block.Header.Height, indexTx, privacyCustomTokenTx.Hash()[:])
+		err = db.StorePrivacyCustomTokenTx(&privacyCustomTokenTx.TxTokenPrivacyData.PropertyID, block.Header.ShardID, block.Header.Height, indexTx, privacyCustomTokenTx.Hash()[:])
 		if err != nil {
 			return err
 		}
 
-		err = blockchain.StoreSerialNumbersFromTxViewPoint(*privacyCustomTokenSubView)
+		err = blockchain.StoreSerialNumbersFromTxViewPoint(db, *privacyCustomTokenSubView)
 		if err != nil {
 			return err
 		}
 
-		err = blockchain.StoreCommitmentsFromTxViewPoint(*privacyCustomTokenSubView, block.Header.ShardID)
+		err = blockchain.StoreCommitmentsFromTxViewPoint(db, *privacyCustomTokenSubView, block.Header.ShardID)
 		if err != nil {
 			return err
 		}
 
-		err = blockchain.StoreSNDerivatorsFromTxViewPoint(*privacyCustomTokenSubView, block.Header.ShardID)
+		err = blockchain.StoreSNDerivatorsFromTxViewPoint(db, *privacyCustomTokenSubView, block.Header.ShardID)
 		if err != nil {
 			return err
 		}
@@ -673,17 +818,17 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 	// Update the list nullifiers and commitment, snd set using the state of the used tx view point. This
 	// entails adding the new
 	// ones created by the block.
-	err = blockchain.StoreSerialNumbersFromTxViewPoint(*view)
+	err = blockchain.StoreSerialNumbersFromTxViewPoint(db, *view)
 	if err != nil {
 		return err
 	}
 
-	err = blockchain.StoreCommitmentsFromTxViewPoint(*view, block.Header.ShardID)
+	err = blockchain.StoreCommitmentsFromTxViewPoint(db, *view, block.Header.ShardID)
 	if err != nil {
 		return err
 	}
 
-	err = blockchain.StoreSNDerivatorsFromTxViewPoint(*view, block.Header.ShardID)
+	err = blockchain.StoreSNDerivatorsFromTxViewPoint(db, *view, block.Header.ShardID)
 	if err != nil {
 		return err
 	}
@@ -691,20 +836,20 @@ func (blockchain *BlockChain) CreateAndSaveTxViewPointFromBlock(block *ShardBloc
 	return nil
 }
 
-func (blockchain *BlockChain) CreateAndSaveCrossTransactionCoinViewPointFromBlock(block *ShardBlock) error {
+func (blockchain *BlockChain) CreateAndSaveCrossTransactionCoinViewPointFromBlock(db database.DatabaseInterface, block *ShardBlock) error {
 	// Fetch data from block into tx View point
 	view := NewTxViewPoint(block.Header.ShardID)
 
-	err := view.fetchCrossTransactionViewPointFromBlock(blockchain.config.DataBase, block)
+	err := view.fetchCrossTransactionViewPointFromBlock(db, block)
 	if err != nil {
 		Logger.log.Error("CreateAndSaveCrossTransactionCoinViewPointFromBlock", err)
 	}
 	for _, privacyCustomTokenSubView := range view.privacyCustomTokenViewPoint {
 		// 0xsirrush updated: check existed tokenID
 		tokenID := privacyCustomTokenSubView.tokenID
-		existed := blockchain.PrivacyCustomTokenIDExisted(tokenID)
+		existed := db.PrivacyCustomTokenIDExisted(tokenID)
 		if !existed {
-			existedCrossShard := blockchain.PrivacyCustomTokenIDCrossShardExisted(tokenID)
+			existedCrossShard := db.PrivacyCustomTokenIDCrossShardExisted(tokenID)
 			if !existedCrossShard {
 				Logger.log.Info("Store custom token when it is issued ", tokenID, privacyCustomTokenSubView.privacyCustomTokenMetadata.PropertyName, privacyCustomTokenSubView.privacyCustomTokenMetadata.PropertySymbol, privacyCustomTokenSubView.privacyCustomTokenMetadata.Amount, privacyCustomTokenSubView.privacyCustomTokenMetadata.Mintable)
 				tokenDataBytes, _ := json.Marshal(privacyCustomTokenSubView.privacyCustomTokenMetadata)
@@ -713,7 +858,7 @@ func (blockchain *BlockChain) CreateAndSaveCrossTransactionCoinViewPointFromBloc
 				// json.Unmarshal(tokenDataBytes, &crossShardTokenPrivacyMetaData)
 				// fmt.Println("New Token CrossShardTokenPrivacyMetaData", crossShardTokenPrivacyMetaDatla)
 
-				if err := blockchain.config.DataBase.StorePrivacyCustomTokenCrossShard(tokenID, tokenDataBytes); err != nil {
+				if err := db.StorePrivacyCustomTokenCrossShard(tokenID, tokenDataBytes); err != nil {
 					return err
 				}
 			}
@@ -732,18 +877,18 @@ func (blockchain *BlockChain) CreateAndSaveCrossTransactionCoinViewPointFromBloc
 				// json.Unmarshal(tokenDataBytes, &crossShardTokenPrivacyMetaData)
 				// fmt.Println("New Token CrossShardTokenPrivacyMetaData", crossShardTokenPrivacyMetaData)
 
-				if err := blockchain.config.DataBase.StorePrivacyCustomTokenCrossShard(tokenID, tokenDataBytes); err != nil {
+				if err := db.StorePrivacyCustomTokenCrossShard(tokenID, tokenDataBytes); err != nil {
 					return err
 				}
 			}
 		}*/
 		// Store both commitment and outcoin
-		err = blockchain.StoreCommitmentsFromTxViewPoint(*privacyCustomTokenSubView, block.Header.ShardID)
+		err = blockchain.StoreCommitmentsFromTxViewPoint(db, *privacyCustomTokenSubView, block.Header.ShardID)
 		if err != nil {
 			return err
 		}
 		// store snd
-		err = blockchain.StoreSNDerivatorsFromTxViewPoint(*privacyCustomTokenSubView, block.Header.ShardID)
+		err = blockchain.StoreSNDerivatorsFromTxViewPoint(db, *privacyCustomTokenSubView, block.Header.ShardID)
 		if err != nil {
 			return err
 		}
@@ -752,12 +897,12 @@ func (blockchain *BlockChain) CreateAndSaveCrossTransactionCoinViewPointFromBloc
 	// Update the list nullifiers and commitment, snd set using the state of the used tx view point. This
 	// entails adding the new
 	// ones created by the block.
-	err = blockchain.StoreCommitmentsFromTxViewPoint(*view, block.Header.ShardID)
+	err = blockchain.StoreCommitmentsFromTxViewPoint(db, *view, block.Header.ShardID)
 	if err != nil {
 		return err
 	}
 
-	err = blockchain.StoreSNDerivatorsFromTxViewPoint(*view, block.Header.ShardID)
+	err = blockchain.StoreSNDerivatorsFromTxViewPoint(db, *view, block.Header.ShardID)
 	if err != nil {
 		return err
 	}
@@ -769,7 +914,7 @@ func (blockchain *BlockChain) CreateAndSaveCrossTransactionCoinViewPointFromBloc
 // 	KeyWallet: token-paymentAddress  -[-]-  {tokenId}  -[-]-  {paymentAddress}  -[-]-  {txHash}  -[-]-  {voutIndex}
 //   H: value-spent/unspent
 */
-func (blockchain *BlockChain) StoreCustomTokenPaymentAddresstHistory(customTokenTx *transaction.TxCustomToken, shardID byte) error {
+func (blockchain *BlockChain) StoreCustomTokenPaymentAddresstHistory(db database.DatabaseInterface, customTokenTx *transaction.TxCustomToken, shardID byte) error {
 	Splitter := lvdb.Splitter
 	TokenPaymentAddressPrefix := lvdb.TokenPaymentAddressPrefix
 	unspent := lvdb.Unspent
@@ -790,11 +935,11 @@ func (blockchain *BlockChain) StoreCustomTokenPaymentAddresstHistory(customToken
 		paymentAddressKey = append(paymentAddressKey, utxoHash[:]...)
 		paymentAddressKey = append(paymentAddressKey, Splitter...)
 		paymentAddressKey = append(paymentAddressKey, common.Int32ToBytes(int32(voutIndex))...)
-		_, err := blockchain.config.DataBase.HasValue(paymentAddressKey)
+		_, err := db.HasValue(paymentAddressKey)
 		if err != nil {
 			return err
 		}
-		value, err := blockchain.config.DataBase.Get(paymentAddressKey)
+		value, err := db.Get(paymentAddressKey)
 		if err != nil {
 			return err
 		}
@@ -805,7 +950,7 @@ func (blockchain *BlockChain) StoreCustomTokenPaymentAddresstHistory(customToken
 		}
 		// new value: {value}-spent-unreward/reward
 		newValues := values[0] + string(Splitter) + string(spent) + string(Splitter) + values[2]
-		if err := blockchain.config.DataBase.Put(paymentAddressKey, []byte(newValues)); err != nil {
+		if err := db.Put(paymentAddressKey, []byte(newValues)); err != nil {
 			return err
 		}
 	}
@@ -835,7 +980,7 @@ func (blockchain *BlockChain) StoreCustomTokenPaymentAddresstHistory(customToken
 		paymentAddressKey = append(paymentAddressKey, utxoHash[:]...)
 		paymentAddressKey = append(paymentAddressKey, Splitter...)
 		paymentAddressKey = append(paymentAddressKey, common.Int32ToBytes(int32(voutIndex))...)
-		ok, err := blockchain.config.DataBase.HasValue(paymentAddressKey)
+		ok, err := db.HasValue(paymentAddressKey)
 		// Vout already exist
 		if ok {
 			return errors.New("UTXO already exist")
@@ -845,7 +990,7 @@ func (blockchain *BlockChain) StoreCustomTokenPaymentAddresstHistory(customToken
 		}
 		// init value: {value}-unspent-unreward
 		paymentAddressValue := strconv.Itoa(int(value)) + string(Splitter) + string(unspent) + string(Splitter) + string(unreward)
-		if err := blockchain.config.DataBase.Put(paymentAddressKey, []byte(paymentAddressValue)); err != nil {
+		if err := db.Put(paymentAddressKey, []byte(paymentAddressValue)); err != nil {
 			return err
 		}
 		fmt.Printf("STORE UTXO FOR CUSTOM TOKEN: tokenID %+v \n paymentAddress %+v \n txHash %+v, voutIndex %+v, value %+v \n", (customTokenTx.TxTokenData.PropertyID).String(), vout.PaymentAddress, customTokenTx.Hash(), voutIndex, value)
@@ -904,17 +1049,17 @@ func (blockchain *BlockChain) GetListOutputCoinsByKeyset(keyset *cashec.KeySet,
 	blockchain.BestState.Shard[shardID].lock.Lock()
 	defer blockchain.BestState.Shard[shardID].lock.Unlock()
 
-	outCointsInBytes, err := blockchain.config.DataBase.GetOutcoinsByPubkey(tokenID, keyset.PaymentAddress.Pk[:], shardID)
-	if err != nil {
-		return nil, err
-	}
 	// convert from []byte to object
 	outCoints := make([]*privacy.OutputCoin, 0)
-	for _, item := range outCointsInBytes {
+	err := blockchain.config.DataBase.IterateOutcoinsByPubkey(tokenID, keyset.PaymentAddress.Pk[:], shardID, func(item []byte) bool {
 		outcoin := &privacy.OutputCoin{}
 		outcoin.Init()
 		outcoin.SetBytes(item)
 		outCoints = append(outCoints, outcoin)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// loop on all outputcoin to decrypt data
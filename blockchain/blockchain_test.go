@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/constant-money/constant-chain/metadata"
+	"github.com/constant-money/constant-chain/transaction"
+)
+
+func newTestCacheChain(t *testing.T) (*BlockChain, *database.CacheDB) {
+	Logger.Init(common.NewBackend(ioutil.Discard).Logger("BlockChain test", true))
+	db, err := database.Open("memdb")
+	if err != nil {
+		t.Fatalf("could not open memdb: %+v", err)
+	}
+	cache, err := database.NewCacheDB(db, 100)
+	if err != nil {
+		t.Fatalf("database.NewCacheDB returns err: %+v", err)
+	}
+	return &BlockChain{config: Config{DataBase: cache}}, cache
+}
+
+// TestGetShardBlockByHashCopiesCachedBlock changes a block which is returned
+// from cache, the cached block stays the same
+func TestGetShardBlockByHashCopiesCachedBlock(t *testing.T) {
+	chain, cache := newTestCacheChain(t)
+	stored := &ShardBlock{
+		ValidatorsIdx: [][]int{{0}, {0, 1}},
+		Header:        ShardHeader{Height: 2, CrossShards: []byte{1}},
+		Body: ShardBody{
+			Instructions: [][]string{{"inst"}},
+			Transactions: []metadata.Transaction{&transaction.Tx{Type: common.TxNormalType}},
+		},
+	}
+	if err := cache.StoreShardBlock(stored, 0); err != nil {
+		t.Fatalf("cache.StoreShardBlock returns err: %+v", err)
+	}
+	block, _, err := chain.GetShardBlockByHash(stored.Hash())
+	if err != nil {
+		t.Fatalf("chain.GetShardBlockByHash returns err: %+v", err)
+	}
+	block.Header.Height = 3
+	block.Header.CrossShards[0] = 2
+	block.ValidatorsIdx[1][0] = 5
+	block.Body.Instructions[0][0] = "changed"
+	block.Body.Transactions[0] = nil
+
+	cached, _, err := chain.GetShardBlockByHash(stored.Hash())
+	if err != nil {
+		t.Fatalf("chain.GetShardBlockByHash returns err: %+v", err)
+	}
+	if cached == block {
+		t.Fatalf("cached block should not be returned")
+	}
+	if cached.Header.Height != 2 || cached.Header.CrossShards[0] != 1 || cached.ValidatorsIdx[1][0] != 0 ||
+		cached.Body.Instructions[0][0] != "inst" || cached.Body.Transactions[0] == nil {
+		t.Fatalf("cached block is changed: %+v", cached)
+	}
+}
+
+// TestGetBeaconBlockByHashCopiesCachedBlock changes a beacon block which is
+// returned from cache, the cached block stays the same
+func TestGetBeaconBlockByHashCopiesCachedBlock(t *testing.T) {
+	chain, cache := newTestCacheChain(t)
+	stored := &BeaconBlock{
+		ValidatorsIdx: [][]int{{0}, {0, 1}},
+		Header:        BeaconHeader{Height: 2},
+		Body: BeaconBody{
+			ShardState:   map[byte][]ShardState{0: {{Height: 2, CrossShard: []byte{1}}}},
+			Instructions: [][]string{{"inst"}},
+		},
+	}
+	if err := cache.StoreBeaconBlock(stored); err != nil {
+		t.Fatalf("cache.StoreBeaconBlock returns err: %+v", err)
+	}
+	block, _, err := chain.GetBeaconBlockByHash(stored.Hash())
+	if err != nil {
+		t.Fatalf("chain.GetBeaconBlockByHash returns err: %+v", err)
+	}
+	block.Header.Height = 3
+	block.ValidatorsIdx[1][0] = 5
+	block.Body.Instructions[0][0] = "changed"
+	block.Body.ShardState[0][0].CrossShard[0] = 2
+	block.Body.ShardState[1] = nil
+
+	cached, _, err := chain.GetBeaconBlockByHash(stored.Hash())
+	if err != nil {
+		t.Fatalf("chain.GetBeaconBlockByHash returns err: %+v", err)
+	}
+	if cached.Header.Height != 2 || cached.ValidatorsIdx[1][0] != 0 || cached.Body.Instructions[0][0] != "inst" ||
+		cached.Body.ShardState[0][0].CrossShard[0] != 1 || len(cached.Body.ShardState) != 1 {
+		t.Fatalf("cached beacon block is changed: %+v", cached)
+	}
+}
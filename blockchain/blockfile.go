@@ -0,0 +1,186 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+)
+
+/*
+Block file is a portable stream of beacon and shard blocks, it is written by
+ExportBlocks and replayed on another node by ImportBlocks.
+It starts with blockFileMagic, blockFileVersion and the chain name, then
+every block is a record of kind (beacon or shard), shard id, length of block
+and json of block. Beacon blocks are written before shard blocks, so beacon
+blocks which shard blocks refer to are inserted first
+*/
+const (
+	blockFileMagic   = "CONSTANT-BLOCKS"
+	blockFileVersion = 1
+
+	blockFileBeacon = 0
+	blockFileShard  = 1
+
+	// maxBlockFileRecord is the max length of a block in block file
+	maxBlockFileRecord = 64 * 1024 * 1024
+)
+
+func writeBlockRecord(w io.Writer, kind byte, shardID byte, data []byte) error {
+	header := make([]byte, 6)
+	header[0] = kind
+	header[1] = shardID
+	binary.BigEndian.PutUint32(header[2:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+/*
+ExportBlocks writes all beacon and shard blocks of db to w, it returns the
+number of written blocks. It fails if a block is pruned
+*/
+func ExportBlocks(db database.DatabaseInterface, chainName string, w io.Writer) (uint64, error) {
+	bw := bufio.NewWriter(w)
+	header := append([]byte(blockFileMagic), blockFileVersion, byte(len(chainName)))
+	header = append(header, []byte(chainName)...)
+	if _, err := bw.Write(header); err != nil {
+		return 0, NewBlockChainError(BlockFileError, err)
+	}
+	count := uint64(0)
+	for height := uint64(1); ; height++ {
+		hash, err := db.GetBeaconBlockHashByIndex(height)
+		if err != nil {
+			break
+		}
+		data, err := db.FetchBeaconBlock(hash)
+		if err != nil {
+			return count, NewBlockChainError(DBError, err)
+		}
+		if err := writeBlockRecord(bw, blockFileBeacon, 0, data); err != nil {
+			return count, NewBlockChainError(BlockFileError, err)
+		}
+		count++
+	}
+	for shardID := 0; shardID < common.MAX_SHARD_NUMBER; shardID++ {
+		for height := uint64(1); ; height++ {
+			hash, err := db.GetBlockByIndex(height, byte(shardID))
+			if err != nil {
+				break
+			}
+			data, err := db.FetchBlock(hash)
+			if err != nil {
+				return count, NewBlockChainError(DBError, err)
+			}
+			if err := writeBlockRecord(bw, blockFileShard, byte(shardID), data); err != nil {
+				return count, NewBlockChainError(BlockFileError, err)
+			}
+			count++
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return count, NewBlockChainError(BlockFileError, err)
+	}
+	return count, nil
+}
+
+/*
+ImportBlocks reads a block file from r and inserts its blocks with
+InsertBeaconBlock and InsertShardBlock. Blocks which are already in chain
+are skipped. Every block is verified like a block from a peer, so a block
+file can't put blocks in chain which the node wouldn't accept.
+It returns the number of inserted blocks
+*/
+func (blockchain *BlockChain) ImportBlocks(r io.Reader) (uint64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(blockFileMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return 0, NewBlockChainError(BlockFileError, err)
+	}
+	if string(header[:len(blockFileMagic)]) != blockFileMagic {
+		return 0, NewBlockChainError(BlockFileError, errors.New("not a block file"))
+	}
+	if header[len(blockFileMagic)] != blockFileVersion {
+		return 0, NewBlockChainError(BlockFileError, fmt.Errorf("unsupported block file version %d", header[len(blockFileMagic)]))
+	}
+	chainName := make([]byte, header[len(blockFileMagic)+1])
+	if _, err := io.ReadFull(br, chainName); err != nil {
+		return 0, NewBlockChainError(BlockFileError, err)
+	}
+	if string(chainName) != blockchain.config.ChainParams.Name {
+		return 0, NewBlockChainError(BlockFileError, fmt.Errorf("block file of chain %s can't be imported to chain %s", chainName, blockchain.config.ChainParams.Name))
+	}
+
+	count := uint64(0)
+	recordHeader := make([]byte, 6)
+	for {
+		if _, err := io.ReadFull(br, recordHeader); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return count, NewBlockChainError(BlockFileError, err)
+		}
+		length := binary.BigEndian.Uint32(recordHeader[2:])
+		if length > maxBlockFileRecord {
+			return count, NewBlockChainError(BlockFileError, fmt.Errorf("block of %d bytes is too large", length))
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return count, NewBlockChainError(BlockFileError, err)
+		}
+		inserted, err := blockchain.importBlock(recordHeader[0], recordHeader[1], data)
+		if err != nil {
+			return count, err
+		}
+		if inserted {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// importBlock inserts a block of block file, it returns false if block is
+// already in chain
+func (blockchain *BlockChain) importBlock(kind byte, shardID byte, data []byte) (bool, error) {
+	switch kind {
+	case blockFileBeacon:
+		block := &BeaconBlock{}
+		if err := json.Unmarshal(data, block); err != nil {
+			return false, NewBlockChainError(UnmashallJsonBlockError, err)
+		}
+		if block.Header.Height <= blockchain.BestState.Beacon.BeaconHeight {
+			return false, nil
+		}
+		if err := blockchain.InsertBeaconBlock(block, false); err != nil {
+			return false, err
+		}
+	case blockFileShard:
+		block := &ShardBlock{}
+		if err := json.Unmarshal(data, block); err != nil {
+			return false, NewBlockChainError(UnmashallJsonBlockError, err)
+		}
+		if block.Header.ShardID != shardID {
+			return false, NewBlockChainError(BlockFileError, fmt.Errorf("block of shard %d is in record of shard %d", block.Header.ShardID, shardID))
+		}
+		bestState, ok := blockchain.BestState.Shard[shardID]
+		if !ok {
+			return false, NewBlockChainError(ShardIDError, fmt.Errorf("shard %d is not active", shardID))
+		}
+		if block.Header.Height <= bestState.ShardHeight {
+			return false, nil
+		}
+		if err := blockchain.InsertShardBlock(block, false); err != nil {
+			return false, err
+		}
+	default:
+		return false, NewBlockChainError(BlockFileError, fmt.Errorf("unknown block kind %d", kind))
+	}
+	return true, nil
+}
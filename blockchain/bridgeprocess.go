@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"strconv"
 
+	"github.com/constant-money/constant-chain/database"
 	"github.com/constant-money/constant-chain/metadata"
 )
 
@@ -16,23 +17,23 @@ type ContractingReqAction struct {
 	Meta metadata.ContractingRequest `json:"meta"`
 }
 
-func (chain *BlockChain) processBridgeInstructions(block *BeaconBlock) error {
+func (chain *BlockChain) processBridgeInstructions(db database.DatabaseInterface, block *BeaconBlock) error {
 	for _, inst := range block.Body.Instructions {
 		if len(inst) < 2 {
 			continue // Not error, just not bridge instruction
 		}
 		switch inst[0] {
 		case strconv.Itoa(metadata.IssuingRequestMeta):
-			return chain.processIssuingReq(inst)
+			return chain.processIssuingReq(db, inst)
 
 		case strconv.Itoa(metadata.ContractingRequestMeta):
-			return chain.processContractingReq(inst)
+			return chain.processContractingReq(db, inst)
 		}
 	}
 	return nil
 }
 
-func (bc *BlockChain) processIssuingReq(inst []string) error {
+func (bc *BlockChain) processIssuingReq(db database.DatabaseInterface, inst []string) error {
 	actionContentStr := inst[1]
 	contentBytes, err := base64.StdEncoding.DecodeString(actionContentStr)
 	if err != nil {
@@ -44,14 +45,14 @@ func (bc *BlockChain) processIssuingReq(inst []string) error {
 		return err
 	}
 	md := issuingReqAction.Meta
-	err = bc.GetDatabase().CountUpDepositedAmtByTokenID(&md.TokenID, md.DepositedAmount)
+	err = db.CountUpDepositedAmtByTokenID(&md.TokenID, md.DepositedAmount)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (bc *BlockChain) processContractingReq(inst []string) error {
+func (bc *BlockChain) processContractingReq(db database.DatabaseInterface, inst []string) error {
 	actionContentStr := inst[1]
 	contentBytes, err := base64.StdEncoding.DecodeString(actionContentStr)
 	if err != nil {
@@ -63,7 +64,7 @@ func (bc *BlockChain) processContractingReq(inst []string) error {
 		return err
 	}
 	md := contractingReqAction.Meta
-	err = bc.GetDatabase().DeductAmtByTokenID(&md.TokenID, md.BurnedAmount)
+	err = db.DeductAmtByTokenID(&md.TokenID, md.BurnedAmount)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,240 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+)
+
+/*
+CompactShardBlock is a shard block which carries short ids of its transactions
+instead of the transactions. Peers of the same shard already have most of
+these transactions in mempool, so they rebuild the block from mempool and only
+request the transactions they miss.
+Transactions which are created by block producer (salary, return staking) are
+never in mempool of other nodes, so they are sent in PrefilledTxs
+*/
+type CompactShardBlock struct {
+	AggregatedSig     string  `json:"AggregatedSig"`
+	R                 string  `json:"R"`
+	ValidatorsIdx     [][]int `json:"ValidatorsIdx"` //[0]: R | [1]:AggregatedSig
+	ProducerSig       string  `json:"ProducerSig"`
+	Header            ShardHeader
+	Instructions      [][]string
+	CrossTransactions map[byte][]CrossTransaction
+	// ShortTxIDs has one short id for every transaction of block
+	ShortTxIDs []uint64
+	// PrefilledIndexes are indexes in block of PrefilledTxs
+	PrefilledIndexes []int
+	PrefilledTxs     []metadata.Transaction
+}
+
+/*
+ShardBlockTxs contains the transactions at Indexes of a shard block, it is the
+answer to a request of missing transactions of a compact block
+*/
+type ShardBlockTxs struct {
+	BlockHash    common.Hash
+	Indexes      []int
+	Transactions []metadata.Transaction
+}
+
+/*
+ShortTxID returns the short id of a transaction in a block, block hash is
+mixed in so short ids of the same transaction are different in every block
+*/
+func ShortTxID(blockHash common.Hash, txHash common.Hash) uint64 {
+	data := append(blockHash.GetBytes(), txHash.GetBytes()...)
+	hash := common.HashH(data)
+	return binary.LittleEndian.Uint64(hash[:8])
+}
+
+func isPrefilledTx(tx metadata.Transaction) bool {
+	return tx.IsSalaryTx() || tx.GetType() == common.TxRewardType || tx.GetType() == common.TxReturnStakingType
+}
+
+// NewCompactShardBlock builds compact block of a shard block
+func NewCompactShardBlock(block *ShardBlock) *CompactShardBlock {
+	blockHash := block.Header.Hash()
+	compact := &CompactShardBlock{
+		AggregatedSig:     block.AggregatedSig,
+		R:                 block.R,
+		ValidatorsIdx:     block.ValidatorsIdx,
+		ProducerSig:       block.ProducerSig,
+		Header:            block.Header,
+		Instructions:      block.Body.Instructions,
+		CrossTransactions: block.Body.CrossTransactions,
+		ShortTxIDs:        make([]uint64, len(block.Body.Transactions)),
+		PrefilledIndexes:  make([]int, 0),
+		PrefilledTxs:      make([]metadata.Transaction, 0),
+	}
+	for index, tx := range block.Body.Transactions {
+		compact.ShortTxIDs[index] = ShortTxID(blockHash, *tx.Hash())
+		if isPrefilledTx(tx) {
+			compact.PrefilledIndexes = append(compact.PrefilledIndexes, index)
+			compact.PrefilledTxs = append(compact.PrefilledTxs, tx)
+		}
+	}
+	return compact
+}
+
+func (compact *CompactShardBlock) Hash() *common.Hash {
+	hash := compact.Header.Hash()
+	return &hash
+}
+
+func (compact *CompactShardBlock) UnmarshalJSON(data []byte) error {
+	type Alias CompactShardBlock
+	temp := &struct {
+		PrefilledTxs []map[string]interface{}
+		*Alias
+	}{
+		Alias: (*Alias)(compact),
+	}
+	err := json.Unmarshal(data, &temp)
+	if err != nil {
+		return NewBlockChainError(UnmashallJsonBlockError, err)
+	}
+	compact.PrefilledTxs, err = parseTransactions(temp.PrefilledTxs)
+	return err
+}
+
+func (blockTxs *ShardBlockTxs) UnmarshalJSON(data []byte) error {
+	type Alias ShardBlockTxs
+	temp := &struct {
+		Transactions []map[string]interface{}
+		*Alias
+	}{
+		Alias: (*Alias)(blockTxs),
+	}
+	err := json.Unmarshal(data, &temp)
+	if err != nil {
+		return NewBlockChainError(UnmashallJsonBlockError, err)
+	}
+	blockTxs.Transactions, err = parseTransactions(temp.Transactions)
+	return err
+}
+
+/*
+ReconstructShardBlock rebuilds a shard block from compact block with
+prefilled transactions and transactions in mempool.
+It returns the block and indexes of transactions which are not found, these
+transactions are nil in block and must be filled by FillMissingTxs.
+A short id which matches more than one transaction in mempool is treated as
+missing
+*/
+func (blockchain *BlockChain) ReconstructShardBlock(compact *CompactShardBlock) (*ShardBlock, []int, error) {
+	if len(compact.PrefilledIndexes) != len(compact.PrefilledTxs) {
+		return nil, nil, NewBlockChainError(CompactBlockError, errors.New("prefilled indexes and transactions mismatch"))
+	}
+	block := &ShardBlock{
+		AggregatedSig: compact.AggregatedSig,
+		R:             compact.R,
+		ValidatorsIdx: compact.ValidatorsIdx,
+		ProducerSig:   compact.ProducerSig,
+		Header:        compact.Header,
+		Body: ShardBody{
+			Instructions:      compact.Instructions,
+			CrossTransactions: compact.CrossTransactions,
+			Transactions:      make([]metadata.Transaction, len(compact.ShortTxIDs)),
+		},
+	}
+	for i, index := range compact.PrefilledIndexes {
+		if index < 0 || index >= len(block.Body.Transactions) {
+			return nil, nil, NewBlockChainError(CompactBlockError, fmt.Errorf("prefilled index %d out of range", index))
+		}
+		block.Body.Transactions[index] = compact.PrefilledTxs[i]
+	}
+
+	// short ids of mempool, ambiguous short ids are mapped to nil
+	blockHash := compact.Header.Hash()
+	poolTxs := make(map[uint64]metadata.Transaction)
+	if blockchain.config.TxPool != nil {
+		for _, txDesc := range blockchain.config.TxPool.MiningDescs() {
+			shortID := ShortTxID(blockHash, *txDesc.Tx.Hash())
+			if _, ok := poolTxs[shortID]; ok {
+				poolTxs[shortID] = nil
+			} else {
+				poolTxs[shortID] = txDesc.Tx
+			}
+		}
+	}
+
+	missing := make([]int, 0)
+	for index, shortID := range compact.ShortTxIDs {
+		if block.Body.Transactions[index] != nil {
+			continue
+		}
+		tx, ok := poolTxs[shortID]
+		if !ok || tx == nil {
+			missing = append(missing, index)
+			continue
+		}
+		block.Body.Transactions[index] = tx
+	}
+	if len(missing) == 0 {
+		if err := block.verifyTxRoot(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return block, missing, nil
+}
+
+/*
+FillMissingTxs puts transactions which are received for a compact block into
+block, transaction root is verified when block has all of its transactions
+*/
+func (block *ShardBlock) FillMissingTxs(blockTxs *ShardBlockTxs) error {
+	if len(blockTxs.Indexes) != len(blockTxs.Transactions) {
+		return NewBlockChainError(CompactBlockError, errors.New("indexes and transactions mismatch"))
+	}
+	for i, index := range blockTxs.Indexes {
+		if index < 0 || index >= len(block.Body.Transactions) {
+			return NewBlockChainError(CompactBlockError, fmt.Errorf("tx index %d out of range", index))
+		}
+		block.Body.Transactions[index] = blockTxs.Transactions[i]
+	}
+	for index, tx := range block.Body.Transactions {
+		if tx == nil {
+			return NewBlockChainError(CompactBlockError, fmt.Errorf("tx at index %d is still missing", index))
+		}
+	}
+	return block.verifyTxRoot()
+}
+
+func (block *ShardBlock) verifyTxRoot() error {
+	txMerkle := Merkle{}.BuildMerkleTreeStore(block.Body.Transactions)
+	txRoot := &common.Hash{}
+	if len(txMerkle) > 0 {
+		txRoot = txMerkle[len(txMerkle)-1]
+	}
+	if !txRoot.IsEqual(&block.Header.TxRoot) {
+		return NewBlockChainError(CompactBlockError, errors.New("can't verify transaction root of reconstructed block"))
+	}
+	return nil
+}
+
+// GetShardBlockTxs returns the transactions at indexes of a stored shard block
+func (blockchain *BlockChain) GetShardBlockTxs(blockHash common.Hash, indexes []int) (*ShardBlockTxs, error) {
+	block, _, err := blockchain.GetShardBlockByHash(&blockHash)
+	if err != nil {
+		return nil, NewBlockChainError(DBError, err)
+	}
+	blockTxs := &ShardBlockTxs{
+		BlockHash:    blockHash,
+		Indexes:      make([]int, 0, len(indexes)),
+		Transactions: make([]metadata.Transaction, 0, len(indexes)),
+	}
+	for _, index := range indexes {
+		if index < 0 || index >= len(block.Body.Transactions) {
+			return nil, NewBlockChainError(CompactBlockError, fmt.Errorf("tx index %d out of range", index))
+		}
+		blockTxs.Indexes = append(blockTxs.Indexes, index)
+		blockTxs.Transactions = append(blockTxs.Transactions, block.Body.Transactions[index])
+	}
+	return blockTxs, nil
+}
@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+	"github.com/constant-money/constant-chain/transaction"
+)
+
+// testCompactPool is a mempool which only has mining descs
+type testCompactPool struct {
+	TxPool
+	txs []metadata.Transaction
+}
+
+func (pool *testCompactPool) MiningDescs() []*metadata.TxDesc {
+	descs := make([]*metadata.TxDesc, 0, len(pool.txs))
+	for _, tx := range pool.txs {
+		descs = append(descs, &metadata.TxDesc{Tx: tx})
+	}
+	return descs
+}
+
+// newTestCompactBlock returns a block whose first tx is created by producer
+// and the others are normal txs
+func newTestCompactBlock(normalTxs int) *ShardBlock {
+	txs := []metadata.Transaction{&transaction.Tx{Type: common.TxReturnStakingType}}
+	for i := 0; i < normalTxs; i++ {
+		txs = append(txs, &transaction.Tx{Type: common.TxNormalType, LockTime: int64(i + 1)})
+	}
+	block := &ShardBlock{Header: ShardHeader{Height: 2}, Body: ShardBody{Transactions: txs}}
+	txMerkle := Merkle{}.BuildMerkleTreeStore(txs)
+	block.Header.TxRoot = *txMerkle[len(txMerkle)-1]
+	return block
+}
+
+func TestReconstructShardBlockFromPool(t *testing.T) {
+	block := newTestCompactBlock(3)
+	chain := &BlockChain{}
+	chain.config.TxPool = &testCompactPool{txs: block.Body.Transactions[1:]}
+
+	compact := NewCompactShardBlock(block)
+	if len(compact.PrefilledTxs) != 1 || compact.PrefilledIndexes[0] != 0 {
+		t.Fatalf("tx created by producer should be prefilled, prefilled indexes %+v", compact.PrefilledIndexes)
+	}
+	rebuilt, missing, err := chain.ReconstructShardBlock(compact)
+	if err != nil {
+		t.Fatalf("ReconstructShardBlock returns err: %+v", err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("block should be rebuilt without missing txs, missing %+v", missing)
+	}
+	for index, tx := range rebuilt.Body.Transactions {
+		if !tx.Hash().IsEqual(block.Body.Transactions[index].Hash()) {
+			t.Fatalf("tx at index %d is not tx of block", index)
+		}
+	}
+}
+
+func TestReconstructShardBlockWithMissingTxs(t *testing.T) {
+	block := newTestCompactBlock(3)
+	chain := &BlockChain{}
+	// tx at index 2 isn't in mempool
+	chain.config.TxPool = &testCompactPool{txs: []metadata.Transaction{block.Body.Transactions[1], block.Body.Transactions[3]}}
+
+	rebuilt, missing, err := chain.ReconstructShardBlock(NewCompactShardBlock(block))
+	if err != nil {
+		t.Fatalf("ReconstructShardBlock returns err: %+v", err)
+	}
+	if len(missing) != 1 || missing[0] != 2 {
+		t.Fatalf("missing txs are %+v, want [2]", missing)
+	}
+	wrongTx := &transaction.Tx{Type: common.TxNormalType, LockTime: 100}
+	if err := rebuilt.FillMissingTxs(&ShardBlockTxs{Indexes: []int{2}, Transactions: []metadata.Transaction{wrongTx}}); err == nil {
+		t.Fatalf("block filled with a wrong tx should fail tx root check")
+	}
+	if err := rebuilt.FillMissingTxs(&ShardBlockTxs{Indexes: []int{5}, Transactions: []metadata.Transaction{wrongTx}}); err == nil {
+		t.Fatalf("tx index out of range should be rejected")
+	}
+	blockTxs := &ShardBlockTxs{Indexes: []int{2}, Transactions: []metadata.Transaction{block.Body.Transactions[2]}}
+	if err := rebuilt.FillMissingTxs(blockTxs); err != nil {
+		t.Fatalf("FillMissingTxs returns err: %+v", err)
+	}
+	if !rebuilt.Hash().IsEqual(block.Hash()) {
+		t.Fatalf("rebuilt block hash %+v, want %+v", rebuilt.Hash(), block.Hash())
+	}
+}
+
+func TestReconstructShardBlockWithWrongTxRoot(t *testing.T) {
+	block := newTestCompactBlock(2)
+	block.Header.TxRoot = common.Hash{}
+	chain := &BlockChain{}
+	chain.config.TxPool = &testCompactPool{txs: block.Body.Transactions[1:]}
+	if _, _, err := chain.ReconstructShardBlock(NewCompactShardBlock(block)); err == nil {
+		t.Fatalf("block whose txs don't match tx root should be rejected")
+	}
+}
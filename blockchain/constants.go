@@ -15,7 +15,13 @@ const (
 	defaultProcessPeerStateTime = 3 * time.Second  // in second
 	defaultMaxBlockSyncTime     = 1 * time.Second  // in second
 	defaultCacheCleanupTime     = 30 * time.Second // in second
-	workerNum                   = 5
+
+	// MinPruneDepth is the smallest prune depth, blocks in this depth are
+	// still read to verify new shard, beacon and cross shard blocks
+	MinPruneDepth = 1000
+	// maxPrunePerBlock is the max number of blocks which are pruned when a
+	// block is stored, so pruning of an old chain is spread over new blocks
+	maxPrunePerBlock = 100
 )
 
 // CONSTANT for network MAINNET
@@ -34,6 +40,9 @@ const (
 	MainnetRewardHalflife             = 100000
 	MainnetFeePerTxKb                 = 0
 	MainnetGenesisblockPaymentAddress = "1Uv2zzR4LgfX8ToQe8ub3bYcCLk3uDU1sm9U9hiu9EKYXoS77UdikfT9s8d5YjhsTJm61eazsMwk2otFZBYpPHwiMn8z6bKWWJRspsLky"
+
+	// shard height from which txs may have an expiry height
+	MainnetExpiryHeightActivation = 200000
 	// ------------- end Mainnet --------------------------------------
 )
 
@@ -69,6 +78,9 @@ const (
 	TestnetRewardHalflife             = 100000
 	TestnetFeePerTxKb                 = 2
 	TestnetGenesisBlockPaymentAddress = "1Uv46Pu4pqBvxCcPw7MXhHfiAD5Rmi2xgEE7XB6eQurFAt4vSYvfyGn3uMMB1xnXDq9nRTPeiAZv5gRFCBDroRNsXJF1sxPSjNQtivuHk"
+
+	// shard height from which txs may have an expiry height
+	TestnetExpiryHeightActivation = 100000
 )
 
 // for beacon
@@ -38,6 +38,9 @@ const (
 	InstructionError
 	SwapError
 	DuplicateBlockErr
+	CompactBlockError
+	BlockFileError
+	RevertBlockError
 )
 
 var ErrCodeMessage = map[int]struct {
@@ -70,6 +73,9 @@ var ErrCodeMessage = map[int]struct {
 	SwapError:                     {-24, "Swap Error"},
 	MashallJsonError:              {-25, "MashallJson Error"},
 	DuplicateBlockErr:             {-26, "Duplicate Block Error"},
+	CompactBlockError:             {-27, "Compact Block Error"},
+	BlockFileError:                {-28, "Block File Error"},
+	RevertBlockError:              {-29, "Revert Block Error"},
 }
 
 type BlockChainError struct {
@@ -34,6 +34,13 @@ type CrossShardPool interface {
 	UpdatePool() (map[byte]uint64, error)
 }
 
+// FeeEstimator estimates fee rates of a shard from the time txs wait in
+// mempool before they are in a block of the shard
+type FeeEstimator interface {
+	RegisterBlock(block *ShardBlock) error
+	Rollback(hash *common.Hash) error
+}
+
 type ShardPool interface {
 	RemoveBlock(uint64)
 	AddShardBlock(block *ShardBlock) error
@@ -70,6 +77,14 @@ type TxPool interface {
 	// RemoveTx remove tx from tx resource
 	RemoveTx(tx metadata.Transaction, isInBlock bool) error
 
+	// RemoveExpiredTxs removes txs of a shard which can't be in a block after
+	// block at height of the shard by their expiry height
+	RemoveExpiredTxs(shardID byte, height uint64)
+
+	// RecordTxsInBlock adds inclusion in block at height to lifecycle
+	// history of txs of the block
+	RecordTxsInBlock(txs []metadata.Transaction, height uint64)
+
 	RemoveCandidateList([]string)
 
 	RemoveTokenIDList([]string)
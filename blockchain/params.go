@@ -24,6 +24,17 @@ type Params struct {
 	GenesisShardBlock *ShardBlock
 	BasicReward       uint64
 	RewardHalflife    uint64
+
+	// ExpiryHeightActivation is the shard height from which txs may have an
+	// expiry height, a tx with expiry height isn't valid in a block below
+	// it. Nodes which don't know expiry height hash such a tx differently
+	ExpiryHeightActivation uint64
+}
+
+// IsExpiryHeightActive returns true if txs in a shard block at height may
+// have an expiry height
+func (params *Params) IsExpiryHeightActive(height uint64) bool {
+	return height >= params.ExpiryHeightActivation
 }
 
 type GenesisParams struct {
@@ -71,6 +82,8 @@ var ChainTestParam = Params{
 	GenesisShardBlock:  CreateShardGenesisBlock(1, genesisParamsTestnetNew),
 	BasicReward:        genesisParamsTestnetNew.BasicReward,
 	RewardHalflife:     genesisParamsTestnetNew.RewardHalflife,
+
+	ExpiryHeightActivation: TestnetExpiryHeightActivation,
 }
 
 // END TESTNET
@@ -100,4 +113,6 @@ var ChainMainParam = Params{
 	GenesisShardBlock:  CreateShardGenesisBlock(1, genesisParamsMainnetNew),
 	BasicReward:        genesisParamsMainnetNew.BasicReward,
 	RewardHalflife:     genesisParamsMainnetNew.RewardHalflife,
+
+	ExpiryHeightActivation: MainnetExpiryHeightActivation,
 }
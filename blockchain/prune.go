@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"encoding/json"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+)
+
+// prunedBlock is a stored block of which only header is kept
+type prunedBlock struct {
+	Header json.RawMessage
+}
+
+/*
+pruneBlocks deletes bodies of blocks which are older than PruneDepth from
+height, headers are kept. It is called in the transaction which stores the
+block at height, so pruning is committed with the block. At most
+maxPrunePerBlock blocks are pruned at a time, genesis block is never pruned
+*/
+func (blockchain *BlockChain) pruneBlocks(db database.DatabaseInterface, isBeacon bool, shardID byte, height uint64) error {
+	depth := blockchain.config.PruneDepth
+	if depth == 0 || height <= depth+1 {
+		return nil
+	}
+	prunedHeight, err := db.FetchPrunedHeight(isBeacon, shardID)
+	if err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	if prunedHeight < 1 {
+		prunedHeight = 1
+	}
+	toHeight := height - depth
+	if toHeight > prunedHeight+maxPrunePerBlock {
+		toHeight = prunedHeight + maxPrunePerBlock
+	}
+	if toHeight <= prunedHeight {
+		return nil
+	}
+	for h := prunedHeight + 1; h <= toHeight; h++ {
+		var hash *common.Hash
+		var data []byte
+		if isBeacon {
+			hash, err = db.GetBeaconBlockHashByIndex(h)
+			if err == nil {
+				data, err = db.FetchBeaconBlock(hash)
+			}
+		} else {
+			hash, err = db.GetBlockByIndex(h, shardID)
+			if err == nil {
+				data, err = db.FetchBlock(hash)
+			}
+		}
+		if err != nil {
+			if database.IsBlockPruned(err) {
+				continue
+			}
+			return NewBlockChainError(DBError, err)
+		}
+		block := prunedBlock{}
+		if err := json.Unmarshal(data, &block); err != nil {
+			return NewBlockChainError(UnmashallJsonBlockError, err)
+		}
+		if err := db.PruneBlock(hash, block.Header); err != nil {
+			return NewBlockChainError(DBError, err)
+		}
+	}
+	Logger.log.Debugf("Pruned blocks to height %d, beacon %t, shard %d", toHeight, isBeacon, shardID)
+	if err := db.StorePrunedHeight(isBeacon, shardID, toHeight); err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	return nil
+}
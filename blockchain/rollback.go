@@ -0,0 +1,262 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+)
+
+/*
+Blocks are reverted with their undo logs, which are stored with the blocks
+in ProcessStoreShardBlock and processStoreBeaconBlock. Reverting a block
+restores its commitments, serial numbers, SN derivators, token balances,
+bridge amounts, indexes and best state in database, then best state is
+reloaded from database. Genesis blocks, pruned blocks and blocks stored
+before undo logs can't be reverted. Transactions of reverted blocks are not
+added back to mempool.
+A better fork of best block is verified against the best state of its parent
+and against the database transaction in which best block is reverted, then
+the fork is stored in the same transaction
+*/
+
+// countSignatures returns number of distinct validators which signed a block
+func countSignatures(validatorsIdx [][]int) int {
+	if len(validatorsIdx) < 2 {
+		return 0
+	}
+	signers := make(map[int]struct{}, len(validatorsIdx[1]))
+	for _, idx := range validatorsIdx[1] {
+		signers[idx] = struct{}{}
+	}
+	return len(signers)
+}
+
+// isBetterShardFork returns true if block has the same parent as best block
+// of its shard and claims more signatures. Signatures of block are not
+// verified here, it must be verified against the parent best state
+func (blockchain *BlockChain) isBetterShardFork(block *ShardBlock) bool {
+	bestBlock := blockchain.BestState.Shard[block.Header.ShardID].BestBlock
+	if bestBlock == nil || bestBlock.Header.Height <= 1 || block.Header.Height != bestBlock.Header.Height {
+		return false
+	}
+	if !block.Header.PrevBlockHash.IsEqual(&bestBlock.Header.PrevBlockHash) {
+		return false
+	}
+	return countSignatures(block.ValidatorsIdx) > countSignatures(bestBlock.ValidatorsIdx)
+}
+
+// isBetterBeaconFork returns true if block has the same parent as best
+// beacon block and claims more signatures. Signatures of block are not
+// verified here, it must be verified against the parent best state
+func (blockchain *BlockChain) isBetterBeaconFork(block *BeaconBlock) bool {
+	bestBlock := &blockchain.BestState.Beacon.BestBlock
+	if bestBlock.Header.Height <= 1 || block.Header.Height != bestBlock.Header.Height {
+		return false
+	}
+	if !block.Header.PrevBlockHash.IsEqual(&bestBlock.Header.PrevBlockHash) {
+		return false
+	}
+	return countSignatures(block.ValidatorsIdx) > countSignatures(bestBlock.ValidatorsIdx)
+}
+
+/*
+forkView returns a view of blockchain which reads database from db, the
+transaction in which best block is reverted, so a fork is verified against
+the state of its parent. The view is only used to verify blocks
+*/
+func (blockchain *BlockChain) forkView(db database.DatabaseInterface) *BlockChain {
+	config := blockchain.config
+	config.DataBase = db
+	return &BlockChain{BestState: blockchain.BestState, config: config}
+}
+
+/*
+RevertShardBlocks reverts the best count blocks of a shard, it returns after
+the first block which can't be reverted
+*/
+func (blockchain *BlockChain) RevertShardBlocks(shardID byte, count uint64) error {
+	if _, ok := blockchain.BestState.Shard[shardID]; !ok {
+		return NewBlockChainError(ShardIDError, fmt.Errorf("shard %d is not active", shardID))
+	}
+	blockchain.BestState.Shard[shardID].lock.Lock()
+	defer func() {
+		blockchain.BestState.Shard[shardID].lock.Unlock()
+	}()
+	for i := uint64(0); i < count; i++ {
+		if err := blockchain.revertShardBlock(shardID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBeaconBlocks reverts the best count beacon blocks, it returns after
+// the first block which can't be reverted
+func (blockchain *BlockChain) RevertBeaconBlocks(count uint64) error {
+	blockchain.chainLock.Lock()
+	defer blockchain.chainLock.Unlock()
+	for i := uint64(0); i < count; i++ {
+		if err := blockchain.revertBeaconBlock(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+revertShardBlock reverts best block of a shard. Caller must hold lock of best
+state of the shard, the lock is handed over to the reloaded best state
+*/
+func (blockchain *BlockChain) revertShardBlock(shardID byte) error {
+	blockHash := blockchain.BestState.Shard[shardID].BestBlockHash
+	db, prevBestState, err := blockchain.beginRevertShardBlock(shardID)
+	if err != nil {
+		return err
+	}
+	defer db.Rollback()
+	if err := db.Commit(); err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	blockchain.setBestStateShard(shardID, prevBestState)
+	blockchain.rollbackFeeEstimator(shardID, &blockHash)
+	Logger.log.Infof("SHARD %+v | Reverted to block height %+v at hash %+v", shardID, prevBestState.ShardHeight, prevBestState.BestBlockHash)
+	return nil
+}
+
+/*
+beginRevertShardBlock reverts best block of a shard in a new database
+transaction and returns the transaction with best state of the parent block.
+Caller must commit or roll back the transaction, best state in memory is not
+changed
+*/
+func (blockchain *BlockChain) beginRevertShardBlock(shardID byte) (database.Transaction, *BestStateShard, error) {
+	bestState := blockchain.BestState.Shard[shardID]
+	if bestState.ShardHeight <= 1 || bestState.BestBlock == nil {
+		return nil, nil, NewBlockChainError(RevertBlockError, errors.New("genesis block can't be reverted"))
+	}
+	blockHash := bestState.BestBlockHash
+	prevBlockHash := bestState.BestBlock.Header.PrevBlockHash
+	Logger.log.Infof("SHARD %+v | Revert block height %+v at hash %+v", shardID, bestState.ShardHeight, blockHash)
+
+	db, err := blockchain.config.DataBase.Begin()
+	if err != nil {
+		return nil, nil, NewBlockChainError(DBError, err)
+	}
+	if err := db.RevertBlock(&blockHash); err != nil {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(RevertBlockError, err)
+	}
+	bestStateBytes, err := db.FetchShardBestState(shardID)
+	if err != nil {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(DBError, err)
+	}
+	prevBestState := &BestStateShard{}
+	if err := json.Unmarshal(bestStateBytes, prevBestState); err != nil {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(UnmashallJsonBlockError, err)
+	}
+	if !prevBestState.BestBlockHash.IsEqual(&prevBlockHash) {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(RevertBlockError, fmt.Errorf("reverted best state is at block %+v instead of %+v", prevBestState.BestBlockHash, prevBlockHash))
+	}
+	return db, prevBestState, nil
+}
+
+/*
+setBestStateShard replaces best state of a shard in memory. Caller must hold
+lock of the current best state, the lock is handed over to the new one
+*/
+func (blockchain *BlockChain) setBestStateShard(shardID byte, bestState *BestStateShard) {
+	oldBestState := blockchain.BestState.Shard[shardID]
+	bestState.lock.Lock()
+	SetBestStateShard(shardID, bestState)
+	blockchain.BestState.Shard[shardID] = bestState
+	oldBestState.lock.Unlock()
+
+	if pool, ok := blockchain.config.ShardPool[shardID]; ok && pool != nil {
+		pool.SetShardState(bestState.ShardHeight)
+	}
+}
+
+// rollbackFeeEstimator drops observations of a reverted block from fee
+// estimator of its shard
+func (blockchain *BlockChain) rollbackFeeEstimator(shardID byte, blockHash *common.Hash) {
+	if feeEstimator, ok := blockchain.config.FeeEstimator[shardID]; ok {
+		if err := feeEstimator.Rollback(blockHash); err != nil {
+			Logger.log.Warnf("SHARD %+v | Fee estimator can't roll back block %+v: %+v", shardID, *blockHash, err)
+		}
+	}
+}
+
+// revertBeaconBlock reverts best beacon block, caller must hold chainLock
+func (blockchain *BlockChain) revertBeaconBlock() error {
+	db, prevBestState, err := blockchain.beginRevertBeaconBlock()
+	if err != nil {
+		return err
+	}
+	defer db.Rollback()
+	if err := db.Commit(); err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	blockchain.setBestStateBeacon(prevBestState)
+	Logger.log.Infof("Reverted beacon to block height %+v at hash %+v", prevBestState.BeaconHeight, prevBestState.BestBlockHash)
+	return nil
+}
+
+/*
+beginRevertBeaconBlock reverts best beacon block in a new database
+transaction and returns the transaction with best state of the parent block.
+Caller must commit or roll back the transaction, best state in memory is not
+changed
+*/
+func (blockchain *BlockChain) beginRevertBeaconBlock() (database.Transaction, *BestStateBeacon, error) {
+	bestState := blockchain.BestState.Beacon
+	if bestState.BeaconHeight <= 1 {
+		return nil, nil, NewBlockChainError(RevertBlockError, errors.New("genesis block can't be reverted"))
+	}
+	blockHash := bestState.BestBlockHash
+	prevBlockHash := bestState.BestBlock.Header.PrevBlockHash
+	Logger.log.Infof("Revert beacon block height %+v at hash %+v", bestState.BeaconHeight, blockHash)
+
+	db, err := blockchain.config.DataBase.Begin()
+	if err != nil {
+		return nil, nil, NewBlockChainError(DBError, err)
+	}
+	if err := db.RevertBlock(&blockHash); err != nil {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(RevertBlockError, err)
+	}
+	bestStateBytes, err := db.FetchBeaconBestState()
+	if err != nil {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(DBError, err)
+	}
+	prevBestState := &BestStateBeacon{}
+	if err := json.Unmarshal(bestStateBytes, prevBestState); err != nil {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(UnmashallJsonBlockError, err)
+	}
+	if !prevBestState.BestBlockHash.IsEqual(&prevBlockHash) {
+		db.Rollback()
+		return nil, nil, NewBlockChainError(RevertBlockError, fmt.Errorf("reverted best state is at block %+v instead of %+v", prevBestState.BestBlockHash, prevBlockHash))
+	}
+	return db, prevBestState, nil
+}
+
+// setBestStateBeacon replaces best beacon state in memory, caller must hold
+// chainLock
+func (blockchain *BlockChain) setBestStateBeacon(bestState *BestStateBeacon) {
+	SetBestStateBeacon(bestState)
+	blockchain.BestState.Beacon = GetBestStateBeacon()
+
+	if blockchain.config.BeaconPool != nil {
+		blockchain.config.BeaconPool.SetBeaconState(bestState.BeaconHeight)
+	}
+	if blockchain.config.ShardToBeaconPool != nil {
+		blockchain.config.ShardToBeaconPool.SetShardState(bestState.GetBestShardHeight())
+	}
+}
@@ -0,0 +1,223 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+)
+
+func newTestChain(t *testing.T) *BlockChain {
+	Logger.Init(common.NewBackend(ioutil.Discard).Logger("BlockChain test", true))
+	db, err := database.Open("memdb")
+	if err != nil {
+		t.Fatalf("could not open memdb: %+v", err)
+	}
+	chain := &BlockChain{}
+	if err := chain.Init(&Config{DataBase: db, ChainParams: &ChainTestParam}); err != nil {
+		t.Fatalf("chain.Init returns err: %+v", err)
+	}
+	return chain
+}
+
+// testSigners returns indexes of n validators which signed a block
+func testSigners(n int) []int {
+	signers := make([]int, n)
+	for i := range signers {
+		signers[i] = i
+	}
+	return signers
+}
+
+// storeTestShardBlock stores an empty block on best block of shard 0 like
+// InsertShardBlock does after the block is verified
+func storeTestShardBlock(t *testing.T, chain *BlockChain, signatures int) *ShardBlock {
+	bestState := chain.BestState.Shard[0]
+	block := &ShardBlock{
+		ValidatorsIdx: [][]int{{}, testSigners(signatures)},
+		Header: ShardHeader{
+			ShardID:       0,
+			Height:        bestState.ShardHeight + 1,
+			PrevBlockHash: bestState.BestBlockHash,
+			BeaconHeight:  bestState.BeaconHeight,
+		},
+	}
+	bestState.BestBlock = block
+	bestState.BestBlockHash = *block.Hash()
+	bestState.ShardHeight = block.Header.Height
+	if err := chain.ProcessStoreShardBlock(block); err != nil {
+		t.Fatalf("chain.ProcessStoreShardBlock returns err: %+v", err)
+	}
+	return block
+}
+
+func TestRevertShardBlocks(t *testing.T) {
+	chain := newTestChain(t)
+	db := chain.config.DataBase
+	genesisHash := chain.BestState.Shard[0].BestBlockHash
+
+	block2 := storeTestShardBlock(t, chain, 1)
+	block3 := storeTestShardBlock(t, chain, 1)
+	if err := chain.RevertShardBlocks(0, 2); err != nil {
+		t.Fatalf("chain.RevertShardBlocks returns err: %+v", err)
+	}
+	bestState := chain.BestState.Shard[0]
+	if bestState.ShardHeight != 1 || !bestState.BestBlockHash.IsEqual(&genesisHash) {
+		t.Fatalf("best state is at height %d, hash %+v", bestState.ShardHeight, bestState.BestBlockHash)
+	}
+	for _, block := range []*ShardBlock{block2, block3} {
+		if has, _ := db.HasBlock(block.Hash()); has {
+			t.Fatalf("block %d should be reverted", block.Header.Height)
+		}
+		if _, err := db.GetBlockByIndex(block.Header.Height, 0); err == nil {
+			t.Fatalf("index of block %d should be reverted", block.Header.Height)
+		}
+	}
+	if err := chain.RevertShardBlocks(0, 1); err == nil {
+		t.Fatalf("revert of genesis block should return err")
+	}
+
+	// reverted blocks can be stored again
+	block2 = storeTestShardBlock(t, chain, 1)
+	if hash, err := db.GetBlockByIndex(2, 0); err != nil || !hash.IsEqual(block2.Hash()) {
+		t.Fatalf("db.GetBlockByIndex returns %+v, %+v", hash, err)
+	}
+	if height, _, err := db.GetIndexOfBlock(block2.Hash()); err != nil || height != 2 {
+		t.Fatalf("db.GetIndexOfBlock returns %d, %+v", height, err)
+	}
+}
+
+func TestBetterShardFork(t *testing.T) {
+	chain := newTestChain(t)
+	block2 := storeTestShardBlock(t, chain, 2)
+	fork := &ShardBlock{
+		ValidatorsIdx: [][]int{{}, testSigners(2)},
+		Header:        block2.Header,
+	}
+	fork.Header.Timestamp++
+	if chain.isBetterShardFork(fork) {
+		t.Fatalf("fork with the same number of signatures should not be better")
+	}
+	fork.ValidatorsIdx[1] = []int{0, 1, 1, 0}
+	if chain.isBetterShardFork(fork) {
+		t.Fatalf("repeated signatures should be counted once")
+	}
+	fork.ValidatorsIdx[1] = testSigners(3)
+	if !chain.isBetterShardFork(fork) {
+		t.Fatalf("fork with more signatures should be better")
+	}
+	fork.Header.Height++
+	if chain.isBetterShardFork(fork) {
+		t.Fatalf("block at another height should not be a fork")
+	}
+}
+
+func TestInsertUnverifiedShardFork(t *testing.T) {
+	chain := newTestChain(t)
+	db := chain.config.DataBase
+	block2 := storeTestShardBlock(t, chain, 1)
+	fork := &ShardBlock{
+		ValidatorsIdx: [][]int{{}, testSigners(3)},
+		Header:        block2.Header,
+	}
+	fork.Header.Timestamp++
+	if !chain.isBetterShardFork(fork) {
+		t.Fatalf("fork with more signatures should be better")
+	}
+	// fork isn't signed, it must be rejected before best block is reverted
+	if err := chain.InsertShardBlock(fork, true); err == nil {
+		t.Fatalf("unsigned fork should be rejected")
+	}
+	bestState := chain.BestState.Shard[0]
+	if bestState.ShardHeight != 2 || !bestState.BestBlockHash.IsEqual(block2.Hash()) {
+		t.Fatalf("best state is at height %d, hash %+v", bestState.ShardHeight, bestState.BestBlockHash)
+	}
+	if has, _ := db.HasBlock(block2.Hash()); !has {
+		t.Fatalf("best block should not be reverted")
+	}
+	if has, _ := db.HasBlock(fork.Hash()); has {
+		t.Fatalf("fork should not be stored")
+	}
+	if hash, err := db.GetBlockByIndex(2, 0); err != nil || !hash.IsEqual(block2.Hash()) {
+		t.Fatalf("db.GetBlockByIndex returns %+v, %+v", hash, err)
+	}
+	// best state is unlocked after the fork is rejected
+	if err := chain.RevertShardBlocks(0, 1); err != nil {
+		t.Fatalf("chain.RevertShardBlocks returns err: %+v", err)
+	}
+}
+
+// TestRevertShardFork reverts a fork which replaced best block, the undo log
+// of the fork restores the parent and not the replaced block
+func TestRevertShardFork(t *testing.T) {
+	chain := newTestChain(t)
+	db := chain.config.DataBase
+	genesisHash := chain.BestState.Shard[0].BestBlockHash
+	block2 := storeTestShardBlock(t, chain, 1)
+	fork := &ShardBlock{
+		ValidatorsIdx: [][]int{{}, testSigners(3)},
+		Header:        block2.Header,
+	}
+	fork.Header.Timestamp++
+
+	// fork replaces best block like InsertShardBlock does after the fork is
+	// verified
+	chain.BestState.Shard[0].lock.Lock()
+	forkDB, parentBestState, err := chain.beginRevertShardBlock(0)
+	if err != nil {
+		t.Fatalf("chain.beginRevertShardBlock returns err: %+v", err)
+	}
+	defer forkDB.Rollback()
+	chain.setBestStateShard(0, parentBestState)
+	parentBestState.BestBlock = fork
+	parentBestState.BestBlockHash = *fork.Hash()
+	parentBestState.ShardHeight = fork.Header.Height
+	if err := chain.processStoreShardBlock(forkDB, fork); err != nil {
+		t.Fatalf("chain.processStoreShardBlock returns err: %+v", err)
+	}
+	if err := forkDB.Commit(); err != nil {
+		t.Fatalf("forkDB.Commit returns err: %+v", err)
+	}
+	chain.BestState.Shard[0].lock.Unlock()
+	if hash, err := db.GetBlockByIndex(2, 0); err != nil || !hash.IsEqual(fork.Hash()) {
+		t.Fatalf("db.GetBlockByIndex returns %+v, %+v", hash, err)
+	}
+
+	if err := chain.RevertShardBlocks(0, 1); err != nil {
+		t.Fatalf("chain.RevertShardBlocks returns err: %+v", err)
+	}
+	bestState := chain.BestState.Shard[0]
+	if bestState.ShardHeight != 1 || !bestState.BestBlockHash.IsEqual(&genesisHash) {
+		t.Fatalf("best state is at height %d, hash %+v", bestState.ShardHeight, bestState.BestBlockHash)
+	}
+	for _, block := range []*ShardBlock{block2, fork} {
+		if has, _ := db.HasBlock(block.Hash()); has {
+			t.Fatalf("block %+v should not be stored", *block.Hash())
+		}
+	}
+	if _, err := db.GetBlockByIndex(2, 0); err == nil {
+		t.Fatalf("index of fork should be reverted")
+	}
+}
+
+// TestForkView reads the database transaction in which best block is
+// reverted, blocks of a fork are verified against it
+func TestForkView(t *testing.T) {
+	chain := newTestChain(t)
+	block2 := storeTestShardBlock(t, chain, 1)
+	chain.BestState.Shard[0].lock.Lock()
+	defer chain.BestState.Shard[0].lock.Unlock()
+	forkDB, _, err := chain.beginRevertShardBlock(0)
+	if err != nil {
+		t.Fatalf("chain.beginRevertShardBlock returns err: %+v", err)
+	}
+	defer forkDB.Rollback()
+	view := chain.forkView(forkDB)
+	if has, _ := view.GetDatabase().HasBlock(block2.Hash()); has {
+		t.Fatalf("reverted block should not be in fork view")
+	}
+	if has, _ := chain.GetDatabase().HasBlock(block2.Hash()); !has {
+		t.Fatalf("best block should stay in database until the fork is stored")
+	}
+}
@@ -42,8 +42,20 @@ func (shardBody *ShardBody) UnmarshalJSON(data []byte) error {
 		return NewBlockChainError(UnmashallJsonBlockError, err)
 	}
 
-	// process tx from tx interface of temp
-	for _, txTemp := range temp.Transactions {
+	txs, err := parseTransactions(temp.Transactions)
+	if err != nil {
+		return err
+	}
+	shardBody.Transactions = append(shardBody.Transactions, txs...)
+
+	return nil
+}
+
+// parseTransactions parses txs which are unmarshalled as maps into
+// transactions of their type
+func parseTransactions(txsTemp []map[string]interface{}) ([]metadata.Transaction, error) {
+	txs := make([]metadata.Transaction, 0)
+	for _, txTemp := range txsTemp {
 		txTempJson, _ := json.MarshalIndent(txTemp, "", "\t")
 		Logger.log.Debugf("Tx json data: ", string(txTempJson))
 
@@ -67,22 +79,22 @@ func (shardBody *ShardBody) UnmarshalJSON(data []byte) error {
 			}
 		default:
 			{
-				return NewBlockChainError(UnmashallJsonBlockError, errors.New("can not parse a wrong tx"))
+				return nil, NewBlockChainError(UnmashallJsonBlockError, errors.New("can not parse a wrong tx"))
 			}
 		}
 
 		if parseErr != nil {
-			return NewBlockChainError(UnmashallJsonBlockError, parseErr)
+			return nil, NewBlockChainError(UnmashallJsonBlockError, parseErr)
 		}
 		/*meta, parseErr := metadata.ParseMetadata(txTemp["Metadata"])
 		if parseErr != nil {
-			return NewBlockChainError(UnmashallJsonBlockError, parseErr)
+			return nil, NewBlockChainError(UnmashallJsonBlockError, parseErr)
 		}
 		tx.SetMetadata(meta)*/
-		shardBody.Transactions = append(shardBody.Transactions, tx)
+		txs = append(txs, tx)
 	}
 
-	return nil
+	return txs, nil
 }
 
 /*
@@ -13,6 +13,7 @@ import (
 	"github.com/constant-money/constant-chain/cashec"
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/common/base58"
+	"github.com/constant-money/constant-chain/database"
 	"github.com/constant-money/constant-chain/metadata"
 	"github.com/constant-money/constant-chain/transaction"
 )
@@ -84,7 +85,10 @@ func (blockchain *BlockChain) VerifyPreSignShardBlock(block *ShardBlock, shardID
 func (blockchain *BlockChain) InsertShardBlock(block *ShardBlock, isValidated bool) error {
 	shardID := block.Header.ShardID
 	blockchain.BestState.Shard[shardID].lock.Lock()
-	defer blockchain.BestState.Shard[shardID].lock.Unlock()
+	// best state is replaced when a fork is chosen, unlock the current one
+	defer func() {
+		blockchain.BestState.Shard[shardID].lock.Unlock()
+	}()
 	Logger.log.Infof("SHARD %+v | Check block existence for insert height %+v at hash %+v", block.Header.ShardID, block.Header.Height, *block.Hash())
 	isExist, _ := blockchain.config.DataBase.HasBlock(block.Hash())
 	if isExist {
@@ -92,7 +96,11 @@ func (blockchain *BlockChain) InsertShardBlock(block *ShardBlock, isValidated bo
 		return NewBlockChainError(DuplicateBlockErr, errors.New("This block has been stored already"))
 	}
 	Logger.log.Infof("SHARD %+v | Begin Insert new block height %+v at hash %+v", block.Header.ShardID, block.Header.Height, *block.Hash())
-	if !isValidated {
+	bestBlockHash := &blockchain.BestState.Shard[shardID].BestBlockHash
+	isFork := !bestBlockHash.IsEqual(&block.Header.PrevBlockHash)
+	if isFork {
+		Logger.log.Infof("SHARD %+v | Verify Pre Processing of fork %+v after best block is reverted \n", block.Header.ShardID, *block.Hash())
+	} else if !isValidated {
 		Logger.log.Infof("SHARD %+v | Verify Pre Processing  Block %+v \n", block.Header.ShardID, *block.Hash())
 		if err := blockchain.VerifyPreProcessingShardBlock(block, shardID, false); err != nil {
 			return err
@@ -103,13 +111,47 @@ func (blockchain *BlockChain) InsertShardBlock(block *ShardBlock, isValidated bo
 	//========Verify block with previous best state
 	// check with current final best state
 	// block can only be insert if it match the current best state
-	bestBlockHash := &blockchain.BestState.Shard[shardID].BestBlockHash
-	if !bestBlockHash.IsEqual(&block.Header.PrevBlockHash) {
-		return NewBlockChainError(BeaconError, errors.New("beacon Block does not match with any Beacon State in cache or in Database"))
+	// a fork of best block which has more signatures replaces best block
+	// best block is reverted in a database transaction, the fork is verified
+	// with best state of its parent and the reverted database, then it is
+	// stored in the same transaction
+	var forkDB database.Transaction
+	var revertedBestState *BestStateShard
+	if isFork {
+		if !blockchain.isBetterShardFork(block) {
+			return NewBlockChainError(BeaconError, errors.New("beacon Block does not match with any Beacon State in cache or in Database"))
+		}
+		Logger.log.Infof("SHARD %+v | Block %+v is a better fork of best block, verify it with best state of parent", shardID, *block.Hash())
+		db, parentBestState, err := blockchain.beginRevertShardBlock(shardID)
+		if err != nil {
+			return err
+		}
+		defer db.Rollback()
+		// signatures of a fork are always verified, they decide which block is kept
+		if err := parentBestState.VerifyBestStateWithShardBlock(block, true, shardID); err != nil {
+			return err
+		}
+		forkDB = db
+		// best state of parent is updated with the fork, best state of
+		// reverted block is restored if the fork isn't stored
+		revertedBestState = blockchain.BestState.Shard[shardID]
+		blockchain.setBestStateShard(shardID, parentBestState)
+		defer func() {
+			if forkDB != nil {
+				blockchain.setBestStateShard(shardID, revertedBestState)
+			}
+		}()
+		if !isValidated {
+			if err := blockchain.forkView(forkDB).VerifyPreProcessingShardBlock(block, shardID, false); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Verify block with previous best state
-	if !isValidated {
+	if forkDB != nil {
+		Logger.log.Infof("SHARD %+v | Fork %+v is verified with BestState of parent \n", block.Header.ShardID, *block.Hash())
+	} else if !isValidated {
 		Logger.log.Infof("SHARD %+v | Verify BestState with Block %+v \n", block.Header.ShardID, *block.Hash())
 		if err := blockchain.BestState.Shard[shardID].VerifyBestStateWithShardBlock(block, true, shardID); err != nil {
 			return err
@@ -158,6 +200,20 @@ func (blockchain *BlockChain) InsertShardBlock(block *ShardBlock, isValidated bo
 		}
 	}
 
+	//========Store new  Shard block and new shard bestState
+	if forkDB != nil {
+		if err := blockchain.processStoreShardBlock(forkDB, block); err != nil {
+			return err
+		}
+		if err := forkDB.Commit(); err != nil {
+			return NewBlockChainError(DBError, err)
+		}
+		forkDB = nil
+		blockchain.rollbackFeeEstimator(shardID, &revertedBestState.BestBlockHash)
+	} else if err := blockchain.ProcessStoreShardBlock(block); err != nil {
+		return err
+	}
+
 	//=========Remove invalid shard block in pool
 	blockchain.config.ShardPool[shardID].SetShardState(blockchain.BestState.Shard[shardID].ShardHeight)
 
@@ -194,21 +250,23 @@ func (blockchain *BlockChain) InsertShardBlock(block *ShardBlock, isValidated bo
 		blockchain.config.TxPool.RemoveCandidateList(candidates)
 		blockchain.config.TxPool.RemoveTokenIDList(tokenIDs)
 
+		blockchain.config.TxPool.RecordTxsInBlock(block.Body.Transactions, block.Header.Height)
 		//Remove tx out of pool
 		for _, tx := range block.Body.Transactions {
 			go func(tx metadata.Transaction) {
 				blockchain.config.TxPool.RemoveTx(tx, true)
-				blockchain.config.CRemovedTxs <- tx
 			}(tx)
 		}
+		// Txs which expire at this block can't be in a later block
+		blockchain.config.TxPool.RemoveExpiredTxs(block.Header.ShardID, block.Header.Height)
 	}()
 
-	//========Store new  Shard block and new shard bestState
-	err = blockchain.ProcessStoreShardBlock(block)
-	if err != nil {
-		return err
-	}
 	blockchain.config.ShardPool[block.Header.ShardID].RemoveBlock(block.Header.Height)
+	if feeEstimator, ok := blockchain.config.FeeEstimator[block.Header.ShardID]; ok {
+		if err := feeEstimator.RegisterBlock(block); err != nil {
+			Logger.log.Errorf("SHARD %+v | Fee estimator can't register block %d: %+v", block.Header.ShardID, block.Header.Height, err)
+		}
+	}
 	Logger.log.Infof("SHARD %+v | Finish Insert new block %d, with hash %+v", block.Header.ShardID, block.Header.Height, *block.Hash())
 	return nil
 }
@@ -219,20 +277,41 @@ func (blockchain *BlockChain) InsertShardBlock(block *ShardBlock, isValidated bo
 	- Shard Best State
 	- Transaction => UTXO, serial number, snd, commitment
 	- Cross Output Coin => UTXO, snd, commmitment
+	- Undo log of all of above, so the block can be reverted
+	- Pruning of old shard blocks
+	All of them are stored in one database transaction, so a block is stored
+	completely or not at all
 */
 func (blockchain *BlockChain) ProcessStoreShardBlock(block *ShardBlock) error {
+	db, err := blockchain.config.DataBase.Begin()
+	if err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	defer db.Rollback()
+	if err := blockchain.processStoreShardBlock(db, block); err != nil {
+		return err
+	}
+	if err := db.Commit(); err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	return nil
+}
+
+// processStoreShardBlock stores block and best state of its shard in db,
+// caller commits db
+func (blockchain *BlockChain) processStoreShardBlock(db database.Transaction, block *ShardBlock) error {
 	blockHash := block.Hash().String()
 	Logger.log.Infof("SHARD %+v | Process store block height %+v at hash %+v", block.Header.ShardID, block.Header.Height, *block.Hash())
 
-	if err := blockchain.StoreShardBlock(block); err != nil {
+	if err := blockchain.StoreShardBlock(db, block); err != nil {
 		return err
 	}
 
-	if err := blockchain.StoreShardBlockIndex(block); err != nil {
+	if err := blockchain.StoreShardBlockIndex(db, block); err != nil {
 		return err
 	}
 
-	if err := blockchain.StoreShardBestState(block.Header.ShardID); err != nil {
+	if err := blockchain.StoreShardBestState(db, block.Header.ShardID); err != nil {
 		return err
 	}
 
@@ -247,26 +326,28 @@ func (blockchain *BlockChain) ProcessStoreShardBlock(block *ShardBlock) error {
 		Logger.log.Critical("ProcessStoreShardBlock/CrossTransactions	", block.Body.CrossTransactions)
 	}
 
-	if err := blockchain.CreateAndSaveTxViewPointFromBlock(block); err != nil {
+	if err := blockchain.CreateAndSaveTxViewPointFromBlock(db, block); err != nil {
 		return err
 	}
 
 	for index, tx := range block.Body.Transactions {
-		if err := blockchain.StoreTransactionIndex(tx.Hash(), block.Hash(), index); err != nil {
+		if err := blockchain.StoreTransactionIndex(db, tx.Hash(), block.Hash(), index); err != nil {
 			Logger.log.Error("ERROR", err, "Transaction in block with hash", blockHash, "and index", index, ":", tx)
 			return NewBlockChainError(UnExpectedError, err)
 		}
 		Logger.log.Debugf("Transaction in block with hash", blockHash, "and index", index)
 	}
 	// Store Incomming Cross Shard
-	if err := blockchain.CreateAndSaveCrossTransactionCoinViewPointFromBlock(block); err != nil {
+	if err := blockchain.CreateAndSaveCrossTransactionCoinViewPointFromBlock(db, block); err != nil {
 		return err
 	}
-	err := blockchain.StoreIncomingCrossShard(block)
-	if err != nil {
+	if err := blockchain.StoreIncomingCrossShard(db, block); err != nil {
 		return NewBlockChainError(UnExpectedError, err)
 	}
-	return nil
+	if err := db.StoreUndoLog(block.Hash()); err != nil {
+		return NewBlockChainError(DBError, err)
+	}
+	return blockchain.pruneBlocks(db, false, block.Header.ShardID, block.Header.Height)
 }
 
 /* Verify Pre-prosessing data
@@ -424,7 +505,7 @@ func (blockchain *BlockChain) VerifyPreProcessingShardBlock(block *ShardBlock, s
 	// @NOTICE: COMMENT to bypass verify cross shard block
 	if isPresig {
 		// Verify Transaction
-		if err := blockchain.VerifyTransactionFromNewBlock(block.Body.Transactions); err != nil {
+		if err := blockchain.VerifyTransactionFromNewBlock(block.Body.Transactions, block.Header.Height); err != nil {
 			return NewBlockChainError(TransactionError, err)
 		}
 
@@ -537,7 +618,9 @@ func (bestStateShard *BestStateShard) VerifyBestStateWithShardBlock(block *Shard
 		if len(bestStateShard.ShardCommittee) > 3 && len(block.ValidatorsIdx[1]) < (len(bestStateShard.ShardCommittee)>>1) {
 			return NewBlockChainError(SignatureError, errors.New("block validators and Shard committee is not compatible"))
 		}
-		ValidateAggSignature(block.ValidatorsIdx, bestStateShard.ShardCommittee, block.AggregatedSig, block.R, block.Hash())
+		if err := ValidateAggSignature(block.ValidatorsIdx, bestStateShard.ShardCommittee, block.AggregatedSig, block.R, block.Hash()); err != nil {
+			return NewBlockChainError(SignatureError, err)
+		}
 	}
 	//=============End Verify Aggegrate signature
 	if bestStateShard.ShardHeight+1 != block.Header.Height {
@@ -713,11 +796,24 @@ func (blockchain *BestStateShard) VerifyPostProcessingShardBlock(block *ShardBlo
 	9. Not accept a salary tx
 	10. Check duplicate staker public key in block
 	11. Check duplicate Init Custom Token in block
+	12. Tx has no expiry height before its activation and isn't expired at height of block
 */
-func (blockChain *BlockChain) VerifyTransactionFromNewBlock(txs []metadata.Transaction) error {
+func (blockChain *BlockChain) VerifyTransactionFromNewBlock(txs []metadata.Transaction, blockHeight uint64) error {
 	if len(txs) == 0 {
 		return nil
 	}
+	for _, tx := range txs {
+		expiryHeight := tx.GetExpiryHeight()
+		if expiryHeight == 0 {
+			continue
+		}
+		if !blockChain.config.ChainParams.IsExpiryHeightActive(blockHeight) {
+			return NewBlockChainError(TransactionError, fmt.Errorf("transaction %+v has expiry height which is active from height %d, block height is %d", tx.Hash().String(), blockChain.config.ChainParams.ExpiryHeightActivation, blockHeight))
+		}
+		if expiryHeight < blockHeight {
+			return NewBlockChainError(TransactionError, fmt.Errorf("transaction %+v expired at height %d, block height is %d", tx.Hash().String(), expiryHeight, blockHeight))
+		}
+	}
 	isEmpty := blockChain.config.TempTxPool.EmptyPool()
 	if !isEmpty {
 		panic("TempTxPool Is not Empty")
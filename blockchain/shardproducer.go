@@ -329,11 +329,22 @@ func (blockgen *BlkTmplGenerator) getCrossShardData(shardID byte, lastBeaconHeig
 	10. Check duplicate staker public key in block
 	11. Check duplicate Init Custom Token in block
 */
+// pendingTxsByPriority returns txs of pool in the order they are selected
+// for a block, from the highest fee per KB of a tx or of its package with its
+// children. A child waits for its parents, see TxPool.MiningDescs
+func (blockgen *BlkTmplGenerator) pendingTxsByPriority() []metadata.Transaction {
+	txs := []metadata.Transaction{}
+	for _, txDesc := range blockgen.txPool.MiningDescs() {
+		txs = append(txs, txDesc.Tx)
+	}
+	return txs
+}
+
 func (blockgen *BlkTmplGenerator) getPendingTransaction(
 	shardID byte,
 	beaconBlocks []*BeaconBlock,
 ) (txsToAdd []metadata.Transaction, txToRemove []metadata.Transaction, totalFee uint64) {
-	sourceTxns := blockgen.txPool.MiningDescs()
+	sourceTxns := blockgen.pendingTxsByPriority()
 	isEmpty := blockgen.chain.config.TempTxPool.EmptyPool()
 	if !isEmpty {
 		panic("TempTxPool Is not Empty")
@@ -347,13 +358,18 @@ func (blockgen *BlkTmplGenerator) getPendingTransaction(
 	// }
 	// instUsed := make([]int, len(instsForValidations))
 
-	for _, txDesc := range sourceTxns {
+	for _, tx := range sourceTxns {
 		//Logger.log.Criticalf("Tx index %+v value %+v", i, txDesc)
-		tx := txDesc.Tx
 		txShardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
 		if txShardID != shardID {
 			continue
 		}
+		// txs come from the highest fee per KB, a tx which doesn't fit in
+		// block is skipped so smaller txs after it can still be added
+		tempSize := tx.GetTxActualSize()
+		if currentSize+tempSize >= common.MaxBlockSize {
+			continue
+		}
 		tempTxDesc, err := blockgen.chain.config.TempTxPool.MaybeAcceptTransactionForBlockProducing(tx)
 		if err != nil {
 			txToRemove = append(txToRemove, tx)
@@ -370,11 +386,6 @@ func (blockgen *BlkTmplGenerator) getPendingTransaction(
 
 		tempTx := tempTxDesc.Tx
 		totalFee += tx.GetTxFee()
-
-		tempSize := tempTx.GetTxActualSize()
-		if currentSize+tempSize >= common.MaxBlockSize {
-			break
-		}
 		currentSize += tempSize
 		txsToAdd = append(txsToAdd, tempTx)
 		if len(txsToAdd) == common.MaxTxsInBlock {
@@ -399,7 +410,7 @@ func (blockgen *BlkTmplGenerator) getPendingTransactionV2(
 	shardID byte,
 	beaconBlocks []*BeaconBlock,
 ) (txsToAdd []metadata.Transaction, txToRemove []metadata.Transaction, totalFee uint64) {
-	sourceTxns := blockgen.GetPendingTxsV2()
+	sourceTxns := blockgen.pendingTxsByPriority()
 	txsProcessTimeInBlockCreation := int64(float64(common.MinShardBlkInterval.Nanoseconds()) * MaxTxsProcessTimeInBlockCreation)
 	var elasped int64
 	Logger.log.Critical("Number of transaction get from pool: ", len(sourceTxns))
@@ -425,6 +436,12 @@ func (blockgen *BlkTmplGenerator) getPendingTransactionV2(
 		if txShardID != shardID {
 			continue
 		}
+		// a tx which doesn't fit in block is skipped so smaller txs after it
+		// can still be added
+		tempSize := tx.GetTxActualSize()
+		if currentSize+tempSize >= common.MaxBlockSize {
+			continue
+		}
 		tempTxDesc, err := blockgen.chain.config.TempTxPool.MaybeAcceptTransactionForBlockProducing(tx)
 		if err != nil {
 			txToRemove = append(txToRemove, tx)
@@ -438,11 +455,6 @@ func (blockgen *BlkTmplGenerator) getPendingTransactionV2(
 
 		tempTx := tempTxDesc.Tx
 		totalFee += tx.GetTxFee()
-
-		tempSize := tempTx.GetTxActualSize()
-		if currentSize+tempSize >= common.MaxBlockSize {
-			break
-		}
 		currentSize += tempSize
 		txsToAdd = append(txsToAdd, tempTx)
 		if len(txsToAdd) == MaxTxsInBlock {
@@ -690,11 +690,14 @@ func VerifyMerkleCrossTransaction(crossTransactions map[byte][]CrossTransaction,
 	return newHash.IsEqual(res)
 }
 
-func (blockchain *BlockChain) StoreIncomingCrossShard(block *ShardBlock) error {
+func (blockchain *BlockChain) StoreIncomingCrossShard(db database.DatabaseInterface, block *ShardBlock) error {
 	crossShardMap, _ := block.Body.ExtractIncomingCrossShardMap()
 	for crossShard, crossBlks := range crossShardMap {
 		for _, crossBlk := range crossBlks {
-			blockchain.config.DataBase.StoreIncomingCrossShard(block.Header.ShardID, crossShard, block.Header.Height, &crossBlk)
+			err := db.StoreIncomingCrossShard(block.Header.ShardID, crossShard, block.Header.Height, &crossBlk)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
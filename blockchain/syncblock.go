@@ -203,7 +203,10 @@ func (blockchain *BlockChain) StartSyncBlk() {
 			if RCS.ClosestBeaconState.Height-blockchain.BestState.Beacon.BeaconHeight > defaultMaxBlkReqPerTime {
 				RCS.ClosestBeaconState.Height = blockchain.BestState.Beacon.BeaconHeight + defaultMaxBlkReqPerTime
 			}
-			for peerID := range blockchain.syncStatus.PeersState {
+			for peerID, peerState := range blockchain.syncStatus.PeersState {
+				if peerState.Beacon == nil || !blockchain.peerHasBlock(peerID, peerState.Beacon.Height, currentBcnReqHeight) {
+					continue
+				}
 				if currentBcnReqHeight+defaultMaxBlkReqPerPeer-1 >= RCS.ClosestBeaconState.Height {
 					//fmt.Println("SyncBlk1:", currentBcnReqHeight, RCS.ClosestBeaconState.Height)
 					blockchain.SyncBlkBeacon(false, false, nil, currentBcnReqHeight, RCS.ClosestBeaconState.Height, peerID)
@@ -228,7 +231,7 @@ func (blockchain *BlockChain) StartSyncBlk() {
 							if GetBestStateBeacon().GetBestHeightOfShard(shardID) < RCS.ClosestShardsState[shardID].Height {
 								currentShardReqHeight := GetBestStateBeacon().GetBestHeightOfShard(shardID) + 1
 								for peerID, peerState := range blockchain.syncStatus.PeersState {
-									if _, ok := peerState.Shard[shardID]; ok {
+									if shardState, ok := peerState.Shard[shardID]; ok && blockchain.peerHasBlock(peerID, shardState.Height, currentShardReqHeight) {
 										if currentShardReqHeight+defaultMaxBlkReqPerPeer-1 >= RCS.ClosestShardsState[shardID].Height {
 											blockchain.SyncBlkShardToBeacon(shardID, false, false, false, nil, nil, currentShardReqHeight, RCS.ClosestShardsState[shardID].Height, peerID)
 										} else {
@@ -264,7 +267,7 @@ func (blockchain *BlockChain) StartSyncBlk() {
 				for peerID := range blockchain.syncStatus.PeersState {
 					if shardState, ok := blockchain.syncStatus.PeersState[peerID].Shard[shardID]; ok {
 						fmt.Println("SyncShard 123 ", shardState.Height, shardID)
-						if shardState.Height >= currentShardReqHeight {
+						if shardState.Height >= currentShardReqHeight && blockchain.peerHasBlock(peerID, shardState.Height, currentShardReqHeight) {
 							if currentShardReqHeight+defaultMaxBlkReqPerPeer-1 >= RCS.ClosestShardsState[shardID].Height {
 								fmt.Println("SyncShard 1234 ")
 								blockchain.SyncBlkShard(shardID, false, false, nil, currentShardReqHeight, RCS.ClosestShardsState[shardID].Height, peerID)
@@ -292,6 +295,19 @@ func (blockchain *BlockChain) StartSyncBlk() {
 	}
 }
 
+/*
+peerHasBlock returns true if peer whose best height is peerHeight keeps body
+of block at height. A peer which isn't archive may prune blocks, but it always
+keeps the last MinPruneDepth blocks, older blocks are requested from archive
+peers
+*/
+func (blockchain *BlockChain) peerHasBlock(peerID libp2p.ID, peerHeight uint64, height uint64) bool {
+	if height+MinPruneDepth > peerHeight {
+		return true
+	}
+	return blockchain.config.Server.IsArchivePeer(peerID)
+}
+
 func (blockchain *BlockChain) SyncShard(shardID byte) error {
 	blockchain.syncStatus.Lock()
 	defer blockchain.syncStatus.Unlock()
@@ -8,7 +8,8 @@ import (
 
 // See loadConfig for details on the configuration load process.
 type config struct {
-	RPCPort int `long:"rpcport" short:"p" description:"Linsten port of RPC server"`
+	RPCPort   int    `long:"rpcport" short:"p" description:"Linsten port of RPC server"`
+	PeersFile string `long:"peersfile" description:"File to save peer table, so it is kept after restart. Empty to disable"`
 }
 
 // newConfigParser returns a new command line flags parser.
@@ -19,7 +20,8 @@ func newConfigParser(cfg *config, options flags.Options) *flags.Parser {
 
 func loadConfig() (*config, error) {
 	cfg := config{
-		RPCPort: RpcServerPort,
+		RPCPort:   RpcServerPort,
+		PeersFile: DefaultPeersFile,
 	}
 
 	//preCfg := cfg
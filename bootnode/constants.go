@@ -3,4 +3,6 @@ package main
 const (
 	Version       = "1.0.0"
 	RpcServerPort = 9330
+	// DefaultPeersFile is the file which bootnode saves its peer table to
+	DefaultPeersFile = "bootnode_peers.json"
 )
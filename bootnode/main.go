@@ -23,7 +23,8 @@ func main() {
 	cfg = tcfg
 
 	rpcConfig := server.RpcServerConfig{
-		Port: cfg.RPCPort,
+		Port:      cfg.RPCPort,
+		PeersFile: cfg.PeersFile,
 	}
 	server := &server.RpcServer{}
 	err = server.Init(&rpcConfig)
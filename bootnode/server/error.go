@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	UnexpectedError = iota
+	RateLimitError
+	InvalidPingArgsError
+	ExpiredPingError
+	InvalidSignatureError
+	SavePeersError
+	LoadPeersError
+)
+
+var ErrCodeMessage = map[int]struct {
+	code    int
+	message string
+}{
+	UnexpectedError:       {-1, "Unexpected error"},
+	RateLimitError:        {-1000, "Too many pings, try again later"},
+	InvalidPingArgsError:  {-1001, "Invalid ping arguments"},
+	ExpiredPingError:      {-1002, "Ping timestamp is expired or replayed"},
+	InvalidSignatureError: {-1003, "Invalid signature of ping data"},
+	SavePeersError:        {-1004, "Can not save peers to file"},
+	LoadPeersError:        {-1005, "Can not load peers from file"},
+}
+
+type BootnodeError struct {
+	Code    int
+	Message string
+	err     error
+}
+
+func (e BootnodeError) Error() string {
+	return fmt.Sprintf("%d: %s %+v", e.Code, e.Message, e.err)
+}
+
+func NewBootnodeError(key int, err error) *BootnodeError {
+	return &BootnodeError{
+		Code:    ErrCodeMessage[key].code,
+		Message: ErrCodeMessage[key].message,
+		err:     errors.Wrap(err, ErrCodeMessage[key].message),
+	}
+}
@@ -7,26 +7,46 @@ import (
 )
 
 type Handler struct {
-	server *RpcServer
+	server     *RpcServer
+	remoteAddr string
 }
 
+// PingArgs is the request of a node which registers itself on bootnode.
+// SignData is the signature of PublicKey over PingSignData(RawAddress,
+// Role, ShardID, Timestamp), Timestamp must be close to bootnode time so an
+// old ping can not be replayed. Role and ShardID of a ping without public key
+// are not trusted
 type PingArgs struct {
 	RawAddress string
 	PublicKey  string
 	SignData   string
+	Role       string
+	ShardID    *byte
+	Timestamp  int64
 }
 
 func (s Handler) Ping(args *PingArgs, peers *[]wire.RawPeer) error {
-	fmt.Println("Ping", args)
-	// update peer information to server
-	s.server.AddOrUpdatePeer(args.RawAddress, args.PublicKey, args.SignData)
-	// return note list
-	s.server.peersMtx.Lock()
-	for _, p := range s.server.Peers {
-		*peers = append(*peers, wire.RawPeer{p.RawAddress, p.PublicKey})
+	if !s.server.limiter.allow(s.remoteAddr) {
+		return NewBootnodeError(RateLimitError, fmt.Errorf("too many pings from %s", s.remoteAddr))
 	}
-	s.server.peersMtx.Unlock()
-	fmt.Println("Response", *peers)
-
+	// role and shard of sender filter peer list only if they are signed
+	role := ""
+	var shardID *byte
+	if args.PublicKey != "" {
+		// update peer information to server, a node without external
+		// address only gets peer list
+		var err error
+		if args.RawAddress != "" {
+			err = s.server.AddOrUpdatePeer(args)
+		} else {
+			err = s.server.VerifyPing(args)
+		}
+		if err != nil {
+			return err
+		}
+		role, shardID = args.Role, args.ShardID
+	}
+	// return filtered peer list of role and shard of sender
+	*peers = s.server.GetPeersFor(args.PublicKey, role, shardID)
 	return nil
 }
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/wire"
+)
+
+func newTestHandler() Handler {
+	server := &RpcServer{
+		Peers:   make(map[string]*Peer),
+		limiter: newPingLimiter(pingRateLimit, pingRateWindow*time.Second),
+	}
+	return Handler{server: server, remoteAddr: "127.0.0.1:1000"}
+}
+
+func newSignedPing(t *testing.T, seed string, rawAddress string, role string, shardID *byte) *PingArgs {
+	keySet := cashec.KeySet{}
+	keySet.GenerateKey([]byte(seed))
+	args := &PingArgs{
+		RawAddress: rawAddress,
+		PublicKey:  keySet.GetPublicKeyB58(),
+		Role:       role,
+		ShardID:    shardID,
+		Timestamp:  time.Now().Unix(),
+	}
+	signData, err := keySet.SignDataB58(PingSignData(args.RawAddress, args.Role, args.ShardID, args.Timestamp))
+	if err != nil {
+		t.Fatalf("failed to sign ping: %+v", err)
+	}
+	args.SignData = signData
+	return args
+}
+
+func TestPingAddsSignedPeer(t *testing.T) {
+	handler := newTestHandler()
+	shardID := byte(1)
+	args := newSignedPing(t, "node1", "/ip4/1.2.3.4/tcp/9333/ipfs/a", common.SHARD_ROLE, &shardID)
+	peers := []wire.RawPeer{}
+	if err := handler.Ping(args, &peers); err != nil {
+		t.Fatalf("Ping returns err: %+v", err)
+	}
+	p, ok := handler.server.Peers[args.PublicKey]
+	if !ok || p.Role != common.SHARD_ROLE || p.ShardID == nil || *p.ShardID != shardID {
+		t.Fatalf("peer is not saved with its role and shard: %+v", p)
+	}
+}
+
+func TestPingRejectsTamperedRoleAndShard(t *testing.T) {
+	handler := newTestHandler()
+	shardID := byte(1)
+	args := newSignedPing(t, "node1", "/ip4/1.2.3.4/tcp/9333/ipfs/a", common.SHARD_ROLE, &shardID)
+	args.Role = common.BEACON_ROLE
+	peers := []wire.RawPeer{}
+	if err := handler.Ping(args, &peers); err == nil {
+		t.Fatalf("ping with changed role should be rejected")
+	}
+
+	args = newSignedPing(t, "node1", "/ip4/1.2.3.4/tcp/9333/ipfs/a", common.SHARD_ROLE, &shardID)
+	otherShardID := byte(2)
+	args.ShardID = &otherShardID
+	if err := handler.Ping(args, &peers); err == nil {
+		t.Fatalf("ping with changed shard should be rejected")
+	}
+	// a node without external address still signs its role and shard
+	args = newSignedPing(t, "node1", "", common.SHARD_ROLE, &shardID)
+	args.Role = common.BEACON_ROLE
+	if err := handler.Ping(args, &peers); err == nil {
+		t.Fatalf("ping without address with changed role should be rejected")
+	}
+	if len(handler.server.Peers) != 0 {
+		t.Fatalf("rejected pings should not be saved, peer table has %d peers", len(handler.server.Peers))
+	}
+}
+
+func TestPingWithoutPublicKeyIgnoresRole(t *testing.T) {
+	handler := newTestHandler()
+	shardID := byte(1)
+	shardPeer := newSignedPing(t, "node1", "/ip4/1.2.3.4/tcp/9333/ipfs/a", common.SHARD_ROLE, &shardID)
+	peers := []wire.RawPeer{}
+	if err := handler.Ping(shardPeer, &peers); err != nil {
+		t.Fatalf("Ping returns err: %+v", err)
+	}
+	for i := 0; i < maxPeersOtherShard; i++ {
+		otherPeer := newSignedPing(t, fmt.Sprintf("node%d", i+2), fmt.Sprintf("/ip4/1.2.3.5/tcp/9333/ipfs/peer%d", i+2), common.SHARD_ROLE, &shardID)
+		if err := handler.Ping(otherPeer, &peers); err != nil {
+			t.Fatalf("Ping returns err: %+v", err)
+		}
+	}
+	// an unsigned ping which claims shard 1 gets the peer list of a node
+	// without role: at most maxPeersOtherShard peers of shard 1
+	args := &PingArgs{Role: common.SHARD_ROLE, ShardID: &shardID, Timestamp: time.Now().Unix()}
+	if err := handler.Ping(args, &peers); err != nil {
+		t.Fatalf("Ping returns err: %+v", err)
+	}
+	if len(peers) != maxPeersOtherShard {
+		t.Fatalf("unsigned ping gets %d peers, want %d", len(peers), maxPeersOtherShard)
+	}
+}
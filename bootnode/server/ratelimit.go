@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// pingLimiter counts pings of every remote host in a fixed time window,
+// a host which sends more than limit pings in the window is rejected until
+// the next window
+type pingLimiter struct {
+	mtx    sync.Mutex
+	limit  int
+	window time.Duration
+	hosts  map[string]*pingCounter
+}
+
+type pingCounter struct {
+	start time.Time
+	count int
+}
+
+func newPingLimiter(limit int, window time.Duration) *pingLimiter {
+	return &pingLimiter{
+		limit:  limit,
+		window: window,
+		hosts:  make(map[string]*pingCounter),
+	}
+}
+
+func (limiter *pingLimiter) allow(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	now := time.Now()
+	limiter.mtx.Lock()
+	defer limiter.mtx.Unlock()
+	counter, ok := limiter.hosts[host]
+	if !ok || now.Sub(counter.start) > limiter.window {
+		limiter.hosts[host] = &pingCounter{start: now, count: 1}
+		return true
+	}
+	if counter.count >= limiter.limit {
+		return false
+	}
+	counter.count++
+	return true
+}
+
+// cleanup removes the counters of expired windows
+func (limiter *pingLimiter) cleanup() {
+	now := time.Now()
+	limiter.mtx.Lock()
+	defer limiter.mtx.Unlock()
+	for host, counter := range limiter.hosts {
+		if now.Sub(counter.start) > limiter.window {
+			delete(limiter.hosts, host)
+		}
+	}
+}
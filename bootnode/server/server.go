@@ -1,19 +1,37 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/rpc"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/wire"
 )
 
 const (
 	heartbeatInterval = 5
 	heartbeatTimeout  = 60
+
+	// a ping is accepted only if its timestamp is not older or newer than
+	// pingTimeDrift seconds from bootnode time
+	pingTimeDrift = 30
+	// max number of pings of a remote host in pingRateWindow seconds
+	pingRateLimit  = 20
+	pingRateWindow = 60
+
+	// max number of peers of every other shard and of no role which are
+	// returned in a ping response
+	maxPeersOtherShard = 8
+	maxPeersNoRole     = 16
 )
 
 // timeZeroVal is simply the zero value for a time.Time and is used to avoid
@@ -28,6 +46,9 @@ type Peer struct {
 	ID         string
 	RawAddress string
 	PublicKey  string
+	Role       string
+	ShardID    *byte
+	Timestamp  int64
 	FirstPing  time.Time
 	LastPing   time.Time
 }
@@ -36,54 +57,164 @@ type Peer struct {
 type RpcServer struct {
 	Peers    map[string]*Peer
 	peersMtx sync.Mutex
+	// peersChanged is set when peer table is updated and not saved to file yet
+	peersChanged bool
+
+	limiter *pingLimiter
 
 	Config RpcServerConfig
 }
 
 type RpcServerConfig struct {
 	Port int
+	// PeersFile is the file which peer table is saved to, so it is not lost
+	// when bootnode restarts. Peer table is not saved if it is empty string
+	PeersFile string
+}
+
+// PingSignData returns data which is signed by a node when it pings bootnode
+func PingSignData(rawAddress string, role string, shardID *byte, timestamp int64) []byte {
+	shard := -1
+	if shardID != nil {
+		shard = int(*shardID)
+	}
+	return []byte(fmt.Sprintf("%s|%s|%d|%d", rawAddress, role, shard, timestamp))
 }
 
 func (self *RpcServer) Init(config *RpcServerConfig) error {
 	self.Config = *config
 	self.Peers = make(map[string]*Peer)
+	self.limiter = newPingLimiter(pingRateLimit, pingRateWindow*time.Second)
+	err := self.loadPeers()
+	if err != nil {
+		log.Println("Load peers error", err)
+	}
 	go self.PeerHeartBeat()
 	return nil
 }
 
 func (self *RpcServer) Start() {
-	handler := &Handler{self}
-	server := rpc.NewServer()
-	server.Register(handler)
 	l, e := net.Listen("tcp", fmt.Sprintf(":%d", self.Config.Port))
 	if e != nil {
 		log.Fatal("listen error:", e)
 	}
-	server.Accept(l)
-	l.Close()
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Println("accept error:", err)
+			return
+		}
+		// every connection has its own handler, so pings can be limited by
+		// remote address
+		server := rpc.NewServer()
+		server.Register(&Handler{server: self, remoteAddr: conn.RemoteAddr().String()})
+		go server.ServeConn(conn)
+	}
 }
 
-func (self *RpcServer) AddOrUpdatePeer(rawAddress string, publicKeyB58 string, signDataB58 string) {
-	if signDataB58 != "" && publicKeyB58 != "" && rawAddress != "" {
-		err := cashec.ValidateDataB58(publicKeyB58, signDataB58, []byte(rawAddress))
-		if err == nil {
-			self.peersMtx.Lock()
-			self.Peers[publicKeyB58] = &Peer{
-				ID:         self.CombineID(rawAddress, publicKeyB58),
-				RawAddress: rawAddress,
-				PublicKey:  publicKeyB58,
-				FirstPing:  time.Now().Local(),
-				LastPing:   time.Now().Local(),
-			}
-			self.peersMtx.Unlock()
-		} else {
-			log.Println("AddOrUpdatePeer error", err)
+// VerifyPing checks that ping is fresh and its raw address, role and shard
+// are signed by its public key
+func (self *RpcServer) VerifyPing(args *PingArgs) error {
+	if args.SignData == "" || args.PublicKey == "" {
+		return NewBootnodeError(InvalidPingArgsError, errors.New("public key and sign data are required"))
+	}
+	now := time.Now().Unix()
+	drift := now - args.Timestamp
+	if drift > pingTimeDrift || drift < -pingTimeDrift {
+		return NewBootnodeError(ExpiredPingError, fmt.Errorf("timestamp %d, bootnode time %d", args.Timestamp, now))
+	}
+	err := cashec.ValidateDataB58(args.PublicKey, args.SignData, PingSignData(args.RawAddress, args.Role, args.ShardID, args.Timestamp))
+	if err != nil {
+		log.Println("VerifyPing error", err)
+		return NewBootnodeError(InvalidSignatureError, err)
+	}
+	return nil
+}
+
+// AddOrUpdatePeer verifies ping and saves its sender into peer table
+func (self *RpcServer) AddOrUpdatePeer(args *PingArgs) error {
+	if args.RawAddress == "" {
+		return NewBootnodeError(InvalidPingArgsError, errors.New("raw address is required"))
+	}
+	if err := self.VerifyPing(args); err != nil {
+		return err
+	}
+	now := time.Now().Local()
+
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+	firstPing := now
+	if p, ok := self.Peers[args.PublicKey]; ok {
+		// a ping which is not newer than the last one is a replay
+		if args.Timestamp <= p.Timestamp {
+			return NewBootnodeError(ExpiredPingError, fmt.Errorf("timestamp %d is not newer than %d", args.Timestamp, p.Timestamp))
 		}
+		if p.RawAddress == args.RawAddress {
+			firstPing = p.FirstPing
+		}
+	}
+	self.Peers[args.PublicKey] = &Peer{
+		ID:         self.CombineID(args.RawAddress, args.PublicKey),
+		RawAddress: args.RawAddress,
+		PublicKey:  args.PublicKey,
+		Role:       args.Role,
+		ShardID:    args.ShardID,
+		Timestamp:  args.Timestamp,
+		FirstPing:  firstPing,
+		LastPing:   now,
 	}
+	self.peersChanged = true
+	return nil
 }
 
+// GetPeersFor returns the peers which are useful for a node of role and shard:
+// all beacon peers, all peers of the same shard, some peers of every other
+// shard and some peers which have no role
+func (self *RpcServer) GetPeersFor(publicKey string, role string, shardID *byte) []wire.RawPeer {
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+	result := make([]wire.RawPeer, 0)
+	otherShards := make(map[byte][]*Peer)
+	noRole := make([]*Peer, 0)
+	for _, p := range self.Peers {
+		if p.PublicKey == publicKey {
+			continue
+		}
+		switch {
+		case p.Role == common.BEACON_ROLE:
+			result = append(result, wire.RawPeer{RawAddress: p.RawAddress, PublicKey: p.PublicKey})
+		case p.ShardID != nil && shardID != nil && *p.ShardID == *shardID && role != common.BEACON_ROLE:
+			result = append(result, wire.RawPeer{RawAddress: p.RawAddress, PublicKey: p.PublicKey})
+		case p.ShardID != nil:
+			otherShards[*p.ShardID] = append(otherShards[*p.ShardID], p)
+		default:
+			noRole = append(noRole, p)
+		}
+	}
+	for _, peers := range otherShards {
+		result = append(result, randRawPeers(peers, maxPeersOtherShard)...)
+	}
+	result = append(result, randRawPeers(noRole, maxPeersNoRole)...)
+	return result
+}
+
+// randRawPeers picks randomly at most max peers
+func randRawPeers(peers []*Peer, max int) []wire.RawPeer {
+	result := make([]wire.RawPeer, 0)
+	for len(peers) > 0 && len(result) < max {
+		randN := common.RandInt() % len(peers)
+		p := peers[randN]
+		peers = append(peers[:randN], peers[randN+1:]...)
+		result = append(result, wire.RawPeer{RawAddress: p.RawAddress, PublicKey: p.PublicKey})
+	}
+	return result
+}
+
+// RemovePeerByPbk removes a peer from peer table, caller must hold peersMtx
 func (self *RpcServer) RemovePeerByPbk(publicKey string) {
 	delete(self.Peers, publicKey)
+	self.peersChanged = true
 }
 
 func (self *RpcServer) CombineID(rawAddress string, publicKey string) string {
@@ -93,15 +224,81 @@ func (self *RpcServer) CombineID(rawAddress string, publicKey string) string {
 func (self *RpcServer) PeerHeartBeat() {
 	for {
 		now := time.Now().Local()
-		if len(self.Peers) > 0 {
-		loop:
-			for publicKey, peer := range self.Peers {
-				if now.Sub(peer.LastPing).Seconds() > heartbeatTimeout {
-					self.RemovePeerByPbk(publicKey)
-					goto loop
-				}
+		self.peersMtx.Lock()
+		for publicKey, peer := range self.Peers {
+			if now.Sub(peer.LastPing).Seconds() > heartbeatTimeout {
+				self.RemovePeerByPbk(publicKey)
 			}
 		}
+		if self.peersChanged {
+			err := self.savePeers()
+			if err != nil {
+				log.Println("Save peers error", err)
+			} else {
+				self.peersChanged = false
+			}
+		}
+		self.peersMtx.Unlock()
+		self.limiter.cleanup()
 		time.Sleep(heartbeatInterval * time.Second)
 	}
 }
+
+// savePeers writes peer table to PeersFile, caller must hold peersMtx
+func (self *RpcServer) savePeers() error {
+	if self.Config.PeersFile == "" {
+		return nil
+	}
+	peers := make([]*Peer, 0, len(self.Peers))
+	for _, p := range self.Peers {
+		peers = append(peers, p)
+	}
+	data, err := json.Marshal(peers)
+	if err != nil {
+		return NewBootnodeError(SavePeersError, err)
+	}
+	// write to a temporary file then rename, so a crash doesn't leave a
+	// broken peers file
+	tmpFile := self.Config.PeersFile + ".tmp"
+	err = ioutil.WriteFile(tmpFile, data, 0600)
+	if err != nil {
+		return NewBootnodeError(SavePeersError, err)
+	}
+	err = os.Rename(tmpFile, self.Config.PeersFile)
+	if err != nil {
+		return NewBootnodeError(SavePeersError, err)
+	}
+	return nil
+}
+
+// loadPeers reads peer table from PeersFile. Loaded peers get a new last ping
+// time so they have heartbeatTimeout seconds to ping again before removed
+func (self *RpcServer) loadPeers() error {
+	if self.Config.PeersFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(self.Config.PeersFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return NewBootnodeError(LoadPeersError, err)
+	}
+	peers := make([]*Peer, 0)
+	err = json.Unmarshal(data, &peers)
+	if err != nil {
+		return NewBootnodeError(LoadPeersError, err)
+	}
+	now := time.Now().Local()
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+	for _, p := range peers {
+		if p.PublicKey == "" || p.RawAddress == "" {
+			continue
+		}
+		p.LastPing = now
+		self.Peers[p.PublicKey] = p
+	}
+	log.Printf("Loaded %d peers from file %s", len(self.Peers), self.Config.PeersFile)
+	return nil
+}
@@ -14,6 +14,7 @@ const (
 	defaultConfigFilename = "component.conf"
 	defaultDataDirname    = "data"
 	defaultLogDirname     = "logs"
+	defaultDatabaseDir    = "block"
 )
 
 var (
@@ -34,6 +35,10 @@ type params struct {
 	WalletPassphrase  string `long:"walletpassphrase" description:"Wallet passphrase"`
 	WalletAccountName string `long:"walletaccountname" description:"Wallet account name"`
 
+	// Database, node must be stopped because its database is opened
+	DatabaseDir string `long:"datapre" description:"Database dir in data dir, default is 'block'"`
+	OutPath     string `long:"out" description:"Path of backup directory, .tar.gz file or block file"`
+
 	// pToken
 	PNetwork string `long:"pNetwork" description:"Bridge network"`
 	PToken   string `long:"pToken" description:"Bridge token"`
@@ -47,8 +52,9 @@ func newConfigParser(cfg *params, options flags.Options) *flags.Parser {
 
 func loadParams() (*params, error) {
 	cfg := params{
-		DataDir: defaultDataDir,
-		TestNet: false,
+		DataDir:     defaultDataDir,
+		DatabaseDir: defaultDatabaseDir,
+		TestNet:     false,
 	}
 
 	preParser := newConfigParser(&cfg, flags.HelpFlag)
@@ -115,6 +115,32 @@ func main() {
 				}
 				log.Println(string(result))
 			}
+		case BackupDBCmd:
+			{
+				if cfg.OutPath == "" {
+					log.Println("Wrong param")
+					return
+				}
+				err := backupDB()
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				log.Printf("Backup database to %s", cfg.OutPath)
+			}
+		case ExportBlocksCmd:
+			{
+				if cfg.OutPath == "" {
+					log.Println("Wrong param")
+					return
+				}
+				count, err := exportBlocks()
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				log.Printf("Export %d blocks to %s", count, cfg.OutPath)
+			}
 		}
 	} else {
 		log.Println("Parse component error", err.Error())
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	_ "github.com/constant-money/constant-chain/database/lvdb"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+/*
+constantdb inspects the block database of a stopped node: key prefixes,
+blocks, best states, commitments and serial numbers, and verifies that block
+and transaction indexes point to stored blocks. It opens database read-only
+except for rollback.
+*/
+
+var cfg *params
+
+func main() {
+	tcfg, err := loadParams()
+	if err != nil {
+		os.Exit(1)
+	}
+	cfg = tcfg
+
+	switch cfg.Command {
+	case prefixesCmd:
+		err = listPrefixes()
+	case blockCmd:
+		err = withDB(database.MigrateReadOnly, dumpBlock)
+	case bestStateCmd:
+		err = withDB(database.MigrateReadOnly, dumpBestState)
+	case commitmentsCmd:
+		err = withDB(database.MigrateReadOnly, dumpCommitments)
+	case serialNumbersCmd:
+		err = withDB(database.MigrateReadOnly, dumpSerialNumbers)
+	case verifyCmd:
+		err = withDB(database.MigrateReadOnly, verifyIndexes)
+	case rollbackCmd:
+		mode := database.MigrateReadOnly
+		if cfg.Force {
+			mode = database.MigrateNone
+		}
+		err = withDB(mode, rollback)
+	default:
+		err = fmt.Errorf("unknown cmd %s, list cmd: %+v", cfg.Command, cmdList)
+	}
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+func withDB(mode database.MigrationMode, fn func(db database.DatabaseInterface) error) error {
+	db, err := database.Open("leveldb", cfg.DBPath, mode)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return fn(db)
+}
+
+func printJSON(data []byte) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "\t"); err != nil {
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println(out.String())
+}
+
+func tokenID() (*common.Hash, error) {
+	if cfg.TokenID == "" {
+		tokenID := common.ConstantID
+		return &tokenID, nil
+	}
+	return common.NewHashFromStr(cfg.TokenID)
+}
+
+// keyPrefix returns the readable prefix of a key, it is the key up to the
+// last '-' of its leading printable characters. Keys which don't start with
+// a readable prefix are grouped as binary
+func keyPrefix(key []byte) string {
+	end := -1
+	for i, c := range key {
+		if c < 0x20 || c > 0x7e {
+			break
+		}
+		if c == '-' {
+			end = i
+		}
+	}
+	if end < 0 {
+		return "<binary>"
+	}
+	return string(key[:end+1])
+}
+
+type prefixStat struct {
+	prefix    string
+	count     uint64
+	keySize   uint64
+	valueSize uint64
+}
+
+// listPrefixes prints number of keys and size of keys and values of every key
+// prefix
+func listPrefixes() error {
+	db, err := leveldb.OpenFile(cfg.DBPath, &opt.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	stats := make(map[string]*prefixStat)
+	iter := db.NewIterator(nil, nil)
+	for iter.Next() {
+		prefix := keyPrefix(iter.Key())
+		stat, ok := stats[prefix]
+		if !ok {
+			stat = &prefixStat{prefix: prefix}
+			stats[prefix] = stat
+		}
+		stat.count++
+		stat.keySize += uint64(len(iter.Key()))
+		stat.valueSize += uint64(len(iter.Value()))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	result := make([]*prefixStat, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].prefix < result[j].prefix
+	})
+	fmt.Printf("%-32s %12s %14s %14s\n", "PREFIX", "KEYS", "KEY BYTES", "VALUE BYTES")
+	for _, stat := range result {
+		fmt.Printf("%-32q %12d %14d %14d\n", stat.prefix, stat.count, stat.keySize, stat.valueSize)
+	}
+	return nil
+}
+
+func blockHashByHeight(db database.DatabaseInterface, height uint64) (*common.Hash, error) {
+	if cfg.Beacon {
+		return db.GetBeaconBlockHashByIndex(height)
+	}
+	return db.GetBlockByIndex(height, byte(cfg.ShardID))
+}
+
+func dumpBlock(db database.DatabaseInterface) error {
+	hash, err := blockHashByHeight(db, cfg.Height)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if cfg.Beacon {
+		data, err = db.FetchBeaconBlock(hash)
+	} else {
+		data, err = db.FetchBlock(hash)
+	}
+	if database.IsBlockPruned(err) {
+		fmt.Printf("Block %s is pruned, header:\n", hash.String())
+		data, err = db.FetchBlockHeader(hash)
+	}
+	if err != nil {
+		return err
+	}
+	printJSON(data)
+	return nil
+}
+
+func dumpBestState(db database.DatabaseInterface) error {
+	var data []byte
+	var err error
+	if cfg.Beacon {
+		data, err = db.FetchBeaconBestState()
+	} else {
+		data, err = db.FetchShardBestState(byte(cfg.ShardID))
+	}
+	if err != nil {
+		return err
+	}
+	printJSON(data)
+	return nil
+}
+
+func dumpCommitments(db database.DatabaseInterface) error {
+	tokenID, err := tokenID()
+	if err != nil {
+		return err
+	}
+	count := 0
+	err = db.IterateCommitments(tokenID, byte(cfg.ShardID), func(index uint64, commitment []byte) bool {
+		fmt.Printf("%d %s\n", index, hex.EncodeToString(commitment))
+		count++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d commitments of token %s in shard %d\n", count, tokenID.String(), cfg.ShardID)
+	return nil
+}
+
+func dumpSerialNumbers(db database.DatabaseInterface) error {
+	tokenID, err := tokenID()
+	if err != nil {
+		return err
+	}
+	serialNumbers, err := db.FetchSerialNumbers(tokenID, byte(cfg.ShardID))
+	if err != nil {
+		return err
+	}
+	for _, serialNumber := range serialNumbers {
+		fmt.Println(hex.EncodeToString(serialNumber))
+	}
+	fmt.Printf("%d serial numbers of token %s in shard %d\n", len(serialNumbers), tokenID.String(), cfg.ShardID)
+	return nil
+}
+
+/*
+verifyIndexes checks height -> hash -> block -> height of beacon and shard
+chains, and tx index -> block of every transaction of shard blocks
+*/
+func verifyIndexes(db database.DatabaseInterface) error {
+	problems := 0
+	report := func(format string, args ...interface{}) {
+		problems++
+		fmt.Printf(format+"\n", args...)
+	}
+
+	height := uint64(1)
+	for ; ; height++ {
+		hash, err := db.GetBeaconBlockHashByIndex(height)
+		if err != nil {
+			break
+		}
+		data, err := db.FetchBeaconBlock(hash)
+		if database.IsBlockPruned(err) {
+			continue
+		}
+		if err != nil {
+			report("beacon height %d: block %s can't be fetched: %v", height, hash.String(), err)
+			continue
+		}
+		block := blockchain.BeaconBlock{}
+		if err := json.Unmarshal(data, &block); err != nil {
+			report("beacon height %d: block %s can't be parsed: %v", height, hash.String(), err)
+			continue
+		}
+		if !block.Hash().IsEqual(hash) || block.Header.Height != height {
+			report("beacon height %d: index points to block %s of height %d", height, block.Hash().String(), block.Header.Height)
+		}
+		if index, err := db.GetIndexOfBeaconBlock(hash); err != nil || index != height {
+			report("beacon height %d: block %s has index %d, %v", height, hash.String(), index, err)
+		}
+	}
+	fmt.Printf("Verified %d beacon blocks\n", height-1)
+
+	for shardID := 0; shardID < common.MAX_SHARD_NUMBER; shardID++ {
+		height := uint64(1)
+		for ; ; height++ {
+			hash, err := db.GetBlockByIndex(height, byte(shardID))
+			if err != nil {
+				break
+			}
+			data, err := db.FetchBlock(hash)
+			if database.IsBlockPruned(err) {
+				continue
+			}
+			if err != nil {
+				report("shard %d height %d: block %s can't be fetched: %v", shardID, height, hash.String(), err)
+				continue
+			}
+			block := blockchain.ShardBlock{}
+			if err := json.Unmarshal(data, &block); err != nil {
+				report("shard %d height %d: block %s can't be parsed: %v", shardID, height, hash.String(), err)
+				continue
+			}
+			if !block.Hash().IsEqual(hash) || block.Header.Height != height || int(block.Header.ShardID) != shardID {
+				report("shard %d height %d: index points to block %s of shard %d height %d", shardID, height, block.Hash().String(), block.Header.ShardID, block.Header.Height)
+			}
+			if index, indexShardID, err := db.GetIndexOfBlock(hash); err != nil || index != height || int(indexShardID) != shardID {
+				report("shard %d height %d: block %s has index %d of shard %d, %v", shardID, height, hash.String(), index, indexShardID, err)
+			}
+			for i, tx := range block.Body.Transactions {
+				blockHash, index, dbErr := db.GetTransactionIndexById(tx.Hash())
+				if dbErr != nil {
+					report("shard %d height %d: tx %s has no index: %v", shardID, height, tx.Hash().String(), dbErr)
+					continue
+				}
+				if !blockHash.IsEqual(hash) || index != i {
+					report("shard %d height %d: tx %s index points to block %s at %d", shardID, height, tx.Hash().String(), blockHash.String(), index)
+				}
+			}
+		}
+		if height > 1 {
+			fmt.Printf("Verified %d blocks of shard %d\n", height-1, shardID)
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problems", problems)
+	}
+	fmt.Println("No problem is found")
+	return nil
+}
+
+/*
+rollback deletes blocks of beacon chain or a shard which are higher than
+--height with DeleteBeaconBlock and DeleteBlock. Blocks are only listed
+unless --force is set. Best state and coins of deleted blocks are not
+reverted, node must rebuild them
+*/
+func rollback(db database.DatabaseInterface) error {
+	if cfg.Height < 1 {
+		return fmt.Errorf("genesis block can't be deleted, --height must be at least 1")
+	}
+	hashes := make([]*common.Hash, 0)
+	for height := cfg.Height + 1; ; height++ {
+		hash, err := blockHashByHeight(db, height)
+		if err != nil {
+			break
+		}
+		hashes = append(hashes, hash)
+		fmt.Printf("height %d: %s\n", height, hash.String())
+	}
+	chain := fmt.Sprintf("shard %d", cfg.ShardID)
+	if cfg.Beacon {
+		chain = "beacon"
+	}
+	if !cfg.Force {
+		fmt.Printf("%d blocks of %s would be deleted, run with --force to delete them\n", len(hashes), chain)
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i := len(hashes) - 1; i >= 0; i-- {
+		height := cfg.Height + 1 + uint64(i)
+		if cfg.Beacon {
+			err = tx.DeleteBeaconBlock(hashes[i], height)
+		} else {
+			err = tx.DeleteBlock(hashes[i], height, byte(cfg.ShardID))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d blocks of %s, best state and coins of these blocks are not reverted\n", len(hashes), chain)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+)
+
+const (
+	prefixesCmd      = "prefixes"
+	blockCmd         = "block"
+	bestStateCmd     = "beststate"
+	commitmentsCmd   = "commitments"
+	serialNumbersCmd = "serialnumbers"
+	verifyCmd        = "verify"
+	rollbackCmd      = "rollback"
+)
+
+var cmdList = []string{prefixesCmd, blockCmd, bestStateCmd, commitmentsCmd, serialNumbersCmd, verifyCmd, rollbackCmd}
+
+// params of constantdb, node must be stopped because its database is opened
+type params struct {
+	Command string `long:"cmd" short:"c" description:"Command name"`
+	DBPath  string `long:"dbpath" description:"Path of block database, it is <datadir>/<network>/block of node"`
+
+	Beacon  bool   `long:"beacon" description:"Use beacon chain instead of a shard"`
+	ShardID int    `long:"shard" description:"Shard id"`
+	Height  uint64 `long:"height" description:"Block height, rollback keeps blocks up to this height"`
+	TokenID string `long:"token" description:"Token id, default is constant"`
+	Force   bool   `long:"force" description:"Really delete blocks in rollback, without it rollback only lists blocks to delete"`
+}
+
+func loadParams() (*params, error) {
+	cfg := params{}
+	parser := flags.NewParser(&cfg, flags.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
+			fmt.Printf("List cmd: %+v \n", cmdList)
+		}
+		return nil, err
+	}
+	if cfg.DBPath == "" {
+		return nil, fmt.Errorf("--dbpath is required")
+	}
+	if _, err := os.Stat(cfg.DBPath); err != nil {
+		return nil, err
+	}
+	if cfg.ShardID < 0 || cfg.ShardID > 255 {
+		return nil, fmt.Errorf("invalid shard id %d", cfg.ShardID)
+	}
+	return &cfg, nil
+}
@@ -6,6 +6,8 @@ const (
 	GetWalletAccountCmd    = "getaccount"
 	CreateWalletAccountCmd = "createaccount"
 	getprivacytokenid      = "getprivacytokenid"
+	BackupDBCmd            = "backupdb"
+	ExportBlocksCmd        = "exportblocks"
 )
 
-var CmdList = []string{CreateWalletCmd, ListWalletAccountCmd, GetWalletAccountCmd, CreateWalletAccountCmd}
+var CmdList = []string{CreateWalletCmd, ListWalletAccountCmd, GetWalletAccountCmd, CreateWalletAccountCmd, BackupDBCmd, ExportBlocksCmd}
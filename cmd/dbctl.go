@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/database"
+	_ "github.com/constant-money/constant-chain/database/lvdb"
+)
+
+// openDB opens database of a stopped node, a running node must be backed up
+// by backupdb rpc instead
+func openDB() (database.DatabaseInterface, error) {
+	return database.Open("leveldb", filepath.Join(cfg.DataDir, cfg.DatabaseDir), database.MigrateNone)
+}
+
+func backupDB() error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Backup(cfg.OutPath)
+}
+
+func exportBlocks() (uint64, error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	file, err := os.OpenFile(cfg.OutPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	chainName := blockchain.ChainMainParam.Name
+	if cfg.TestNet {
+		chainName = blockchain.ChainTestParam.Name
+	}
+	return blockchain.ExportBlocks(db, chainName, file)
+}
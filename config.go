@@ -12,10 +12,14 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/constant-money/constant-chain/blockchain"
 	"github.com/constant-money/constant-chain/cashec"
 	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/constant-money/constant-chain/mempool"
 	"github.com/constant-money/constant-chain/wallet"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/jessevdk/go-flags"
@@ -27,6 +31,9 @@ const (
 	defaultDataDirname            = "data"
 	defaultDatabaseDirname        = "block"
 	defaultDatabaseMempoolDirname = "mempool"
+	defaultDBMigrate              = "auto"
+	defaultDBDriver               = "leveldb"
+	defaultDBCacheSize            = 10000
 	defaultLogLevel               = "info"
 	defaultLogDirname             = "logs"
 	defaultLogFilename            = "log.log"
@@ -43,6 +50,10 @@ const (
 	defaultNodeMode               = common.NODEMODE_RELAY
 	defaultTxPoolTTL              = uint(86400) * 10 // in second
 	defaultTxPoolMaxTx            = uint64(20000)
+	defaultTxPoolMaxPending       = 1000
+	defaultTxPoolRBFIncrement     = uint64(1)
+	defaultTxHistory              = 10000
+	defaultTxHistoryFileSize      = 100 // in MB
 	// For wallet
 	defaultWalletName     = "wallet"
 	defaultPersistMempool = false
@@ -57,6 +68,19 @@ var (
 	defaultLogDir      = filepath.Join(defaultHomeDir, defaultLogDirname)
 )
 
+// dbMigrationModes maps values of --dbmigrate to migration modes of database
+var dbMigrationModes = map[string]database.MigrationMode{
+	"auto":   database.MigrateAuto,
+	"dryrun": database.MigrateDryRun,
+	"no":     database.MigrateNone,
+}
+
+// dbDrivers are the values of --dbdriver, names of block database drivers
+var dbDrivers = map[string]bool{
+	"leveldb":  true,
+	"badgerdb": true,
+}
+
 // runServiceCommand is only set to a real function on Windows.  It is used
 // to parse and execute service commands specified via the -s flag.
 var runServiceCommand func(string) error
@@ -69,6 +93,12 @@ type config struct {
 	DataDir            string `short:"D" long:"datadir" description:"Directory to store data"`
 	DatabaseDir        string `short:"d" long:"datapre" description:"Database dir"`
 	DatabaseMempoolDir string `short:"m" long:"datamempool" description:"Mempool Database Dir"`
+	MemDB              bool   `long:"memdb" description:"Keep block and mempool database in memory, nothing is stored and data is lost when node stops"`
+	PruneDepth         uint64 `long:"prune" description:"Delete bodies of shard and beacon blocks which are older than this number of blocks, headers and state are kept -- 0 disables pruning"`
+	Archive            bool   `long:"archive" description:"Keep full history of blocks and advertise it to peers, can't be used with --prune"`
+	DBMigrate          string `long:"dbmigrate" description:"What to do with pending database schema migrations at startup {auto, dryrun, no} -- dryrun logs pending migrations and exits"`
+	DBCacheSize        int    `long:"dbcache" description:"Number of blocks, headers and commitment lookups which are cached in memory in front of block database -- 0 disables the cache"`
+	DBDriver           string `long:"dbdriver" description:"Key-value store of block database {leveldb, badgerdb} -- an existing database must be opened with the driver which created it"`
 	LogDir             string `short:"l" long:"logdir" description:"Directory to log output."`
 	LogLevel           string `long:"loglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 
@@ -89,6 +119,9 @@ type config struct {
 
 	ExternalAddress string `long:"externaladdress" description:"External address"`
 
+	DisableCompactBlock bool `long:"nocompactblock" description:"Disable compact shard block relay, full shard blocks are sent and received"`
+	DisableGossip       bool `long:"nogossip" description:"Disable gossip pubsub topics, shard and beacon messages are flooded to connected peers"`
+
 	RPCDisableAuth bool     `long:"norpcauth" description:"Disable RPC authorization by username/password"`
 	RPCUser        string   `short:"u" long:"rpcuser" description:"Username for RPC connections"`
 	RPCPass        string   `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
@@ -121,11 +154,23 @@ type config struct {
 
 	FastStartup bool `long:"faststartup" description:"Load existed shard/chain dependencies instead of rebuild from block data"`
 
-	TxPoolTTL   uint   `long:"txpoolttl" description:"Set Time To Live (TTL) Value for transaction that enter pool"`
-	TxPoolMaxTx uint64 `long:"txpoolmaxtx" description:"Set Maximum number of transaction in pool"`
-
-	LoadMempool    bool `long:"loadmempool" description:"Load transactions from Mempool database"`
-	PersistMempool bool `long:"persistmempool" description:"Persistence transaction in memepool database"`
+	TxPoolTTL          uint     `long:"txpoolttl" description:"Set Time To Live (TTL) Value for transaction that enter pool"`
+	TxPoolMaxTx        uint64   `long:"txpoolmaxtx" description:"Set Maximum number of transaction of a shard in pool -- a full pool evicts its lowest fee per KB transaction for a new one which pays more"`
+	TxPoolMaxSize      uint64   `long:"txpoolmaxsize" description:"Set Maximum size in KB of transactions of a shard in pool -- 0 is no limit"`
+	TxPoolShardLimits  []string `long:"txpoolshardlimit" description:"Set limits of pool for transactions of a shard as shardid:maxtx:maxsize:ttl, they override --txpoolmaxtx, --txpoolmaxsize and --txpoolttl for that shard -- may be specified multiple times"`
+	TxPoolReplaceByFee bool     `long:"txpoolrbf" description:"Let a transaction replace transactions in pool which spend its serial numbers if it spends all of their serial numbers and pays more fee and fee per KB"`
+	TxPoolRBFIncrement uint64   `long:"txpoolrbfincrement" description:"Fee per KB which a replacing transaction pays on top of fees of all transactions it evicts with their descendants"`
+	TxPoolWorkers      int      `long:"txpoolworkers" description:"Number of transactions whose privacy proofs are verified at the same time -- 0 is number of CPU"`
+	TxPoolMaxPending   int      `long:"txpoolmaxpending" description:"Max number of transactions which wait to be verified, transactions from peers are dropped when it is reached"`
+	TxPoolMaxRejects   int      `long:"txpoolmaxrejects" description:"Max number of recently rejected transactions whose reject reason is kept for getmempoolentry"`
+	TxPoolCPFP         bool     `long:"txpoolcpfp" description:"Let a transaction without privacy spend output coins of transactions in pool, its fee counts for fee per KB of their package when blocks are built"`
+	TxHistory          int      `long:"txhistory" description:"Max number of transactions whose lifecycle history (received from peer, validated, forwarded, included in block, removed from pool) is kept for gettxhistory -- 0 is no history"`
+	TxHistoryFile      string   `long:"txhistoryfile" description:"File which lifecycle events of transactions are appended to as JSON lines, for debugging propagation of transactions"`
+	TxHistoryFileSize  int      `long:"txhistoryfilesize" description:"Size in MB at which tx history file is renamed with suffix .1 and a new file is started"`
+
+	LoadMempool     bool `long:"loadmempool" description:"Load transactions from Mempool database"`
+	PersistMempool  bool `long:"persistmempool" description:"Persistence transaction in memepool database"`
+	MempoolSnapshot uint `long:"mempoolsnapshot" description:"Interval in second of snapshots of mempool to mempool database with --persistmempool, 0 is no periodic snapshot -- mempool is also saved when node stops"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service on
@@ -284,6 +329,9 @@ func loadConfig() (*config, []string, error) {
 		DataDir:            defaultDataDir,
 		DatabaseDir:        defaultDatabaseDirname,
 		DatabaseMempoolDir: defaultDatabaseMempoolDirname,
+		DBMigrate:          defaultDBMigrate,
+		DBDriver:           defaultDBDriver,
+		DBCacheSize:        defaultDBCacheSize,
 		LogDir:             defaultLogDir,
 		RPCKey:             defaultRPCKeyFile,
 		RPCCert:            defaultRPCCertFile,
@@ -300,6 +348,10 @@ func loadConfig() (*config, []string, error) {
 		FastStartup:          defaultFastStartup,
 		TxPoolTTL:            defaultTxPoolTTL,
 		TxPoolMaxTx:          defaultTxPoolMaxTx,
+		TxPoolMaxPending:     defaultTxPoolMaxPending,
+		TxPoolRBFIncrement:   defaultTxPoolRBFIncrement,
+		TxHistory:            defaultTxHistory,
+		TxHistoryFileSize:    defaultTxHistoryFileSize,
 		PersistMempool:       defaultPersistMempool,
 	}
 
@@ -444,6 +496,68 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// --prune keeps enough blocks to verify new blocks and it can't be used
+	// with --archive
+	if cfg.PruneDepth > 0 && cfg.PruneDepth < blockchain.MinPruneDepth {
+		str := "%s: the --prune option must be 0 or at least %d"
+		err := fmt.Errorf(str, funcName, blockchain.MinPruneDepth)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.PruneDepth > 0 && cfg.Archive {
+		str := "%s: the --prune and --archive options can not be mixed"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --dbmigrate must be a known mode
+	if _, ok := dbMigrationModes[cfg.DBMigrate]; !ok {
+		str := "%s: the --dbmigrate option must be one of auto, dryrun, no: %s"
+		err := fmt.Errorf(str, funcName, cfg.DBMigrate)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --dbdriver must be a known driver
+	if !dbDrivers[cfg.DBDriver] {
+		str := "%s: the --dbdriver option must be one of leveldb, badgerdb: %s"
+		err := fmt.Errorf(str, funcName, cfg.DBDriver)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --dbcache can't be negative
+	if cfg.DBCacheSize < 0 {
+		str := "%s: the --dbcache option can't be negative: %d"
+		err := fmt.Errorf(str, funcName, cfg.DBCacheSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --txpoolworkers, --txpoolmaxpending, --txpoolmaxrejects, --txhistory and --txhistoryfilesize can't be negative
+	if cfg.TxPoolWorkers < 0 || cfg.TxPoolMaxPending < 0 || cfg.TxPoolMaxRejects < 0 || cfg.TxHistory < 0 || cfg.TxHistoryFileSize < 0 {
+		str := "%s: the --txpoolworkers, --txpoolmaxpending, --txpoolmaxrejects, --txhistory and --txhistoryfilesize options can't be negative: %d, %d, %d, %d, %d"
+		err := fmt.Errorf(str, funcName, cfg.TxPoolWorkers, cfg.TxPoolMaxPending, cfg.TxPoolMaxRejects, cfg.TxHistory, cfg.TxHistoryFileSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --txpoolshardlimit must be shardid:maxtx:maxsize:ttl of a known shard
+	if _, err := parseTxPoolShardLimits(cfg.TxPoolShardLimits); err != nil {
+		str := "%s: the --txpoolshardlimit option is invalid: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// --proxy or --connect without --listen disables listening.
 	if (cfg.Proxy != common.EmptyString || len(cfg.ConnectPeers) > 0) &&
 		len(cfg.Listener) == 0 {
@@ -552,8 +666,7 @@ func loadConfig() (*config, []string, error) {
 
 	if cfg.DiscoverPeers {
 		if cfg.DiscoverPeersAddress == "" {
-			err := errors.New("discover peers server is empty")
-			return nil, nil, err
+			Logger.log.Info("Discover peers server is empty, peers are discovered from known addresses only")
 		}
 	}
 
@@ -566,6 +679,40 @@ func loadConfig() (*config, []string, error) {
 	return &cfg, remainingArgs, nil
 }
 
+// parseTxPoolShardLimits parses values of --txpoolshardlimit, each value is
+// shardid:maxtx:maxsize:ttl
+func parseTxPoolShardLimits(values []string) (map[byte]mempool.PoolLimit, error) {
+	limits := make(map[byte]mempool.PoolLimit)
+	for _, value := range values {
+		fields := strings.Split(value, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s is not shardid:maxtx:maxsize:ttl", value)
+		}
+		shardID, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil || shardID >= common.MAX_SHARD_NUMBER {
+			return nil, fmt.Errorf("shard id of %s must be less than %d", value, common.MAX_SHARD_NUMBER)
+		}
+		maxTx, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("max transaction of %s is invalid: %v", value, err)
+		}
+		maxSize, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("max size of %s is invalid: %v", value, err)
+		}
+		ttl, err := strconv.ParseUint(fields[3], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("ttl of %s is invalid: %v", value, err)
+		}
+		limits[byte(shardID)] = mempool.PoolLimit{
+			MaxTx:      maxTx,
+			MaxSize:    maxSize,
+			TxLifeTime: uint(ttl),
+		}
+	}
+	return limits, nil
+}
+
 // supportedSubsystems returns a sorted slice of the supported subsystems for
 // logging purposes.
 func supportedSubsystems() []string {
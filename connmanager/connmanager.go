@@ -1,6 +1,7 @@
 package connmanager
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -11,6 +12,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/constant-money/constant-chain/addrmanager"
 	"github.com/constant-money/constant-chain/bootnode/server"
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/peer"
@@ -126,6 +128,11 @@ type Config struct {
 	DiscoverPeers        bool
 	DiscoverPeersAddress string
 	ConsensusState       *ConsensusState
+
+	// AddrManager keeps the addresses learned from the bootnode and from addr
+	// messages of connected peers, it is used to discover peers when the
+	// bootnode is not reachable
+	AddrManager *addrmanager.AddrManager
 }
 
 type DiscoverPeerInfo struct {
@@ -226,22 +233,24 @@ func (connManager ConnManager) New(cfg *Config) *ConnManager {
 }
 
 func (connManager *ConnManager) GetPeerId(addr string) string {
-	ipfsAddr, err := ma.NewMultiaddr(addr)
+	peerId, err := connManager.getPeerIdFromRawAddress(addr)
 	if err != nil {
 		Logger.log.Error(err)
 		return common.EmptyString
 	}
-	pid, err := ipfsAddr.ValueForProtocol(ma.P_IPFS)
+	return peerId.Pretty()
+}
+
+func (connManager *ConnManager) getPeerIdFromRawAddress(addr string) (libpeer.ID, error) {
+	ipfsAddr, err := ma.NewMultiaddr(addr)
 	if err != nil {
-		Logger.log.Error(err)
-		return common.EmptyString
+		return "", err
 	}
-	peerId, err := libpeer.IDB58Decode(pid)
+	pid, err := ipfsAddr.ValueForProtocol(ma.P_IPFS)
 	if err != nil {
-		Logger.log.Error(err)
-		return common.EmptyString
+		return "", err
 	}
-	return peerId.Pretty()
+	return libpeer.IDB58Decode(pid)
 }
 
 // Connect assigns an id and dials a connection to the address of the
@@ -293,12 +302,10 @@ func (connManager *ConnManager) Connect(addr string, pubKey string, cConn chan *
 		HandleFailed:       connManager.handleFailed,
 	}
 
-	if pubKey != common.EmptyString {
-		peer.PublicKey = pubKey
-	}
-
+	// public key of remote peer is not trusted until it is proven in
+	// version handshake
 	listen.Host.Peerstore().AddAddr(peer.PeerID, peer.TargetAddress, pstore.PermanentAddrTTL)
-	Logger.log.Info("DEBUG Connect to RemotePeer", peer.PublicKey)
+	Logger.log.Info("DEBUG Connect to RemotePeer", pubKey)
 	Logger.log.Info(listen.Host.Peerstore().Addrs(peer.PeerID))
 	listen.PushConn(&peer, cConn)
 }
@@ -323,7 +330,7 @@ func (connManager *ConnManager) Start(discoverPeerAddress string) {
 		go connManager.listenHandler(listner)
 		connManager.ListeningPeer = listner
 
-		if connManager.Config.DiscoverPeers && connManager.Config.DiscoverPeersAddress != common.EmptyString {
+		if connManager.Config.DiscoverPeers {
 			Logger.log.Infof("DiscoverPeers: true\n----------------------------------------------------------------\n|               Discover peer url: %s               |\n----------------------------------------------------------------", connManager.Config.DiscoverPeersAddress)
 			go connManager.DiscoverPeers(discoverPeerAddress)
 		}
@@ -375,99 +382,178 @@ func (connManager *ConnManager) DiscoverPeers(discoverPeerAddress string) {
 }
 
 func (connManager *ConnManager) processDiscoverPeers() {
-	discoverPeerAddress := connManager.discoverPeerAddress
-	if discoverPeerAddress == "" {
+	if !connManager.Config.DiscoverPeers {
 		return
 	}
-	client, err := rpc.Dial("tcp", discoverPeerAddress)
+	mPeers := make(map[string]*wire.RawPeer)
+	response, err := connManager.pingBootnode()
 	if err != nil {
-		Logger.log.Error("[Exchange Peers] re-connect:")
+		// bootnode is down or not configured, ask connected peers for their
+		// known addresses, the answers are collected by addr manager
+		Logger.log.Error("[Exchange Peers] discover from bootnode failed, fallback to known addresses")
 		Logger.log.Error(err)
+		connManager.requestAddrFromPeers()
+	}
+	// make models
+	for _, rawPeer := range response {
+		p := rawPeer
+		mPeers[rawPeer.PublicKey] = &p
+		connManager.addKnownAddress(&p)
+	}
+	for _, rawPeer := range connManager.getKnownPeers() {
+		if _, ok := mPeers[rawPeer.PublicKey]; !ok {
+			p := rawPeer
+			mPeers[rawPeer.PublicKey] = &p
+		}
+	}
+	if len(mPeers) == 0 {
 		return
 	}
-	if client != nil {
-		defer client.Close()
-
-		listener := connManager.Config.ListenerPeer
-		var response []wire.RawPeer
+	// connect to beacon peers
+	connManager.handleRandPeersOfBeacon(connManager.Config.MaxPeersBeacon, mPeers)
+	// connect to same shard peers
+	connManager.handleRandPeersOfShard(connManager.Config.ConsensusState.CurrentShard, connManager.Config.MaxPeersSameShard, mPeers)
+	// connect to other shard peers
+	connManager.handleRandPeersOfOtherShard(connManager.Config.ConsensusState.CurrentShard, connManager.Config.MaxPeersOtherShard, connManager.Config.MaxPeersOther, mPeers)
+	// connect to no shard peers
+	connManager.handleRandPeersOfNoShard(connManager.Config.MaxPeersNoShard, mPeers)
+}
 
-		externalAddress := connManager.Config.ExternalAddress
-		Logger.log.Info("Start Process Discover Peers ExternalAddress", externalAddress)
+// pingBootnode registers this node on the bootnode server and returns the
+// list of peers which the bootnode knows
+func (connManager *ConnManager) pingBootnode() ([]wire.RawPeer, error) {
+	discoverPeerAddress := connManager.discoverPeerAddress
+	if discoverPeerAddress == common.EmptyString {
+		return nil, errors.New("discover peers address is empty")
+	}
+	client, err := rpc.Dial("tcp", discoverPeerAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
 
-		// remove later
-		rawAddress := listener.RawAddress
-		rawPort := listener.Port
-		if externalAddress == common.EmptyString {
-			externalAddress = os.Getenv("EXTERNAL_ADDRESS")
+	listener := connManager.Config.ListenerPeer
+	var response []wire.RawPeer
+
+	externalAddress := connManager.Config.ExternalAddress
+	Logger.log.Info("Start Process Discover Peers ExternalAddress", externalAddress)
+
+	// remove later
+	rawAddress := listener.RawAddress
+	rawPort := listener.Port
+	if externalAddress == common.EmptyString {
+		externalAddress = os.Getenv("EXTERNAL_ADDRESS")
+	}
+	if externalAddress != common.EmptyString {
+		host, port, err := net.SplitHostPort(externalAddress)
+		if err == nil && host != common.EmptyString {
+			rawAddress = strings.Replace(rawAddress, "127.0.0.1", host, 1)
+			rawAddress = strings.Replace(rawAddress, "0.0.0.0", host, 1)
+			rawAddress = strings.Replace(rawAddress, "localhost", host, 1)
+			rawAddress = strings.Replace(rawAddress, fmt.Sprintf("/%s/", rawPort), fmt.Sprintf("/%s/", port), 1)
 		}
-		if externalAddress != common.EmptyString {
-			host, port, err := net.SplitHostPort(externalAddress)
-			if err == nil && host != common.EmptyString {
-				rawAddress = strings.Replace(rawAddress, "127.0.0.1", host, 1)
-				rawAddress = strings.Replace(rawAddress, "0.0.0.0", host, 1)
-				rawAddress = strings.Replace(rawAddress, "localhost", host, 1)
-				rawAddress = strings.Replace(rawAddress, fmt.Sprintf("/%s/", rawPort), fmt.Sprintf("/%s/", port), 1)
-			}
-		} else {
-			rawAddress = ""
+	} else {
+		rawAddress = ""
+	}
+
+	role, shardID := connManager.GetCurrentRoleShard()
+	timestamp := time.Now().Unix()
+	pbkB58 := ""
+	signDataB58 := ""
+	if listener.Config.UserKeySet != nil {
+		pbkB58 = listener.Config.UserKeySet.GetPublicKeyB58()
+		Logger.log.Info("Start Process Discover Peers", pbkB58)
+		// sign data, bootnode verifies it is signed by pbkB58 and not older
+		// than its time drift
+		signDataB58, err = listener.Config.UserKeySet.SignDataB58(server.PingSignData(rawAddress, role, shardID, timestamp))
+		if err != nil {
+			Logger.log.Error(err)
 		}
+	}
 
-		pbkB58 := ""
-		signDataB58 := ""
-		if listener.Config.UserKeySet != nil {
-			pbkB58 = listener.Config.UserKeySet.GetPublicKeyB58()
-			Logger.log.Info("Start Process Discover Peers", pbkB58)
-			// sign data
-			signDataB58, err = listener.Config.UserKeySet.SignDataB58([]byte(rawAddress))
-			if err != nil {
-				Logger.log.Error(err)
-			}
-		}
+	args := &server.PingArgs{
+		RawAddress: rawAddress,
+		PublicKey:  pbkB58,
+		SignData:   signDataB58,
+		Role:       role,
+		ShardID:    shardID,
+		Timestamp:  timestamp,
+	}
+	Logger.log.Infof("[Exchange Peers] Ping %+v", args)
 
-		args := &server.PingArgs{
-			RawAddress: rawAddress,
-			PublicKey:  pbkB58,
-			SignData:   signDataB58,
-		}
-		Logger.log.Infof("[Exchange Peers] Ping %+v", args)
-
-		Logger.log.Info("Dump PeerConns", len(listener.PeerConns))
-		for pubK, info := range connManager.discoveredPeers {
-			var result []string
-			for _, peerConn := range listener.PeerConns {
-				if peerConn.RemotePeer.PublicKey == pubK {
-					result = append(result, peerConn.RemotePeer.PeerID.Pretty())
-				}
+	Logger.log.Info("Dump PeerConns", len(listener.PeerConns))
+	for pubK, info := range connManager.discoveredPeers {
+		var result []string
+		for _, peerConn := range listener.PeerConns {
+			if peerConn.RemotePeer.PublicKey == pubK {
+				result = append(result, peerConn.RemotePeer.PeerID.Pretty())
 			}
-			Logger.log.Infof("Public PubKey %s, %s, %s", pubK, info.PeerID.Pretty(), result)
 		}
+		Logger.log.Infof("Public PubKey %s, %s, %s", pubK, info.PeerID.Pretty(), result)
+	}
 
-		for _, peerConn := range listener.PeerConns {
-			Logger.log.Info("PeerConn state %s %s %s", peerConn.ConnState(), peerConn.GetIsOutbound(), peerConn.RemotePeerID.Pretty(), peerConn.RemotePeer.RawAddress)
-		}
+	for _, peerConn := range listener.PeerConns {
+		Logger.log.Info("PeerConn state %s %s %s", peerConn.ConnState(), peerConn.GetIsOutbound(), peerConn.RemotePeerID.Pretty(), peerConn.RemotePeer.RawAddress)
+	}
 
-		err := client.Call("Handler.Ping", args, &response)
-		if err != nil {
-			Logger.log.Error("[Exchange Peers] Ping:")
-			Logger.log.Error(err)
-			client = nil
-			return
-		}
-		// make models
-		mPeers := make(map[string]*wire.RawPeer)
-		for _, rawPeer := range response {
-			p := rawPeer
-			mPeers[rawPeer.PublicKey] = &p
+	err = client.Call("Handler.Ping", args, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// requestAddrFromPeers sends a getaddr message to all connected peers, they
+// answer with the addresses they know through addr message
+func (connManager *ConnManager) requestAddrFromPeers() {
+	listener := connManager.Config.ListenerPeer
+	if listener == nil {
+		return
+	}
+	msg, err := wire.MakeEmptyMessage(wire.CmdGetAddr)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	for _, peerConn := range listener.GetPeerConnOfAll() {
+		peerConn.QueueMessageWithEncoding(msg, nil, peer.MESSAGE_TO_PEER, nil)
+	}
+}
+
+// addKnownAddress saves a peer from bootnode into addr manager, so it can be
+// reached again after a restart even if the bootnode is down
+func (connManager *ConnManager) addKnownAddress(rawPeer *wire.RawPeer) {
+	if connManager.Config.AddrManager == nil || rawPeer.RawAddress == common.EmptyString {
+		return
+	}
+	peerID, err := connManager.getPeerIdFromRawAddress(rawPeer.RawAddress)
+	if err != nil {
+		return
+	}
+	connManager.Config.AddrManager.AddAddress(&peer.Peer{
+		RawAddress: rawPeer.RawAddress,
+		PublicKey:  rawPeer.PublicKey,
+		PeerID:     peerID,
+	})
+}
+
+// getKnownPeers returns the peers with public key in addr manager, they were
+// learned from bootnode, connected peers or addr messages
+func (connManager *ConnManager) getKnownPeers() []wire.RawPeer {
+	result := make([]wire.RawPeer, 0)
+	if connManager.Config.AddrManager == nil {
+		return result
+	}
+	for _, p := range connManager.Config.AddrManager.AddressCache() {
+		if p.PublicKey == common.EmptyString || p.RawAddress == common.EmptyString {
+			continue
 		}
-		// connect to beacon peers
-		connManager.handleRandPeersOfBeacon(connManager.Config.MaxPeersBeacon, mPeers)
-		// connect to same shard peers
-		connManager.handleRandPeersOfShard(connManager.Config.ConsensusState.CurrentShard, connManager.Config.MaxPeersSameShard, mPeers)
-		// connect to other shard peers
-		connManager.handleRandPeersOfOtherShard(connManager.Config.ConsensusState.CurrentShard, connManager.Config.MaxPeersOtherShard, connManager.Config.MaxPeersOther, mPeers)
-		// connect to no shard peers
-		connManager.handleRandPeersOfNoShard(connManager.Config.MaxPeersNoShard, mPeers)
+		result = append(result, wire.RawPeer{
+			RawAddress: p.RawAddress,
+			PublicKey:  p.PublicKey,
+		})
 	}
+	return result
 }
 
 func (connManager *ConnManager) getPeerIdsFromPbk(pbk string) []libpeer.ID {
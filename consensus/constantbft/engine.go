@@ -257,6 +257,14 @@ func (engine *Engine) execShardRole(shardID byte) {
 			return
 		}
 		go func() {
+			//PUSH SHARD BLOCK TO SHARD PEERS, only proposer pushes it so
+			//peers don't get a copy from every committee member
+			if roundRole == common.PROPOSER_ROLE {
+				err := engine.config.Server.PushShardBlockToShard(shardBlk)
+				if err != nil {
+					Logger.log.Error("Push shard block error", err)
+				}
+			}
 			//PUSH SHARD TO BEACON
 			//fmt.Println("Create And Push Shard To Beacon Block")
 			newShardToBeaconBlock := shardBlk.CreateShardToBeaconBlock(engine.config.BlockChain)
@@ -1,6 +1,7 @@
 package constantbft
 
 import (
+	"github.com/constant-money/constant-chain/blockchain"
 	"github.com/constant-money/constant-chain/wire"
 	libp2p "github.com/libp2p/go-libp2p-peer"
 )
@@ -19,5 +20,6 @@ type serverInterface interface {
 	PushMessageToShard(wire.Message, byte) error
 	PushMessageToBeacon(wire.Message) error
 	PushMessageToPbk(wire.Message, string) error
+	PushShardBlockToShard(*blockchain.ShardBlock) error
 	UpdateConsensusState(role string, userPbk string, currentShard *byte, beaconCommittee []string, shardCommittee map[byte][]string)
 }
@@ -10,6 +10,7 @@ import (
 	"runtime/debug"
 
 	"github.com/constant-money/constant-chain/database"
+	_ "github.com/constant-money/constant-chain/database/badgerdb"
 	_ "github.com/constant-money/constant-chain/database/lvdb"
 	"github.com/constant-money/constant-chain/databasemp"
 	_ "github.com/constant-money/constant-chain/databasemp/lvdb"
@@ -53,15 +54,37 @@ func mainMaster(serverChan chan<- *Server) error {
 		return nil
 	}
 
-	db, err := database.Open("leveldb", filepath.Join(cfg.DataDir, cfg.DatabaseDir))
+	var db database.DatabaseInterface
+	var dbmp databasemp.DatabaseInterface
+	if cfg.MemDB {
+		Logger.log.Warn("Database is kept in memory, data is lost when node stops")
+		db, err = database.Open("memdb")
+	} else {
+		db, err = database.Open(cfg.DBDriver, filepath.Join(cfg.DataDir, cfg.DatabaseDir), dbMigrationModes[cfg.DBMigrate])
+	}
 	// Create db and use it.
 	if err != nil {
-		Logger.log.Error("could not open connection to leveldb")
+		Logger.log.Errorf("could not open connection to %s", cfg.DBDriver)
 		Logger.log.Error(err)
 		panic(err)
 	}
+	if dbMigrationModes[cfg.DBMigrate] == database.MigrateDryRun {
+		Logger.log.Info("Database migration dry run is done, exit")
+		return db.Close()
+	}
+	if cfg.DBCacheSize > 0 {
+		db, err = database.NewCacheDB(db, cfg.DBCacheSize)
+		if err != nil {
+			Logger.log.Error(err)
+			panic(err)
+		}
+	}
 	// Create db mempool and use it
-	dbmp, err := databasemp.Open("leveldbmempool", filepath.Join(cfg.DataDir, cfg.DatabaseMempoolDir))
+	if cfg.MemDB {
+		dbmp, err = databasemp.Open("memdbmempool")
+	} else {
+		dbmp, err = databasemp.Open("leveldbmempool", filepath.Join(cfg.DataDir, cfg.DatabaseMempoolDir))
+	}
 	if err != nil {
 		Logger.log.Error("could not open connection to leveldb")
 		Logger.log.Error(err)
@@ -0,0 +1,41 @@
+package badgerdb
+
+import (
+	"errors"
+
+	"github.com/constant-money/constant-chain/database"
+	"github.com/constant-money/constant-chain/database/lvdb"
+)
+
+// badgerdb stores the same keys as leveldb driver in a BadgerDB, see lvdb.KV
+func init() {
+	driver := database.Driver{
+		DbType: "badgerdb",
+		Open:   openDriver,
+	}
+	if err := database.RegisterDriver(driver); err != nil {
+		panic("failed to register badgerdb driver")
+	}
+}
+
+func openDriver(args ...interface{}) (database.DatabaseInterface, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, errors.New("invalid arguments")
+	}
+	dbPath, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("expected db path")
+	}
+	mode := database.MigrateAuto
+	if len(args) == 2 {
+		mode, ok = args[1].(database.MigrationMode)
+		if !ok {
+			return nil, errors.New("expected migration mode")
+		}
+	}
+	kv, err := openBadgerKV(dbPath, mode == database.MigrateReadOnly)
+	if err != nil {
+		return nil, database.NewDatabaseError(database.OpenDbErr, err)
+	}
+	return lvdb.OpenKV(kv, mode)
+}
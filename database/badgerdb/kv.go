@@ -0,0 +1,197 @@
+package badgerdb
+
+import (
+	"os"
+
+	"github.com/constant-money/constant-chain/database/lvdb"
+	"github.com/dgraph-io/badger"
+	"github.com/pkg/errors"
+)
+
+// badgerKV is lvdb.KV of a BadgerDB, it doesn't stall on compaction like
+// leveldb when many blocks are inserted
+type badgerKV struct {
+	db *badger.DB
+}
+
+func openBadgerKV(dir string, readOnly bool) (*badgerKV, error) {
+	opts := badger.DefaultOptions
+	opts.Dir = dir
+	opts.ValueDir = dir
+	opts.ReadOnly = readOnly
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "badger.Open %s", dir)
+	}
+	return &badgerKV{db: db}, nil
+}
+
+func (kv *badgerKV) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := kv.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, lvdb.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+func (kv *badgerKV) Has(key []byte) (bool, error) {
+	err := kv.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (kv *badgerKV) Put(key, value []byte) error {
+	return kv.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (kv *badgerKV) Delete(key []byte) error {
+	return kv.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// Write stores writes in one badger transaction, so they are atomic. It
+// fails with badger.ErrTxnTooBig and stores nothing if writes don't fit in a
+// transaction
+func (kv *badgerKV) Write(writes map[string][]byte) error {
+	return kv.db.Update(func(txn *badger.Txn) error {
+		for key, value := range writes {
+			var err error
+			if value == nil {
+				err = txn.Delete([]byte(key))
+			} else {
+				err = txn.Set([]byte(key), value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (kv *badgerKV) NewIterator(prefix []byte) lvdb.Iterator {
+	txn := kv.db.NewTransaction(false)
+	return &badgerIterator{
+		txn:    txn,
+		iter:   txn.NewIterator(badger.DefaultIteratorOptions),
+		prefix: prefix,
+	}
+}
+
+// Backup copies keys of a read transaction to a new BadgerDB, so writes
+// after Backup is called are not in the copy. Keys are written in as many
+// transactions as needed, backup is not used until it is done
+func (kv *badgerKV) Backup(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return errors.Errorf("backup %s already exists", dir)
+	}
+	backup, err := openBadgerKV(dir, false)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+	txn := backup.db.NewTransaction(true)
+	defer func() {
+		txn.Discard()
+	}()
+	iter := kv.NewIterator(nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := append([]byte{}, iter.Key()...)
+		value := append([]byte{}, iter.Value()...)
+		err := txn.Set(key, value)
+		if err == badger.ErrTxnTooBig {
+			if err := txn.Commit(nil); err != nil {
+				return errors.Wrap(err, "txn.Commit")
+			}
+			txn = backup.db.NewTransaction(true)
+			err = txn.Set(key, value)
+		}
+		if err != nil {
+			return errors.Wrap(err, "txn.Set")
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return errors.Wrap(err, "iter.Error")
+	}
+	return errors.Wrap(txn.Commit(nil), "txn.Commit")
+}
+
+func (kv *badgerKV) Close() error {
+	return kv.db.Close()
+}
+
+// badgerIterator iterates keys with prefix in a read transaction, which is
+// discarded on Release
+type badgerIterator struct {
+	txn     *badger.Txn
+	iter    *badger.Iterator
+	prefix  []byte
+	started bool
+	key     []byte
+	value   []byte
+	err     error
+}
+
+func (iter *badgerIterator) Next() bool {
+	if iter.err != nil || iter.iter == nil {
+		return false
+	}
+	if iter.started {
+		iter.iter.Next()
+	} else {
+		iter.iter.Seek(iter.prefix)
+		iter.started = true
+	}
+	if !iter.iter.ValidForPrefix(iter.prefix) {
+		iter.key, iter.value = nil, nil
+		return false
+	}
+	item := iter.iter.Item()
+	iter.key = item.KeyCopy(nil)
+	iter.value, iter.err = item.ValueCopy(nil)
+	return iter.err == nil
+}
+
+func (iter *badgerIterator) Key() []byte {
+	return iter.key
+}
+
+func (iter *badgerIterator) Value() []byte {
+	return iter.value
+}
+
+func (iter *badgerIterator) Release() {
+	if iter.iter == nil {
+		return
+	}
+	iter.iter.Close()
+	iter.txn.Discard()
+	iter.iter = nil
+}
+
+func (iter *badgerIterator) Error() error {
+	return iter.err
+}
@@ -0,0 +1,397 @@
+package database
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/constant-money/constant-chain/common"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+)
+
+// kinds of values in CacheDB, they prefix cache keys and group stats
+const (
+	cacheShardBlock       = "shardblock"
+	cacheBeaconBlock      = "beaconblock"
+	cacheBlockHeader      = "blockheader"
+	cacheShardBlockIndex  = "shardblockindex"
+	cacheBeaconBlockIndex = "beaconblockindex"
+	cacheSerialNumber     = "serialnumber"
+	cacheCommitment       = "commitment"
+	cacheCommitmentIndex  = "commitmentindex"
+)
+
+/*
+CacheDB is a bounded LRU read-through cache in front of a DatabaseInterface
+for hot chain lookups: raw and decoded blocks, headers, block indexes and
+commitment lookups. Serial numbers and commitments are cached only when they
+exist, so storing new ones doesn't make the cache stale.
+DeleteBlock, DeleteBeaconBlock and PruneBlock remove the values of their
+block, RevertBlock and Clean* drop the whole cache. In a transaction they
+take effect on Commit. Put and Delete of raw keys are not tracked
+*/
+type CacheDB struct {
+	DatabaseInterface
+	cache    *lru.Cache
+	capacity int
+
+	mtx sync.Mutex
+	// generation changes on every invalidation, a value read from db is
+	// added only if no invalidation happened while it was read
+	generation uint64
+	stats      map[string]*CacheKindStats
+}
+
+// CacheKindStats counts lookups of a kind of value in CacheDB
+type CacheKindStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CacheStats is a snapshot of CacheDB counters
+type CacheStats struct {
+	Size     int
+	Capacity int
+	Kinds    map[string]CacheKindStats
+}
+
+// NewCacheDB wraps db with a cache of at most size values
+func NewCacheDB(db DatabaseInterface, size int) (*CacheDB, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, NewDatabaseError(UnexpectedError, errors.Wrap(err, "lru.New"))
+	}
+	return &CacheDB{
+		DatabaseInterface: db,
+		cache:             cache,
+		capacity:          size,
+		stats:             make(map[string]*CacheKindStats),
+	}, nil
+}
+
+func cacheKey(kind string, parts ...[]byte) string {
+	size := len(kind) + 1
+	for _, part := range parts {
+		size += len(part)
+	}
+	key := make([]byte, 0, size)
+	key = append(key, kind...)
+	key = append(key, '-')
+	for _, part := range parts {
+		key = append(key, part...)
+	}
+	return string(key)
+}
+
+func heightBytes(height uint64) []byte {
+	return big.NewInt(0).SetUint64(height).Bytes()
+}
+
+// lookup returns cached value of key or loads it from db, load returns false
+// when its value must not be cached
+func (c *CacheDB) lookup(kind string, key string, load func() (interface{}, bool, error)) (interface{}, error) {
+	if value, ok := c.cache.Get(key); ok {
+		c.count(kind, true)
+		return value, nil
+	}
+	c.count(kind, false)
+	c.mtx.Lock()
+	generation := c.generation
+	c.mtx.Unlock()
+	value, cacheable, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if cacheable {
+		c.mtx.Lock()
+		if generation == c.generation {
+			c.cache.Add(key, value)
+		}
+		c.mtx.Unlock()
+	}
+	return value, nil
+}
+
+func (c *CacheDB) count(kind string, hit bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	stats, ok := c.stats[kind]
+	if !ok {
+		stats = &CacheKindStats{}
+		c.stats[kind] = stats
+	}
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+}
+
+func (c *CacheDB) remove(keys ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.generation++
+	for _, key := range keys {
+		c.cache.Remove(key)
+	}
+}
+
+func (c *CacheDB) purge() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.generation++
+	c.cache.Purge()
+}
+
+// Stats returns hit and miss counters of every kind of value
+func (c *CacheDB) Stats() CacheStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	stats := CacheStats{
+		Size:     c.cache.Len(),
+		Capacity: c.capacity,
+		Kinds:    make(map[string]CacheKindStats, len(c.stats)),
+	}
+	for kind, kindStats := range c.stats {
+		stats.Kinds[kind] = *kindStats
+	}
+	return stats
+}
+
+// blockKeys are the cache keys of a block which is deleted or pruned
+func blockKeys(hash *common.Hash) []string {
+	return []string{
+		cacheKey(cacheShardBlock, hash[:]),
+		cacheKey(cacheBeaconBlock, hash[:]),
+		cacheKey(cacheBlockHeader, hash[:]),
+		cacheKey("decoded"+cacheShardBlock, hash[:]),
+		cacheKey("decoded"+cacheBeaconBlock, hash[:]),
+	}
+}
+
+func shardBlockIndexKey(height uint64, shardID byte) string {
+	return cacheKey(cacheShardBlockIndex, []byte{shardID}, heightBytes(height))
+}
+
+func beaconBlockIndexKey(height uint64) string {
+	return cacheKey(cacheBeaconBlockIndex, heightBytes(height))
+}
+
+/*
+FetchDecodedBlock returns the value which decode builds from the bytes of
+block hash, isBeacon tells which chain the block is of. Decoded blocks are
+shared by callers and must not be modified
+*/
+func (c *CacheDB) FetchDecodedBlock(hash *common.Hash, isBeacon bool, decode func([]byte) (interface{}, error)) (interface{}, error) {
+	kind := "decoded" + cacheShardBlock
+	fetch := c.DatabaseInterface.FetchBlock
+	if isBeacon {
+		kind = "decoded" + cacheBeaconBlock
+		fetch = c.DatabaseInterface.FetchBeaconBlock
+	}
+	return c.lookup(kind, cacheKey(kind, hash[:]), func() (interface{}, bool, error) {
+		blockBytes, err := fetch(hash)
+		if err != nil {
+			return nil, false, err
+		}
+		value, err := decode(blockBytes)
+		return value, err == nil, err
+	})
+}
+
+func (c *CacheDB) fetchBytes(kind string, hash *common.Hash, fetch func(*common.Hash) ([]byte, error)) ([]byte, error) {
+	value, err := c.lookup(kind, cacheKey(kind, hash[:]), func() (interface{}, bool, error) {
+		value, err := fetch(hash)
+		return value, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+func (c *CacheDB) FetchBlock(hash *common.Hash) ([]byte, error) {
+	return c.fetchBytes(cacheShardBlock, hash, c.DatabaseInterface.FetchBlock)
+}
+
+func (c *CacheDB) FetchBeaconBlock(hash *common.Hash) ([]byte, error) {
+	return c.fetchBytes(cacheBeaconBlock, hash, c.DatabaseInterface.FetchBeaconBlock)
+}
+
+func (c *CacheDB) FetchBlockHeader(hash *common.Hash) ([]byte, error) {
+	return c.fetchBytes(cacheBlockHeader, hash, c.DatabaseInterface.FetchBlockHeader)
+}
+
+func (c *CacheDB) GetBlockByIndex(height uint64, shardID byte) (*common.Hash, error) {
+	value, err := c.lookup(cacheShardBlockIndex, shardBlockIndexKey(height, shardID), func() (interface{}, bool, error) {
+		hash, err := c.DatabaseInterface.GetBlockByIndex(height, shardID)
+		return hash, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	hash := *value.(*common.Hash)
+	return &hash, nil
+}
+
+func (c *CacheDB) GetBeaconBlockHashByIndex(height uint64) (*common.Hash, error) {
+	value, err := c.lookup(cacheBeaconBlockIndex, beaconBlockIndexKey(height), func() (interface{}, bool, error) {
+		hash, err := c.DatabaseInterface.GetBeaconBlockHashByIndex(height)
+		return hash, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	hash := *value.(*common.Hash)
+	return &hash, nil
+}
+
+func (c *CacheDB) HasSerialNumber(tokenID *common.Hash, data []byte, shardID byte) (bool, error) {
+	key := cacheKey(cacheSerialNumber, tokenID[:], []byte{shardID}, data)
+	value, err := c.lookup(cacheSerialNumber, key, func() (interface{}, bool, error) {
+		has, err := c.DatabaseInterface.HasSerialNumber(tokenID, data, shardID)
+		return has, has, err
+	})
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+func (c *CacheDB) HasCommitment(tokenID *common.Hash, commitment []byte, shardID byte) (bool, error) {
+	key := cacheKey(cacheCommitment, tokenID[:], []byte{shardID}, commitment)
+	value, err := c.lookup(cacheCommitment, key, func() (interface{}, bool, error) {
+		has, err := c.DatabaseInterface.HasCommitment(tokenID, commitment, shardID)
+		return has, has, err
+	})
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+func (c *CacheDB) GetCommitmentByIndex(tokenID *common.Hash, commitmentIndex uint64, shardID byte) ([]byte, error) {
+	key := cacheKey(cacheCommitmentIndex, tokenID[:], []byte{shardID}, heightBytes(commitmentIndex))
+	value, err := c.lookup(cacheCommitmentIndex, key, func() (interface{}, bool, error) {
+		commitment, err := c.DatabaseInterface.GetCommitmentByIndex(tokenID, commitmentIndex, shardID)
+		return commitment, err == nil && commitment != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	commitment, _ := value.([]byte)
+	return commitment, nil
+}
+
+func (c *CacheDB) DeleteBlock(hash *common.Hash, height uint64, shardID byte) error {
+	defer c.remove(append(blockKeys(hash), shardBlockIndexKey(height, shardID))...)
+	return c.DatabaseInterface.DeleteBlock(hash, height, shardID)
+}
+
+func (c *CacheDB) DeleteBeaconBlock(hash *common.Hash, height uint64) error {
+	defer c.remove(append(blockKeys(hash), beaconBlockIndexKey(height))...)
+	return c.DatabaseInterface.DeleteBeaconBlock(hash, height)
+}
+
+func (c *CacheDB) PruneBlock(hash *common.Hash, header interface{}) error {
+	defer c.remove(blockKeys(hash)...)
+	return c.DatabaseInterface.PruneBlock(hash, header)
+}
+
+func (c *CacheDB) StoreShardBlockIndex(hash *common.Hash, height uint64, shardID byte) error {
+	defer c.remove(shardBlockIndexKey(height, shardID))
+	return c.DatabaseInterface.StoreShardBlockIndex(hash, height, shardID)
+}
+
+func (c *CacheDB) StoreBeaconBlockIndex(hash *common.Hash, height uint64) error {
+	defer c.remove(beaconBlockIndexKey(height))
+	return c.DatabaseInterface.StoreBeaconBlockIndex(hash, height)
+}
+
+func (c *CacheDB) RevertBlock(hash *common.Hash) error {
+	defer c.purge()
+	return c.DatabaseInterface.RevertBlock(hash)
+}
+
+func (c *CacheDB) CleanSerialNumbers() error {
+	defer c.purge()
+	return c.DatabaseInterface.CleanSerialNumbers()
+}
+
+func (c *CacheDB) CleanCommitments() error {
+	defer c.purge()
+	return c.DatabaseInterface.CleanCommitments()
+}
+
+// Begin opens a transaction whose invalidations of cache are applied when
+// it is committed, reads of the transaction don't use the cache
+func (c *CacheDB) Begin() (Transaction, error) {
+	tx, err := c.DatabaseInterface.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &cacheTx{Transaction: tx, cache: c}, nil
+}
+
+// cacheTx keeps the cache keys which are invalidated by writes of a
+// transaction until it is committed
+type cacheTx struct {
+	Transaction
+	cache *CacheDB
+	keys  []string
+	purge bool
+}
+
+func (tx *cacheTx) Commit() error {
+	err := tx.Transaction.Commit()
+	// db may be written even if commit fails, so the cache is invalidated
+	// anyway
+	if tx.purge {
+		tx.cache.purge()
+	} else if len(tx.keys) > 0 {
+		tx.cache.remove(tx.keys...)
+	}
+	return err
+}
+
+func (tx *cacheTx) DeleteBlock(hash *common.Hash, height uint64, shardID byte) error {
+	tx.keys = append(tx.keys, blockKeys(hash)...)
+	tx.keys = append(tx.keys, shardBlockIndexKey(height, shardID))
+	return tx.Transaction.DeleteBlock(hash, height, shardID)
+}
+
+func (tx *cacheTx) DeleteBeaconBlock(hash *common.Hash, height uint64) error {
+	tx.keys = append(tx.keys, blockKeys(hash)...)
+	tx.keys = append(tx.keys, beaconBlockIndexKey(height))
+	return tx.Transaction.DeleteBeaconBlock(hash, height)
+}
+
+func (tx *cacheTx) PruneBlock(hash *common.Hash, header interface{}) error {
+	tx.keys = append(tx.keys, blockKeys(hash)...)
+	return tx.Transaction.PruneBlock(hash, header)
+}
+
+func (tx *cacheTx) StoreShardBlockIndex(hash *common.Hash, height uint64, shardID byte) error {
+	tx.keys = append(tx.keys, shardBlockIndexKey(height, shardID))
+	return tx.Transaction.StoreShardBlockIndex(hash, height, shardID)
+}
+
+func (tx *cacheTx) StoreBeaconBlockIndex(hash *common.Hash, height uint64) error {
+	tx.keys = append(tx.keys, beaconBlockIndexKey(height))
+	return tx.Transaction.StoreBeaconBlockIndex(hash, height)
+}
+
+func (tx *cacheTx) RevertBlock(hash *common.Hash) error {
+	tx.purge = true
+	return tx.Transaction.RevertBlock(hash)
+}
+
+func (tx *cacheTx) CleanSerialNumbers() error {
+	tx.purge = true
+	return tx.Transaction.CleanSerialNumbers()
+}
+
+func (tx *cacheTx) CleanCommitments() error {
+	tx.purge = true
+	return tx.Transaction.CleanCommitments()
+}
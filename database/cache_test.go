@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/constant-money/constant-chain/database"
+)
+
+func TestCacheDBInvalidation(t *testing.T) {
+	db, teardown := openMemdb(t)
+	defer teardown()
+	cache, err := database.NewCacheDB(db, 100)
+	if err != nil {
+		t.Fatalf("database.NewCacheDB returns err: %+v", err)
+	}
+	block := testBlock{Height: 2}
+	shardID := byte(0)
+	if err := cache.StoreShardBlock(block, shardID); err != nil {
+		t.Fatalf("cache.StoreShardBlock returns err: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if data, err := cache.FetchBlock(block.Hash()); err != nil || !bytes.Equal(data, []byte(`{"Height":2}`)) {
+			t.Fatalf("cache.FetchBlock returns %s, %+v", data, err)
+		}
+	}
+	if stats := cache.Stats().Kinds["shardblock"]; stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("shard block stats are %+v, want 1 hit and 1 miss", stats)
+	}
+
+	// the block is cached until the transaction which deletes it commits
+	tx, err := cache.Begin()
+	if err != nil {
+		t.Fatalf("cache.Begin returns err: %+v", err)
+	}
+	if err := tx.DeleteBlock(block.Hash(), block.Height, shardID); err != nil {
+		t.Fatalf("tx.DeleteBlock returns err: %+v", err)
+	}
+	if _, err := cache.FetchBlock(block.Hash()); err != nil {
+		t.Fatalf("block should be fetched before commit: %+v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit returns err: %+v", err)
+	}
+	if _, err := cache.FetchBlock(block.Hash()); err == nil {
+		t.Fatalf("deleted block should not be fetched from cache")
+	}
+}
+
+func TestCacheDBSerialNumber(t *testing.T) {
+	db, teardown := openMemdb(t)
+	defer teardown()
+	cache, err := database.NewCacheDB(db, 100)
+	if err != nil {
+		t.Fatalf("database.NewCacheDB returns err: %+v", err)
+	}
+	tokenID := testBlock{}.Hash()
+	// a missing serial number is not cached, so it is found once stored
+	if has, _ := cache.HasSerialNumber(tokenID, []byte("sn"), 0); has {
+		t.Fatalf("serial number should not exist")
+	}
+	if err := cache.StoreSerialNumbers(tokenID, []byte("sn"), 0); err != nil {
+		t.Fatalf("cache.StoreSerialNumbers returns err: %+v", err)
+	}
+	if has, _ := cache.HasSerialNumber(tokenID, []byte("sn"), 0); !has {
+		t.Fatalf("serial number should exist")
+	}
+	if err := cache.CleanSerialNumbers(); err != nil {
+		t.Fatalf("cache.CleanSerialNumbers returns err: %+v", err)
+	}
+	if has, _ := cache.HasSerialNumber(tokenID, []byte("sn"), 0); has {
+		t.Fatalf("serial number should be cleaned")
+	}
+}
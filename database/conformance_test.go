@@ -0,0 +1,554 @@
+package database_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	_ "github.com/constant-money/constant-chain/database/badgerdb"
+	_ "github.com/constant-money/constant-chain/database/lvdb"
+)
+
+// drivers lists the drivers which run the conformance tests, every driver
+// must behave the same way. Backup of a driver is opened by backupDriver
+var drivers = []struct {
+	name         string
+	open         func(t *testing.T) (database.DatabaseInterface, func())
+	backupDriver string
+}{
+	{"leveldb", openDiskDriver("leveldb"), "leveldb"},
+	{"memdb", openMemdb, "leveldb"},
+	{"badgerdb", openDiskDriver("badgerdb"), "badgerdb"},
+	{"cachedb", openCacheDB, "leveldb"},
+}
+
+func openDiskDriver(name string) func(t *testing.T) (database.DatabaseInterface, func()) {
+	return func(t *testing.T) (database.DatabaseInterface, func()) {
+		dbPath, err := ioutil.TempDir(os.TempDir(), "test_")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %+v", err)
+		}
+		db, err := database.Open(name, dbPath)
+		if err != nil {
+			t.Fatalf("could not open db path: %s, %+v", dbPath, err)
+		}
+		return db, func() {
+			if err := db.Close(); err != nil {
+				t.Fatalf("db.close %+v", err)
+			}
+			os.RemoveAll(dbPath)
+		}
+	}
+}
+
+func openMemdb(t *testing.T) (database.DatabaseInterface, func()) {
+	db, err := database.Open("memdb")
+	if err != nil {
+		t.Fatalf("could not open memdb: %+v", err)
+	}
+	return db, func() {
+		if err := db.Close(); err != nil {
+			t.Fatalf("db.close %+v", err)
+		}
+	}
+}
+
+// openCacheDB opens a memdb behind CacheDB, it must behave like the db
+// which it wraps
+func openCacheDB(t *testing.T) (database.DatabaseInterface, func()) {
+	db, teardown := openMemdb(t)
+	cache, err := database.NewCacheDB(db, 100)
+	if err != nil {
+		t.Fatalf("database.NewCacheDB returns err: %+v", err)
+	}
+	return cache, teardown
+}
+
+func runConformance(t *testing.T, test func(t *testing.T, db database.DatabaseInterface)) {
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			db, teardown := driver.open(t)
+			defer teardown()
+			test(t, db)
+		})
+	}
+}
+
+type testBlock struct {
+	Height uint64
+}
+
+func (block testBlock) Hash() *common.Hash {
+	hash := common.HashH(common.Uint64ToBytes(block.Height))
+	return &hash
+}
+
+func TestConformanceKeyValue(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		key, value := []byte("key"), []byte("value")
+		if err := db.Put(key, value); err != nil {
+			t.Fatalf("db.Put returns err: %+v", err)
+		}
+		got, err := db.Get(key)
+		if err != nil || !bytes.Equal(got, value) {
+			t.Fatalf("db.Get returns %s, %+v", got, err)
+		}
+		if has, _ := db.HasValue(key); !has {
+			t.Fatalf("key should exist")
+		}
+		if err := db.Delete(key); err != nil {
+			t.Fatalf("db.Delete returns err: %+v", err)
+		}
+		if has, _ := db.HasValue(key); has {
+			t.Fatalf("key should be deleted")
+		}
+		if _, err := db.Get(key); err == nil {
+			t.Fatalf("db.Get of deleted key should return err")
+		}
+	})
+}
+
+func TestConformanceTransaction(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("db.Begin returns err: %+v", err)
+		}
+		tx.Put([]byte("a"), []byte("1"))
+		if has, _ := tx.HasValue([]byte("a")); !has {
+			t.Fatalf("transaction should see its writes")
+		}
+		if has, _ := db.HasValue([]byte("a")); has {
+			t.Fatalf("db should not see writes of opened transaction")
+		}
+		tx.Rollback()
+		if has, _ := db.HasValue([]byte("a")); has {
+			t.Fatalf("rolled back write should not be stored")
+		}
+
+		tx, _ = db.Begin()
+		tx.Put([]byte("b"), []byte("2"))
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("tx.Commit returns err: %+v", err)
+		}
+		if has, _ := db.HasValue([]byte("b")); !has {
+			t.Fatalf("committed write should be stored")
+		}
+		if err := tx.Commit(); err == nil {
+			t.Fatalf("commit of closed transaction should return err")
+		}
+	})
+}
+
+// TestConformanceLargeTransaction commits more writes than a badger
+// transaction can hold, a driver may refuse them but must not store a part
+// of them
+func TestConformanceLargeTransaction(t *testing.T) {
+	const count = 200000
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("db.Begin returns err: %+v", err)
+		}
+		for i := uint64(0); i < count; i++ {
+			if err := tx.Put(common.Uint64ToBytes(i), []byte("value")); err != nil {
+				t.Fatalf("tx.Put returns err: %+v", err)
+			}
+		}
+		committed := tx.Commit() == nil
+		stored := 0
+		for i := uint64(0); i < count; i++ {
+			if got, err := db.Get(common.Uint64ToBytes(i)); err == nil && bytes.Equal(got, []byte("value")) {
+				stored++
+			}
+		}
+		if committed && stored != count {
+			t.Fatalf("%d of %d keys are stored after commit", stored, count)
+		}
+		if !committed && stored != 0 {
+			t.Fatalf("%d of %d keys are stored after commit failed", stored, count)
+		}
+	})
+}
+
+func TestConformanceShardBlock(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		block := testBlock{Height: 2}
+		shardID := byte(1)
+		if err := db.StoreShardBlock(block, shardID); err != nil {
+			t.Fatalf("db.StoreShardBlock returns err: %+v", err)
+		}
+		if err := db.StoreShardBlock(block, shardID); err == nil {
+			t.Fatalf("storing block twice should return err")
+		}
+		if has, _ := db.HasBlock(block.Hash()); !has {
+			t.Fatalf("block should exist")
+		}
+		if data, err := db.FetchBlock(block.Hash()); err != nil || !bytes.Equal(data, []byte(`{"Height":2}`)) {
+			t.Fatalf("db.FetchBlock returns %s, %+v", data, err)
+		}
+		if err := db.StoreShardBlockIndex(block.Hash(), block.Height, shardID); err != nil {
+			t.Fatalf("db.StoreShardBlockIndex returns err: %+v", err)
+		}
+		height, gotShardID, err := db.GetIndexOfBlock(block.Hash())
+		if err != nil || height != block.Height || gotShardID != shardID {
+			t.Fatalf("db.GetIndexOfBlock returns %d %d, %+v", height, gotShardID, err)
+		}
+		hash, err := db.GetBlockByIndex(block.Height, shardID)
+		if err != nil || !hash.IsEqual(block.Hash()) {
+			t.Fatalf("db.GetBlockByIndex returns %v, %+v", hash, err)
+		}
+		if err := db.DeleteBlock(block.Hash(), block.Height, shardID); err != nil {
+			t.Fatalf("db.DeleteBlock returns err: %+v", err)
+		}
+		if has, _ := db.HasBlock(block.Hash()); has {
+			t.Fatalf("block should be deleted")
+		}
+	})
+}
+
+func TestConformanceBeaconBlock(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		block := testBlock{Height: 3}
+		if err := db.StoreBeaconBlock(block); err != nil {
+			t.Fatalf("db.StoreBeaconBlock returns err: %+v", err)
+		}
+		if has, _ := db.HasBeaconBlock(block.Hash()); !has {
+			t.Fatalf("beacon block should exist")
+		}
+		if err := db.StoreBeaconBlockIndex(block.Hash(), block.Height); err != nil {
+			t.Fatalf("db.StoreBeaconBlockIndex returns err: %+v", err)
+		}
+		height, err := db.GetIndexOfBeaconBlock(block.Hash())
+		if err != nil || height != block.Height {
+			t.Fatalf("db.GetIndexOfBeaconBlock returns %d, %+v", height, err)
+		}
+		hash, err := db.GetBeaconBlockHashByIndex(block.Height)
+		if err != nil || !hash.IsEqual(block.Hash()) {
+			t.Fatalf("db.GetBeaconBlockHashByIndex returns %v, %+v", hash, err)
+		}
+	})
+}
+
+func TestConformanceTransactionIndex(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		txID := common.HashH([]byte("tx"))
+		blockHash := common.HashH([]byte("block"))
+		if err := db.StoreTransactionIndex(&txID, &blockHash, 7); err != nil {
+			t.Fatalf("db.StoreTransactionIndex returns err: %+v", err)
+		}
+		hash, index, dbErr := db.GetTransactionIndexById(&txID)
+		if dbErr != nil || !hash.IsEqual(&blockHash) || index != 7 {
+			t.Fatalf("db.GetTransactionIndexById returns %v %d, %+v", hash, index, dbErr)
+		}
+	})
+}
+
+func TestConformanceCommitments(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tokenID := common.HashH([]byte("token"))
+		shardID := byte(0)
+		pubkey := []byte("pubkey")
+		commitments := [][]byte{[]byte("c0"), []byte("c1"), []byte("c2")}
+		for _, commitment := range commitments {
+			if err := db.StoreCommitments(&tokenID, pubkey, commitment, shardID); err != nil {
+				t.Fatalf("db.StoreCommitments returns err: %+v", err)
+			}
+			if err := db.StoreOutputCoins(&tokenID, pubkey, commitment, shardID); err != nil {
+				t.Fatalf("db.StoreOutputCoins returns err: %+v", err)
+			}
+		}
+		length, err := db.GetCommitmentLength(&tokenID, shardID)
+		if err != nil || length.Uint64() != 3 {
+			t.Fatalf("db.GetCommitmentLength returns %v, %+v", length, err)
+		}
+		fetched, err := db.FetchCommitments(&tokenID, shardID)
+		if err != nil || len(fetched) != 3 {
+			t.Fatalf("db.FetchCommitments returns %d commitments, %+v", len(fetched), err)
+		}
+		for i, commitment := range commitments {
+			if !bytes.Equal(fetched[i], commitment) {
+				t.Fatalf("commitment %d is %s, expected %s", i, fetched[i], commitment)
+			}
+			index, err := db.GetCommitmentIndex(&tokenID, commitment, shardID)
+			if err != nil || index.Uint64() != uint64(i) {
+				t.Fatalf("db.GetCommitmentIndex returns %v, %+v", index, err)
+			}
+			got, err := db.GetCommitmentByIndex(&tokenID, uint64(i), shardID)
+			if err != nil || !bytes.Equal(got, commitment) {
+				t.Fatalf("db.GetCommitmentByIndex returns %s, %+v", got, err)
+			}
+		}
+		if has, _ := db.HasCommitment(&tokenID, []byte("c3"), shardID); has {
+			t.Fatalf("commitment should not exist")
+		}
+		indexes, err := db.GetCommitmentIndexsByPubkey(&tokenID, pubkey, shardID)
+		if err != nil || len(indexes) != 3 {
+			t.Fatalf("db.GetCommitmentIndexsByPubkey returns %d indexes, %+v", len(indexes), err)
+		}
+		outcoins, err := db.GetOutcoinsByPubkey(&tokenID, pubkey, shardID)
+		if err != nil || len(outcoins) != 3 || !bytes.Equal(outcoins[2], commitments[2]) {
+			t.Fatalf("db.GetOutcoinsByPubkey returns %d outcoins, %+v", len(outcoins), err)
+		}
+		if err := db.CleanCommitments(); err != nil {
+			t.Fatalf("db.CleanCommitments returns err: %+v", err)
+		}
+		if has, _ := db.HasCommitment(&tokenID, commitments[0], shardID); has {
+			t.Fatalf("commitments should be cleaned")
+		}
+	})
+}
+
+func TestConformanceSerialNumbers(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tokenID := common.HashH([]byte("token"))
+		if err := db.StoreSerialNumbers(&tokenID, []byte("sn"), 0); err != nil {
+			t.Fatalf("db.StoreSerialNumbers returns err: %+v", err)
+		}
+		if has, _ := db.HasSerialNumber(&tokenID, []byte("sn"), 0); !has {
+			t.Fatalf("serial number should exist")
+		}
+		if has, _ := db.HasSerialNumber(&tokenID, []byte("sn"), 1); has {
+			t.Fatalf("serial number should not exist in other shard")
+		}
+		if err := db.StoreSNDerivators(&tokenID, []byte("snd"), 0); err != nil {
+			t.Fatalf("db.StoreSNDerivators returns err: %+v", err)
+		}
+		if has, _ := db.HasSNDerivator(&tokenID, []byte("snd"), 0); !has {
+			t.Fatalf("snderivator should exist")
+		}
+	})
+}
+
+func TestConformanceCustomToken(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tokenID := common.HashH([]byte("token"))
+		txHash := common.HashH([]byte("init"))
+		if err := db.StoreCustomToken(&tokenID, txHash[:]); err != nil {
+			t.Fatalf("db.StoreCustomToken returns err: %+v", err)
+		}
+		if !db.CustomTokenIDExisted(&tokenID) {
+			t.Fatalf("custom token should exist")
+		}
+		tokens, err := db.ListCustomToken()
+		if err != nil || len(tokens) != 1 || !bytes.Equal(tokens[0], txHash[:]) {
+			t.Fatalf("db.ListCustomToken returns %d tokens, %+v", len(tokens), err)
+		}
+		if db.PrivacyCustomTokenIDExisted(&tokenID) {
+			t.Fatalf("privacy custom token should not exist")
+		}
+	})
+}
+
+func TestConformanceBridge(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tokenID := common.HashH([]byte("token"))
+		if existed, _ := db.IsBridgeTokenExisted(&tokenID); existed {
+			t.Fatalf("bridge token should not exist")
+		}
+		if err := db.CountUpDepositedAmtByTokenID(&tokenID, 100); err != nil {
+			t.Fatalf("db.CountUpDepositedAmtByTokenID returns err: %+v", err)
+		}
+		if err := db.DeductAmtByTokenID(&tokenID, 40); err != nil {
+			t.Fatalf("db.DeductAmtByTokenID returns err: %+v", err)
+		}
+		if existed, _ := db.IsBridgeTokenExisted(&tokenID); !existed {
+			t.Fatalf("bridge token should exist")
+		}
+		amounts, err := db.GetBridgeTokensAmounts()
+		if err != nil || len(amounts) != 1 || !bytes.Contains(amounts[0], []byte(`"amount":60`)) {
+			t.Fatalf("db.GetBridgeTokensAmounts returns %s, %+v", amounts, err)
+		}
+	})
+}
+
+func TestSchemaVersionNewer(t *testing.T) {
+	dbPath, err := ioutil.TempDir(os.TempDir(), "test_")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %+v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	db, err := database.Open("leveldb", dbPath)
+	if err != nil {
+		t.Fatalf("could not open db path: %s, %+v", dbPath, err)
+	}
+	version := make([]byte, 8)
+	binary.BigEndian.PutUint64(version, 1<<32)
+	db.Put([]byte("schema-version"), version)
+	db.Close()
+	db, err = database.Open("leveldb", dbPath)
+	if err == nil {
+		db.Close()
+		t.Fatalf("db written by a newer schema should not be opened")
+	}
+	if dbErr, ok := err.(*database.DatabaseError); !ok || dbErr.GetErrorCode() != database.NewDatabaseError(database.SchemaVersionErr, nil).GetErrorCode() {
+		t.Fatalf("open returns unexpected err: %+v", err)
+	}
+}
+
+func TestConformancePruneBlock(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		block := testBlock{Height: 4}
+		if err := db.StoreShardBlock(block, 0); err != nil {
+			t.Fatalf("db.StoreShardBlock returns err: %+v", err)
+		}
+		if err := db.PruneBlock(block.Hash(), map[string]uint64{"Height": block.Height}); err != nil {
+			t.Fatalf("db.PruneBlock returns err: %+v", err)
+		}
+		if has, _ := db.HasBlock(block.Hash()); !has {
+			t.Fatalf("pruned block should still be known")
+		}
+		if _, err := db.FetchBlock(block.Hash()); !database.IsBlockPruned(err) {
+			t.Fatalf("db.FetchBlock of pruned block returns err: %+v", err)
+		}
+		if header, err := db.FetchBlockHeader(block.Hash()); err != nil || !bytes.Equal(header, []byte(`{"Height":4}`)) {
+			t.Fatalf("db.FetchBlockHeader returns %s, %+v", header, err)
+		}
+		if err := db.StorePrunedHeight(false, 0, 4); err != nil {
+			t.Fatalf("db.StorePrunedHeight returns err: %+v", err)
+		}
+		if height, err := db.FetchPrunedHeight(false, 0); err != nil || height != 4 {
+			t.Fatalf("db.FetchPrunedHeight returns %d, %+v", height, err)
+		}
+		if height, err := db.FetchPrunedHeight(true, 0); err != nil || height != 0 {
+			t.Fatalf("db.FetchPrunedHeight of beacon returns %d, %+v", height, err)
+		}
+	})
+}
+
+func TestConformanceBackup(t *testing.T) {
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			db, teardown := driver.open(t)
+			defer teardown()
+			testBackup(t, db, driver.backupDriver)
+		})
+	}
+}
+
+func testBackup(t *testing.T, db database.DatabaseInterface, backupDriver string) {
+	backupPath, err := ioutil.TempDir(os.TempDir(), "backup_")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %+v", err)
+	}
+	defer os.RemoveAll(backupPath)
+	db.Put([]byte("key"), []byte("value"))
+	dbPath := filepath.Join(backupPath, "db")
+	if err := db.Backup(dbPath); err != nil {
+		t.Fatalf("db.Backup returns err: %+v", err)
+	}
+	if err := db.Backup(dbPath); err == nil {
+		t.Fatalf("backup to an existing db should return err")
+	}
+	if err := db.Backup(filepath.Join(backupPath, "db.tar.gz")); err != nil {
+		t.Fatalf("db.Backup to tarball returns err: %+v", err)
+	}
+	backup, err := database.Open(backupDriver, dbPath)
+	if err != nil {
+		t.Fatalf("could not open backup: %+v", err)
+	}
+	defer backup.Close()
+	if value, err := backup.Get([]byte("key")); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("backup.Get returns %s, %+v", value, err)
+	}
+}
+
+func TestConformanceUndoLog(t *testing.T) {
+	runConformance(t, func(t *testing.T, db database.DatabaseInterface) {
+		tokenID := common.HashH([]byte("token"))
+		shardID := byte(0)
+		block := testBlock{Height: 5}
+		db.Put([]byte("changed"), []byte("old"))
+		db.Put([]byte("deleted"), []byte("old"))
+		if err := db.StoreCommitments(&tokenID, []byte("pubkey"), []byte("c0"), shardID); err != nil {
+			t.Fatalf("db.StoreCommitments returns err: %+v", err)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("db.Begin returns err: %+v", err)
+		}
+		tx.Put([]byte("changed"), []byte("new"))
+		tx.Put([]byte("added"), []byte("new"))
+		tx.Delete([]byte("deleted"))
+		if err := tx.StoreCommitments(&tokenID, []byte("pubkey"), []byte("c1"), shardID); err != nil {
+			t.Fatalf("tx.StoreCommitments returns err: %+v", err)
+		}
+		if err := tx.StoreSerialNumbers(&tokenID, []byte("sn"), shardID); err != nil {
+			t.Fatalf("tx.StoreSerialNumbers returns err: %+v", err)
+		}
+		if err := tx.StoreShardBlock(block, shardID); err != nil {
+			t.Fatalf("tx.StoreShardBlock returns err: %+v", err)
+		}
+		if err := tx.StoreUndoLog(block.Hash()); err != nil {
+			t.Fatalf("tx.StoreUndoLog returns err: %+v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("tx.Commit returns err: %+v", err)
+		}
+		if has, err := db.HasUndoLog(block.Hash()); err != nil || !has {
+			t.Fatalf("db.HasUndoLog returns %t, %+v", has, err)
+		}
+
+		if err := db.RevertBlock(block.Hash()); err != nil {
+			t.Fatalf("db.RevertBlock returns err: %+v", err)
+		}
+		if value, err := db.Get([]byte("changed")); err != nil || !bytes.Equal(value, []byte("old")) {
+			t.Fatalf("changed key is %s, %+v", value, err)
+		}
+		if value, err := db.Get([]byte("deleted")); err != nil || !bytes.Equal(value, []byte("old")) {
+			t.Fatalf("deleted key is %s, %+v", value, err)
+		}
+		if has, _ := db.HasValue([]byte("added")); has {
+			t.Fatalf("added key should be deleted")
+		}
+		if length, err := db.GetCommitmentLength(&tokenID, shardID); err != nil || length.Uint64() != 1 {
+			t.Fatalf("db.GetCommitmentLength returns %v, %+v", length, err)
+		}
+		if has, _ := db.HasSerialNumber(&tokenID, []byte("sn"), shardID); has {
+			t.Fatalf("serial number should be reverted")
+		}
+		if has, _ := db.HasBlock(block.Hash()); has {
+			t.Fatalf("block should be reverted")
+		}
+		if has, _ := db.HasUndoLog(block.Hash()); has {
+			t.Fatalf("undo log should be deleted")
+		}
+		if err := db.RevertBlock(block.Hash()); err == nil {
+			t.Fatalf("revert without undo log should return err")
+		}
+	})
+}
+
+func TestSchemaVersionFromLegacyLayoutKey(t *testing.T) {
+	dbPath, err := ioutil.TempDir(os.TempDir(), "test_")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %+v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	db, err := database.Open("leveldb", dbPath)
+	if err != nil {
+		t.Fatalf("could not open db path: %s, %+v", dbPath, err)
+	}
+	// db which migrated item layout before schema version is stored
+	db.Delete([]byte("schema-version"))
+	db.Put([]byte("layout-commitments-outcoins"), []byte{1})
+	db.Close()
+	db, err = database.Open("leveldb", dbPath, database.MigrateNone)
+	if err != nil {
+		t.Fatalf("db with legacy layout key should not have pending migrations: %+v", err)
+	}
+	defer db.Close()
+	version, err := db.Get([]byte("schema-version"))
+	if err != nil || binary.BigEndian.Uint64(version) != 1 {
+		t.Fatalf("schema version is %x, err %+v, want 1", version, err)
+	}
+	if has, _ := db.HasValue([]byte("layout-commitments-outcoins")); has {
+		t.Fatalf("legacy layout key should be replaced by schema version")
+	}
+}
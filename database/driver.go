@@ -9,6 +9,23 @@ type Driver struct {
 	Open   func(args ...interface{}) (DatabaseInterface, error)
 }
 
+// MigrationMode tells a driver what to do with pending schema migrations when
+// it opens a db, it is passed to Open after the db path
+type MigrationMode int
+
+const (
+	// MigrateAuto runs pending migrations
+	MigrateAuto MigrationMode = iota
+	// MigrateDryRun logs pending migrations without running them, db must not
+	// be used after it is opened in this mode
+	MigrateDryRun
+	// MigrateNone refuses to open a db which has pending migrations
+	MigrateNone
+	// MigrateReadOnly opens db read-only, so it refuses pending migrations
+	// too. Writes to db return error
+	MigrateReadOnly
+)
+
 var drivers = make(map[string]*Driver)
 
 // RegisterDriver registers the driver d.
@@ -14,12 +14,17 @@ const (
 	OpenDbErr
 	NotExistValue
 	LvDbNotFound
+	TransactionErr
+	SchemaVersionErr
+	BackupErr
+	UndoLogErr
 
 	// BlockChain err
 	NotImplHashMethod
 	BlockExisted
 	UnexpectedError
 	KeyExisted
+	BlockPruned
 
 	//voting err
 	NotEnoughCandidate
@@ -35,15 +40,20 @@ var ErrCodeMessage = map[int]struct {
 	DriverNotRegisterErr: {-1001, "Driver is not registered"},
 
 	// -2xxx levelDb
-	OpenDbErr:     {-2000, "Open database error"},
-	NotExistValue: {-2001, "H is not existed"},
-	LvDbNotFound:  {-2002, "lvdb not found"},
+	OpenDbErr:        {-2000, "Open database error"},
+	NotExistValue:    {-2001, "H is not existed"},
+	LvDbNotFound:     {-2002, "lvdb not found"},
+	TransactionErr:   {-2003, "Database transaction error"},
+	SchemaVersionErr: {-2004, "Database schema version is not supported"},
+	BackupErr:        {-2005, "Database backup error"},
+	UndoLogErr:       {-2006, "Block undo log error"},
 
 	// -3xxx blockchain
 	NotImplHashMethod: {-3000, "Data does not implement Hash() method"},
 	BlockExisted:      {-3001, "Block already existed"},
 	UnexpectedError:   {-3002, "Unexpected error"},
 	KeyExisted:        {-3003, "PubKey already existed in database"},
+	BlockPruned:       {-3004, "Block body is pruned"},
 
 	// -4xxx voting
 	NotEnoughCandidate: {-4000, "Not enough candidate for DCB Board"},
@@ -71,3 +81,10 @@ func NewDatabaseError(key int, err error) *DatabaseError {
 		message: ErrCodeMessage[key].message,
 	}
 }
+
+// IsBlockPruned returns true if err is returned for a block whose body is
+// pruned
+func IsBlockPruned(err error) bool {
+	dbErr, ok := err.(*DatabaseError)
+	return ok && dbErr.code == ErrCodeMessage[BlockPruned].code
+}
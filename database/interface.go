@@ -13,6 +13,9 @@ type DatabaseInterface interface {
 	Delete(key []byte) error
 	HasValue(key []byte) (bool, error)
 
+	// Transaction
+	Begin() (Transaction, error)
+
 	// Block
 	StoreShardBlock(interface{}, byte) error
 	StoreShardBlockHeader(interface{}, *common.Hash, byte) error
@@ -36,6 +39,12 @@ type DatabaseInterface interface {
 	FetchBeaconBlockChain() ([]*common.Hash, error)
 	DeleteBeaconBlock(*common.Hash, uint64) error
 
+	// Pruning
+	PruneBlock(hash *common.Hash, header interface{}) error
+	FetchBlockHeader(*common.Hash) ([]byte, error)
+	StorePrunedHeight(isBeacon bool, shardID byte, height uint64) error
+	FetchPrunedHeight(isBeacon bool, shardID byte) (uint64, error)
+
 	//Crossshard
 	StoreCrossShardNextHeight(byte, byte, uint64, uint64) error
 	FetchCrossShardNextHeight(byte, byte, uint64) (uint64, error)
@@ -91,6 +100,8 @@ type DatabaseInterface interface {
 	GetCommitmentLength(tokenID *common.Hash, shardID byte) (*big.Int, error)
 	GetCommitmentIndexsByPubkey(tokenID *common.Hash, pubkey []byte, shardID byte) ([][]byte, error)
 	GetOutcoinsByPubkey(tokenID *common.Hash, pubkey []byte, shardID byte) ([][]byte, error)
+	IterateCommitments(tokenID *common.Hash, shardID byte, fn func(index uint64, commitment []byte) bool) error
+	IterateOutcoinsByPubkey(tokenID *common.Hash, pubkey []byte, shardID byte, fn func(outcoin []byte) bool) error
 	CleanCommitments() error
 
 	// SNDerivator
@@ -138,5 +149,27 @@ type DatabaseInterface interface {
 	GetBridgeTokensAmounts() ([][]byte, error)
 	IsBridgeTokenExisted(*common.Hash) (bool, error)
 
+	// Undo log, RevertBlock restores every key which is written when a block
+	// is stored to its value before the block
+	RevertBlock(*common.Hash) error
+	HasUndoLog(*common.Hash) (bool, error)
+
+	// Backup writes a consistent copy of database to a directory or tarball
+	Backup(path string) error
+
 	Close() error
 }
+
+// Transaction is a view of database which keeps its writes in memory until
+// Commit, then all of them are stored atomically. Rollback drops the writes.
+// Reads of a transaction see its own writes.
+// StoreUndoLog stores the values which keys written in the transaction since
+// it began, since the last RevertBlock or since the last StoreUndoLog had in
+// the transaction before, as undo log of a block, so RevertBlock can undo
+// them later
+type Transaction interface {
+	DatabaseInterface
+	StoreUndoLog(*common.Hash) error
+	Commit() error
+	Rollback()
+}
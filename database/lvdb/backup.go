@@ -0,0 +1,90 @@
+package lvdb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/constant-money/constant-chain/database"
+)
+
+/*
+Backup writes a consistent copy of db to path while db is in use. The copy is
+made by KV of db, so writes after Backup is called are not in it. path is a
+new db directory, or a gzipped tarball of it if path ends with .tar.gz or
+.tgz
+*/
+func (db *db) Backup(path string) error {
+	if strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") {
+		tmpDir, err := ioutil.TempDir(filepath.Dir(path), "backup_")
+		if err != nil {
+			return database.NewDatabaseError(database.BackupErr, err)
+		}
+		defer os.RemoveAll(tmpDir)
+		dbDir := filepath.Join(tmpDir, "db")
+		if err := db.backupDir(dbDir); err != nil {
+			return err
+		}
+		if err := tarGzDir(dbDir, path); err != nil {
+			os.Remove(path)
+			return database.NewDatabaseError(database.BackupErr, err)
+		}
+		return nil
+	}
+	return db.backupDir(path)
+}
+
+func (db *db) backupDir(dir string) error {
+	if err := db.kv.Backup(dir); err != nil {
+		return database.NewDatabaseError(database.BackupErr, err)
+	}
+	return nil
+}
+
+// tarGzDir writes files of dir to a gzipped tarball at path
+func tarGzDir(dir string, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name, err = filepath.Rel(filepath.Dir(dir), filePath)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,156 @@
+package lvdb
+
+import (
+	"github.com/constant-money/constant-chain/database"
+	"github.com/pkg/errors"
+)
+
+// batch keeps writes of a transaction in memory, they are written to KV at
+// once on commit. Reads of the transaction see them, a nil value is a
+// deleted key. For undo log it keeps the value which every key written
+// since the last undo mark had in the transaction before the first write
+type batch struct {
+	writes map[string][]byte
+	prior  map[string]undoRecord
+	done   bool
+}
+
+// Begin opens a transaction on db, db is not changed until the transaction
+// is committed
+func (db *db) Begin() (database.Transaction, error) {
+	if db.batch != nil {
+		return nil, database.NewDatabaseError(database.TransactionErr, errors.New("transaction is already opened"))
+	}
+	return db.begin(), nil
+}
+
+func (db *db) begin() *db {
+	tx := *db
+	tx.batch = &batch{
+		writes: make(map[string][]byte),
+		prior:  make(map[string]undoRecord),
+	}
+	return &tx
+}
+
+// Commit writes all changes of transaction to db atomically
+func (db *db) Commit() error {
+	if db.batch == nil || db.batch.done {
+		return database.NewDatabaseError(database.TransactionErr, errors.New("transaction is not opened"))
+	}
+	db.batch.done = true
+	err := db.kv.Write(db.batch.writes)
+	if err != nil {
+		return database.NewDatabaseError(database.TransactionErr, errors.Wrap(err, "db.kv.Write"))
+	}
+	return nil
+}
+
+// Rollback drops all changes of transaction
+func (db *db) Rollback() {
+	if db.batch == nil || db.batch.done {
+		return
+	}
+	db.batch.done = true
+	db.batch.writes = nil
+}
+
+func (db *db) put(key, value []byte) error {
+	if db.batch == nil {
+		return db.kv.Put(key, value)
+	}
+	if db.batch.done {
+		return errors.New("transaction is closed")
+	}
+	if err := db.recordPrior(key); err != nil {
+		return err
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	db.batch.writes[string(key)] = v
+	return nil
+}
+
+func (db *db) delete(key []byte) error {
+	if db.batch == nil {
+		return db.kv.Delete(key)
+	}
+	if db.batch.done {
+		return errors.New("transaction is closed")
+	}
+	if err := db.recordPrior(key); err != nil {
+		return err
+	}
+	db.batch.writes[string(key)] = nil
+	return nil
+}
+
+// recordPrior keeps the value of key in transaction before it is first
+// written since the last undo mark
+func (db *db) recordPrior(key []byte) error {
+	if _, ok := db.batch.prior[string(key)]; ok {
+		return nil
+	}
+	value, err := db.get(key)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	db.batch.prior[string(key)] = undoRecord{key: []byte(string(key)), existed: err == nil, value: value}
+	return nil
+}
+
+// markUndo starts a new undo log, keys written before are not in it
+func (db *db) markUndo() {
+	db.batch.prior = make(map[string]undoRecord)
+}
+
+func (db *db) get(key []byte) ([]byte, error) {
+	if db.batch != nil {
+		if value, ok := db.batch.writes[string(key)]; ok {
+			if value == nil {
+				return nil, ErrNotFound
+			}
+			return value, nil
+		}
+	}
+	return db.kv.Get(key)
+}
+
+func (db *db) has(key []byte) (bool, error) {
+	if db.batch != nil {
+		if value, ok := db.batch.writes[string(key)]; ok {
+			return value != nil, nil
+		}
+	}
+	return db.kv.Has(key)
+}
+
+// newIterator iterates keys with prefix, in a transaction the keys of db are
+// merged with writes of transaction
+func (db *db) newIterator(prefix []byte) Iterator {
+	if db.batch == nil || len(db.batch.writes) == 0 {
+		return db.kv.NewIterator(prefix)
+	}
+	merged := make(map[string][]byte)
+	iter := db.kv.NewIterator(prefix)
+	for iter.Next() {
+		value := make([]byte, len(iter.Value()))
+		copy(value, iter.Value())
+		merged[string(iter.Key())] = value
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return &sliceIterator{err: err}
+	}
+	for key, value := range db.batch.writes {
+		if !hasPrefix([]byte(key), prefix) {
+			continue
+		}
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return newSliceIterator(merged)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/database"
 	"github.com/pkg/errors"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 func (db *db) StoreCrossShardNextHeight(fromShard, toShard byte, curHeight uint64, nextHeight uint64) error {
@@ -134,8 +133,11 @@ func (db *db) FetchBeaconBlock(hash *common.Hash) ([]byte, error) {
 		return []byte{}, err
 	}
 	key := append(blockKeyPrefix, hash[:]...)
-	block, err := db.Get(key)
+	block, err := db.get(key)
 	if err != nil {
+		if pruned, _ := db.isPruned(hash); pruned {
+			return nil, database.NewDatabaseError(database.BlockPruned, errors.Errorf("beacon block %s is pruned", hash.String()))
+		}
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 	ret := make([]byte, len(block))
@@ -147,13 +149,13 @@ func (db *db) DeleteBeaconBlock(hash *common.Hash, idx uint64) error {
 	// Delete block
 	// bea-b-{hash}
 	key := append(append(beaconPrefix, blockKeyPrefix...), hash[:]...)
-	err := db.lvdb.Delete(key, nil)
+	err := db.delete(key)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
 	}
 	// b-{hash}
 	keyB := append(blockKeyPrefix, hash[:]...)
-	err = db.lvdb.Delete(keyB, nil)
+	err = db.delete(keyB)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
 	}
@@ -161,7 +163,7 @@ func (db *db) DeleteBeaconBlock(hash *common.Hash, idx uint64) error {
 	// delete by index
 	// bea-i-{hash} -> index
 	keyIndex := append(append(beaconPrefix, blockKeyIdxPrefix...), hash[:]...)
-	err = db.lvdb.Delete(keyIndex, nil)
+	err = db.delete(keyIndex)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
@@ -169,15 +171,23 @@ func (db *db) DeleteBeaconBlock(hash *common.Hash, idx uint64) error {
 	// index -> {hash}
 	buf := make([]byte, 8)
 	binary.LittleEndian.PutUint64(buf, idx)
-	err = db.lvdb.Delete(buf, nil)
+	err = db.delete(buf)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 
-	err = db.lvdb.Delete(key, nil)
+	err = db.delete(key)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
 	}
+
+	// header of pruned block and undo log
+	if err := db.delete(db.GetKey(string(blockHeaderKeyPrefix), hash)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
+	if err := db.delete(undoKey(hash)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
 	return nil
 }
 
@@ -204,7 +214,7 @@ func (db *db) FetchBeaconBestState() ([]byte, error) {
 
 func (db *db) CleanBeaconBestState() error {
 	key := beaconBestBlockkey
-	err := db.lvdb.Delete(key, nil)
+	err := db.delete(key)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.delete"))
 	}
@@ -216,12 +226,12 @@ func (db *db) StoreBeaconBlockIndex(h *common.Hash, idx uint64) error {
 	binary.LittleEndian.PutUint64(buf, idx)
 	key := append(append(beaconPrefix, blockKeyIdxPrefix...), h[:]...)
 	//{bea-i-{hash}}:index
-	if err := db.lvdb.Put(key, buf, nil); err != nil {
+	if err := db.put(key, buf); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	//bea-i-{index}:[hash]
 	beaconBuf := append(append(beaconPrefix, blockKeyIdxPrefix...), buf...)
-	if err := db.lvdb.Put(beaconBuf, h[:], nil); err != nil {
+	if err := db.put(beaconBuf, h[:]); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	return nil
@@ -229,7 +239,7 @@ func (db *db) StoreBeaconBlockIndex(h *common.Hash, idx uint64) error {
 
 func (db *db) GetIndexOfBeaconBlock(h *common.Hash) (uint64, error) {
 	key := append(append(beaconPrefix, blockKeyIdxPrefix...), h[:]...)
-	b, err := db.lvdb.Get(key, nil)
+	b, err := db.get(key)
 	//{bea-i-[hash]}:index
 	if err != nil {
 		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.get"))
@@ -247,7 +257,7 @@ func (db *db) GetBeaconBlockHashByIndex(idx uint64) (*common.Hash, error) {
 	binary.LittleEndian.PutUint64(buf, idx)
 	//bea-i-{index}:[hash]
 	beaconBuf := append(append(beaconPrefix, blockKeyIdxPrefix...), buf...)
-	b, err := db.lvdb.Get(beaconBuf, nil)
+	b, err := db.get(beaconBuf)
 	if err != nil {
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
@@ -260,7 +270,7 @@ func (db *db) FetchBeaconBlockChain() ([]*common.Hash, error) {
 	keys := []*common.Hash{}
 	prefix := append(beaconPrefix, blockKeyPrefix...)
 	// prefix: bea-b-...
-	iter := db.lvdb.NewIterator(util.BytesPrefix(prefix), nil)
+	iter := db.newIterator(prefix)
 	for iter.Next() {
 		h := new(common.Hash)
 		_ = h.SetBytes(iter.Key()[len(prefix):])
@@ -280,7 +290,7 @@ func (db *db) StoreAcceptedShardToBeacon(shardID byte, blkHeight uint64, shardBl
 	prefix := append([]byte{shardID}, shardBlkHash[:]...)
 	// stb-ShardID-ShardBlockHash : BeaconBlockHeight
 	key := append(shardToBeaconKeyPrefix, prefix...)
-	if err := db.lvdb.Put(key, buf, nil); err != nil {
+	if err := db.put(key, buf); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	return nil
@@ -325,7 +335,7 @@ func (db *db) StoreCommitteeByHeight(blkHeight uint64, v interface{}) error {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Marshal"))
 	}
 
-	if err := db.lvdb.Put(key, val, nil); err != nil {
+	if err := db.put(key, val); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	return nil
@@ -338,7 +348,7 @@ func (db *db) FetchCommitteeByHeight(blkHeight uint64) ([]byte, error) {
 	binary.LittleEndian.PutUint64(buf, blkHeight)
 	key = append(key, buf[:]...)
 
-	b, err := db.lvdb.Get(key, nil)
+	b, err := db.get(key)
 	if err != nil {
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.get"))
 	}
@@ -360,7 +370,7 @@ func (db *db) StoreCommitteeByEpoch(blkEpoch uint64, v interface{}) error {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Marshal"))
 	}
 
-	if err := db.lvdb.Put(key, val, nil); err != nil {
+	if err := db.put(key, val); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	return nil
@@ -374,7 +384,7 @@ func (db *db) FetchCommitteeByEpoch(blkEpoch uint64) ([]byte, error) {
 	binary.LittleEndian.PutUint64(buf, blkEpoch)
 	key = append(key, buf[:]...)
 
-	b, err := db.lvdb.Get(key, nil)
+	b, err := db.get(key)
 	if err != nil {
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.get"))
 	}
@@ -388,7 +398,7 @@ func (db *db) HasCommitteeByEpoch(blkEpoch uint64) (bool, error) {
 	binary.LittleEndian.PutUint64(buf, blkEpoch)
 	key = append(key, buf[:]...)
 
-	exist, err := db.lvdb.Has(key, nil)
+	exist, err := db.has(key)
 	if err != nil {
 		return false, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.get"))
 	}
@@ -6,8 +6,6 @@ import (
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/database"
 	"github.com/pkg/errors"
-	lvdberr "github.com/syndtr/goleveldb/leveldb/errors"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 type TokenWithAmount struct {
@@ -22,8 +20,8 @@ func (db *db) CountUpDepositedAmtByTokenID(
 	// don't need to have atomic operation here since instructions on beacon would be processed one by one, not in parallel
 	key := append(centralizedBridgePrefix, tokenID[:]...)
 
-	tokenWithAmtBytes, dbErr := db.lvdb.Get(key, nil)
-	if dbErr != nil && dbErr != lvdberr.ErrNotFound {
+	tokenWithAmtBytes, dbErr := db.get(key)
+	if dbErr != nil && dbErr != ErrNotFound {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(dbErr, "db.lvdb.Get"))
 	}
 
@@ -63,7 +61,7 @@ func buildBridgedTokensAmounts(item []byte, results [][]byte) [][]byte {
 }
 
 func (db *db) GetBridgeTokensAmounts() ([][]byte, error) {
-	iter := db.lvdb.NewIterator(util.BytesPrefix(centralizedBridgePrefix), nil)
+	iter := db.newIterator(centralizedBridgePrefix)
 	results := [][]byte{}
 	for iter.Next() {
 		value := iter.Value()
@@ -74,7 +72,7 @@ func (db *db) GetBridgeTokensAmounts() ([][]byte, error) {
 
 	iter.Release()
 	err := iter.Error()
-	if err != nil && err != lvdberr.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 	return results, nil
@@ -87,8 +85,8 @@ func (db *db) DeductAmtByTokenID(
 	// don't need to have atomic operation here since instructions on beacon would be processed one by one, not in parallel
 	key := append(centralizedBridgePrefix, tokenID[:]...)
 
-	tokenWithAmtBytes, dbErr := db.lvdb.Get(key, nil)
-	if dbErr != nil && dbErr != lvdberr.ErrNotFound {
+	tokenWithAmtBytes, dbErr := db.get(key)
+	if dbErr != nil && dbErr != ErrNotFound {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(dbErr, "db.lvdb.Get"))
 	}
 
@@ -126,8 +124,8 @@ func (db *db) IsBridgeTokenExisted(
 	tokenID *common.Hash,
 ) (bool, error) {
 	key := append(centralizedBridgePrefix, tokenID[:]...)
-	tokenWithAmtBytes, dbErr := db.lvdb.Get(key, nil)
-	if dbErr != nil && dbErr != lvdberr.ErrNotFound {
+	tokenWithAmtBytes, dbErr := db.get(key)
+	if dbErr != nil && dbErr != ErrNotFound {
 		return false, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(dbErr, "db.lvdb.Get"))
 	}
 	if len(tokenWithAmtBytes) == 0 {
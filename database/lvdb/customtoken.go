@@ -11,14 +11,13 @@ import (
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/common/base58"
 	"github.com/constant-money/constant-chain/privacy"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Key: token-init-{tokenID}
 // Value: txHash
 func (db *db) StoreCustomToken(tokenID *common.Hash, txHash []byte) error {
 	key := db.GetKey(string(tokenInitPrefix), tokenID)
-	if err := db.lvdb.Put(key, txHash, nil); err != nil {
+	if err := db.put(key, txHash); err != nil {
 		return err
 	}
 	return nil
@@ -26,7 +25,7 @@ func (db *db) StoreCustomToken(tokenID *common.Hash, txHash []byte) error {
 
 func (db *db) StorePrivacyCustomToken(tokenID *common.Hash, txHash []byte) error {
 	key := db.GetKey(string(privacyTokenInitPrefix), tokenID) // token-init-{tokenID}
-	if err := db.lvdb.Put(key, txHash, nil); err != nil {
+	if err := db.put(key, txHash); err != nil {
 		return err
 	}
 	return nil
@@ -42,7 +41,7 @@ func (db *db) StoreCustomTokenTx(tokenID *common.Hash, shardID byte, blockHeight
 	binary.LittleEndian.PutUint32(bs, uint32(bigNumber-txIndex))
 	key = append(key, bs...)
 	log.Println(string(key))
-	if err := db.lvdb.Put(key, txHash, nil); err != nil {
+	if err := db.put(key, txHash); err != nil {
 		return err
 	}
 	return nil
@@ -58,7 +57,7 @@ func (db *db) StorePrivacyCustomTokenTx(tokenID *common.Hash, shardID byte, bloc
 	binary.LittleEndian.PutUint32(bs, uint32(bigNumber-txIndex))
 	key = append(key, bs...)
 	log.Println(string(key))
-	if err := db.lvdb.Put(key, txHash, nil); err != nil {
+	if err := db.put(key, txHash); err != nil {
 		return err
 	}
 	return nil
@@ -66,7 +65,7 @@ func (db *db) StorePrivacyCustomTokenTx(tokenID *common.Hash, shardID byte, bloc
 
 func (db *db) CustomTokenIDExisted(tokenID *common.Hash) bool {
 	key := db.GetKey(string(tokenInitPrefix), tokenID)
-	data, err := db.lvdb.Get(key, nil)
+	data, err := db.get(key)
 	if err != nil {
 		return false
 	}
@@ -78,7 +77,7 @@ func (db *db) CustomTokenIDExisted(tokenID *common.Hash) bool {
 
 func (db *db) PrivacyCustomTokenIDExisted(tokenID *common.Hash) bool {
 	key := db.GetKey(string(privacyTokenInitPrefix), tokenID) // token-init-{tokenID}
-	data, err := db.lvdb.Get(key, nil)
+	data, err := db.get(key)
 	if err != nil {
 		return false
 	}
@@ -90,7 +89,7 @@ func (db *db) PrivacyCustomTokenIDExisted(tokenID *common.Hash) bool {
 
 func (db *db) PrivacyCustomTokenIDCrossShardExisted(tokenID *common.Hash) bool {
 	key := db.GetKey(string(PrivacyTokenCrossShardPrefix), tokenID)
-	data, err := db.lvdb.Get(key, nil)
+	data, err := db.get(key)
 	if err != nil {
 		return false
 	}
@@ -105,7 +104,7 @@ func (db *db) PrivacyCustomTokenIDCrossShardExisted(tokenID *common.Hash) bool {
 */
 func (db *db) ListCustomToken() ([][]byte, error) {
 	result := make([][]byte, 0)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(tokenInitPrefix), nil)
+	iter := db.newIterator(tokenInitPrefix)
 	for iter.Next() {
 		value := make([]byte, len(iter.Value()))
 		copy(value, iter.Value())
@@ -120,7 +119,7 @@ func (db *db) ListCustomToken() ([][]byte, error) {
 */
 func (db *db) ListPrivacyCustomToken() ([][]byte, error) {
 	result := make([][]byte, 0)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(privacyTokenInitPrefix), nil)
+	iter := db.newIterator(privacyTokenInitPrefix)
 	for iter.Next() {
 		value := make([]byte, len(iter.Value()))
 		copy(value, iter.Value())
@@ -134,7 +133,7 @@ func (db *db) CustomTokenTxs(tokenID *common.Hash) ([]*common.Hash, error) {
 	result := make([]*common.Hash, 0)
 	key := db.GetKey(string(TokenPrefix), tokenID)
 	// PubKey = token-{tokenID}
-	iter := db.lvdb.NewIterator(util.BytesPrefix(key), nil)
+	iter := db.newIterator(key)
 	log.Println(string(key))
 	for iter.Next() {
 		value := iter.Value()
@@ -150,7 +149,7 @@ func (db *db) PrivacyCustomTokenTxs(tokenID *common.Hash) ([]*common.Hash, error
 	result := make([]*common.Hash, 0)
 	key := db.GetKey(string(PrivacyTokenPrefix), tokenID)
 	// PubKey = token-{tokenID}
-	iter := db.lvdb.NewIterator(util.BytesPrefix(key), nil)
+	iter := db.newIterator(key)
 	log.Println(string(key))
 	for iter.Next() {
 		value := iter.Value()
@@ -171,7 +170,7 @@ func (db *db) GetCustomTokenListPaymentAddress(tokenID *common.Hash) ([][]byte,
 	prefix := TokenPaymentAddressPrefix
 	prefix = append(prefix, Splitter...)
 	prefix = append(prefix, (*tokenID)[:]...)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(prefix), nil)
+	iter := db.newIterator(prefix)
 	for iter.Next() {
 		key := string(iter.Key())
 		value := string(iter.Value())
@@ -201,7 +200,7 @@ func (db *db) GetCustomTokenPaymentAddressesBalance(tokenID *common.Hash) (map[s
 	prefix = append(prefix, Splitter...)
 	prefix = append(prefix, []byte(tokenID.String())...)
 	//fmt.Println("GetCustomTokenPaymentAddressesBalance, prefix", prefix)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(prefix), nil)
+	iter := db.newIterator(prefix)
 	for iter.Next() {
 		key := string(iter.Key())
 		value := string(iter.Value())
@@ -241,7 +240,7 @@ func (db *db) GetCustomTokenPaymentAddressesBalanceUnreward(tokenID *common.Hash
 	prefix := TokenPaymentAddressPrefix
 	prefix = append(prefix, Splitter...)
 	prefix = append(prefix, []byte(tokenID.String())...)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(prefix), nil)
+	iter := db.newIterator(prefix)
 	for iter.Next() {
 		key := string(iter.Key())
 		value := string(iter.Value())
@@ -275,7 +274,7 @@ func (db *db) GetCustomTokenPaymentAddressUTXO(tokenID *common.Hash, paymentAddr
 	prefix = append(prefix, base58.Base58Check{}.Encode(paymentAddress, 0x00)...)
 	log.Println(hex.EncodeToString(prefix))
 	results := make(map[string]string)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(prefix), nil)
+	iter := db.newIterator(prefix)
 	for iter.Next() {
 		key := string(iter.Key())
 		// token-paymentAddress  -[-]-  {tokenId}  -[-]-  {paymentAddress}  -[-]-  {txHash}  -[-]-  {voutIndex}
@@ -288,7 +287,7 @@ func (db *db) GetCustomTokenPaymentAddressUTXO(tokenID *common.Hash, paymentAddr
 
 func (db *db) StorePrivacyCustomTokenCrossShard(tokenID *common.Hash, tokenValue []byte) error {
 	key := db.GetKey(string(PrivacyTokenCrossShardPrefix), tokenID)
-	if err := db.lvdb.Put(key, tokenValue, nil); err != nil {
+	if err := db.put(key, tokenValue); err != nil {
 		return err
 	}
 	return nil
@@ -300,7 +299,7 @@ func (db *db) StorePrivacyCustomTokenCrossShard(tokenID *common.Hash, tokenValue
 */
 func (db *db) ListPrivacyCustomTokenCrossShard() ([][]byte, error) {
 	result := make([][]byte, 0)
-	iter := db.lvdb.NewIterator(util.BytesPrefix(PrivacyTokenCrossShardPrefix), nil)
+	iter := db.newIterator(PrivacyTokenCrossShardPrefix)
 	for iter.Next() {
 		value := make([]byte, len(iter.Value()))
 		copy(value, iter.Value())
@@ -4,11 +4,12 @@ import (
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/database"
 	"github.com/pkg/errors"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type db struct {
-	lvdb *leveldb.DB
+	kv KV
+	// batch is not nil when db is a transaction
+	batch *batch
 }
 
 type hasher interface {
@@ -35,6 +36,16 @@ var (
 	serialNumbersPrefix          = []byte("serinalnumbers-")
 	commitmentsPrefix            = []byte("commitments-")
 	outcoinsPrefix               = []byte("outcoins-")
+	commitmentsIndexPrefix       = []byte("commitments-index-")
+	commitmentsValuePrefix       = []byte("commitments-value-")
+	commitmentsLenPrefix         = []byte("commitments-len-")
+	commitmentsPubkeyPrefix      = []byte("commitments-pubkey-")
+	outcoinsPubkeyPrefix         = []byte("outcoins-pubkey-")
+	outcoinsLenPrefix            = []byte("outcoins-len-")
+	schemaVersionKey             = []byte("schema-version")
+	legacyItemLayoutKey          = []byte("layout-commitments-outcoins") // set by nodes which migrated before schema version
+	prunedHeightPrefix           = []byte("pruned-")
+	undoPrefix                   = []byte("undo-")
 	snderivatorsPrefix           = []byte("snderivators-")
 	bestBlockKey                 = []byte("bestBlock")
 	feeEstimator                 = []byte("feeEstimator")
@@ -60,20 +71,42 @@ var (
 	UnMintable = []byte("unmintable")
 )
 
-func open(dbPath string) (database.DatabaseInterface, error) {
-	lvdb, err := leveldb.OpenFile(dbPath, nil)
+func open(dbPath string, mode database.MigrationMode) (database.DatabaseInterface, error) {
+	kv, err := openLeveldbKV(dbPath, mode == database.MigrateReadOnly)
 	if err != nil {
-		return nil, database.NewDatabaseError(database.OpenDbErr, errors.Wrapf(err, "levelvdb.OpenFile %s", dbPath))
+		return nil, database.NewDatabaseError(database.OpenDbErr, err)
 	}
-	return &db{lvdb: lvdb}, nil
+	return OpenKV(kv, mode)
+}
+
+// openMem opens a db which keeps all data in memory, data is lost when it is
+// closed
+func openMem() (database.DatabaseInterface, error) {
+	return OpenKV(newMemKV(), database.MigrateAuto)
+}
+
+// OpenKV opens a db on kv and migrates it as mode says, kv is closed if db
+// can't be opened
+func OpenKV(kv KV, mode database.MigrationMode) (database.DatabaseInterface, error) {
+	result := &db{kv: kv}
+	if err := result.migrate(mode); err != nil {
+		kv.Close()
+		return nil, err
+	}
+	return result, nil
 }
 
 func (db *db) Close() error {
-	return errors.Wrap(db.lvdb.Close(), "db.lvdb.Close")
+	// closing a transaction drops its changes, db is still opened
+	if db.batch != nil {
+		db.Rollback()
+		return nil
+	}
+	return errors.Wrap(db.kv.Close(), "db.kv.Close")
 }
 
 func (db *db) HasValue(key []byte) (bool, error) {
-	ret, err := db.lvdb.Has(key, nil)
+	ret, err := db.has(key)
 	if err != nil {
 		return false, database.NewDatabaseError(database.NotExistValue, err)
 	}
@@ -81,14 +114,14 @@ func (db *db) HasValue(key []byte) (bool, error) {
 }
 
 func (db *db) Put(key, value []byte) error {
-	if err := db.lvdb.Put(key, value, nil); err != nil {
+	if err := db.put(key, value); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Put"))
 	}
 	return nil
 }
 
 func (db *db) Delete(key []byte) error {
-	err := db.lvdb.Delete(key, nil)
+	err := db.delete(key)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
 	}
@@ -96,7 +129,7 @@ func (db *db) Delete(key []byte) error {
 }
 
 func (db *db) Get(key []byte) ([]byte, error) {
-	value, err := db.lvdb.Get(key, nil)
+	value, err := db.get(key)
 	if err != nil {
 		return nil, database.NewDatabaseError(database.LvDbNotFound, errors.Wrap(err, "db.lvdb.Get"))
 	}
@@ -110,6 +143,8 @@ func (db db) GetKey(keyType string, key interface{}) []byte {
 		dbkey = append(blockKeyPrefix, key.(*common.Hash)[:]...)
 	case string(blockKeyIdxPrefix):
 		dbkey = append(blockKeyIdxPrefix, key.(*common.Hash)[:]...)
+	case string(blockHeaderKeyPrefix):
+		dbkey = append(blockHeaderKeyPrefix, key.(*common.Hash)[:]...)
 	case string(serialNumbersPrefix):
 		dbkey = append(serialNumbersPrefix, []byte(key.(*common.Hash).String())...)
 	case string(commitmentsPrefix):
@@ -2,7 +2,7 @@ package lvdb
 
 import (
 	"errors"
-	
+
 	"github.com/constant-money/constant-chain/database"
 )
 
@@ -14,15 +14,38 @@ func init() {
 	if err := database.RegisterDriver(driver); err != nil {
 		panic("failed to register db driver")
 	}
+	// memdb has the same layout as leveldb but keeps data in a map in
+	// memory (see memKV), it is used by tests and ephemeral nodes
+	memDriver := database.Driver{
+		DbType: "memdb",
+		Open:   openMemDriver,
+	}
+	if err := database.RegisterDriver(memDriver); err != nil {
+		panic("failed to register memdb driver")
+	}
 }
 
 func openDriver(args ...interface{}) (database.DatabaseInterface, error) {
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 2 {
 		return nil, errors.New("invalid arguments")
 	}
 	dbPath, ok := args[0].(string)
 	if !ok {
 		return nil, errors.New("expected db path")
 	}
-	return open(dbPath)
+	mode := database.MigrateAuto
+	if len(args) == 2 {
+		mode, ok = args[1].(database.MigrationMode)
+		if !ok {
+			return nil, errors.New("expected migration mode")
+		}
+	}
+	return open(dbPath, mode)
+}
+
+func openMemDriver(args ...interface{}) (database.DatabaseInterface, error) {
+	if len(args) != 0 {
+		return nil, errors.New("invalid arguments")
+	}
+	return openMem()
 }
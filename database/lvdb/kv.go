@@ -0,0 +1,99 @@
+package lvdb
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+/*
+KV is the key-value store under db. db builds keys, encodes values and keeps
+transactions, a KV only stores bytes, so chain data has the same layout in
+every store. leveldb is the default KV, other stores are registered as
+drivers which open their KV with OpenKV
+*/
+type KV interface {
+	// Get returns ErrNotFound if key doesn't exist
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// Write stores writes atomically, a nil value deletes its key
+	Write(writes map[string][]byte) error
+	// NewIterator iterates keys with prefix in order, all keys if prefix is
+	// nil
+	NewIterator(prefix []byte) Iterator
+	// Backup writes a consistent copy of store to a new directory
+	Backup(dir string) error
+	Close() error
+}
+
+// Iterator iterates keys and values of a KV, key and value are valid until
+// Next is called
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Release()
+	Error() error
+}
+
+var (
+	// ErrNotFound is returned by KV when key doesn't exist
+	ErrNotFound = errors.New("key not found")
+	// ErrClosed is returned by memdb KV after it is closed
+	ErrClosed = errors.New("kv is closed")
+)
+
+// sliceIterator iterates sorted keys and values in memory
+type sliceIterator struct {
+	keys   []string
+	values map[string][]byte
+	pos    int
+	err    error
+}
+
+func newSliceIterator(values map[string][]byte) *sliceIterator {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return &sliceIterator{keys: keys, values: values, pos: -1}
+}
+
+func (iter *sliceIterator) Next() bool {
+	if iter.err != nil || iter.pos >= len(iter.keys) {
+		return false
+	}
+	iter.pos++
+	return iter.pos < len(iter.keys)
+}
+
+func (iter *sliceIterator) Key() []byte {
+	if iter.pos < 0 || iter.pos >= len(iter.keys) {
+		return nil
+	}
+	return []byte(iter.keys[iter.pos])
+}
+
+func (iter *sliceIterator) Value() []byte {
+	if iter.pos < 0 || iter.pos >= len(iter.keys) {
+		return nil
+	}
+	return iter.values[iter.keys[iter.pos]]
+}
+
+func (iter *sliceIterator) Release() {
+	iter.keys = nil
+	iter.values = nil
+}
+
+func (iter *sliceIterator) Error() error {
+	return iter.err
+}
+
+// hasPrefix is bytes.HasPrefix where a nil prefix matches all keys
+func hasPrefix(key []byte, prefix []byte) bool {
+	return prefix == nil || bytes.HasPrefix(key, prefix)
+}
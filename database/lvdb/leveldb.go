@@ -0,0 +1,113 @@
+package lvdb
+
+import (
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	lvdberr "github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// backupBatchSize is the number of keys which are written to backup db in
+// one batch
+const backupBatchSize = 10000
+
+// leveldbKV is KV of a leveldb on disk
+type leveldbKV struct {
+	lvdb *leveldb.DB
+}
+
+func openLeveldbKV(dbPath string, readOnly bool) (*leveldbKV, error) {
+	lvdb, err := leveldb.OpenFile(dbPath, &opt.Options{ReadOnly: readOnly})
+	if err != nil {
+		return nil, errors.Wrapf(err, "levelvdb.OpenFile %s", dbPath)
+	}
+	return &leveldbKV{lvdb: lvdb}, nil
+}
+
+func (kv *leveldbKV) Get(key []byte) ([]byte, error) {
+	value, err := kv.lvdb.Get(key, nil)
+	if err == lvdberr.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (kv *leveldbKV) Has(key []byte) (bool, error) {
+	return kv.lvdb.Has(key, nil)
+}
+
+func (kv *leveldbKV) Put(key, value []byte) error {
+	return kv.lvdb.Put(key, value, nil)
+}
+
+func (kv *leveldbKV) Delete(key []byte) error {
+	return kv.lvdb.Delete(key, nil)
+}
+
+func (kv *leveldbKV) Write(writes map[string][]byte) error {
+	batch := new(leveldb.Batch)
+	for key, value := range writes {
+		if value == nil {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Put([]byte(key), value)
+		}
+	}
+	return kv.lvdb.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+func (kv *leveldbKV) NewIterator(prefix []byte) Iterator {
+	return kv.lvdb.NewIterator(rangeOf(prefix), nil)
+}
+
+func rangeOf(prefix []byte) *util.Range {
+	if prefix == nil {
+		return nil
+	}
+	return util.BytesPrefix(prefix)
+}
+
+// Backup copies keys of a leveldb snapshot, so writes after Backup is called
+// are not in the copy
+func (kv *leveldbKV) Backup(dir string) error {
+	snapshot, err := kv.lvdb.GetSnapshot()
+	if err != nil {
+		return errors.Wrap(err, "db.lvdb.GetSnapshot")
+	}
+	defer snapshot.Release()
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+	return writeLeveldbBackup(dir, iter)
+}
+
+// writeLeveldbBackup writes keys of iter to a new leveldb in dir
+func writeLeveldbBackup(dir string, iter Iterator) error {
+	backup, err := leveldb.OpenFile(dir, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return errors.Wrapf(err, "levelvdb.OpenFile %s", dir)
+	}
+	defer backup.Close()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		if batch.Len() >= backupBatchSize {
+			if err := backup.Write(batch, nil); err != nil {
+				return errors.Wrap(err, "backup.Write")
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return errors.Wrap(err, "iter.Error")
+	}
+	if err := backup.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return errors.Wrap(err, "backup.Write")
+	}
+	return nil
+}
+
+func (kv *leveldbKV) Close() error {
+	return kv.lvdb.Close()
+}
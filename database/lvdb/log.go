@@ -0,0 +1,16 @@
+package lvdb
+
+import (
+	"github.com/constant-money/constant-chain/common"
+)
+
+type LvdbLogger struct {
+	log common.Logger
+}
+
+func (lvdbLogger *LvdbLogger) Init(inst common.Logger) {
+	lvdbLogger.log = inst
+}
+
+// Global instant to use
+var Logger = LvdbLogger{}
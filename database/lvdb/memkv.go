@@ -0,0 +1,151 @@
+package lvdb
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+memKV is KV of the memdb driver, it keeps keys and values in a map and keys in
+a sorted slice for iterators. Data is lost when it is closed, it is used by
+tests and ephemeral nodes
+*/
+type memKV struct {
+	mtx    sync.RWMutex
+	values map[string][]byte
+	keys   []string // sorted keys of values
+	closed bool
+}
+
+func newMemKV() *memKV {
+	return &memKV{values: make(map[string][]byte)}
+}
+
+func (kv *memKV) Get(key []byte) ([]byte, error) {
+	kv.mtx.RLock()
+	defer kv.mtx.RUnlock()
+	if kv.closed {
+		return nil, ErrClosed
+	}
+	value, ok := kv.values[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (kv *memKV) Has(key []byte) (bool, error) {
+	kv.mtx.RLock()
+	defer kv.mtx.RUnlock()
+	if kv.closed {
+		return false, ErrClosed
+	}
+	_, ok := kv.values[string(key)]
+	return ok, nil
+}
+
+func (kv *memKV) Put(key, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	return kv.Write(map[string][]byte{string(key): value})
+}
+
+func (kv *memKV) Delete(key []byte) error {
+	return kv.Write(map[string][]byte{string(key): nil})
+}
+
+// Write applies writes, new keys are sorted and merged into keys at once so
+// a large write doesn't shift keys for every new key
+func (kv *memKV) Write(writes map[string][]byte) error {
+	kv.mtx.Lock()
+	defer kv.mtx.Unlock()
+	if kv.closed {
+		return ErrClosed
+	}
+	var added []string
+	deleted := false
+	for key, value := range writes {
+		_, ok := kv.values[key]
+		if value == nil {
+			if ok {
+				delete(kv.values, key)
+				deleted = true
+			}
+			continue
+		}
+		if !ok {
+			added = append(added, key)
+		}
+		kv.values[key] = append([]byte{}, value...)
+	}
+	if deleted {
+		keys := kv.keys[:0]
+		for _, key := range kv.keys {
+			if _, ok := kv.values[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+		kv.keys = keys
+	}
+	if len(added) > 0 {
+		sort.Strings(added)
+		kv.keys = mergeSortedKeys(kv.keys, added)
+	}
+	return nil
+}
+
+// mergeSortedKeys merges two sorted slices of distinct keys
+func mergeSortedKeys(a, b []string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] < b[0] {
+			keys, a = append(keys, a[0]), a[1:]
+		} else {
+			keys, b = append(keys, b[0]), b[1:]
+		}
+	}
+	keys = append(keys, a...)
+	return append(keys, b...)
+}
+
+// NewIterator iterates a snapshot of keys with prefix, writes after it is
+// created are not seen
+func (kv *memKV) NewIterator(prefix []byte) Iterator {
+	kv.mtx.RLock()
+	defer kv.mtx.RUnlock()
+	if kv.closed {
+		return &sliceIterator{pos: -1, err: ErrClosed}
+	}
+	start := sort.SearchStrings(kv.keys, string(prefix))
+	end := start
+	for end < len(kv.keys) && strings.HasPrefix(kv.keys[end], string(prefix)) {
+		end++
+	}
+	keys := make([]string, end-start)
+	copy(keys, kv.keys[start:end])
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		// values are never changed in place, so they are shared
+		values[key] = kv.values[key]
+	}
+	return &sliceIterator{keys: keys, values: values, pos: -1}
+}
+
+// Backup writes a snapshot of memKV to a new leveldb, so a backup of memdb is
+// opened by leveldb driver
+func (kv *memKV) Backup(dir string) error {
+	iter := kv.NewIterator(nil)
+	defer iter.Release()
+	return writeLeveldbBackup(dir, iter)
+}
+
+func (kv *memKV) Close() error {
+	kv.mtx.Lock()
+	defer kv.mtx.Unlock()
+	kv.closed = true
+	kv.values = nil
+	kv.keys = nil
+	return nil
+}
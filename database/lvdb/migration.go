@@ -0,0 +1,260 @@
+package lvdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/pkg/errors"
+)
+
+// schemaVersion is the version of key layout which this code reads and
+// writes, it is the version of the last migration
+var schemaVersion = migrations[len(migrations)-1].version
+
+// migration changes key layout of db from version-1 to version
+type migration struct {
+	version     uint64
+	description string
+	migrate     func(tx *db) error
+}
+
+// migrations are run in order, a new migration is appended with the next
+// version whenever key layout is changed
+var migrations = []migration{
+	{version: 1, description: "store commitments and output coins key per item", migrate: (*db).migrateItemLayout},
+}
+
+func (db *db) getSchemaVersion() (uint64, error) {
+	value, err := db.get(schemaVersionKey)
+	if err == nil {
+		if len(value) != 8 {
+			return 0, database.NewDatabaseError(database.SchemaVersionErr, errors.Errorf("invalid schema version %x", value))
+		}
+		return binary.BigEndian.Uint64(value), nil
+	}
+	if err != ErrNotFound {
+		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	// item layout migration was marked by its own key before schema version
+	// is stored, so it isn't run again
+	legacy, err := db.has(legacyItemLayoutKey)
+	if err != nil {
+		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Has"))
+	}
+	if legacy {
+		return 1, nil
+	}
+	// db without version is either empty, so it is created by this code, or
+	// written before schema version is stored
+	iter := db.kv.NewIterator(nil)
+	empty := !iter.Next()
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	if empty {
+		return schemaVersion, nil
+	}
+	return 0, nil
+}
+
+/*
+migrate checks schema version of db and runs pending migrations as mode says.
+A db which is written by a newer schema is never opened because old code
+can't read it. Every migration is run in a transaction with the update of
+schema version, so a crash leaves db at the version of the last finished
+migration
+*/
+func (db *db) migrate(mode database.MigrationMode) error {
+	version, err := db.getSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return database.NewDatabaseError(database.SchemaVersionErr, errors.Errorf("db schema version %d is newer than %d, please upgrade node", version, schemaVersion))
+	}
+	pending := make([]migration, 0)
+	for _, m := range migrations {
+		if m.version > version {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		if has, _ := db.has(schemaVersionKey); !has && mode != database.MigrateDryRun && mode != database.MigrateReadOnly {
+			// legacy key is replaced by schema version
+			writes := map[string][]byte{string(schemaVersionKey): indexToBytes(version), string(legacyItemLayoutKey): nil}
+			if err := db.kv.Write(writes); err != nil {
+				return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.kv.Write"))
+			}
+		}
+		return nil
+	}
+	switch mode {
+	case database.MigrateNone, database.MigrateReadOnly:
+		return database.NewDatabaseError(database.SchemaVersionErr, errors.Errorf("db schema version %d is older than %d, %d migrations are pending", version, schemaVersion, len(pending)))
+	case database.MigrateDryRun:
+		for i, m := range pending {
+			Logger.log.Infof("Database migration %d/%d is pending: schema version %d, %s", i+1, len(pending), m.version, m.description)
+		}
+		return nil
+	}
+	for i, m := range pending {
+		Logger.log.Infof("Database migration %d/%d: schema version %d, %s", i+1, len(pending), m.version, m.description)
+		start := time.Now()
+		tx := db.begin()
+		if err := m.migrate(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.put(schemaVersionKey, indexToBytes(m.version)); err != nil {
+			tx.Rollback()
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Put"))
+		}
+		if err := tx.delete(legacyItemLayoutKey); err != nil {
+			tx.Rollback()
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		Logger.log.Infof("Database migration %d/%d is done in %s", i+1, len(pending), time.Since(start))
+	}
+	return nil
+}
+
+/*
+migrateItemLayout moves commitments and output coins which are stored as json
+arrays (one array of all commitments of a token and shard, one array of
+output coins of every pubkey) to key per item layout
+*/
+func (db *db) migrateItemLayout() error {
+	if err := db.migrateCommitments(); err != nil {
+		return err
+	}
+	return db.migrateOutcoins()
+}
+
+// oldItemKey returns token id and shard id of a key of json array layout:
+// prefix-tokenID-shardID[-suffix]. Keys of key per item layout are not
+// matched because their prefixes are not followed by a hex token id
+func oldItemKey(prefix []byte, key []byte) (*common.Hash, byte, bool) {
+	baseLen := len(prefix) + common.HashSize*2 + 1
+	if len(key) < baseLen {
+		return nil, 0, false
+	}
+	tokenHex := string(key[len(prefix) : baseLen-1])
+	if _, err := hex.DecodeString(tokenHex); err != nil {
+		return nil, 0, false
+	}
+	tokenID, err := common.Hash{}.NewHashFromStr(tokenHex)
+	if err != nil {
+		return nil, 0, false
+	}
+	return tokenID, key[baseLen-1], true
+}
+
+func (db *db) migrateCommitments() error {
+	baseLen := len(commitmentsPrefix) + common.HashSize*2 + 1
+	iter := db.kv.NewIterator(commitmentsPrefix)
+	defer iter.Release()
+	var base []byte
+	var commitmentSet map[string]bool
+	for iter.Next() {
+		key := make([]byte, len(iter.Key()))
+		copy(key, iter.Key())
+		tokenID, shardID, ok := oldItemKey(commitmentsPrefix, key)
+		if !ok {
+			continue
+		}
+		if err := db.delete(key); err != nil {
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+		}
+		if len(key) == baseLen {
+			// array of all commitments of token and shard, keys of this
+			// token and shard are after it
+			var commitments [][]byte
+			if err := json.Unmarshal(iter.Value(), &commitments); err != nil {
+				return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
+			}
+			commitmentSet = make(map[string]bool)
+			for index, commitment := range commitments {
+				indexBytes := indexToBytes(uint64(index))
+				if err := db.put(itemKey(commitmentsIndexPrefix, tokenID, shardID, indexBytes), commitment); err != nil {
+					return err
+				}
+				if err := db.put(itemKey(commitmentsValuePrefix, tokenID, shardID, commitment), indexBytes); err != nil {
+					return err
+				}
+				commitmentSet[string(commitment)] = true
+			}
+			if err := db.put(itemKey(commitmentsLenPrefix, tokenID, shardID), indexToBytes(uint64(len(commitments)))); err != nil {
+				return err
+			}
+			base = key
+			continue
+		}
+		if base == nil || !bytes.HasPrefix(key, base) {
+			continue
+		}
+		// other keys are index, commitment, len or pubkey, only pubkey keys
+		// have an array of indexes
+		suffix := key[baseLen:]
+		if commitmentSet[string(suffix)] || string(suffix) == "len" {
+			continue
+		}
+		var indexes [][]byte
+		if err := json.Unmarshal(iter.Value(), &indexes); err != nil {
+			continue
+		}
+		for _, index := range indexes {
+			indexBytes := indexToBytes(new(big.Int).SetBytes(index).Uint64())
+			if err := db.put(itemKey(commitmentsPubkeyPrefix, tokenID, shardID, suffix, indexBytes), indexBytes); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	return nil
+}
+
+func (db *db) migrateOutcoins() error {
+	baseLen := len(outcoinsPrefix) + common.HashSize*2 + 1
+	iter := db.kv.NewIterator(outcoinsPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		key := make([]byte, len(iter.Key()))
+		copy(key, iter.Key())
+		tokenID, shardID, ok := oldItemKey(outcoinsPrefix, key)
+		if !ok || len(key) == baseLen {
+			continue
+		}
+		if err := db.delete(key); err != nil {
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+		}
+		pubkey := key[baseLen:]
+		var outcoins [][]byte
+		if err := json.Unmarshal(iter.Value(), &outcoins); err != nil {
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
+		}
+		for seq, outcoin := range outcoins {
+			if err := db.put(itemKey(outcoinsPubkeyPrefix, tokenID, shardID, pubkey, indexToBytes(uint64(seq))), outcoin); err != nil {
+				return err
+			}
+		}
+		if err := db.put(itemKey(outcoinsLenPrefix, tokenID, shardID, pubkey), indexToBytes(uint64(len(outcoins)))); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package lvdb
+
+import (
+	"encoding/json"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/pkg/errors"
+)
+
+/*
+PruneBlock deletes body of a shard or beacon block and keeps its header.
+Keys of block index and of beacon and shard lookup are kept, so the block is
+still known and FetchBlock returns BlockPruned error. Undo log of the block
+is deleted, a pruned block can't be reverted
+*/
+func (db *db) PruneBlock(hash *common.Hash, header interface{}) error {
+	val, err := json.Marshal(header)
+	if err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Marshal"))
+	}
+	if err := db.put(db.GetKey(string(blockHeaderKeyPrefix), hash), val); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Put"))
+	}
+	if err := db.delete(db.GetKey(string(blockKeyPrefix), hash)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
+	if err := db.delete(undoKey(hash)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
+	return nil
+}
+
+// FetchBlockHeader returns header of a pruned block
+func (db *db) FetchBlockHeader(hash *common.Hash) ([]byte, error) {
+	header, err := db.get(db.GetKey(string(blockHeaderKeyPrefix), hash))
+	if err != nil {
+		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	ret := make([]byte, len(header))
+	copy(ret, header)
+	return ret, nil
+}
+
+func (db *db) isPruned(hash *common.Hash) (bool, error) {
+	return db.has(db.GetKey(string(blockHeaderKeyPrefix), hash))
+}
+
+func prunedHeightKey(isBeacon bool, shardID byte) []byte {
+	key := make([]byte, 0, len(prunedHeightPrefix)+len(beaconPrefix)+len(shardIDPrefix)+1)
+	key = append(key, prunedHeightPrefix...)
+	if isBeacon {
+		return append(key, beaconPrefix...)
+	}
+	return append(append(key, shardIDPrefix...), shardID)
+}
+
+// StorePrunedHeight stores the height which blocks up to are pruned
+func (db *db) StorePrunedHeight(isBeacon bool, shardID byte, height uint64) error {
+	if err := db.put(prunedHeightKey(isBeacon, shardID), common.Uint64ToBytes(height)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Put"))
+	}
+	return nil
+}
+
+// FetchPrunedHeight returns 0 if no block is pruned
+func (db *db) FetchPrunedHeight(isBeacon bool, shardID byte) (uint64, error) {
+	value, err := db.get(prunedHeightKey(isBeacon, shardID))
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	if len(value) != 8 {
+		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Errorf("invalid pruned height %x", value))
+	}
+	return common.BytesToUint64(value), nil
+}
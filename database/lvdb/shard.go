@@ -8,7 +8,6 @@ import (
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/database"
 	"github.com/pkg/errors"
-	lvdberr "github.com/syndtr/goleveldb/leveldb/errors"
 )
 
 func (db *db) StoreShardBlock(v interface{}, shardID byte) error {
@@ -64,19 +63,25 @@ func (db *db) StoreShardBlockHeader(v interface{}, hash *common.Hash, shardID by
 	return nil
 }
 
+// HasBlock returns true for a pruned block too, its header is still stored
 func (db *db) HasBlock(hash *common.Hash) (bool, error) {
 	exists, err := db.HasValue(db.GetKey(string(blockKeyPrefix), hash))
 	if err != nil {
 		return false, err
-	} else {
-		return exists, nil
 	}
+	if !exists {
+		return db.isPruned(hash)
+	}
+	return exists, nil
 }
 
 func (db *db) FetchBlock(hash *common.Hash) ([]byte, error) {
-	block, err := db.lvdb.Get(db.GetKey(string(blockKeyPrefix), hash), nil)
+	block, err := db.get(db.GetKey(string(blockKeyPrefix), hash))
 	if err != nil {
-		if err == lvdberr.ErrNotFound {
+		if err == ErrNotFound {
+			if pruned, _ := db.isPruned(hash); pruned {
+				return nil, database.NewDatabaseError(database.BlockPruned, errors.Errorf("block %s is pruned", hash.String()))
+			}
 			return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 		}
 		return []byte{}, nil
@@ -88,23 +93,36 @@ func (db *db) FetchBlock(hash *common.Hash) ([]byte, error) {
 
 func (db *db) DeleteBlock(hash *common.Hash, idx uint64, shardID byte) error {
 	// Delete block
-	err := db.lvdb.Delete(db.GetKey(string(blockKeyPrefix), hash), nil)
+	err := db.delete(db.GetKey(string(blockKeyPrefix), hash))
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 
 	// Delete block index
-	err = db.lvdb.Delete(db.GetKey(string(blockKeyIdxPrefix), hash), nil)
+	err = db.delete(db.GetKey(string(blockKeyIdxPrefix), hash))
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 	buf := make([]byte, 9)
 	binary.LittleEndian.PutUint64(buf, idx)
 	buf[8] = shardID
-	err = db.lvdb.Delete(buf, nil)
+	err = db.delete(buf)
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
+
+	// Delete shard lookup key, header of pruned block and undo log, so the
+	// block can be stored again
+	key := append(append(append([]byte{}, shardIDPrefix...), shardID), append(blockKeyPrefix, hash[:]...)...)
+	if err := db.delete(key); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
+	if err := db.delete(db.GetKey(string(blockHeaderKeyPrefix), hash)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
+	if err := db.delete(undoKey(hash)); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+	}
 	return nil
 }
 
@@ -132,7 +150,7 @@ func (db *db) FetchShardBestState(shardID byte) ([]byte, error) {
 func (db *db) CleanShardBestState() error {
 	for shardID := byte(0); shardID < common.MAX_SHARD_NUMBER; shardID++ {
 		key := append(bestBlockKey, shardID)
-		err := db.lvdb.Delete(key, nil)
+		err := db.delete(key)
 		if err != nil {
 			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.delete"))
 		}
@@ -145,18 +163,18 @@ func (db *db) StoreShardBlockIndex(h *common.Hash, idx uint64, shardID byte) err
 	binary.LittleEndian.PutUint64(buf, idx)
 	buf[8] = shardID
 	//{i-[hash]}:index-shardID
-	if err := db.lvdb.Put(db.GetKey(string(blockKeyIdxPrefix), h), buf, nil); err != nil {
+	if err := db.put(db.GetKey(string(blockKeyIdxPrefix), h), buf); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	//{index-shardID}:[hash]
-	if err := db.lvdb.Put(buf, h[:], nil); err != nil {
+	if err := db.put(buf, h[:]); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	return nil
 }
 
 func (db *db) GetIndexOfBlock(h *common.Hash) (uint64, byte, error) {
-	b, err := db.lvdb.Get(db.GetKey(string(blockKeyIdxPrefix), h), nil)
+	b, err := db.get(db.GetKey(string(blockKeyIdxPrefix), h))
 	//{i-[hash]}:index-shardID
 	if err != nil {
 		return 0, 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.get"))
@@ -179,7 +197,7 @@ func (db *db) GetBlockByIndex(idx uint64, shardID byte) (*common.Hash, error) {
 	buf[8] = shardID
 	// {index-shardID}: {blockhash}
 
-	b, err := db.lvdb.Get(buf, nil)
+	b, err := db.get(buf)
 	if err != nil {
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.GetBlockByIndex"))
 	}
@@ -201,7 +219,7 @@ func (db *db) StoreIncomingCrossShard(shardID byte, crossShardID byte, blkHeight
 	if ok, _ := db.HasValue(key); ok {
 		return database.NewDatabaseError(database.BlockExisted, errors.Errorf("block %s already exists"))
 	}
-	if err := db.lvdb.Put(key, buf, nil); err != nil {
+	if err := db.put(key, buf); err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.put"))
 	}
 	return nil
@@ -1,6 +1,7 @@
 package lvdb
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"strconv"
 	"strings"
@@ -12,16 +13,14 @@ import (
 	"math/big"
 
 	"github.com/pkg/errors"
-	lvdberr "github.com/syndtr/goleveldb/leveldb/errors"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // StoreSerialNumbers - store list serialNumbers by shardID
 func (db *db) StoreSerialNumbers(tokenID *common.Hash, serialNumber []byte, shardID byte) error {
 	key := db.GetKey(string(serialNumbersPrefix), tokenID)
 	key = append(key, shardID)
-	res, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
+	res, err := db.get(key)
+	if err != nil && err != ErrNotFound {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 
@@ -39,7 +38,7 @@ func (db *db) StoreSerialNumbers(tokenID *common.Hash, serialNumber []byte, shar
 	}
 	//keySpec1 := make([]byte, len(key))
 	keySpec1 := append(key, serialNumber...)
-	if err := db.lvdb.Put(keySpec1, newIndex, nil); err != nil {
+	if err := db.put(keySpec1, newIndex); err != nil {
 		return err
 	}
 
@@ -48,7 +47,7 @@ func (db *db) StoreSerialNumbers(tokenID *common.Hash, serialNumber []byte, shar
 	if err != nil {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Marshal"))
 	}
-	if err := db.lvdb.Put(key, b, nil); err != nil {
+	if err := db.put(key, b); err != nil {
 		return err
 	}
 	return nil
@@ -58,8 +57,8 @@ func (db *db) StoreSerialNumbers(tokenID *common.Hash, serialNumber []byte, shar
 func (db *db) FetchSerialNumbers(tokenID *common.Hash, shardID byte) ([][]byte, error) {
 	key := db.GetKey(string(serialNumbersPrefix), tokenID)
 	key = append(key, shardID)
-	res, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
+	res, err := db.get(key)
+	if err != nil && err != ErrNotFound {
 		return make([][]byte, 0), database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 
@@ -115,9 +114,9 @@ func (db *db) HasSerialNumber(tokenID *common.Hash, serialNumber []byte, shardID
 
 // CleanSerialNumbers - clear all list serialNumber in DB
 func (db *db) CleanSerialNumbers() error {
-	iter := db.lvdb.NewIterator(util.BytesPrefix(serialNumbersPrefix), nil)
+	iter := db.newIterator(serialNumbersPrefix)
 	for iter.Next() {
-		err := db.lvdb.Delete(iter.Key(), nil)
+		err := db.delete(iter.Key())
 		if err != nil {
 			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 		}
@@ -129,245 +128,218 @@ func (db *db) CleanSerialNumbers() error {
 	return nil
 }
 
-func (db *db) StoreOutputCoins(tokenID *common.Hash, pubkey []byte, outputcoin []byte, shardID byte) error {
-	key := db.GetKey(string(outcoinsPrefix), tokenID)
+/*
+	Commitments and output coins are stored key per item, so storing one item
+	doesn't rewrite the items which are stored before it:
+	- commitmentsIndexPrefix-tokenID-shardID-index: commitment
+	- commitmentsValuePrefix-tokenID-shardID-commitment: index
+	- commitmentsLenPrefix-tokenID-shardID: number of commitments
+	- commitmentsPubkeyPrefix-tokenID-shardID-pubkey-index: index
+	- outcoinsPubkeyPrefix-tokenID-shardID-pubkey-seq: output coin
+	- outcoinsLenPrefix-tokenID-shardID-pubkey: number of output coins of pubkey
+	Index and seq are 8 bytes big endian, so iterators return items in the
+	order they are stored
+*/
+func itemKey(prefix []byte, tokenID *common.Hash, shardID byte, suffix ...[]byte) []byte {
+	key := make([]byte, 0, len(prefix)+common.HashSize*2+1)
+	key = append(key, prefix...)
+	key = append(key, []byte(tokenID.String())...)
 	key = append(key, shardID)
-
-	// store for pubkey:[outcoint1, outcoint2, ...]
-	key = append(key, pubkey...)
-	var arrDatabyPubkey [][]byte
-	resByPubkey, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
-		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	for _, item := range suffix {
+		key = append(key, item...)
 	}
-	if len(resByPubkey) > 0 {
-		if err := json.Unmarshal(resByPubkey, &arrDatabyPubkey); err != nil {
-			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
+	return key
+}
+
+func indexToBytes(index uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, index)
+	return b
+}
+
+// getCounter returns value of a counter key, it is 0 if key doesn't exist
+func (db *db) getCounter(key []byte) (uint64, error) {
+	res, err := db.get(key)
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
 		}
+		return 0, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
-	arrDatabyPubkey = append(arrDatabyPubkey, outputcoin)
-	resByPubkey, err = json.Marshal(arrDatabyPubkey)
+	return binary.BigEndian.Uint64(res), nil
+}
+
+func (db *db) StoreOutputCoins(tokenID *common.Hash, pubkey []byte, outputcoin []byte, shardID byte) error {
+	lenKey := itemKey(outcoinsLenPrefix, tokenID, shardID, pubkey)
+	seq, err := db.getCounter(lenKey)
 	if err != nil {
 		return err
 	}
-	if err := db.lvdb.Put(key, resByPubkey, nil); err != nil {
+	if err := db.put(itemKey(outcoinsPubkeyPrefix, tokenID, shardID, pubkey, indexToBytes(seq)), outputcoin); err != nil {
+		return err
+	}
+	if err := db.put(lenKey, indexToBytes(seq+1)); err != nil {
 		return err
 	}
-
 	return nil
 }
 
-// StoreCommitments - store list commitments by shardID
+// StoreCommitments - store commitment by shardID, commitment gets the next index
 func (db *db) StoreCommitments(tokenID *common.Hash, pubkey []byte, commitments []byte, shardID byte) error {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	res, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
-		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
-	}
-
-	var arrData [][]byte
-	if len(res) > 0 {
-		if err := json.Unmarshal(res, &arrData); err != nil {
-			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
-		}
-	}
-
 	// use for create proof random
-	lenData := uint64(len(arrData))
-	newIndex := new(big.Int).SetUint64(lenData).Bytes()
-	if lenData == 0 {
-		newIndex = []byte{0}
+	lenKey := itemKey(commitmentsLenPrefix, tokenID, shardID)
+	index, err := db.getCounter(lenKey)
+	if err != nil {
+		return err
 	}
-	//keySpec1 := make([]byte, len(key))
-	keySpec1 := append(key, newIndex...)
-	if err := db.lvdb.Put(keySpec1, commitments, nil); err != nil {
+	indexBytes := indexToBytes(index)
+	if err := db.put(itemKey(commitmentsIndexPrefix, tokenID, shardID, indexBytes), commitments); err != nil {
 		return err
 	}
-
 	// use for validate
-	//keySpec2 := make([]byte, len(key))
-	keySpec2 := append(key, commitments...)
-	if err := db.lvdb.Put(keySpec2, newIndex, nil); err != nil {
+	if err := db.put(itemKey(commitmentsValuePrefix, tokenID, shardID, commitments), indexBytes); err != nil {
 		return err
 	}
-
-	// store length of array commitment
-	//keySpec3 := make([]byte, len(key))
-	keySpec3 := append(key, []byte("len")...)
-	if err := db.lvdb.Put(keySpec3, newIndex, nil); err != nil {
+	// store for pubkey
+	if err := db.put(itemKey(commitmentsPubkeyPrefix, tokenID, shardID, pubkey, indexBytes), indexBytes); err != nil {
 		return err
 	}
-
-	// store for pubkey:[newindex1, newindex2]
-	//keySpec4 := make([]byte, len(key))
-	keySpec4 := append(key, pubkey...)
-	var arrDatabyPubkey [][]byte
-	resByPubkey, err := db.lvdb.Get(keySpec4, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
-		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
-	}
-	if len(resByPubkey) > 0 {
-		if err := json.Unmarshal(resByPubkey, &arrDatabyPubkey); err != nil {
-			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
-		}
-	}
-	arrDatabyPubkey = append(arrDatabyPubkey, newIndex)
-	resByPubkey, err = json.Marshal(arrDatabyPubkey)
-	if err != nil {
-		return err
-	}
-	if err := db.lvdb.Put(keySpec4, resByPubkey, nil); err != nil {
+	// store length of commitments
+	if err := db.put(lenKey, indexToBytes(index+1)); err != nil {
 		return err
 	}
+	return nil
+}
 
-	arrData = append(arrData, commitments)
-	b, err := json.Marshal(arrData)
-	if err != nil {
-		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Marshal"))
+// IterateCommitments calls fn with every commitment of shardID in order of
+// index until fn returns false
+func (db *db) IterateCommitments(tokenID *common.Hash, shardID byte, fn func(index uint64, commitment []byte) bool) error {
+	prefix := itemKey(commitmentsIndexPrefix, tokenID, shardID)
+	iter := db.newIterator(prefix)
+	defer iter.Release()
+	for iter.Next() {
+		index := binary.BigEndian.Uint64(iter.Key()[len(prefix):])
+		commitment := make([]byte, len(iter.Value()))
+		copy(commitment, iter.Value())
+		if !fn(index, commitment) {
+			break
+		}
 	}
-	if err := db.lvdb.Put(key, b, nil); err != nil {
-		return err
+	if err := iter.Error(); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
 	}
 	return nil
 }
 
 // FetchCommitments - Get list commitments by shardID
 func (db *db) FetchCommitments(tokenID *common.Hash, shardID byte) ([][]byte, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	res, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
-		return make([][]byte, 0), database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
-	}
-
-	var txs [][]byte
-	if len(res) > 0 {
-		if err := json.Unmarshal(res, &txs); err != nil {
-			return make([][]byte, 0), database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
-		}
+	commitments := make([][]byte, 0)
+	err := db.IterateCommitments(tokenID, shardID, func(index uint64, commitment []byte) bool {
+		commitments = append(commitments, commitment)
+		return true
+	})
+	if err != nil {
+		return make([][]byte, 0), err
 	}
-	return txs, nil
+	return commitments, nil
 }
 
 // HasCommitment - Check commitment in list commitments by shardID
 func (db *db) HasCommitment(tokenID *common.Hash, commitment []byte, shardID byte) (bool, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	keySpec := append(key, commitment...)
-	_, err := db.Get(keySpec)
+	ok, err := db.has(itemKey(commitmentsValuePrefix, tokenID, shardID, commitment))
 	if err != nil {
 		return false, nil
-	} else {
-		return true, nil
 	}
-	return false, nil
+	return ok, nil
 }
 
 func (db *db) HasCommitmentIndex(tokenID *common.Hash, commitmentIndex uint64, shardID byte) (bool, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	keySpec := append(key, new(big.Int).SetUint64(commitmentIndex).Bytes()...)
-	_, err := db.Get(keySpec)
+	ok, err := db.has(itemKey(commitmentsIndexPrefix, tokenID, shardID, indexToBytes(commitmentIndex)))
 	if err != nil {
-		return false, err
-	} else {
-		return true, nil
+		return false, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Has"))
 	}
-	return false, nil
+	if !ok {
+		return false, database.NewDatabaseError(database.LvDbNotFound, errors.Errorf("commitment index %d", commitmentIndex))
+	}
+	return true, nil
 }
 
 func (db *db) GetCommitmentByIndex(tokenID *common.Hash, commitmentIndex uint64, shardID byte) ([]byte, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	//keySpec := make([]byte, len(key))
-	var keySpec []byte
-	if commitmentIndex == 0 {
-		keySpec = append(key, byte(0))
-	} else {
-		keySpec = append(key, new(big.Int).SetUint64(commitmentIndex).Bytes()...)
-	}
-	data, err := db.Get(keySpec)
-	if err != nil {
-		return data, err
-	} else {
-		return data, nil
-	}
-	return data, nil
+	return db.Get(itemKey(commitmentsIndexPrefix, tokenID, shardID, indexToBytes(commitmentIndex)))
 }
 
 // GetCommitmentIndex - return index of commitment in db list
 func (db *db) GetCommitmentIndex(tokenID *common.Hash, commitment []byte, shardID byte) (*big.Int, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	keySpec := append(key, commitment...)
-	data, err := db.Get(keySpec)
+	data, err := db.Get(itemKey(commitmentsValuePrefix, tokenID, shardID, commitment))
 	if err != nil {
 		return nil, err
-	} else {
-		return new(big.Int).SetBytes(data), nil
 	}
-	return nil, nil
+	return new(big.Int).SetBytes(data), nil
 }
 
-// GetCommitmentIndex - return index of commitment in db list
+// GetCommitmentLength - return number of commitments in db list
 func (db *db) GetCommitmentLength(tokenID *common.Hash, shardID byte) (*big.Int, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-	keySpec := append(key, []byte("len")...)
-	data, err := db.Get(keySpec)
+	data, err := db.Get(itemKey(commitmentsLenPrefix, tokenID, shardID))
 	if err != nil {
 		return nil, err
-	} else {
-		lenArray := new(big.Int).SetBytes(data)
-		lenArray = lenArray.Add(lenArray, new(big.Int).SetInt64(1))
-		return lenArray, nil
 	}
-	return nil, nil
+	return new(big.Int).SetBytes(data), nil
 }
 
 func (db *db) GetCommitmentIndexsByPubkey(tokenID *common.Hash, pubkey []byte, shardID byte) ([][]byte, error) {
-	key := db.GetKey(string(commitmentsPrefix), tokenID)
-	key = append(key, shardID)
-
-	//keySpec4 := make([]byte, len(key))
-	keySpec4 := append(key, pubkey...)
-	var arrDatabyPubkey [][]byte
-	resByPubkey, err := db.lvdb.Get(keySpec4, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
-		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	prefix := itemKey(commitmentsPubkeyPrefix, tokenID, shardID, pubkey)
+	iter := db.newIterator(prefix)
+	defer iter.Release()
+	indexes := make([][]byte, 0)
+	for iter.Next() {
+		// index bytes are without leading zeros like big.Int bytes, index 0 is [0]
+		index := new(big.Int).SetBytes(iter.Value()).Bytes()
+		if len(index) == 0 {
+			index = []byte{0}
+		}
+		indexes = append(indexes, index)
 	}
-	if len(resByPubkey) > 0 {
-		if err := json.Unmarshal(resByPubkey, &arrDatabyPubkey); err != nil {
-			return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
+	if err := iter.Error(); err != nil {
+		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	return indexes, nil
+}
+
+// IterateOutcoinsByPubkey calls fn with every output coin of pubkey in order
+// they are stored until fn returns false
+func (db *db) IterateOutcoinsByPubkey(tokenID *common.Hash, pubkey []byte, shardID byte, fn func(outcoin []byte) bool) error {
+	iter := db.newIterator(itemKey(outcoinsPubkeyPrefix, tokenID, shardID, pubkey))
+	defer iter.Release()
+	for iter.Next() {
+		outcoin := make([]byte, len(iter.Value()))
+		copy(outcoin, iter.Value())
+		if !fn(outcoin) {
+			break
 		}
 	}
-	return arrDatabyPubkey, nil
+	if err := iter.Error(); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	return nil
 }
 
 func (db *db) GetOutcoinsByPubkey(tokenID *common.Hash, pubkey []byte, shardID byte) ([][]byte, error) {
-	key := db.GetKey(string(outcoinsPrefix), tokenID)
-	key = append(key, shardID)
-
-	key = append(key, pubkey...)
-	var arrDatabyPubkey [][]byte
-	resByPubkey, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
-		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
-	}
-	if len(resByPubkey) > 0 {
-		if err := json.Unmarshal(resByPubkey, &arrDatabyPubkey); err != nil {
-			return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "json.Unmarshal"))
-		}
+	outcoins := make([][]byte, 0)
+	err := db.IterateOutcoinsByPubkey(tokenID, pubkey, shardID, func(outcoin []byte) bool {
+		outcoins = append(outcoins, outcoin)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
-	return arrDatabyPubkey, nil
+	return outcoins, nil
 }
 
 // CleanCommitments - clear all list commitments in DB
 func (db *db) CleanCommitments() error {
-	iter := db.lvdb.NewIterator(util.BytesPrefix(commitmentsPrefix), nil)
+	iter := db.newIterator(commitmentsPrefix)
 	for iter.Next() {
-		err := db.lvdb.Delete(iter.Key(), nil)
+		err := db.delete(iter.Key())
 		if err != nil {
 			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 		}
@@ -383,14 +355,14 @@ func (db *db) CleanCommitments() error {
 func (db *db) StoreSNDerivators(tokenID *common.Hash, data []byte, shardID byte) error {
 	key := db.GetKey(string(snderivatorsPrefix), tokenID)
 	key = append(key, shardID)
-	_, err := db.lvdb.Get(key, nil)
-	if err != nil && err != lvdberr.ErrNotFound {
+	_, err := db.get(key)
+	if err != nil && err != ErrNotFound {
 		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
 
 	// "snderivator-data:nil"
 	keySpec := append(key, data...)
-	if err := db.lvdb.Put(keySpec, []byte{}, nil); err != nil {
+	if err := db.put(keySpec, []byte{}); err != nil {
 		return err
 	}
 
@@ -413,9 +385,9 @@ func (db *db) HasSNDerivator(tokenID *common.Hash, data []byte, shardID byte) (b
 
 // CleanCommitments - clear all list commitments in DB
 func (db *db) CleanSNDerivator() error {
-	iter := db.lvdb.NewIterator(util.BytesPrefix(snderivatorsPrefix), nil)
+	iter := db.newIterator(snderivatorsPrefix)
 	for iter.Next() {
-		err := db.lvdb.Delete(iter.Key(), nil)
+		err := db.delete(iter.Key())
 		if err != nil {
 			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 		}
@@ -437,7 +409,7 @@ func (db *db) StoreFeeEstimator(val []byte, shardID byte) error {
 
 // GetFeeEstimator - Get data for FeeEstimator object as a json in byte format
 func (db *db) GetFeeEstimator(shardID byte) ([]byte, error) {
-	b, err := db.lvdb.Get(append(feeEstimator, shardID), nil)
+	b, err := db.get(append(feeEstimator, shardID))
 	if err != nil {
 		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
 	}
@@ -446,9 +418,9 @@ func (db *db) GetFeeEstimator(shardID byte) ([]byte, error) {
 
 // CleanFeeEstimator - Clear FeeEstimator
 func (db *db) CleanFeeEstimator() error {
-	iter := db.lvdb.NewIterator(util.BytesPrefix(feeEstimator), nil)
+	iter := db.newIterator(feeEstimator)
 	for iter.Next() {
-		err := db.lvdb.Delete(iter.Key(), nil)
+		err := db.delete(iter.Key())
 		if err != nil {
 			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
 		}
@@ -469,7 +441,7 @@ func (db *db) CleanFeeEstimator() error {
 func (db *db) StoreTransactionIndex(txId *common.Hash, blockHash *common.Hash, index int) error {
 	key := string(transactionKeyPrefix) + txId.String()
 	value := blockHash.String() + string(Splitter) + strconv.Itoa(index)
-	if err := db.lvdb.Put([]byte(key), []byte(value), nil); err != nil {
+	if err := db.put([]byte(key), []byte(value)); err != nil {
 		return err
 	}
 
@@ -0,0 +1,160 @@
+package lvdb
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/pkg/errors"
+)
+
+/*
+Undo log of a block keeps the value of every key written when the block is
+stored as it was before the block, so commitments, serial numbers, SN
+derivators, token balances, bridge amounts, indexes and best state of the
+block are undone by writing these values back. It is a list of records of
+key length, key, existed flag, value length and value; lengths are 4 bytes
+big endian
+*/
+
+func undoKey(hash *common.Hash) []byte {
+	key := make([]byte, 0, len(undoPrefix)+common.HashSize)
+	key = append(key, undoPrefix...)
+	return append(key, hash[:]...)
+}
+
+type undoRecord struct {
+	key     []byte
+	existed bool
+	value   []byte
+}
+
+func encodeUndoLog(records []undoRecord) []byte {
+	size := 0
+	for _, record := range records {
+		size += 9 + len(record.key) + len(record.value)
+	}
+	buf := make([]byte, 0, size)
+	length := make([]byte, 4)
+	for _, record := range records {
+		binary.BigEndian.PutUint32(length, uint32(len(record.key)))
+		buf = append(buf, length...)
+		buf = append(buf, record.key...)
+		if record.existed {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		binary.BigEndian.PutUint32(length, uint32(len(record.value)))
+		buf = append(buf, length...)
+		buf = append(buf, record.value...)
+	}
+	return buf
+}
+
+func decodeUndoLog(buf []byte) ([]undoRecord, error) {
+	records := []undoRecord{}
+	readBytes := func() ([]byte, bool) {
+		if len(buf) < 4 {
+			return nil, false
+		}
+		length := binary.BigEndian.Uint32(buf)
+		if uint64(len(buf)-4) < uint64(length) {
+			return nil, false
+		}
+		data := buf[4 : 4+length]
+		buf = buf[4+length:]
+		return data, true
+	}
+	for len(buf) > 0 {
+		record := undoRecord{}
+		var ok bool
+		if record.key, ok = readBytes(); !ok || len(buf) < 1 {
+			return nil, errors.New("undo log is truncated")
+		}
+		record.existed = buf[0] == 1
+		buf = buf[1:]
+		if record.value, ok = readBytes(); !ok {
+			return nil, errors.New("undo log is truncated")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+/*
+StoreUndoLog stores the values which keys written in the transaction since it
+began, since the last RevertBlock or since the last StoreUndoLog had in the
+transaction before, as undo log of block hash. So a block which is stored
+after a block is reverted in the same transaction is undone to the reverted
+state. Keys written after StoreUndoLog are not in the undo log
+*/
+func (db *db) StoreUndoLog(hash *common.Hash) error {
+	if db.batch == nil || db.batch.done {
+		return database.NewDatabaseError(database.TransactionErr, errors.New("transaction is not opened"))
+	}
+	keys := make([]string, 0, len(db.batch.prior))
+	for key := range db.batch.prior {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	records := make([]undoRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, db.batch.prior[key])
+	}
+	if err := db.put(undoKey(hash), encodeUndoLog(records)); err != nil {
+		return database.NewDatabaseError(database.UndoLogErr, errors.Wrap(err, "db.lvdb.Put"))
+	}
+	db.markUndo()
+	return nil
+}
+
+func (db *db) HasUndoLog(hash *common.Hash) (bool, error) {
+	exist, err := db.has(undoKey(hash))
+	if err != nil {
+		return false, database.NewDatabaseError(database.UndoLogErr, errors.Wrap(err, "db.lvdb.Has"))
+	}
+	return exist, nil
+}
+
+/*
+RevertBlock writes back the values of undo log of block hash and deletes the
+undo log. Out of a transaction all writes are committed at once
+*/
+func (db *db) RevertBlock(hash *common.Hash) error {
+	if db.batch == nil {
+		tx := db.begin()
+		defer tx.Rollback()
+		if err := tx.RevertBlock(hash); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	buf, err := db.get(undoKey(hash))
+	if err != nil {
+		if err == ErrNotFound {
+			return database.NewDatabaseError(database.UndoLogErr, errors.Errorf("block %s has no undo log", hash.String()))
+		}
+		return database.NewDatabaseError(database.UndoLogErr, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	records, err := decodeUndoLog(buf)
+	if err != nil {
+		return database.NewDatabaseError(database.UndoLogErr, err)
+	}
+	for _, record := range records {
+		if record.existed {
+			err = db.put(record.key, record.value)
+		} else {
+			err = db.delete(record.key)
+		}
+		if err != nil {
+			return database.NewDatabaseError(database.UndoLogErr, err)
+		}
+	}
+	if err := db.delete(undoKey(hash)); err != nil {
+		return database.NewDatabaseError(database.UndoLogErr, errors.Wrap(err, "db.lvdb.Delete"))
+	}
+	db.markUndo()
+	return nil
+}
@@ -0,0 +1,118 @@
+package databasemp_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/databasemp"
+	_ "github.com/constant-money/constant-chain/databasemp/lvdb"
+)
+
+// drivers lists the drivers which run the conformance tests, every driver
+// must behave the same way
+var drivers = []struct {
+	name string
+	open func(t *testing.T) (databasemp.DatabaseInterface, func())
+}{
+	{"leveldbmempool", openLeveldb},
+	{"memdbmempool", openMemdb},
+}
+
+func openLeveldb(t *testing.T) (databasemp.DatabaseInterface, func()) {
+	dbPath, err := ioutil.TempDir(os.TempDir(), "test_")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %+v", err)
+	}
+	db, err := databasemp.Open("leveldbmempool", dbPath)
+	if err != nil {
+		t.Fatalf("could not open db path: %s, %+v", dbPath, err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dbPath)
+	}
+}
+
+func openMemdb(t *testing.T) (databasemp.DatabaseInterface, func()) {
+	db, err := databasemp.Open("memdbmempool")
+	if err != nil {
+		t.Fatalf("could not open memdb: %+v", err)
+	}
+	return db, func() {
+		db.Close()
+	}
+}
+
+func TestConformanceTransactions(t *testing.T) {
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			db, teardown := driver.open(t)
+			defer teardown()
+
+			txHash := common.HashH([]byte("tx"))
+			if err := db.AddTransaction(&txHash, "n", []byte("tx"), []byte("desc")); err != nil {
+				t.Fatalf("db.AddTransaction returns err: %+v", err)
+			}
+			if has, _ := db.HasTransaction(&txHash); !has {
+				t.Fatalf("transaction should exist")
+			}
+			value, err := db.GetTransaction(&txHash)
+			if err != nil || !bytes.Contains(value, []byte("desc")) {
+				t.Fatalf("db.GetTransaction returns %s, %+v", value, err)
+			}
+			keys, values, err := db.Load()
+			if err != nil || len(keys) != 1 || len(values) != 1 {
+				t.Fatalf("db.Load returns %d transactions, %+v", len(keys), err)
+			}
+			if err := db.RemoveTransaction(&txHash); err != nil {
+				t.Fatalf("db.RemoveTransaction returns err: %+v", err)
+			}
+			if has, _ := db.HasTransaction(&txHash); has {
+				t.Fatalf("transaction should be removed")
+			}
+			db.AddTransaction(&txHash, "n", []byte("tx"), []byte("desc"))
+			if err := db.Reset(); err != nil {
+				t.Fatalf("db.Reset returns err: %+v", err)
+			}
+			if keys, _, _ := db.Load(); len(keys) != 0 {
+				t.Fatalf("db should be empty after reset")
+			}
+		})
+	}
+}
+
+func TestConformanceReplaceTransactions(t *testing.T) {
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			db, teardown := driver.open(t)
+			defer teardown()
+
+			if _, ok, err := db.GetVersion(); ok || err != nil {
+				t.Fatalf("db.GetVersion of empty db returns %v, %+v", ok, err)
+			}
+			oldTxHash := common.HashH([]byte("old"))
+			db.AddTransaction(&oldTxHash, "n", []byte("old"), []byte("desc"))
+			newTxHash := common.HashH([]byte("new"))
+			txs := []databasemp.Transaction{{Hash: &newTxHash, Type: "n", ValueTx: []byte("new"), ValueDesc: []byte("desc")}}
+			if err := db.ReplaceTransactions(txs, 2); err != nil {
+				t.Fatalf("db.ReplaceTransactions returns err: %+v", err)
+			}
+			if has, _ := db.HasTransaction(&oldTxHash); has {
+				t.Fatalf("old transaction should be replaced")
+			}
+			value, err := db.GetTransaction(&newTxHash)
+			if err != nil || !bytes.Contains(value, []byte("new")) {
+				t.Fatalf("db.GetTransaction returns %s, %+v", value, err)
+			}
+			if keys, _, _ := db.Load(); len(keys) != 1 {
+				t.Fatalf("db.Load returns %d transactions, expected 1", len(keys))
+			}
+			if version, ok, err := db.GetVersion(); !ok || err != nil || version != 2 {
+				t.Fatalf("db.GetVersion returns %d, %v, %+v", version, ok, err)
+			}
+		})
+	}
+}
@@ -4,6 +4,15 @@ import (
 	"github.com/constant-money/constant-chain/common"
 )
 
+// Transaction is a transaction of mempool in database: its type, its value
+// and value of its desc in pool
+type Transaction struct {
+	Hash      *common.Hash
+	Type      string
+	ValueTx   []byte
+	ValueDesc []byte
+}
+
 type DatabaseInterface interface {
 	Put(key, value []byte) error
 	Get(key []byte) ([]byte, error)
@@ -16,6 +25,13 @@ type DatabaseInterface interface {
 	HasTransaction(key *common.Hash) (bool, error)
 	Reset() error
 	Load() ([][]byte,[][]byte, error)
+
+	// ReplaceTransactions replaces all transactions in database with txs and
+	// sets version of their format in one atomic write
+	ReplaceTransactions(txs []Transaction, version uint32) error
+	// GetVersion returns version of format of transactions in database, false
+	// if it isn't set
+	GetVersion() (uint32, bool, error)
 	
 	Close() error
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/constant-money/constant-chain/databasemp"
 	"github.com/pkg/errors"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
 )
 
 type db struct {
@@ -13,6 +14,7 @@ type db struct {
 var (
 	txKeyPrefix = []byte("tx-")
 	Splitter    = []byte("-[-]-")
+	versionKey  = []byte("version")
 )
 
 func (db *db) GetKey(key interface{}) []byte {
@@ -29,6 +31,15 @@ func open(dbPath string) (databasemp.DatabaseInterface, error) {
 	return &db{lvdb: lvdb}, nil
 }
 
+// openMem opens a mempool db which keeps all data in memory
+func openMem() (databasemp.DatabaseInterface, error) {
+	lvdb, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, databasemp.NewDatabaseMempoolError(databasemp.OpenDbErr, errors.Wrap(err, "levelvdb.Open memory storage"))
+	}
+	return &db{lvdb: lvdb}, nil
+}
+
 func (db *db) Close() error {
 	return errors.Wrap(db.lvdb.Close(), "db.lvdb.Close")
 }
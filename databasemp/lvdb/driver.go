@@ -13,6 +13,13 @@ func init() {
 	if err := databasemp.RegisterDriver(driver); err != nil {
 		panic("failed to register db driver")
 	}
+	memDriver := databasemp.Driver{
+		DbType: "memdbmempool",
+		Open:   openMemDriver,
+	}
+	if err := databasemp.RegisterDriver(memDriver); err != nil {
+		panic("failed to register memdb driver")
+	}
 }
 
 func openDriver(args ...interface{}) (databasemp.DatabaseInterface, error) {
@@ -26,3 +33,9 @@ func openDriver(args ...interface{}) (databasemp.DatabaseInterface, error) {
 	return open(dbPath)
 }
 
+func openMemDriver(args ...interface{}) (databasemp.DatabaseInterface, error) {
+	if len(args) != 0 {
+		return nil, errors.New("invalid arguments")
+	}
+	return openMem()
+}
@@ -1,9 +1,12 @@
 package lvdb
 
 import (
+	"encoding/binary"
+
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/databasemp"
 	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
@@ -11,15 +14,22 @@ import (
 // Value: transaction(byte value)-Splitter-otherDescValue(byte Value)
 func (db *db) AddTransaction(txHash *common.Hash, txType string, valueTx []byte, valueDesc []byte) error {
 	key := db.GetKey(txHash)
-	value := append([]byte(txType),Splitter...)
-	value = append(value, valueTx...)
-	value = append(value, Splitter...)
-	value = append(value, valueDesc...)
+	value := transactionValue(txType, valueTx, valueDesc)
 	if err := db.lvdb.Put(key,value, nil); err != nil {
 		return databasemp.NewDatabaseMempoolError(databasemp.UnexpectedError, errors.Wrap(err, "db.lvdb.Put"))
 	}
 	return nil
 }
+
+// transactionValue joins type, value and desc of a transaction with Splitter
+func transactionValue(txType string, valueTx []byte, valueDesc []byte) []byte {
+	value := append([]byte(txType), Splitter...)
+	value = append(value, valueTx...)
+	value = append(value, Splitter...)
+	value = append(value, valueDesc...)
+	return value
+}
+
 func (db *db) RemoveTransaction(txHash *common.Hash) error {
 	key := db.GetKey(txHash)
 	if err := db.lvdb.Delete(key, nil); err != nil {
@@ -81,3 +91,41 @@ func (db *db) Load() ([][]byte, [][]byte, error) {
 	}
 	return txHashes, txs, nil
 }
+
+func (db *db) ReplaceTransactions(txs []databasemp.Transaction, version uint32) error {
+	batch := new(leveldb.Batch)
+	iter := db.lvdb.NewIterator(util.BytesPrefix(txKeyPrefix), nil)
+	for iter.Next() {
+		key := make([]byte, len(iter.Key()))
+		copy(key, iter.Key())
+		batch.Delete(key)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return databasemp.NewDatabaseMempoolError(databasemp.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	for _, tx := range txs {
+		batch.Put(db.GetKey(tx.Hash), transactionValue(tx.Type, tx.ValueTx, tx.ValueDesc))
+	}
+	versionValue := make([]byte, 4)
+	binary.LittleEndian.PutUint32(versionValue, version)
+	batch.Put(versionKey, versionValue)
+	if err := db.lvdb.Write(batch, nil); err != nil {
+		return databasemp.NewDatabaseMempoolError(databasemp.UnexpectedError, errors.Wrap(err, "db.lvdb.Write"))
+	}
+	return nil
+}
+
+func (db *db) GetVersion() (uint32, bool, error) {
+	value, err := db.lvdb.Get(versionKey, nil)
+	if err == leveldb.ErrNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, databasemp.NewDatabaseMempoolError(databasemp.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	if len(value) != 4 {
+		return 0, false, databasemp.NewDatabaseMempoolError(databasemp.UnexpectedError, errors.Errorf("invalid version value %x", value))
+	}
+	return binary.LittleEndian.Uint32(value), true, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/constant-money/constant-chain/connmanager"
 	"github.com/constant-money/constant-chain/consensus/constantbft"
 	"github.com/constant-money/constant-chain/database"
+	"github.com/constant-money/constant-chain/database/lvdb"
 	"github.com/constant-money/constant-chain/mempool"
 	"github.com/constant-money/constant-chain/netsync"
 	"github.com/constant-money/constant-chain/peer"
@@ -67,6 +68,7 @@ func init() {
 	netsync.Logger.Init(netsyncLogger)
 	peer.Logger.Init(peerLogger)
 	database.Logger.Init(dbLogger)
+	lvdb.Logger.Init(dbLogger)
 	wallet.Logger.Init(walletLogger)
 	blockchain.Logger.Init(blockchainLogger)
 	constantbft.Logger.Init(consensusLogger)
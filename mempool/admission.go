@@ -0,0 +1,75 @@
+package mempool
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/constant-money/constant-chain/metadata"
+)
+
+// defaultMaxPendingTxs is the number of txs which may wait for admission
+// when Config.MaxPendingTxs isn't set
+const defaultMaxPendingTxs = 1000
+
+/*
+admissionQueue bounds admission of txs to pool. Stateless checks of a tx,
+mostly verification of its privacy proof, run outside pool lock on one of a
+fixed number of slots. A tx which comes when too many txs wait for a slot is
+rejected before it is checked, so a flood of txs from peers can't pile up
+goroutines and memory, netsync asks IsAdmissionBusy to drop txs early
+*/
+type admissionQueue struct {
+	pending    int64 // number of txs which are verified or wait, used atomically
+	maxPending int64
+	slots      chan struct{}
+}
+
+func (tp *TxPool) initAdmission() {
+	workers := tp.config.AdmissionWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	maxPending := tp.config.MaxPendingTxs
+	if maxPending <= 0 {
+		maxPending = defaultMaxPendingTxs
+	}
+	tp.admission = admissionQueue{
+		maxPending: int64(maxPending),
+		slots:      make(chan struct{}, workers),
+	}
+}
+
+// startAdmission takes a place of tx in admission queue, it returns false if
+// the queue is full
+func (tp *TxPool) startAdmission() bool {
+	if atomic.AddInt64(&tp.admission.pending, 1) > tp.admission.maxPending {
+		atomic.AddInt64(&tp.admission.pending, -1)
+		return false
+	}
+	return true
+}
+
+// endAdmission releases the place of tx in admission queue
+func (tp *TxPool) endAdmission() {
+	atomic.AddInt64(&tp.admission.pending, -1)
+}
+
+// verifyTx runs stateless checks of tx when a slot is free
+func (tp *TxPool) verifyTx(tx metadata.Transaction) error {
+	tp.admission.slots <- struct{}{}
+	defer func() {
+		<-tp.admission.slots
+	}()
+	return tp.validateTxStateless(tx)
+}
+
+// IsAdmissionBusy returns true if pool doesn't take more txs to verify until
+// txs which wait for admission are checked
+func (tp *TxPool) IsAdmissionBusy() bool {
+	return atomic.LoadInt64(&tp.admission.pending) >= tp.admission.maxPending
+}
+
+// PendingTxs returns number of txs which are verified or wait for admission
+func (tp *TxPool) PendingTxs() int {
+	return int(atomic.LoadInt64(&tp.admission.pending))
+}
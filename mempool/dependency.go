@@ -0,0 +1,268 @@
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/constant-money/constant-chain/metadata"
+)
+
+// maxPackageTxs is the max number of txs in pool which a tx, its ancestors or
+// its descendants in pool may have together
+const maxPackageTxs = 25
+
+/*
+A tx in pool is a parent of another tx (its child) in pool when the child
+spends an output coin of the parent. With AcceptChildTxs on, a tx without
+privacy may spend output coins of txs in pool: its input coins are revealed,
+so they are matched to output coin commitments of txs in pool. A tx with
+privacy proves its input coins with commitments in chain, it can only spend
+coins in chain.
+A block is verified with state of chain before it, so a child can't be in
+the same block as its parent. A child is left out of MiningDescs until its
+parents are in a block, but its fee counts in package fee per KB of its
+ancestors, so a high fee child pulls its parents into a block
+*/
+
+// inputCommitments returns commitments of input coins of tx which are
+// revealed, a tx with privacy doesn't reveal its input coins
+func inputCommitments(tx metadata.Transaction) [][]byte {
+	proof := tx.GetProof()
+	if proof == nil || tx.IsPrivacy() {
+		return nil
+	}
+	commitments := [][]byte{}
+	for _, inputCoin := range proof.InputCoins {
+		if inputCoin == nil || inputCoin.CoinDetails == nil || inputCoin.CoinDetails.CoinCommitment == nil {
+			continue
+		}
+		commitments = append(commitments, inputCoin.CoinDetails.CoinCommitment.Compress())
+	}
+	return commitments
+}
+
+// outputCommitments returns commitments of output coins of tx
+func outputCommitments(tx metadata.Transaction) [][]byte {
+	proof := tx.GetProof()
+	if proof == nil {
+		return nil
+	}
+	commitments := [][]byte{}
+	for _, outputCoin := range proof.OutputCoins {
+		if outputCoin == nil || outputCoin.CoinDetails == nil || outputCoin.CoinDetails.CoinCommitment == nil {
+			continue
+		}
+		commitments = append(commitments, outputCoin.CoinDetails.CoinCommitment.Compress())
+	}
+	return commitments
+}
+
+// poolCommitmentDB is chain database which also has commitments of output
+// coins of txs in pool, a child is verified by itself with it
+type poolCommitmentDB struct {
+	database.DatabaseInterface
+	commitments map[string]bool
+}
+
+func (db poolCommitmentDB) HasCommitment(tokenID *common.Hash, commitment []byte, shardID byte) (bool, error) {
+	if *tokenID == common.ConstantID && db.commitments[string(commitment)] {
+		return true, nil
+	}
+	return db.DatabaseInterface.HasCommitment(tokenID, commitment, shardID)
+}
+
+// validationDB returns database which tx is verified by itself with, it has
+// output coins of txs in pool which tx spends
+func (tp *TxPool) validationDB(tx metadata.Transaction) database.DatabaseInterface {
+	db := tp.config.BlockChain.GetDatabase()
+	if !tp.config.AcceptChildTxs {
+		return db
+	}
+	inputs := inputCommitments(tx)
+	if len(inputs) == 0 {
+		return db
+	}
+	partition := tp.partitionOf(tx)
+	commitments := make(map[string]bool)
+	partition.outputsMtx.RLock()
+	for _, commitment := range inputs {
+		if _, ok := partition.outputs[string(commitment)]; ok {
+			commitments[string(commitment)] = true
+		}
+	}
+	partition.outputsMtx.RUnlock()
+	if len(commitments) == 0 {
+		return db
+	}
+	return poolCommitmentDB{DatabaseInterface: db, commitments: commitments}
+}
+
+/*
+checkPackage checks tx with its parents in pool: a tx with parents doesn't
+make a package of more than maxPackageTxs txs, and input coins of tx which
+aren't output coins of txs in pool are in chain, a parent may leave pool
+after tx is verified. Partition lock of tx must be held
+*/
+func (tp *TxPool) checkPackage(partition *txPartition, tx metadata.Transaction) error {
+	if !tp.config.AcceptChildTxs {
+		return nil
+	}
+	parents := partition.parentsOf(tx)
+	if len(parents) > 0 {
+		ancestors := make(map[common.Hash]*TxDesc)
+		for _, parent := range parents {
+			ancestors[*parent.Desc.Tx.Hash()] = parent
+			for _, ancestor := range partition.ancestors(parent) {
+				ancestors[*ancestor.Desc.Tx.Hash()] = ancestor
+			}
+		}
+		if len(ancestors)+1 > maxPackageTxs {
+			err := MempoolTxError{}
+			err.Init(RejectPackageLimitTx, fmt.Errorf("transaction %+v has %d ancestors in pool, more than %d", tx.Hash().String(), len(ancestors), maxPackageTxs-1))
+			return err
+		}
+		for ancestorHash, ancestor := range ancestors {
+			if len(partition.descendants(ancestor))+2 > maxPackageTxs {
+				err := MempoolTxError{}
+				err.Init(RejectPackageLimitTx, fmt.Errorf("transaction %+v in pool has %d descendants, transaction %+v can't be added", ancestorHash.String(), maxPackageTxs-1, tx.Hash().String()))
+				return err
+			}
+		}
+	}
+	shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
+	db := tp.config.BlockChain.GetDatabase()
+	for _, commitment := range inputCommitments(tx) {
+		if _, ok := partition.outputs[string(commitment)]; ok {
+			continue
+		}
+		if ok, err := db.HasCommitment(&common.ConstantID, commitment, shardID); !ok || err != nil {
+			err := MempoolTxError{}
+			err.Init(RejectInvalidTx, fmt.Errorf("transaction %+v spends coin which is neither in chain nor in pool", tx.Hash().String()))
+			return err
+		}
+	}
+	return nil
+}
+
+// parentsOf returns txs of partition whose output coins tx spends, partition
+// lock must be held
+func (p *txPartition) parentsOf(tx metadata.Transaction) []*TxDesc {
+	parents := []*TxDesc{}
+	found := make(map[*TxDesc]bool)
+	for _, commitment := range inputCommitments(tx) {
+		parent, ok := p.outputs[string(commitment)]
+		if ok && !found[parent] {
+			found[parent] = true
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// link adds txDesc to dependencies of partition when it is added, partition
+// lock must be held
+func (p *txPartition) link(txDesc *TxDesc) {
+	txHash := *txDesc.Desc.Tx.Hash()
+	txDesc.parents = make(map[common.Hash]*TxDesc)
+	txDesc.children = make(map[common.Hash]*TxDesc)
+	for _, parent := range p.parentsOf(txDesc.Desc.Tx) {
+		txDesc.parents[*parent.Desc.Tx.Hash()] = parent
+		parent.children[txHash] = txDesc
+	}
+	p.outputsMtx.Lock()
+	for _, commitment := range outputCommitments(txDesc.Desc.Tx) {
+		p.outputs[string(commitment)] = txDesc
+	}
+	p.outputsMtx.Unlock()
+	for _, ancestor := range p.ancestors(txDesc) {
+		p.updatePackageFee(ancestor)
+	}
+}
+
+// unlink removes txDesc from dependencies of partition when it is removed,
+// its children lose it as parent. Partition lock must be held
+func (p *txPartition) unlink(txDesc *TxDesc) {
+	txHash := *txDesc.Desc.Tx.Hash()
+	ancestors := p.ancestors(txDesc)
+	for _, parent := range txDesc.parents {
+		delete(parent.children, txHash)
+	}
+	for _, child := range txDesc.children {
+		delete(child.parents, txHash)
+	}
+	txDesc.parents, txDesc.children = nil, nil
+	p.outputsMtx.Lock()
+	for _, commitment := range outputCommitments(txDesc.Desc.Tx) {
+		if p.outputs[string(commitment)] == txDesc {
+			delete(p.outputs, string(commitment))
+		}
+	}
+	p.outputsMtx.Unlock()
+	for _, ancestor := range ancestors {
+		p.updatePackageFee(ancestor)
+	}
+}
+
+// ancestors returns parents of txDesc in partition and their ancestors,
+// partition lock must be held
+func (p *txPartition) ancestors(txDesc *TxDesc) []*TxDesc {
+	return walkDependencies(txDesc, func(txDesc *TxDesc) map[common.Hash]*TxDesc {
+		return txDesc.parents
+	})
+}
+
+// descendants returns children of txDesc in partition and their descendants,
+// partition lock must be held
+func (p *txPartition) descendants(txDesc *TxDesc) []*TxDesc {
+	return walkDependencies(txDesc, func(txDesc *TxDesc) map[common.Hash]*TxDesc {
+		return txDesc.children
+	})
+}
+
+// walkDependencies returns txs which are reached from txDesc by next, from
+// the nearest
+func walkDependencies(txDesc *TxDesc, next func(*TxDesc) map[common.Hash]*TxDesc) []*TxDesc {
+	reached := []*TxDesc{}
+	visited := map[*TxDesc]bool{txDesc: true}
+	queue := []*TxDesc{txDesc}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependency := range next(current) {
+			if !visited[dependency] {
+				visited[dependency] = true
+				reached = append(reached, dependency)
+				queue = append(queue, dependency)
+			}
+		}
+	}
+	return reached
+}
+
+// updatePackageFee sets fee per KB of txDesc with its descendants in pool and
+// moves it in fee priority index, partition lock must be held
+func (p *txPartition) updatePackageFee(txDesc *TxDesc) {
+	packageFeePerKB := uint64(0)
+	if descendants := p.descendants(txDesc); len(descendants) > 0 {
+		fee, size := txDesc.Desc.Fee, txDesc.Desc.Tx.GetTxActualSize()
+		for _, descendant := range descendants {
+			fee += descendant.Desc.Fee
+			size += descendant.Desc.Tx.GetTxActualSize()
+		}
+		if size == 0 {
+			size = 1
+		}
+		packageFeePerKB = fee / size
+	}
+	if packageFeePerKB != txDesc.packageFeePerKB {
+		txDesc.packageFeePerKB = packageFeePerKB
+		p.priority.update(txDesc)
+	}
+}
+
+// isChild returns true if transaction spends output coins of txs in pool,
+// partition lock must be held
+func (txDesc *TxDesc) isChild() bool {
+	return len(txDesc.parents) > 0
+}
@@ -0,0 +1,42 @@
+package mempool
+
+import (
+	"testing"
+)
+
+// TestMiningDescsChildPaysForParent mines a low fee parent before a tx with
+// higher fee because of the high fee of its child
+func TestMiningDescsChildPaysForParent(t *testing.T) {
+	tp := newTestPool(t, Config{AcceptChildTxs: true})
+	parent := newTestTx("parent", 10, 1, []int64{1}, []int64{2})
+	middle := newTestTx("middle", 50, 1, []int64{5}, nil)
+	low := newTestTx("low", 20, 1, []int64{6}, nil)
+	addTestTx(tp, parent)
+	addTestTx(tp, middle)
+	addTestTx(tp, low)
+
+	expected := []*testTx{middle, low, parent}
+	descs := tp.MiningDescs()
+	if len(descs) != len(expected) {
+		t.Fatalf("tp.MiningDescs returns %d txs instead of %d", len(descs), len(expected))
+	}
+	for i, desc := range descs {
+		if desc.Tx != expected[i] {
+			t.Fatalf("tx %d of tp.MiningDescs is %+v instead of %+v", i, desc.Tx.Hash(), expected[i].Hash())
+		}
+	}
+
+	// package of parent and child pays (10+190)/2 per KB
+	child := newTestTx("child", 190, 1, []int64{2}, nil)
+	addTestTx(tp, child)
+	expected = []*testTx{parent, middle, low}
+	descs = tp.MiningDescs()
+	if len(descs) != len(expected) {
+		t.Fatalf("tp.MiningDescs returns %d txs instead of %d, child should wait for its parent", len(descs), len(expected))
+	}
+	for i, desc := range descs {
+		if desc.Tx != expected[i] {
+			t.Fatalf("tx %d of tp.MiningDescs is %+v instead of %+v", i, desc.Tx.Hash(), expected[i].Hash())
+		}
+	}
+}
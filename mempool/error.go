@@ -22,6 +22,13 @@ const (
 	DuplicateBlockError
 	OldBlockError
 	MaxPoolSizeError
+	RejectReplacementTx
+	AdmissionBusyError
+	RejectDoubleSpendWithMempoolTx
+	RejectInvalidTxWithBlockchain
+	RejectPackageLimitTx
+	RejectExpiredTx
+	RejectCanceledTx
 )
 
 var ErrCodeMessage = map[int]struct {
@@ -41,6 +48,14 @@ var ErrCodeMessage = map[int]struct {
 	DuplicateBlockError:    {-1009, "Duplicate Block Error"},
 	OldBlockError:          {-1010, "Old Block Error"},
 	MaxPoolSizeError:       {-1011, "Max Pool Size Error"},
+	RejectReplacementTx:    {-1012, "Reject replacement tx"},
+	AdmissionBusyError:     {-1013, "Admission Busy Error"},
+
+	RejectDoubleSpendWithMempoolTx: {-1014, "Reject tx which double spends tx in pool"},
+	RejectInvalidTxWithBlockchain:  {-1015, "Reject tx which is invalid with blockchain"},
+	RejectPackageLimitTx:           {-1016, "Reject tx which exceeds package limit"},
+	RejectExpiredTx:                {-1017, "Reject tx which is expired by its expiry height"},
+	RejectCanceledTx:               {-1018, "Tx is removed from pool by node operator"},
 }
 
 type MempoolTxError struct {
@@ -18,6 +18,7 @@ import (
 
 	"github.com/constant-money/constant-chain/blockchain"
 	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
 	"github.com/constant-money/constant-chain/transaction"
 )
 
@@ -42,6 +43,15 @@ const (
 	// it will provide fee estimations.
 	DefaultEstimateFeeMinRegisteredBlocks = 3
 
+	// DefaultEstimateFeeConfidence is the default share of txs paying the
+	// estimated fee rate which were confirmed in the target number of blocks.
+	DefaultEstimateFeeConfidence = 0.9
+
+	// estimateFeeMinSamples is the minimum number of txs of a fee model with
+	// the highest fee rates which are taken into account before an estimate
+	// with confidence gives up.
+	estimateFeeMinSamples = 10
+
 	bytePerKb = 1000
 )
 
@@ -60,6 +70,94 @@ func NewCoinPerKilobyte(fee uint64, size uint64) CoinPerKilobyte {
 	return CoinPerKilobyte(float64(fee) / float64(size))
 }
 
+// FeeModel is a kind of txs whose fee rates are estimated together. Sizes of
+// txs with privacy proofs or token data differ from sizes of normal txs by
+// orders of magnitude, so are their fee rates.
+type FeeModel byte
+
+const (
+	// FeeModelNormal is for normal txs without privacy.
+	FeeModelNormal FeeModel = iota
+
+	// FeeModelPrivacy is for normal txs with privacy.
+	FeeModelPrivacy
+
+	// FeeModelCustomToken is for custom token txs.
+	FeeModelCustomToken
+
+	// FeeModelCustomTokenPrivacy is for privacy custom token txs.
+	FeeModelCustomTokenPrivacy
+
+	// FeeModelPrivacyTokenFee is for fee which privacy custom token txs pay
+	// in their token, it is in units of the token.
+	FeeModelPrivacyTokenFee
+
+	// numFeeModels is the number of fee models.
+	numFeeModels
+)
+
+var feeModelNames = [numFeeModels]string{
+	FeeModelNormal:             "normal",
+	FeeModelPrivacy:            "privacy",
+	FeeModelCustomToken:        "customtoken",
+	FeeModelCustomTokenPrivacy: "customtokenprivacy",
+	FeeModelPrivacyTokenFee:    "privacytokenfee",
+}
+
+// FeeModels returns all fee models.
+func FeeModels() []FeeModel {
+	models := make([]FeeModel, numFeeModels)
+	for i := range models {
+		models[i] = FeeModel(i)
+	}
+	return models
+}
+
+// String returns the name of the fee model.
+func (m FeeModel) String() string {
+	if m >= numFeeModels {
+		return fmt.Sprintf("unknown(%d)", byte(m))
+	}
+	return feeModelNames[m]
+}
+
+// ParseFeeModel returns the fee model of name.
+func ParseFeeModel(name string) (FeeModel, error) {
+	for model, modelName := range feeModelNames {
+		if modelName == name {
+			return FeeModel(model), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown fee model %s", name)
+}
+
+// NewFeeModel returns the fee model of txs of txType, hasPrivacy tells if
+// the constant part of the tx has privacy.
+func NewFeeModel(txType string, hasPrivacy bool) FeeModel {
+	switch txType {
+	case common.TxCustomTokenType:
+		return FeeModelCustomToken
+	case common.TxCustomTokenPrivacyType:
+		return FeeModelCustomTokenPrivacy
+	}
+	if hasPrivacy {
+		return FeeModelPrivacy
+	}
+	return FeeModelNormal
+}
+
+// FeeModelOf returns the fee model of tx.
+func FeeModelOf(tx metadata.Transaction) FeeModel {
+	return NewFeeModel(tx.GetType(), tx.IsPrivacy())
+}
+
+// observedKey is the key of an observed transaction, a tx which pays fee in
+// its token is observed in FeeModelPrivacyTokenFee too.
+type observedKey struct {
+	hash  common.Hash
+	model FeeModel
+}
+
 // observedTransaction represents an observed transaction and some
 // additional data required for the fee estimation algorithm.
 type observedTransaction struct {
@@ -69,6 +167,9 @@ type observedTransaction struct {
 	// The fee per byte of the transaction in coins.
 	feeRate CoinPerKilobyte
 
+	// The fee model of the transaction.
+	model FeeModel
+
 	// The block height when it was observed.
 	observed uint64
 
@@ -80,6 +181,7 @@ type observedTransaction struct {
 func (o *observedTransaction) Serialize(w io.Writer) {
 	binary.Write(w, binary.BigEndian, o.hash)
 	binary.Write(w, binary.BigEndian, o.feeRate)
+	binary.Write(w, binary.BigEndian, o.model)
 	binary.Write(w, binary.BigEndian, o.observed)
 	binary.Write(w, binary.BigEndian, o.mined)
 }
@@ -93,9 +195,21 @@ func deserializeObservedTransaction(r io.Reader) (*observedTransaction, error) {
 	// The next 8 are CoinPerByte
 	binary.Read(r, binary.BigEndian, &ot.feeRate)
 
-	// And next there are two uint32's.
-	binary.Read(r, binary.BigEndian, &ot.observed)
-	binary.Read(r, binary.BigEndian, &ot.mined)
+	// The next byte is the fee model.
+	binary.Read(r, binary.BigEndian, &ot.model)
+	if ot.model >= numFeeModels {
+		return nil, fmt.Errorf("Invalid fee model %d", ot.model)
+	}
+
+	// And next there are two uint64's.
+	err := binary.Read(r, binary.BigEndian, &ot.observed)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(r, binary.BigEndian, &ot.mined)
+	if err != nil {
+		return nil, err
+	}
 
 	return &ot, nil
 }
@@ -119,7 +233,9 @@ func (rb *registeredBlock) serialize(w io.Writer, txs map[*observedTransaction]u
 }
 
 // feeEstimator manages the data necessary to create
-// fee estimations. It is safe for concurrent access.
+// fee estimations of a shard. Each fee model has its own bins,
+// so txs of a model are only compared with txs of the same model.
+// It is safe for concurrent access.
 type FeeEstimator struct {
 	maxRollback uint32
 	binSize     int32
@@ -139,11 +255,11 @@ type FeeEstimator struct {
 	numBlocksRegistered uint32
 
 	mtx      sync.RWMutex
-	observed map[common.Hash]*observedTransaction
-	bin      [estimateFeeDepth][]*observedTransaction
+	observed map[observedKey]*observedTransaction
+	bin      [numFeeModels][estimateFeeDepth][]*observedTransaction
 
 	// The cached estimates.
-	cached []CoinPerKilobyte
+	cached [numFeeModels][]CoinPerKilobyte
 
 	// Transactions that have been removed from the bins. This allows us to
 	// revert in case of an orphaned block.
@@ -160,12 +276,13 @@ func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
 		lastKnownHeight:     UnminedHeight,
 		binSize:             estimateFeeBinSize,
 		maxReplacements:     estimateFeeMaxReplacements,
-		observed:            make(map[common.Hash]*observedTransaction),
+		observed:            make(map[observedKey]*observedTransaction),
 		dropped:             make([]*registeredBlock, 0, maxRollback),
 	}
 }
 
 // ObserveTransaction is called when a new transaction is observed in the mempool.
+// Fee which a tx pays in its token is observed apart from its constant fee.
 func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
@@ -177,13 +294,24 @@ func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
 	}
 
 	hash := *t.Desc.Tx.Hash()
-	if _, ok := ef.observed[hash]; !ok {
-		size := t.Desc.Tx.GetTxActualSize()
-		ef.observed[hash] = &observedTransaction{
-			hash:     hash,
-			feeRate:  NewCoinPerKilobyte(uint64(t.Desc.Fee), size),
-			observed: t.Desc.Height,
-			mined:    UnminedHeight,
+	size := t.Desc.Tx.GetTxActualSize()
+	if size == 0 {
+		size = 1
+	}
+	fees := map[FeeModel]uint64{FeeModelOf(t.Desc.Tx): t.Desc.Fee}
+	if tokenFee := transaction.TokenFee(t.Desc.Tx); tokenFee > 0 {
+		fees[FeeModelPrivacyTokenFee] = tokenFee
+	}
+	for model, fee := range fees {
+		key := observedKey{hash: hash, model: model}
+		if _, ok := ef.observed[key]; !ok {
+			ef.observed[key] = &observedTransaction{
+				hash:     hash,
+				feeRate:  NewCoinPerKilobyte(fee, size),
+				model:    model,
+				observed: t.Desc.Height,
+				mined:    UnminedHeight,
+			}
 		}
 	}
 }
@@ -194,12 +322,22 @@ func (ef *FeeEstimator) RegisterBlock(block *blockchain.ShardBlock) error {
 	defer ef.mtx.Unlock()
 
 	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
+	ef.cached = [numFeeModels][]CoinPerKilobyte{}
 
 	height := block.Header.Height
 	if height != ef.lastKnownHeight+1 && ef.lastKnownHeight != UnminedHeight {
-		return fmt.Errorf("intermediate block not recorded; current height is %d; new height is %d",
+		// Blocks were missed while the node was down, or a block was
+		// replaced without rollback. Waiting times of unmined txs are
+		// unknown, so they are forgotten, and blocks registered before
+		// can't be rolled back any more. Mined txs in bins are still valid.
+		Logger.log.Warnf("Estimate fee: intermediate block not recorded; current height is %d; new height is %d",
 			ef.lastKnownHeight, height)
+		for key, o := range ef.observed {
+			if o.mined == UnminedHeight {
+				delete(ef.observed, key)
+			}
+		}
+		ef.dropped = ef.dropped[:0]
 	}
 
 	// Update the last known height.
@@ -207,19 +345,14 @@ func (ef *FeeEstimator) RegisterBlock(block *blockchain.ShardBlock) error {
 	ef.numBlocksRegistered++
 
 	// Randomly order txs in block.
-	transactions := make(map[*transaction.Tx]struct{})
+	transactions := make(map[common.Hash]struct{})
 	for _, t := range block.Body.Transactions {
-		switch t.GetType() {
-		case common.TxNormalType, common.TxRewardType, common.TxReturnStakingType:
-			{
-				transactions[t.(*transaction.Tx)] = struct{}{}
-			}
-		}
+		transactions[*t.Hash()] = struct{}{}
 	}
 
 	// Count the number of replacements we make per bin so that we don't
 	// replace too many.
-	var replacementCounts [estimateFeeDepth]int
+	var replacementCounts [numFeeModels][estimateFeeDepth]int
 
 	// Keep track of which txs were dropped in case of an orphan block.
 	dropped := &registeredBlock{
@@ -227,15 +360,18 @@ func (ef *FeeEstimator) RegisterBlock(block *blockchain.ShardBlock) error {
 		transactions: make([]*observedTransaction, 0, 100),
 	}
 
-	// Go through the txs in the block.
-	for t := range transactions {
-		hash := *t.Hash()
-
+	// Go through the observations of txs in the block.
+	observations := []*observedTransaction{}
+	for hash := range transactions {
 		// Have we observed this tx in the mempool?
-		o, ok := ef.observed[hash]
-		if !ok {
-			continue
+		for _, model := range FeeModels() {
+			if o, ok := ef.observed[observedKey{hash: hash, model: model}]; ok {
+				observations = append(observations, o)
+			}
 		}
+	}
+	for _, o := range observations {
+		hash := o.hash
 
 		// Put the observed tx in the oppropriate bin.
 		blocksToConfirm := height - o.observed - 1
@@ -254,20 +390,20 @@ func (ef *FeeEstimator) RegisterBlock(block *blockchain.ShardBlock) error {
 		}
 
 		// Make sure we do not replace too many transactions per min.
-		if replacementCounts[blocksToConfirm] == int(ef.maxReplacements) {
+		if replacementCounts[o.model][blocksToConfirm] == int(ef.maxReplacements) {
 			continue
 		}
 
 		o.mined = height
 
-		replacementCounts[blocksToConfirm]++
+		replacementCounts[o.model][blocksToConfirm]++
 
-		bin := ef.bin[blocksToConfirm]
+		bin := ef.bin[o.model][blocksToConfirm]
 
 		// Remove a random element and replace it with this new tx.
 		if len(bin) == int(ef.binSize) {
 			// Don't drop transactions we have just added from this same block.
-			l := int(ef.binSize) - replacementCounts[blocksToConfirm]
+			l := int(ef.binSize) - replacementCounts[o.model][blocksToConfirm]
 			drop := rand.Intn(l)
 			dropped.transactions = append(dropped.transactions, bin[drop])
 
@@ -276,13 +412,13 @@ func (ef *FeeEstimator) RegisterBlock(block *blockchain.ShardBlock) error {
 		} else {
 			bin = append(bin, o)
 		}
-		ef.bin[blocksToConfirm] = bin
+		ef.bin[o.model][blocksToConfirm] = bin
 	}
 
 	// Go through the mempool for txs that have been in too long.
-	for hash, o := range ef.observed {
+	for key, o := range ef.observed {
 		if o.mined == UnminedHeight && height-o.observed >= estimateFeeDepth {
-			delete(ef.observed, hash)
+			delete(ef.observed, key)
 		}
 	}
 
@@ -344,7 +480,7 @@ func (ef *FeeEstimator) Rollback(hash *common.Hash) error {
 // of registered blocks.
 func (ef *FeeEstimator) rollback() {
 	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
+	ef.cached = [numFeeModels][]CoinPerKilobyte{}
 
 	// pop the last list of dropped txs from the stack.
 	last := len(ef.dropped) - 1
@@ -358,16 +494,16 @@ func (ef *FeeEstimator) rollback() {
 	dropped := ef.dropped[last]
 
 	// where we are in each bin as we replace txs?
-	var replacementCounters [estimateFeeDepth]int
+	var replacementCounters [numFeeModels][estimateFeeDepth]int
 
 	// Go through the txs in the dropped block.
 	for _, o := range dropped.transactions {
 		// Which bin was this tx in?
 		blocksToConfirm := o.mined - o.observed - 1
 
-		bin := ef.bin[blocksToConfirm]
+		bin := ef.bin[o.model][blocksToConfirm]
 
-		var counter = replacementCounters[blocksToConfirm]
+		var counter = replacementCounters[o.model][blocksToConfirm]
 
 		// Continue to go through that bin where we left off.
 		for {
@@ -390,32 +526,35 @@ func (ef *FeeEstimator) rollback() {
 			counter++
 		}
 
-		replacementCounters[blocksToConfirm] = counter
+		replacementCounters[o.model][blocksToConfirm] = counter
 	}
 
 	// Continue going through bins to find other txs to remove
 	// which did not replace any other when they were entered.
-	for i, j := range replacementCounters {
-		for {
-			l := len(ef.bin[i])
-			if j >= l {
-				break
-			}
+	for model := range replacementCounters {
+		bins := &ef.bin[model]
+		for i, j := range replacementCounters[model] {
+			for {
+				l := len(bins[i])
+				if j >= l {
+					break
+				}
 
-			prev := ef.bin[i][j]
+				prev := bins[i][j]
 
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = UnminedHeight
+				if prev.mined == ef.lastKnownHeight {
+					prev.mined = UnminedHeight
 
-				newBin := append(ef.bin[i][0:j], ef.bin[i][j+1:l]...)
-				// leak but it causes a panic when it is uncommented.
-				// ef.bin[i][j] = nil
-				ef.bin[i] = newBin
+					newBin := append(bins[i][0:j], bins[i][j+1:l]...)
+					// leak but it causes a panic when it is uncommented.
+					// bins[i][j] = nil
+					bins[i] = newBin
 
-				continue
-			}
+					continue
+				}
 
-			j++
+				j++
+			}
 		}
 	}
 
@@ -479,12 +618,12 @@ func (b *estimateFeeSet) estimateFee(confirmations int) CoinPerKilobyte {
 }
 
 // newEstimateFeeSet creates a temporary data structure that
-// can be used to find all fee estimates.
-func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
+// can be used to find all fee estimates of a fee model.
+func (ef *FeeEstimator) newEstimateFeeSet(model FeeModel) *estimateFeeSet {
 	set := &estimateFeeSet{}
 
 	capacity := 0
-	for i, b := range ef.bin {
+	for i, b := range ef.bin[model] {
 		l := len(b)
 		set.bin[i] = uint32(l)
 		capacity += l
@@ -493,7 +632,7 @@ func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
 	set.feeRate = make([]CoinPerKilobyte, capacity)
 
 	i := 0
-	for _, b := range ef.bin {
+	for _, b := range ef.bin[model] {
 		for _, o := range b {
 			set.feeRate[i] = o.feeRate
 			i++
@@ -505,10 +644,10 @@ func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
 	return set
 }
 
-// estimates returns the set of all fee estimates from 1 to estimateFeeDepth
-// confirmations from now.
-func (ef *FeeEstimator) estimates() []CoinPerKilobyte {
-	set := ef.newEstimateFeeSet()
+// estimates returns the set of all fee estimates of a fee model from 1 to
+// estimateFeeDepth confirmations from now.
+func (ef *FeeEstimator) estimates(model FeeModel) []CoinPerKilobyte {
+	set := ef.newEstimateFeeSet(model)
 
 	estimates := make([]CoinPerKilobyte, estimateFeeDepth)
 	for i := 0; i < estimateFeeDepth; i++ {
@@ -518,34 +657,115 @@ func (ef *FeeEstimator) estimates() []CoinPerKilobyte {
 	return estimates
 }
 
-// EstimateFee estimates the fee per byte to have a tx confirmed a given
-// number of blocks from now.
-func (ef *FeeEstimator) EstimateFee(numBlocks uint64) (CoinPerKilobyte, error) {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
-
+// checkEstimate returns an error if a fee of model can't be estimated for a
+// tx to be confirmed numBlocks from now.
+func (ef *FeeEstimator) checkEstimate(model FeeModel, numBlocks uint64) error {
 	// If the number of registered blocks is below the minimum, return
 	// an error.
 	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
-		return 0, errors.New("not enough blocks have been observed")
+		return errors.New("not enough blocks have been observed")
+	}
+
+	if model >= numFeeModels {
+		return fmt.Errorf("unknown fee model %d", model)
 	}
 
 	if numBlocks == 0 {
-		return 0, errors.New("cannot confirm transaction in zero blocks")
+		return errors.New("cannot confirm transaction in zero blocks")
 	}
 
 	if numBlocks > estimateFeeDepth {
-		return 0, fmt.Errorf(
+		return fmt.Errorf(
 			"can only estimate fees for up to %d blocks from now",
-			estimateFeeBinSize)
+			estimateFeeDepth)
+	}
+	return nil
+}
+
+// EstimateFee estimates the fee per kilobyte to have a tx of a fee model
+// confirmed a given number of blocks from now.
+func (ef *FeeEstimator) EstimateFee(model FeeModel, numBlocks uint64) (CoinPerKilobyte, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	if err := ef.checkEstimate(model, numBlocks); err != nil {
+		return 0, err
 	}
 
 	// If there are no cached results, generate them.
-	if ef.cached == nil {
-		ef.cached = ef.estimates()
+	if ef.cached[model] == nil {
+		ef.cached[model] = ef.estimates(model)
 	}
 
-	result := ef.cached[int(numBlocks)-1]
+	result := ef.cached[model][int(numBlocks)-1]
+	return result, nil
+}
+
+// confirmedFeeRate is the fee rate of a mined tx and whether it was mined
+// in the target number of blocks.
+type confirmedFeeRate struct {
+	feeRate   CoinPerKilobyte
+	confirmed bool
+}
+
+// EstimateFeeWithConfidence estimates the lowest fee per kilobyte such that
+// at least a confidence share of mined txs of a fee model which paid it or
+// more were confirmed a given number of blocks after they were observed.
+// Txs are taken from the highest fee rate, it gives up at the first fee rate
+// below confidence once estimateFeeMinSamples txs are taken.
+func (ef *FeeEstimator) EstimateFeeWithConfidence(model FeeModel, numBlocks uint64, confidence float64) (CoinPerKilobyte, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	if err := ef.checkEstimate(model, numBlocks); err != nil {
+		return 0, err
+	}
+
+	if confidence <= 0 || confidence > 1 {
+		return 0, fmt.Errorf("confidence %v is not in (0, 1]", confidence)
+	}
+
+	rates := []confirmedFeeRate{}
+	for blocksToConfirm, bin := range ef.bin[model] {
+		for _, o := range bin {
+			rates = append(rates, confirmedFeeRate{
+				feeRate:   o.feeRate,
+				confirmed: uint64(blocksToConfirm) < numBlocks,
+			})
+		}
+	}
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].feeRate > rates[j].feeRate
+	})
+
+	var result CoinPerKilobyte
+	found := false
+	total, confirmed := 0, 0
+	for i, rate := range rates {
+		total++
+		if rate.confirmed {
+			confirmed++
+		}
+
+		// Txs with the same fee rate are taken together.
+		if i+1 < len(rates) && rates[i+1].feeRate == rate.feeRate {
+			continue
+		}
+
+		if float64(confirmed) < confidence*float64(total) {
+			if total >= estimateFeeMinSamples {
+				break
+			}
+			continue
+		}
+		result = rate.feeRate
+		found = true
+	}
+
+	if !found {
+		return 0, fmt.Errorf("not enough %s transactions were confirmed in %d blocks to estimate fee with confidence %v",
+			model, numBlocks, confidence)
+	}
 	return result, nil
 }
 
@@ -553,7 +773,7 @@ func (ef *FeeEstimator) EstimateFee(numBlocks uint64) (CoinPerKilobyte, error) {
 // we use a version number. If the version number changes, it does not make
 // sense to try to upgrade a previous version to a new version. Instead, just
 // start fee estimation over.
-const estimateFeeSaveVersion = 1
+const estimateFeeSaveVersion = 3
 
 func deserializeRegisteredBlock(r io.Reader, txs map[uint32]*observedTransaction) (*registeredBlock, error) {
 	var lenTransactions uint32
@@ -578,14 +798,17 @@ func deserializeRegisteredBlock(r io.Reader, txs map[uint32]*observedTransaction
 type FeeEstimatorState []byte
 
 // observedTxSet is a set of txs that can that is sorted
-// by hash. It exists for serialization purposes so that
+// by hash and fee model. It exists for serialization purposes so that
 // a serialized state always comes out the same.
 type observedTxSet []*observedTransaction
 
 func (q observedTxSet) Len() int { return len(q) }
 
 func (q observedTxSet) Less(i, j int) bool {
-	return strings.Compare(q[i].hash.String(), q[j].hash.String()) < 0
+	if cmp := strings.Compare(q[i].hash.String(), q[j].hash.String()); cmp != 0 {
+		return cmp < 0
+	}
+	return q[i].model < q[j].model
 }
 
 func (q observedTxSet) Swap(i, j int) {
@@ -613,8 +836,8 @@ func (ef *FeeEstimator) Save() FeeEstimatorState {
 	// Put all the observed transactions in a sorted list.
 	var txCount uint32
 	ots := make([]*observedTransaction, len(ef.observed))
-	for hash := range ef.observed {
-		ots[txCount] = ef.observed[hash]
+	for key := range ef.observed {
+		ots[txCount] = ef.observed[key]
 		txCount++
 	}
 
@@ -629,13 +852,15 @@ func (ef *FeeEstimator) Save() FeeEstimatorState {
 		txCount++
 	}
 
-	// Save all the right bins.
-	for _, list := range ef.bin {
+	// Save all the right bins of every fee model.
+	for _, bins := range ef.bin {
+		for _, list := range bins {
 
-		binary.Write(w, binary.BigEndian, uint32(len(list)))
+			binary.Write(w, binary.BigEndian, uint32(len(list)))
 
-		for _, o := range list {
-			binary.Write(w, binary.BigEndian, observed[o])
+			for _, o := range list {
+				binary.Write(w, binary.BigEndian, observed[o])
+			}
 		}
 	}
 
@@ -665,7 +890,7 @@ func RestoreFeeEstimator(data FeeEstimatorState) (*FeeEstimator, error) {
 	}
 
 	ef := &FeeEstimator{
-		observed: make(map[common.Hash]*observedTransaction),
+		observed: make(map[observedKey]*observedTransaction),
 	}
 
 	// Read basic parameters.
@@ -686,25 +911,30 @@ func RestoreFeeEstimator(data FeeEstimatorState) (*FeeEstimator, error) {
 			return nil, err
 		}
 		observed[i] = ot
-		ef.observed[ot.hash] = ot
-	}
-
-	// Read bins.
-	for i := 0; i < estimateFeeDepth; i++ {
-		var numTransactions uint32
-		binary.Read(r, binary.BigEndian, &numTransactions)
-		bin := make([]*observedTransaction, numTransactions)
-		for j := uint32(0); j < numTransactions; j++ {
-			var index uint32
-			binary.Read(r, binary.BigEndian, &index)
-
-			var exists bool
-			bin[j], exists = observed[index]
-			if !exists {
-				return nil, fmt.Errorf("Invalid transaction reference %d", index)
+		ef.observed[observedKey{hash: ot.hash, model: ot.model}] = ot
+	}
+
+	// Read bins of every fee model.
+	for model := 0; model < int(numFeeModels); model++ {
+		for i := 0; i < estimateFeeDepth; i++ {
+			var numTransactions uint32
+			binary.Read(r, binary.BigEndian, &numTransactions)
+			bin := make([]*observedTransaction, numTransactions)
+			for j := uint32(0); j < numTransactions; j++ {
+				var index uint32
+				binary.Read(r, binary.BigEndian, &index)
+
+				var exists bool
+				bin[j], exists = observed[index]
+				if !exists {
+					return nil, fmt.Errorf("Invalid transaction reference %d", index)
+				}
+				if bin[j].model != FeeModel(model) {
+					return nil, fmt.Errorf("Transaction reference %d of fee model %d in bin of fee model %d", index, bin[j].model, model)
+				}
 			}
+			ef.bin[model][i] = bin
 		}
-		ef.bin[i] = bin
 	}
 
 	// Read dropped transactions.
@@ -0,0 +1,68 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+	"github.com/constant-money/constant-chain/transaction"
+)
+
+func newTestShardBlock(height uint64, txs ...metadata.Transaction) *blockchain.ShardBlock {
+	block := &blockchain.ShardBlock{}
+	block.Header.Height = height
+	block.Body.Transactions = txs
+	return block
+}
+
+// TestObserveTokenFee estimates fee which privacy token txs pay in their
+// token apart from their constant fee
+func TestObserveTokenFee(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, 0)
+	if err := ef.RegisterBlock(newTestShardBlock(1)); err != nil {
+		t.Fatalf("ef.RegisterBlock returns %+v", err)
+	}
+	tx := &transaction.TxCustomTokenPrivacy{}
+	tx.Type = common.TxCustomTokenPrivacyType
+	tx.Fee = 100
+	tx.TxTokenPrivacyData.TxNormal.Fee = 5000
+	ef.ObserveTransaction(&TxDesc{Desc: metadata.TxDesc{Tx: tx, Fee: tx.Fee, Height: 1}})
+	if len(ef.observed) != 2 {
+		t.Fatalf("token tx is observed %d times instead of 2", len(ef.observed))
+	}
+
+	block := newTestShardBlock(2, tx)
+	if err := ef.RegisterBlock(block); err != nil {
+		t.Fatalf("ef.RegisterBlock returns %+v", err)
+	}
+	size := tx.GetTxActualSize()
+	expected := map[FeeModel]CoinPerKilobyte{
+		FeeModelCustomTokenPrivacy: NewCoinPerKilobyte(100, size),
+		FeeModelPrivacyTokenFee:    NewCoinPerKilobyte(5000, size),
+	}
+	for model, feeRate := range expected {
+		if estimate, err := ef.EstimateFee(model, 1); err != nil || estimate != feeRate {
+			t.Fatalf("ef.EstimateFee of %s returns %d, %+v instead of %d", model, estimate, err, feeRate)
+		}
+	}
+
+	restored, err := RestoreFeeEstimator(ef.Save())
+	if err != nil {
+		t.Fatalf("RestoreFeeEstimator returns %+v", err)
+	}
+	for model, feeRate := range expected {
+		if estimate, err := restored.EstimateFee(model, 1); err != nil || estimate != feeRate {
+			t.Fatalf("restored estimate of %s is %d, %+v instead of %d", model, estimate, err, feeRate)
+		}
+	}
+
+	if err := ef.Rollback(block.Hash()); err != nil {
+		t.Fatalf("ef.Rollback returns %+v", err)
+	}
+	for model := range expected {
+		if len(ef.bin[model][0]) != 0 {
+			t.Fatalf("bin of %s has %d txs after rollback", model, len(ef.bin[model][0]))
+		}
+	}
+}
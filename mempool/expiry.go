@@ -0,0 +1,94 @@
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+)
+
+/*
+A tx with an expiry height may only be in a block of its shard up to that
+height, 0 is no expiry. Pool rejects a tx which can't be in the next block of
+its shard and removes txs which expire when a block of their shard is
+inserted, so input coins of a stuck tx are free again at a known height.
+Block validation rejects a block with an expired tx. Expiry height is active
+from a height of chain params, a tx with expiry height is rejected before it
+*/
+
+// isExpired returns true if tx can't be in a block at height by its expiry
+// height
+func isExpired(tx metadata.Transaction, height uint64) bool {
+	expiryHeight := tx.GetExpiryHeight()
+	return expiryHeight > 0 && expiryHeight < height
+}
+
+// checkExpiry returns an error if tx can't be in the next block of shard
+func (tp *TxPool) checkExpiry(tx metadata.Transaction, shardID byte) error {
+	bestState, ok := tp.config.BlockChain.BestState.Shard[shardID]
+	if !ok || bestState.BestBlock == nil {
+		return nil
+	}
+	nextHeight := bestState.BestBlock.Header.Height + 1
+	if tx.GetExpiryHeight() > 0 && tp.config.ChainParams != nil && !tp.config.ChainParams.IsExpiryHeightActive(nextHeight) {
+		err := MempoolTxError{}
+		err.Init(RejectInvalidTx, fmt.Errorf("transaction %+v has expiry height which is active from height %d, next block height is %d", tx.Hash().String(), tp.config.ChainParams.ExpiryHeightActivation, nextHeight))
+		return err
+	}
+	if isExpired(tx, nextHeight) {
+		err := MempoolTxError{}
+		err.Init(RejectExpiredTx, fmt.Errorf("transaction %+v expired at height %d, next block height is %d", tx.Hash().String(), tx.GetExpiryHeight(), nextHeight))
+		return err
+	}
+	return nil
+}
+
+// RemoveExpiredTxs removes txs of shard which can't be in a block after block
+// at height by their expiry height, and their descendants
+func (tp *TxPool) RemoveExpiredTxs(shardID byte, height uint64) {
+	if int(shardID) >= len(tp.partitions) {
+		return
+	}
+	partition := tp.partitions[shardID]
+	partition.mtx.Lock()
+	defer partition.mtx.Unlock()
+	for _, txDesc := range partition.expiredAt(height + 1) {
+		tx := txDesc.Desc.Tx
+		// it may be removed as descendant of another expired tx
+		if !partition.isTxInPool(tx.Hash()) {
+			continue
+		}
+		err := MempoolTxError{}
+		err.Init(RejectExpiredTx, fmt.Errorf("transaction %+v expired at height %d", tx.Hash().String(), tx.GetExpiryHeight()))
+		tp.addRecentReject(tx, err)
+		tp.removeFromPool(txDesc, fmt.Sprintf("expired at height %d", tx.GetExpiryHeight()))
+		Logger.log.Infof("Remove transaction %+v which expired at height %d from pool", tx.Hash().String(), tx.GetExpiryHeight())
+	}
+}
+
+/*
+CancelTx removes tx of txHash and its descendants from pool and mempool
+database on request of node operator, so their input coins can be spent by
+other txs. It returns hashes of removed txs
+*/
+func (tp *TxPool) CancelTx(txHash common.Hash) ([]common.Hash, error) {
+	for _, partition := range tp.partitions {
+		partition.mtx.Lock()
+		txDesc, ok := partition.pool[txHash]
+		if !ok {
+			partition.mtx.Unlock()
+			continue
+		}
+		removed := []common.Hash{txHash}
+		for _, descendant := range partition.descendants(txDesc) {
+			removed = append(removed, *descendant.Desc.Tx.Hash())
+		}
+		err := MempoolTxError{}
+		err.Init(RejectCanceledTx, fmt.Errorf("transaction %+v is removed from pool by node operator", txHash.String()))
+		tp.addRecentReject(txDesc.Desc.Tx, err)
+		tp.removeFromPool(txDesc, "canceled by node operator")
+		partition.mtx.Unlock()
+		return removed, nil
+	}
+	return nil, fmt.Errorf("transaction %+v is not in pool", txHash.String())
+}
@@ -0,0 +1,62 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/constant-money/constant-chain/blockchain"
+)
+
+func TestRemoveExpiredTxs(t *testing.T) {
+	tp := newTestPool(t, Config{AcceptChildTxs: true})
+	expiring := newTestTx("expiring", 10, 1, []int64{1}, []int64{2})
+	expiring.ExpiryHeight = 10
+	child := newTestTx("child", 10, 1, []int64{2}, nil)
+	later := newTestTx("later", 10, 1, []int64{3}, nil)
+	later.ExpiryHeight = 11
+	noExpiry := newTestTx("noexpiry", 10, 1, []int64{4}, nil)
+	for _, tx := range []*testTx{expiring, child, later, noExpiry} {
+		addTestTx(tp, tx)
+	}
+
+	// next block at height 10 may still have expiring
+	tp.RemoveExpiredTxs(0, 9)
+	if tp.Count() != 4 {
+		t.Fatalf("pool has %d txs instead of 4", tp.Count())
+	}
+	tp.RemoveExpiredTxs(0, 10)
+	for _, tx := range []*testTx{expiring, child} {
+		if tp.HaveTransaction(&tx.hash) {
+			t.Fatalf("expired tx and its child should be removed")
+		}
+	}
+	for _, tx := range []*testTx{later, noExpiry} {
+		if !tp.HaveTransaction(&tx.hash) {
+			t.Fatalf("tx which isn't expired should stay in pool")
+		}
+	}
+}
+
+func TestCheckExpiry(t *testing.T) {
+	params := blockchain.ChainTestParam
+	params.ExpiryHeightActivation = 10
+	block := &blockchain.ShardBlock{}
+	block.Header.Height = 8
+	chain := &blockchain.BlockChain{BestState: &blockchain.BestState{
+		Shard: map[byte]*blockchain.BestStateShard{0: {BestBlock: block}},
+	}}
+	tp := newTestPool(t, Config{BlockChain: chain, ChainParams: &params})
+	tx := newTestTx("tx", 10, 1, []int64{1}, nil)
+	tx.ExpiryHeight = 20
+	// next block at height 9 is below activation
+	if err := tp.checkExpiry(tx, 0); err == nil {
+		t.Fatalf("tx with expiry height before its activation should be rejected")
+	}
+	block.Header.Height = 9
+	if err := tp.checkExpiry(tx, 0); err != nil {
+		t.Fatalf("tp.checkExpiry returns %+v", err)
+	}
+	block.Header.Height = 20
+	if err := tp.checkExpiry(tx, 0); err == nil {
+		t.Fatalf("tx which can't be in next block should be rejected")
+	}
+}
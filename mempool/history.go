@@ -0,0 +1,247 @@
+package mempool
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+/*
+Pool keeps a bounded lifecycle history of txs by tx hash: when a tx is
+received and from which peer, validated or rejected, forwarded to peers,
+included in a block or removed from pool. The oldest history is dropped when
+Config.MaxTxHistory txs have history, and the oldest events of a tx are
+dropped when it has more than maxTxEvents events. With Config.TxHistoryFile
+every event is also appended to the file as a JSON line, for debugging
+propagation of txs. Events are written by a goroutine, so pool doesn't wait
+for the file: events are dropped while historyFileQueue events wait. A full
+file is renamed with suffix .1, which replaces the previous one, so history
+takes up to twice Config.TxHistoryFileSize on disk
+*/
+
+const (
+	// maxTxEvents is the max number of events which history of a tx keeps
+	maxTxEvents = 32
+	// historyFileQueue is the max number of events which wait to be written
+	// to tx history file
+	historyFileQueue = 1000
+	// defaultTxHistoryFileSize is the size of tx history file when
+	// Config.TxHistoryFileSize isn't set
+	defaultTxHistoryFileSize = 100 * 1024 * 1024
+)
+
+// TxEventType is a step of lifecycle of a tx
+type TxEventType string
+
+const (
+	TxEventReceived  TxEventType = "received"  // tx is received from a peer
+	TxEventValidated TxEventType = "validated" // tx is verified and added to pool
+	TxEventRejected  TxEventType = "rejected"  // tx isn't added to pool
+	TxEventForwarded TxEventType = "forwarded" // tx is pushed to peers
+	TxEventIncluded  TxEventType = "included"  // tx is in a block
+	TxEventEvicted   TxEventType = "evicted"   // tx lived in pool longer than tx life time
+	TxEventRemoved   TxEventType = "removed"   // tx is removed from pool for another reason
+)
+
+// TxEvent is a step of lifecycle of a tx
+type TxEvent struct {
+	TxHash string      `json:"TxHash"`
+	Type   TxEventType `json:"Type"`
+	Time   time.Time   `json:"Time"`
+	PeerID string      `json:"PeerID,omitempty"` // peer which tx is received from
+	Height uint64      `json:"Height,omitempty"` // height of block which tx is included in
+	Detail string      `json:"Detail,omitempty"` // reason of reject or removal
+}
+
+// txHistory is lifecycle history of txs of pool
+type txHistory struct {
+	mtx     sync.Mutex
+	events  *lru.Cache   // events of txs by tx hash
+	queue   chan TxEvent // events which wait to be written to file, nil is no file
+	dropped int          // number of events which aren't written since the last written one
+	file    *historyFile
+	done    chan struct{} // closed when writer of file exits
+}
+
+// historyFile is tx history file which is rotated when it reaches maxSize,
+// it is only used by writer goroutine of history
+type historyFile struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+func (tp *TxPool) initTxHistory() {
+	if tp.config.MaxTxHistory <= 0 {
+		return
+	}
+	history := &txHistory{}
+	history.events, _ = lru.New(tp.config.MaxTxHistory)
+	if tp.config.TxHistoryFile != common.EmptyString {
+		maxSize := tp.config.TxHistoryFileSize
+		if maxSize <= 0 {
+			maxSize = defaultTxHistoryFileSize
+		}
+		file := &historyFile{path: tp.config.TxHistoryFile, maxSize: maxSize}
+		if err := file.open(); err != nil {
+			Logger.log.Errorf("Fail to open tx history file %+v, error: %+v", tp.config.TxHistoryFile, err)
+		} else {
+			history.file = file
+			history.queue = make(chan TxEvent, historyFileQueue)
+			history.done = make(chan struct{})
+			go history.writeFile(history.queue)
+		}
+	}
+	tp.txHistory = history
+}
+
+// open opens file for appending
+func (f *historyFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// write appends line to file, file is rotated first if line doesn't fit in
+// it
+func (f *historyFile) write(line []byte) error {
+	if f.size > 0 && f.size+int64(len(line)) > f.maxSize {
+		f.file.Close()
+		renameErr := os.Rename(f.path, f.path+".1")
+		if err := f.open(); err != nil {
+			return err
+		}
+		if renameErr != nil {
+			return renameErr
+		}
+	}
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+// writeFile writes events of queue to file until queue is closed
+func (history *txHistory) writeFile(queue <-chan TxEvent) {
+	defer close(history.done)
+	defer func() {
+		history.file.file.Close()
+	}()
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	for event := range queue {
+		history.mtx.Lock()
+		dropped := history.dropped
+		history.dropped = 0
+		history.mtx.Unlock()
+		if dropped > 0 {
+			Logger.log.Warnf("%d events are not written to tx history file while it is busy", dropped)
+		}
+		line.Reset()
+		if err := encoder.Encode(event); err != nil {
+			Logger.log.Errorf("Fail to encode event of tx %+v for tx history file, error: %+v", event.TxHash, err)
+			continue
+		}
+		if err := history.file.write(line.Bytes()); err != nil {
+			Logger.log.Errorf("Fail to write event of tx %+v to tx history file, error: %+v", event.TxHash, err)
+		}
+	}
+}
+
+// RecordTxEvent adds event to history of tx of txHash, time of event is set
+// if it is missing
+func (tp *TxPool) RecordTxEvent(txHash common.Hash, event TxEvent) {
+	history := tp.txHistory
+	if history == nil {
+		return
+	}
+	event.TxHash = txHash.String()
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	history.mtx.Lock()
+	defer history.mtx.Unlock()
+	events := []TxEvent{}
+	if value, ok := history.events.Get(txHash); ok {
+		events = value.([]TxEvent)
+	}
+	if len(events) >= maxTxEvents {
+		events = events[len(events)-maxTxEvents+1:]
+	}
+	// a new slice, so history which is returned isn't changed
+	events = append(events[:len(events):len(events)], event)
+	history.events.Add(txHash, events)
+	if history.queue != nil {
+		select {
+		case history.queue <- event:
+		default:
+			history.dropped++
+		}
+	}
+}
+
+// recordTxRejected adds reject of tx by err to its history
+func (tp *TxPool) recordTxRejected(tx metadata.Transaction, err error) {
+	tp.RecordTxEvent(*tx.Hash(), TxEvent{Type: TxEventRejected, Detail: err.Error()})
+}
+
+// RecordTxsInBlock adds inclusion in block at height to history of txs of
+// the block
+func (tp *TxPool) RecordTxsInBlock(txs []metadata.Transaction, height uint64) {
+	if tp.txHistory == nil {
+		return
+	}
+	now := time.Now()
+	for _, tx := range txs {
+		tp.RecordTxEvent(*tx.Hash(), TxEvent{Type: TxEventIncluded, Time: now, Height: height})
+	}
+}
+
+// GetTxHistory returns events of tx of txHash from the oldest, if pool still
+// has its history
+func (tp *TxPool) GetTxHistory(txHash common.Hash) ([]TxEvent, bool) {
+	history := tp.txHistory
+	if history == nil {
+		return nil, false
+	}
+	history.mtx.Lock()
+	defer history.mtx.Unlock()
+	value, ok := history.events.Get(txHash)
+	if !ok {
+		return nil, false
+	}
+	return value.([]TxEvent), true
+}
+
+// CloseTxHistory closes tx history file after events which wait are written,
+// events are no longer written to it
+func (tp *TxPool) CloseTxHistory() error {
+	history := tp.txHistory
+	if history == nil {
+		return nil
+	}
+	history.mtx.Lock()
+	queue := history.queue
+	history.queue = nil
+	history.mtx.Unlock()
+	if queue == nil {
+		return nil
+	}
+	close(queue)
+	<-history.done
+	return nil
+}
@@ -0,0 +1,75 @@
+package mempool
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+)
+
+func TestTxHistoryBounds(t *testing.T) {
+	tp := newTestPool(t, Config{MaxTxHistory: 2})
+	for i := 0; i < maxTxEvents+5; i++ {
+		tp.RecordTxEvent(common.Hash{1}, TxEvent{Type: TxEventForwarded})
+	}
+	if events, ok := tp.GetTxHistory(common.Hash{1}); !ok || len(events) != maxTxEvents {
+		t.Fatalf("history of tx has %d events instead of %d", len(events), maxTxEvents)
+	}
+	tp.RecordTxEvent(common.Hash{2}, TxEvent{Type: TxEventReceived})
+	tp.RecordTxEvent(common.Hash{3}, TxEvent{Type: TxEventReceived})
+	if _, ok := tp.GetTxHistory(common.Hash{1}); ok {
+		t.Fatalf("the oldest history should be dropped")
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("could not open %s: %+v", path, err)
+	}
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+// TestTxHistoryFileRotation writes events to a small tx history file, which
+// is rotated so it never holds more than TxHistoryFileSize bytes
+func TestTxHistoryFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txhistory")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "txhistory.log")
+	tp := newTestPool(t, Config{MaxTxHistory: 10, TxHistoryFile: path, TxHistoryFileSize: 1000})
+	for i := 0; i < 50; i++ {
+		tp.RecordTxEvent(common.Hash{byte(i)}, TxEvent{Type: TxEventReceived})
+	}
+	if err := tp.CloseTxHistory(); err != nil {
+		t.Fatalf("tp.CloseTxHistory returns %+v", err)
+	}
+	// events after close aren't written
+	tp.RecordTxEvent(common.Hash{1}, TxEvent{Type: TxEventReceived})
+
+	for _, name := range []string{path, path + ".1"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("could not stat %s: %+v", name, err)
+		}
+		if info.Size() > 1000 {
+			t.Fatalf("%s has %d bytes, more than 1000", name, info.Size())
+		}
+	}
+	// every event takes more than 100 bytes, so the last file keeps up
+	// to 9 of them
+	if lines := countLines(t, path) + countLines(t, path+".1"); lines == 0 || lines > 18 {
+		t.Fatalf("tx history files have %d events", lines)
+	}
+}
@@ -3,6 +3,7 @@ package mempool
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,6 +19,7 @@ import (
 	"github.com/constant-money/constant-chain/database"
 	"github.com/constant-money/constant-chain/metadata"
 	"github.com/constant-money/constant-chain/transaction"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 // config is a descriptor containing the memory pool configuration.
@@ -28,9 +30,21 @@ type Config struct {
 	ChainParams       *blockchain.Params
 	FeeEstimator      map[byte]*FeeEstimator // FeeEstimatator provides a feeEstimator. If it is not nil, the mempool records all new transactions it observes into the feeEstimator.
 	TxLifeTime        uint                   // Transaction life time in pool
-	MaxTx             uint64                 //Max transaction pool may have
+	MaxTx             uint64                 //Max transaction pool may have for a shard
+	MaxSize           uint64                 // Max size in KB of transactions pool may have for a shard, 0 is no limit
+	ShardLimits       map[byte]PoolLimit     // Limit of pool for a shard which overrides MaxTx, MaxSize and TxLifeTime
 	IsLoadFromMempool bool                   //Reset mempool database when run node
+	SnapshotInterval  time.Duration          // Interval of snapshots of pool to mempool database, 0 is no snapshot
 	PersistMempool    bool
+	ReplaceByFee      bool   // a tx may replace txs in pool which spend its serial numbers by paying more fee
+	ReplaceFeePerKB   uint64 // Fee per KB which a tx pays on top of fees of all txs it replaces
+	AdmissionWorkers  int    // Number of txs which are verified at the same time, 0 is number of CPU
+	MaxPendingTxs     int    // Max number of txs which wait for admission, 0 is the default
+	MaxRecentRejects  int    // Max number of rejected txs whose reason is kept, 0 is the default
+	AcceptChildTxs    bool   // a tx without privacy may spend output coins of txs in pool
+	MaxTxHistory      int    // Max number of txs whose lifecycle history is kept, 0 is no history
+	TxHistoryFile     string // File which events of tx history are appended to, empty is no file
+	TxHistoryFileSize int64  // Size in bytes at which tx history file is rotated, 0 is the default
 	RelayShards       []byte
 	UserKeyset        *cashec.KeySet
 }
@@ -40,16 +54,18 @@ type TxDesc struct {
 	Desc            metadata.TxDesc // transaction details
 	StartTime       time.Time       //Unix Time that transaction enter mempool
 	IsFowardMessage bool
+	priorityIndex   int                     // index of transaction in fee priority index of pool
+	parents         map[common.Hash]*TxDesc // txs in pool whose output coins transaction spends
+	children        map[common.Hash]*TxDesc // txs in pool which spend output coins of transaction
+	packageFeePerKB uint64                  // fee per KB of transaction with its descendants in pool
 }
 
 // TxPool is transaction pool
 type TxPool struct {
 	// The following variables must only be used atomically.
 	lastUpdated       int64 // last time pool was updated
-	mtx               sync.RWMutex
 	config            Config
-	pool              map[common.Hash]*TxDesc
-	poolSerialNumbers map[common.Hash][][]byte
+	partitions        []*txPartition // txs of pool by sender shard
 	txCoinHashHPool   map[common.Hash][]common.Hash
 	coinHashHPool     map[common.Hash]bool
 	cMtx              sync.RWMutex
@@ -63,8 +79,10 @@ type TxPool struct {
 	RoleInCommittees  int                    //Current Role of Node
 	CRoleInCommittees chan int
 	roleMtx           sync.RWMutex
-	CPendingTxs       chan metadata.Transaction // channel to deliver txs to block gen
 	IsBlockGenStarted bool
+	admission         admissionQueue // bounds txs which are verified and wait to be verified
+	recentRejects     *lru.Cache     // reasons of recently rejected txs by tx hash
+	txHistory         *txHistory     // lifecycle history of txs, nil is no history
 }
 
 /*
@@ -73,8 +91,10 @@ Init Txpool from config
 func (tp *TxPool) Init(cfg *Config) {
 	tp.config = *cfg
 	tp.Scantime = 1 * time.Hour
-	tp.pool = make(map[common.Hash]*TxDesc)
-	tp.poolSerialNumbers = make(map[common.Hash][][]byte)
+	tp.partitions = make([]*txPartition, common.MAX_SHARD_NUMBER)
+	for shardID := range tp.partitions {
+		tp.partitions[shardID] = newTxPartition(byte(shardID), tp.limitOf(byte(shardID)))
+	}
 	tp.txCoinHashHPool = make(map[common.Hash][]common.Hash)
 	tp.coinHashHPool = make(map[common.Hash]bool)
 	tp.TokenIDPool = make(map[common.Hash]string)
@@ -83,11 +103,13 @@ func (tp *TxPool) Init(cfg *Config) {
 	tp.DuplicateTxs = make(map[common.Hash]uint64)
 	tp.RoleInCommittees = -1
 	tp.IsBlockGenStarted = false
+	tp.initAdmission()
+	tp.initRecentRejects()
+	tp.initTxHistory()
 }
-func (tp *TxPool) InitChannelMempool(cCacheTx chan common.Hash, cRoleInCommittees chan int, cPendingTxs chan metadata.Transaction) {
+func (tp *TxPool) InitChannelMempool(cCacheTx chan common.Hash, cRoleInCommittees chan int) {
 	tp.cCacheTx = cCacheTx
 	tp.CRoleInCommittees = cRoleInCommittees
-	tp.CPendingTxs = cPendingTxs
 }
 func (tp *TxPool) InitDatabaseMempool(db databasemp.DatabaseInterface) {
 	tp.config.DataBaseMempool = db
@@ -108,34 +130,79 @@ func (tp *TxPool) LoadOrResetDatabaseMP() {
 			Logger.log.Critical("Successfully Reset from database")
 		}
 	} else {
-		txDescs, err := tp.LoadDatabaseMP()
+		report, err := tp.LoadDatabaseMP()
 		if err != nil {
 			Logger.log.Errorf("Fail to load mempool database, error: %+v \n", err)
 		} else {
-			Logger.log.Criticalf("Successfully load %+v from database \n", len(txDescs))
+			Logger.log.Criticalf("Successfully load %+v from database, %+v rejected \n", report.Loaded, len(report.Rejected))
+			for txHash, reason := range report.Rejected {
+				Logger.log.Infof("Transaction %+v in mempool database is rejected: %+v", txHash.String(), reason)
+			}
 		}
 	}
 	//return []TxDesc{}
 }
 
+// limitOf returns limit of pool for txs of a shard
+func (tp *TxPool) limitOf(shardID byte) PoolLimit {
+	if limit, ok := tp.config.ShardLimits[shardID]; ok {
+		return limit
+	}
+	return PoolLimit{
+		MaxTx:      tp.config.MaxTx,
+		MaxSize:    tp.config.MaxSize,
+		TxLifeTime: tp.config.TxLifeTime,
+	}
+}
+
+// partitionOf returns partition of pool for sender shard of tx
+func (tp *TxPool) partitionOf(tx metadata.Transaction) *txPartition {
+	return tp.partitions[common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())]
+}
+
+// findTx returns tx desc in pool by hash, partition lock must not be held
+func (tp *TxPool) findTx(txHash common.Hash) (*TxDesc, bool) {
+	for _, p := range tp.partitions {
+		p.mtx.RLock()
+		txDesc, ok := p.pool[txHash]
+		p.mtx.RUnlock()
+		if ok {
+			return txDesc, true
+		}
+	}
+	return nil, false
+}
+
 // ----------- transaction.MempoolRetriever's implementation -----------------
 func (tp *TxPool) GetSerialNumbers() map[common.Hash][][]byte {
-	return tp.poolSerialNumbers
+	serialNumbers := make(map[common.Hash][][]byte)
+	for _, p := range tp.partitions {
+		p.mtx.RLock()
+		for hash, poolSerialNumbers := range p.poolSerialNumbers {
+			serialNumbers[hash] = poolSerialNumbers
+		}
+		p.mtx.RUnlock()
+	}
+	return serialNumbers
 }
 
 func (tp *TxPool) GetTxsInMem() map[common.Hash]metadata.TxDesc {
 	txsInMem := make(map[common.Hash]metadata.TxDesc)
-	for hash, txDesc := range tp.pool {
-		txsInMem[hash] = txDesc.Desc
+	for _, p := range tp.partitions {
+		p.mtx.RLock()
+		for hash, txDesc := range p.pool {
+			txsInMem[hash] = txDesc.Desc
+		}
+		p.mtx.RUnlock()
 	}
 	return txsInMem
 }
 
 // ----------- end of transaction.MempoolRetriever's implementation -----------------
 
-// check transaction in pool
-func (tp *TxPool) isTxInPool(hash *common.Hash) bool {
-	if _, exists := tp.pool[*hash]; exists {
+// check transaction in partition
+func (p *txPartition) isTxInPool(hash *common.Hash) bool {
+	if _, exists := p.pool[*hash]; exists {
 		return true
 	}
 	return false
@@ -156,10 +223,12 @@ func createTxDescMempool(tx metadata.Transaction, height uint64, fee uint64) *Tx
 
 /*
 // add transaction into pool
+// partition lock of transaction must be held
 */
 func (tp *TxPool) addTx(txD *TxDesc, isStore bool) {
 	tx := txD.Desc.Tx
 	txHash := tx.Hash()
+	txD.Desc.FeePerKB = transaction.FeePerKB(tx)
 
 	if isStore {
 		err := tp.AddTransactionToDatabaseMP(txHash, *txD)
@@ -176,18 +245,15 @@ func (tp *TxPool) addTx(txD *TxDesc, isStore bool) {
 		//	Logger.log.Criticalf("Success Get Transaction %+v from DBMP %+v \n", *txDesc.Desc.Tx.Hash(), txDesc)
 		//}
 	}
-	tp.pool[*tx.Hash()] = txD
+	tp.partitionOf(tx).add(txD)
 	//==================================================
-	tp.poolSerialNumbers[*tx.Hash()] = txD.Desc.Tx.ListNullifiers()
 	atomic.StoreInt64(&tp.lastUpdated, time.Now().Unix())
-	// Record this tx for fee estimation if enabled. only apply for normal tx
-	if tx.GetType() == common.TxNormalType {
-		if tp.config.FeeEstimator != nil {
-			shardID := common.GetShardIDFromLastByte(tx.(*transaction.Tx).PubKeyLastByteSender)
-			if temp, ok := tp.config.FeeEstimator[shardID]; ok {
-				temp.ObserveTransaction(txD)
-			}
-
+	// Record this tx for fee estimation if enabled, txs are estimated by
+	// their fee model
+	if tp.config.FeeEstimator != nil {
+		shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
+		if temp, ok := tp.config.FeeEstimator[shardID]; ok {
+			temp.ObserveTransaction(txD)
 		}
 	}
 	if txHash != nil {
@@ -227,11 +293,26 @@ func (tp *TxPool) addTx(txD *TxDesc, isStore bool) {
 10. Check Duplicate stake public key in pool ONLY with staking transaction
 
 Param#2: isStore: store transaction to persistence storage only work for transaction come from user (not for validation process)
+
+Transaction is validated with txs of its partition, partition lock of
+transaction must be held
 */
 
 func (tp *TxPool) ValidateTransaction(tx metadata.Transaction) error {
-	var shardID byte
-	var err error
+	err := tp.validateTxStateless(tx)
+	if err != nil {
+		return err
+	}
+	return tp.validateTxWithPool(tx)
+}
+
+/*
+validateTxStateless runs checks of tx which don't depend on pool or chain
+state: version, size, fee, type, sanity data and data in tx (privacy proof,
+metadata,...). They are the bulk of validation time and are run outside pool
+lock
+*/
+func (tp *TxPool) validateTxStateless(tx metadata.Transaction) error {
 	txHash := tx.Hash()
 	txType := tx.GetType()
 	if txType == common.TxNormalType {
@@ -241,14 +322,6 @@ func (tp *TxPool) ValidateTransaction(tx metadata.Transaction) error {
 			txType = common.TxNormalNoPrivacy
 		}
 	}
-	// Don't accept the transaction if it already exists in the pool.
-	if tp.isTxInPool(txHash) {
-		str := fmt.Sprintf("already have transaction %+v", txHash.String())
-		go common.AnalyzeTimeSeriesTxDuplicateTimesMetric(txType, float64(1))
-		err := MempoolTxError{}
-		err.Init(RejectDuplicateTx, errors.New(str))
-		return err
-	}
 
 	// check version
 	ok := tx.CheckTxVersion(MaxVersion)
@@ -287,11 +360,8 @@ func (tp *TxPool) ValidateTransaction(tx metadata.Transaction) error {
 
 	ok = tx.ValidateType()
 	if !ok {
-		return err
-	}
-	// check tx with all txs in current mempool
-	err = tx.ValidateTxWithCurrentMempool(tp)
-	if err != nil {
+		err := MempoolTxError{}
+		err.Init(RejectInvalidTx, fmt.Errorf("transaction %+v's type %+v is invalid", txHash.String(), tx.GetType()))
 		return err
 	}
 
@@ -303,9 +373,9 @@ func (tp *TxPool) ValidateTransaction(tx metadata.Transaction) error {
 	}
 
 	// ValidateTransaction tx by it self
-	shardID = common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
+	shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
 	startValidate := time.Now()
-	validated, errValidateTxByItself := tx.ValidateTxByItself(tx.IsPrivacy(), tp.config.BlockChain.GetDatabase(), tp.config.BlockChain, shardID)
+	validated, errValidateTxByItself := tx.ValidateTxByItself(tx.IsPrivacy(), tp.validationDB(tx), tp.config.BlockChain, shardID)
 	go common.AnalyzeTimeSeriesVTBITxTypeMetric(txType, float64(time.Since(startValidate).Seconds()))
 	if !validated {
 		err := MempoolTxError{}
@@ -316,47 +386,144 @@ func (tp *TxPool) ValidateTransaction(tx metadata.Transaction) error {
 		err.Init(RejectInvalidTx, errors.New(messageError))
 		return err
 	}
+	return nil
+}
+
+/*
+validateTxWithPool runs checks of tx with state of pool and chain: tx is not
+in pool, it doesn't double spend txs in pool (or replaces them by fee) or
+chain, it doesn't init a token or stake a public key which a tx in pool
+does. Partition lock of tx must be held, so the checks and adding tx to pool
+are atomic
+*/
+func (tp *TxPool) validateTxWithPool(tx metadata.Transaction) error {
+	txHash := tx.Hash()
+	txType := tx.GetType()
+	if txType == common.TxNormalType {
+		if tx.IsPrivacy() {
+			txType = common.TxNormalPrivacy
+		} else {
+			txType = common.TxNormalNoPrivacy
+		}
+	}
+	partition := tp.partitionOf(tx)
+	// Don't accept the transaction if it already exists in the pool.
+	if partition.isTxInPool(txHash) {
+		str := fmt.Sprintf("already have transaction %+v", txHash.String())
+		go common.AnalyzeTimeSeriesTxDuplicateTimesMetric(txType, float64(1))
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, errors.New(str))
+		return err
+	}
+
+	// check tx with all txs in current mempool, txs which tx replaces by fee
+	// are left out
+	replacedTxs, err := tp.replacedTxs(partition, tx)
+	if err != nil {
+		return err
+	}
+	err = tx.ValidateTxWithCurrentMempool(partition.retrieverWithout(replacedTxs))
+	if err != nil {
+		return rejectError(RejectDoubleSpendWithMempoolTx, err)
+	}
+	err = tp.checkPackage(partition, tx)
+	if err != nil {
+		return err
+	}
+
+	// tx which expires before the next block of its shard can't be in a block
+	shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
+	if err := tp.checkExpiry(tx, shardID); err != nil {
+		return err
+	}
 
 	// validate tx with data of blockchain
 	err = tx.ValidateTxWithBlockChain(tp.config.BlockChain, shardID, tp.config.BlockChain.GetDatabase())
 	if err != nil {
-		return err
+		return rejectError(RejectInvalidTxWithBlockchain, err)
 	}
 
+	// check duplicate token init and stake public key, they are checked
+	// again when tx is added, see claimPoolIndexes
+	tp.candidateMtx.RLock()
+	tp.tokenIDMtx.RLock()
+	defer tp.candidateMtx.RUnlock()
+	defer tp.tokenIDMtx.RUnlock()
+	return tp.checkPoolIndexes(tx, replacedTxs)
+}
+
+// poolIndexesOf returns token ID which a token init tx creates and public key
+// which a staking tx stakes, each of them is in pool for one tx at most
+func poolIndexesOf(tx metadata.Transaction) (tokenID string, candidate string) {
 	if tx.GetType() == common.TxCustomTokenType {
 		customTokenTx := tx.(*transaction.TxCustomToken)
 		if customTokenTx.TxTokenData.Type == transaction.CustomTokenInit {
-			tokenID := customTokenTx.TxTokenData.PropertyID.String()
-			tp.tokenIDMtx.RLock()
-			found := common.IndexOfStrInHashMap(tokenID, tp.TokenIDPool)
-			tp.tokenIDMtx.RUnlock()
-			if found > 0 {
-				str := fmt.Sprintf("Init Transaction of this Token is in pool already %+v", tokenID)
-				err := MempoolTxError{}
-				err.Init(RejectDuplicateInitTokenTx, errors.New(str))
-				return err
-			}
+			tokenID = customTokenTx.TxTokenData.PropertyID.String()
 		}
 	}
-
 	// check duplicate stake public key ONLY with staking transaction
 	if tx.GetMetadata() != nil {
 		if tx.GetMetadata().GetType() == metadata.ShardStakingMeta || tx.GetMetadata().GetType() == metadata.BeaconStakingMeta {
-			pubkey := base58.Base58Check{}.Encode(tx.GetSigPubKey(), common.ZeroByte)
-			tp.candidateMtx.RLock()
-			found := common.IndexOfStrInHashMap(pubkey, tp.CandidatePool)
-			tp.candidateMtx.RUnlock()
-			if found > 0 {
-				str := fmt.Sprintf("This public key already stake and still in pool %+v", pubkey)
-				err := MempoolTxError{}
-				err.Init(RejectDuplicateStakeTx, errors.New(str))
-				return err
-			}
+			candidate = base58.Base58Check{}.Encode(tx.GetSigPubKey(), common.ZeroByte)
 		}
 	}
+	return tokenID, candidate
+}
+
+// checkPoolIndexes returns error if token ID or candidate of tx is in pool
+// for a tx which isn't excluded, candidateMtx and tokenIDMtx must be held
+func (tp *TxPool) checkPoolIndexes(tx metadata.Transaction, excluded map[common.Hash]*TxDesc) error {
+	tokenID, candidate := poolIndexesOf(tx)
+	if tokenID != "" && hasValueOutside(tokenID, tp.TokenIDPool, excluded) {
+		str := fmt.Sprintf("Init Transaction of this Token is in pool already %+v", tokenID)
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateInitTokenTx, errors.New(str))
+		return err
+	}
+	if candidate != "" && hasValueOutside(candidate, tp.CandidatePool, excluded) {
+		str := fmt.Sprintf("This public key already stake and still in pool %+v", candidate)
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateStakeTx, errors.New(str))
+		return err
+	}
+	return nil
+}
+
+/*
+claimPoolIndexes checks token ID and candidate of tx like checkPoolIndexes
+and adds them for tx in the same critical section, so txs of different
+partitions which are accepted at the same time can't both claim them. Txs in
+excluded are replaced by tx
+*/
+func (tp *TxPool) claimPoolIndexes(tx metadata.Transaction, excluded map[common.Hash]*TxDesc) error {
+	tp.candidateMtx.Lock()
+	tp.tokenIDMtx.Lock()
+	defer tp.candidateMtx.Unlock()
+	defer tp.tokenIDMtx.Unlock()
+	if err := tp.checkPoolIndexes(tx, excluded); err != nil {
+		return err
+	}
+	tokenID, candidate := poolIndexesOf(tx)
+	if tokenID != "" {
+		tp.TokenIDPool[*tx.Hash()] = tokenID
+	}
+	if candidate != "" {
+		tp.CandidatePool[*tx.Hash()] = candidate
+	}
 	return nil
 }
 func (tp *TxPool) maybeAcceptTransaction(tx metadata.Transaction, isStore bool, isNewTransaction bool) (*common.Hash, *TxDesc, error) {
+	startValidate := time.Now()
+	err := tp.validateTxStateless(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tp.maybeAcceptVerifiedTransaction(tx, isStore, isNewTransaction, startValidate)
+}
+
+// maybeAcceptVerifiedTransaction validates tx which passed stateless checks
+// with pool and chain and adds it to pool, partition lock of tx must be held
+func (tp *TxPool) maybeAcceptVerifiedTransaction(tx metadata.Transaction, isStore bool, isNewTransaction bool, startValidate time.Time) (*common.Hash, *TxDesc, error) {
 	txType := tx.GetType()
 	if txType == common.TxNormalType {
 		if tx.IsPrivacy() {
@@ -365,8 +532,7 @@ func (tp *TxPool) maybeAcceptTransaction(tx metadata.Transaction, isStore bool,
 			txType = common.TxNormalNoPrivacy
 		}
 	}
-	startValidate := time.Now()
-	err := tp.ValidateTransaction(tx)
+	err := tp.validateTxWithPool(tx)
 	elapsed := float64(time.Since(startValidate).Seconds())
 	//if isNewTransaction {
 	go common.AnalyzeTimeSeriesTxSizeMetric(fmt.Sprintf("%d", tx.GetTxActualSize()), common.TxPoolValidated, elapsed)
@@ -380,7 +546,9 @@ func (tp *TxPool) maybeAcceptTransaction(tx metadata.Transaction, isStore bool,
 	txFee := tx.GetTxFee()
 	txD := createTxDescMempool(tx, bestHeight, txFee)
 	startAdd := time.Now()
-	tp.addTx(txD, isStore)
+	if err := tp.acceptTxDesc(txD, isStore); err != nil {
+		return nil, nil, err
+	}
 	if isNewTransaction {
 		Logger.log.Infof("Add New Txs Into Pool %+v FROM SHARD %+v\n", *tx.Hash(), shardID)
 		go common.AnalyzeTimeSeriesTxSizeMetric(fmt.Sprintf("%d", tx.GetTxActualSize()), common.TxPoolAddedAfterValidation, float64(time.Since(startAdd).Seconds()))
@@ -388,11 +556,28 @@ func (tp *TxPool) maybeAcceptTransaction(tx metadata.Transaction, isStore bool,
 	return tx.Hash(), txD, nil
 }
 
-// remove transaction for pool
+/*
+acceptTxDesc adds txDesc of a validated tx to pool in place of the txs which
+it replaces, it fails if another tx claimed its token ID or candidate since
+validation. Partition lock of tx must be held
+*/
+func (tp *TxPool) acceptTxDesc(txDesc *TxDesc, isStore bool) error {
+	tx := txDesc.Desc.Tx
+	// replaced txs are removed first, so coins of new tx which they spend
+	// stay marked as used
+	replacedTxs, _ := tp.replacedTxs(tp.partitionOf(tx), tx)
+	if err := tp.claimPoolIndexes(tx, replacedTxs); err != nil {
+		return err
+	}
+	tp.replaceTxs(tx.Hash(), replacedTxs)
+	tp.addTx(txDesc, isStore)
+	return nil
+}
+
+// remove transaction for pool, partition lock of transaction must be held
 func (tp *TxPool) removeTx(tx *metadata.Transaction) error {
 	//Logger.log.Infof((*tx).Hash().String())
-	if _, exists := tp.pool[*(*tx).Hash()]; exists {
-		delete(tp.pool, *(*tx).Hash())
+	if _, exists := tp.partitionOf(*tx).remove(*(*tx).Hash()); exists {
 		atomic.StoreInt64(&tp.lastUpdated, time.Now().Unix())
 		return nil
 	} else {
@@ -431,16 +616,26 @@ func (tp *TxPool) CheckPublicKeyRole(tx metadata.Transaction) bool {
 // parent is returned.  Use ProcessTransaction instead if new orphans should
 // be added to the orphan pool.
 //
-// This function is safe for concurrent access.
+// This function is safe for concurrent access. Stateless checks and privacy
+// proof of the transaction are verified by an admission worker without lock,
+// only checks with pool and chain state run under lock of the partition of
+// sender shard of the transaction. It returns AdmissionBusyError without
+// checking the transaction when too many transactions wait for admission.
 func (tp *TxPool) MaybeAcceptTransaction(tx metadata.Transaction) (*common.Hash, *TxDesc, error) {
-	tp.mtx.Lock()
-	defer tp.mtx.Unlock()
+	if !tp.startAdmission() {
+		err := MempoolTxError{}
+		err.Init(AdmissionBusyError, fmt.Errorf("%d transactions wait for admission, transaction %+v is not checked", tp.admission.maxPending, tx.Hash().String()))
+		tp.recordTxRejected(tx, err)
+		return nil, nil, err
+	}
+	defer tp.endAdmission()
 	go func(txHash common.Hash) {
 		tp.cCacheTx <- txHash
 	}(*tx.Hash())
 	if !tp.CheckRelayShard(tx) && !tp.CheckPublicKeyRole(tx) {
 		err := errors.New("Unexpected Transaction Source Shard")
 		Logger.log.Error(err)
+		tp.recordTxRejected(tx, err)
 		return &common.Hash{}, &TxDesc{}, err
 	}
 	txType := tx.GetType()
@@ -451,11 +646,44 @@ func (tp *TxPool) MaybeAcceptTransaction(tx metadata.Transaction) (*common.Hash,
 			txType = common.TxNormalNoPrivacy
 		}
 	}
-	if uint64(len(tp.pool)) >= tp.config.MaxTx {
-		return nil, nil, errors.New("Pool reach max number of transaction")
+	// a tx in pool isn't verified again
+	if tp.HaveTransaction(tx.Hash()) {
+		go common.AnalyzeTimeSeriesTxDuplicateTimesMetric(txType, float64(1))
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, fmt.Errorf("already have transaction %+v", tx.Hash().String()))
+		Logger.log.Error(err)
+		tp.recordTxRejected(tx, err)
+		return nil, nil, err
+	}
+	startValidate := time.Now()
+	err := tp.verifyTx(tx)
+	if err != nil {
+		Logger.log.Error(err)
+		tp.addRecentReject(tx, err)
+		tp.recordTxRejected(tx, err)
+		return nil, nil, err
+	}
+
+	partition := tp.partitionOf(tx)
+	partition.mtx.Lock()
+	defer partition.mtx.Unlock()
+	// a full partition evicts its lowest priority txs for a tx which pays
+	// more fee per KB, txs are evicted only after the new one is accepted
+	evictedTxs, err := tp.evictionsFor(partition, tx)
+	if err != nil {
+		tp.addRecentReject(tx, err)
+		tp.recordTxRejected(tx, err)
+		return nil, nil, err
 	}
 	startAdd := time.Now()
-	hash, txDesc, err := tp.maybeAcceptTransaction(tx, tp.config.PersistMempool, true)
+	hash, txDesc, err := tp.maybeAcceptVerifiedTransaction(tx, tp.config.PersistMempool, true, startValidate)
+	if err == nil {
+		tp.RecordTxEvent(*tx.Hash(), TxEvent{Type: TxEventValidated})
+		for _, evictedTx := range evictedTxs {
+			Logger.log.Infof("Evict tx %+v with fee per KB %+v from full pool of shard %+v", evictedTx.Desc.Tx.Hash().String(), evictedTx.Desc.FeePerKB, partition.shardID)
+			tp.removeFromPool(evictedTx, fmt.Sprintf("evicted from full pool by transaction %+v", tx.Hash().String()))
+		}
+	}
 	// fmt.Printf("[db] pool maybe accept: %d, %h, %+v\n", tx.GetMetadataType(), hash, err)
 	elapsed := float64(time.Since(startAdd).Seconds())
 
@@ -464,7 +692,7 @@ func (tp *TxPool) MaybeAcceptTransaction(tx metadata.Transaction) (*common.Hash,
 
 	size := tp.CalPoolSize()
 
-	go common.AnalyzeTimeSeriesPoolSizeMetric(fmt.Sprintf("%d", len(tp.pool)), float64(size))
+	go common.AnalyzeTimeSeriesPoolSizeMetric(fmt.Sprintf("%d", tp.Count()), float64(size))
 	go common.AnalyzeTimeSeriesTxTypeMetric(tx.GetType(), float64(1))
 
 	if tx.IsPrivacy() {
@@ -474,23 +702,138 @@ func (tp *TxPool) MaybeAcceptTransaction(tx metadata.Transaction) (*common.Hash,
 	}
 	if err != nil {
 		Logger.log.Error(err)
+		tp.addRecentReject(tx, err)
+		tp.recordTxRejected(tx, err)
 	} else {
-		if tp.IsBlockGenStarted {
-			go func(tx metadata.Transaction) {
-				tp.CPendingTxs <- tx
-			}(tx)
-		}
+		tp.removeRecentReject(*tx.Hash())
 	}
 	return hash, txDesc, err
 }
+
+/*
+evictionsFor returns txs which a full partition evicts for tx: its lowest
+priority txs, if tx pays more fee per KB. A tx which replaces txs by fee
+doesn't evict. Partition lock must be held
+*/
+func (tp *TxPool) evictionsFor(partition *txPartition, tx metadata.Transaction) ([]*TxDesc, error) {
+	if replacedTxs, _ := tp.replacedTxs(partition, tx); len(replacedTxs) > 0 {
+		return nil, nil
+	}
+	newTxDesc := &TxDesc{Desc: metadata.TxDesc{Tx: tx, FeePerKB: transaction.FeePerKB(tx)}, StartTime: time.Now()}
+	evictedTxs, ok := partition.evictionsFor(newTxDesc)
+	if !ok {
+		err := MempoolTxError{}
+		err.Init(MaxPoolSizeError, fmt.Errorf("pool of shard %d reaches its limit of %d transactions and %d KB and fee per KB %d of transaction %+v is not higher than fee of its transactions", partition.shardID, partition.limit.MaxTx, partition.limit.MaxSize, newTxDesc.Desc.FeePerKB, tx.Hash().String()))
+		return nil, err
+	}
+	// a tx doesn't evict its ancestors, it would be evicted with them
+	ancestors := make(map[*TxDesc]bool)
+	for _, parent := range partition.parentsOf(tx) {
+		ancestors[parent] = true
+		for _, ancestor := range partition.ancestors(parent) {
+			ancestors[ancestor] = true
+		}
+	}
+	for _, evictedTx := range evictedTxs {
+		if ancestors[evictedTx] {
+			err := MempoolTxError{}
+			err.Init(MaxPoolSizeError, fmt.Errorf("pool of shard %d reaches its limit of %d transactions and %d KB and transaction %+v would evict its ancestor %+v", partition.shardID, partition.limit.MaxTx, partition.limit.MaxSize, tx.Hash().String(), evictedTx.Desc.Tx.Hash().String()))
+			return nil, err
+		}
+	}
+	return evictedTxs, nil
+}
+
+/*
+TestMempoolAccept runs all checks of MaybeAcceptTransaction on tx without
+adding it to pool. It returns desc which tx would have in pool or the
+MempoolTxError which tx would be rejected with. A tx which fails isn't kept
+in recent rejects
+*/
+func (tp *TxPool) TestMempoolAccept(tx metadata.Transaction) (*TxDesc, error) {
+	if !tp.startAdmission() {
+		err := MempoolTxError{}
+		err.Init(AdmissionBusyError, fmt.Errorf("%d transactions wait for admission, transaction %+v is not checked", tp.admission.maxPending, tx.Hash().String()))
+		return nil, err
+	}
+	defer tp.endAdmission()
+	if tp.HaveTransaction(tx.Hash()) {
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, fmt.Errorf("already have transaction %+v", tx.Hash().String()))
+		return nil, err
+	}
+	err := tp.verifyTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	partition := tp.partitionOf(tx)
+	partition.mtx.RLock()
+	defer partition.mtx.RUnlock()
+	err = tp.validateTxWithPool(tx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tp.evictionsFor(partition, tx)
+	if err != nil {
+		return nil, err
+	}
+	shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
+	bestHeight := tp.config.BlockChain.BestState.Shard[shardID].BestBlock.Header.Height
+	txDesc := createTxDescMempool(tx, bestHeight, tx.GetTxFee())
+	txDesc.Desc.FeePerKB = transaction.FeePerKB(tx)
+	return txDesc, nil
+}
+
+// removeFromPool removes txDesc which is evicted or replaced and all of its
+// data from pool and mempool database, its descendants which spend its
+// output coins are removed too. Reason of removal goes to tx history.
+// Partition lock of tx must be held
+func (tp *TxPool) removeFromPool(txDesc *TxDesc, reason string) {
+	partition := tp.partitionOf(txDesc.Desc.Tx)
+	for _, removedTx := range append([]*TxDesc{txDesc}, partition.descendants(txDesc)...) {
+		tx := removedTx.Desc.Tx
+		txHash := *tx.Hash()
+		if !partition.isTxInPool(&txHash) {
+			continue
+		}
+		detail := reason
+		if removedTx != txDesc {
+			detail = fmt.Sprintf("ancestor %+v is removed: %s", txDesc.Desc.Tx.Hash().String(), reason)
+		}
+		tp.RecordTxEvent(txHash, TxEvent{Type: TxEventRemoved, Detail: detail})
+		tp.RemoveTransactionFromDatabaseMP(&txHash)
+		tp.removeTx(&tx)
+		tp.RemoveTxCoinHashH(txHash)
+		tp.candidateMtx.Lock()
+		delete(tp.CandidatePool, txHash)
+		tp.candidateMtx.Unlock()
+		tp.tokenIDMtx.Lock()
+		delete(tp.TokenIDPool, txHash)
+		tp.tokenIDMtx.Unlock()
+	}
+}
+
 func (tp *TxPool) MarkFowardedTransaction(txHash common.Hash) {
-	tp.pool[txHash].IsFowardMessage = true
+	for _, p := range tp.partitions {
+		p.mtx.Lock()
+		txDesc, ok := p.pool[txHash]
+		if ok {
+			txDesc.IsFowardMessage = true
+		}
+		p.mtx.Unlock()
+		if ok {
+			break
+		}
+	}
+	tp.RecordTxEvent(txHash, TxEvent{Type: TxEventForwarded})
 }
 
 // This function is safe for concurrent access.
 func (tp *TxPool) MaybeAcceptTransactionForBlockProducing(tx metadata.Transaction) (*metadata.TxDesc, error) {
-	tp.mtx.Lock()
-	defer tp.mtx.Unlock()
+	partition := tp.partitionOf(tx)
+	partition.mtx.Lock()
+	defer partition.mtx.Unlock()
 	_, txDesc, err := tp.maybeAcceptTransaction(tx, false, false)
 	// fmt.Printf("[db] pool bp maybe accept: %d, %h, %+v\n", tx.GetMetadataType(), tx.Hash(), err)
 	if err != nil {
@@ -504,16 +847,25 @@ func (tp *TxPool) MaybeAcceptTransactionForBlockProducing(tx metadata.Transactio
 
 // RemoveTx safe remove transaction for pool
 func (tp *TxPool) RemoveTx(tx metadata.Transaction, isInBlock bool) error {
-	tp.mtx.Lock()
-	defer tp.mtx.Unlock()
+	partition := tp.partitionOf(tx)
+	partition.mtx.Lock()
+	defer partition.mtx.Unlock()
 	// remove transaction from database mempool
-	txDesc, ok := tp.pool[*tx.Hash()]
+	txDesc, ok := partition.pool[*tx.Hash()]
 	if !ok {
 		return nil
 	}
 	startTime := txDesc.StartTime
+	// descendants of a tx which isn't in a block can't be valid
+	descendants := partition.descendants(txDesc)
 	tp.RemoveTransactionFromDatabaseMP(tx.Hash())
 	err := tp.removeTx(&tx)
+	if !isInBlock {
+		tp.RecordTxEvent(*tx.Hash(), TxEvent{Type: TxEventRemoved, Detail: "not valid for a new block"})
+		for _, descendant := range descendants {
+			tp.removeFromPool(descendant, fmt.Sprintf("ancestor %+v isn't valid for a new block", tx.Hash().String()))
+		}
+	}
 	// remove tx coin hash from pool
 	txHash := tx.Hash()
 	if txHash != nil {
@@ -533,17 +885,15 @@ func (tp *TxPool) RemoveTx(tx metadata.Transaction, isInBlock bool) error {
 		go common.AnalyzeTimeSeriesTxSizeWithTypeMetric(txType+":"+fmt.Sprintf("%d", tx.GetTxActualSize()), common.TxPoolRemoveAfterInBlockWithType, elapsed)
 	}
 	size := tp.CalPoolSize()
-	go common.AnalyzeTimeSeriesPoolSizeMetric(fmt.Sprintf("%d", len(tp.pool)), float64(size))
+	go common.AnalyzeTimeSeriesPoolSizeMetric(fmt.Sprintf("%d", tp.Count()), float64(size))
 
 	return err
 }
 
 // GetTx get transaction info by hash
 func (tp *TxPool) GetTx(txHash *common.Hash) (metadata.Transaction, error) {
-	tp.mtx.Lock()
-	defer tp.mtx.Unlock()
 	Logger.log.Info(txHash.String())
-	txDesc, exists := tp.pool[*txHash]
+	txDesc, exists := tp.findTx(*txHash)
 	if exists {
 		return txDesc.Desc.Tx, nil
 	}
@@ -551,31 +901,49 @@ func (tp *TxPool) GetTx(txHash *common.Hash) (metadata.Transaction, error) {
 	return nil, errors.New("transaction is not in the pool")
 }
 
-// // MiningDescs returns a slice of mining descriptors for all the transactions
-// // in the pool.
+// MiningDescs returns a slice of mining descriptors for all the transactions
+// in the pool, from the highest fee per KB. A child of txs in pool is left
+// out until its parents are in a block
 func (tp *TxPool) MiningDescs() []*metadata.TxDesc {
-	tp.mtx.Lock()
-	defer tp.mtx.Unlock()
+	txDescs := []*TxDesc{}
+	for _, p := range tp.partitions {
+		p.mtx.RLock()
+		for _, txDesc := range p.priority.sorted() {
+			if !txDesc.isChild() {
+				txDescs = append(txDescs, txDesc)
+			}
+		}
+		p.mtx.RUnlock()
+	}
+	sort.SliceStable(txDescs, func(i, j int) bool {
+		return hasHigherPriority(txDescs[i], txDescs[j])
+	})
 	descs := []*metadata.TxDesc{}
-	for _, desc := range tp.pool {
+	for _, desc := range txDescs {
 		descs = append(descs, &desc.Desc)
 	}
 	return descs
 }
 
+// GetPool returns a snapshot of txs of all partitions of pool
 func (tp *TxPool) GetPool() map[common.Hash]*TxDesc {
-	return tp.pool
-}
-func (tp *TxPool) LockPool() {
-	tp.mtx.Lock()
-}
-func (tp *TxPool) UnlockPool() {
-	tp.mtx.Unlock()
+	pool := make(map[common.Hash]*TxDesc)
+	for _, p := range tp.partitions {
+		p.mtx.RLock()
+		for txHash, txDesc := range p.pool {
+			pool[txHash] = txDesc
+		}
+		p.mtx.RUnlock()
+	}
+	return pool
 }
 
 // Count return len of transaction pool
 func (tp *TxPool) Count() int {
-	count := len(tp.pool)
+	count := 0
+	for _, p := range tp.partitions {
+		count += p.Count()
+	}
 	return count
 }
 
@@ -583,28 +951,42 @@ func (tp *TxPool) Count() int {
 Sum of all transactions sizes
 */
 func (tp *TxPool) Size() uint64 {
-	tp.mtx.RLock()
-	defer tp.mtx.RUnlock()
-	size := uint64(0)
-	for _, tx := range tp.pool {
-		size += tx.Desc.Tx.GetTxActualSize()
-	}
-	return size
+	return tp.CalPoolSize()
 }
 
 // Get Max fee
 func (tp *TxPool) MaxFee() uint64 {
-	tp.mtx.RLock()
-	defer tp.mtx.RUnlock()
 	fee := uint64(0)
-	for _, tx := range tp.pool {
-		if tx.Desc.Fee > fee {
-			fee = tx.Desc.Fee
+	for _, txDesc := range tp.GetPool() {
+		if txDesc.Desc.Fee > fee {
+			fee = txDesc.Desc.Fee
 		}
 	}
 	return fee
 }
 
+// PartitionState is the state of txs of a sender shard in pool
+type PartitionState struct {
+	ShardID byte
+	Count   int
+	Size    uint64
+	Limit   PoolLimit
+}
+
+// PartitionStates returns number and size of txs of each shard in pool
+func (tp *TxPool) PartitionStates() []PartitionState {
+	states := []PartitionState{}
+	for _, p := range tp.partitions {
+		states = append(states, PartitionState{
+			ShardID: p.shardID,
+			Count:   p.Count(),
+			Size:    p.Size(),
+			Limit:   p.limit,
+		})
+	}
+	return states
+}
+
 /*
 // LastUpdated returns the last time a transaction was added to or
 	// removed from the source pool.
@@ -618,10 +1000,7 @@ func (tp *TxPool) LastUpdated() time.Time {
 	// exists in the source pool.
 */
 func (tp *TxPool) HaveTransaction(hash *common.Hash) bool {
-	// Protect concurrent access.
-	tp.mtx.RLock()
-	defer tp.mtx.RUnlock()
-	haveTx := tp.isTxInPool(hash)
+	_, haveTx := tp.findTx(*hash)
 	return haveTx
 }
 
@@ -630,7 +1009,7 @@ List all tx ids in mempool
 */
 func (tp *TxPool) ListTxs() []string {
 	result := make([]string, 0)
-	for _, tx := range tp.pool {
+	for _, tx := range tp.GetPool() {
 		result = append(result, tx.Desc.Tx.Hash().String())
 	}
 	return result
@@ -641,7 +1020,7 @@ List all tx ids in mempool
 */
 func (tp *TxPool) ListTxsDetail() []metadata.Transaction {
 	result := make([]metadata.Transaction, 0)
-	for _, tx := range tp.pool {
+	for _, tx := range tp.GetPool() {
 		result = append(result, tx.Desc.Tx)
 	}
 	return result
@@ -743,72 +1122,112 @@ func (tp *TxPool) RemoveTokenIDList(tokenID []string) {
 }
 
 func (tp *TxPool) EmptyPool() bool {
+	for _, p := range tp.partitions {
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+	}
 	tp.cMtx.Lock()
 	tp.candidateMtx.Lock()
 	tp.tokenIDMtx.Lock()
 	defer tp.cMtx.Unlock()
 	defer tp.candidateMtx.Unlock()
 	defer tp.tokenIDMtx.Unlock()
-	if len(tp.pool) == 0 && len(tp.poolSerialNumbers) == 0 && len(tp.txCoinHashHPool) == 0 && len(tp.coinHashHPool) == 0 && len(tp.CandidatePool) == 0 && len(tp.TokenIDPool) == 0 {
+	if tp.isEmpty() {
 		return true
 	}
-	tp.pool = make(map[common.Hash]*TxDesc)
-	tp.poolSerialNumbers = make(map[common.Hash][][]byte)
+	for _, p := range tp.partitions {
+		p.reset()
+	}
 	tp.txCoinHashHPool = make(map[common.Hash][]common.Hash)
 	tp.coinHashHPool = make(map[common.Hash]bool)
 	tp.CandidatePool = make(map[common.Hash]string)
 	tp.TokenIDPool = make(map[common.Hash]string)
-	if len(tp.pool) == 0 && len(tp.poolSerialNumbers) == 0 && len(tp.txCoinHashHPool) == 0 && len(tp.coinHashHPool) == 0 && len(tp.CandidatePool) == 0 && len(tp.TokenIDPool) == 0 {
-		return true
+	return tp.isEmpty()
+}
+
+// isEmpty tells if there is no tx data in pool, all locks of pool must be held
+func (tp *TxPool) isEmpty() bool {
+	for _, p := range tp.partitions {
+		if len(p.pool) != 0 || len(p.poolSerialNumbers) != 0 {
+			return false
+		}
 	}
-	return false
+	return len(tp.txCoinHashHPool) == 0 && len(tp.coinHashHPool) == 0 && len(tp.CandidatePool) == 0 && len(tp.TokenIDPool) == 0
 }
 
 func (tp *TxPool) CalPoolSize() uint64 {
 	var totalSize uint64
-	for _, txDesc := range tp.pool {
-		size := txDesc.Desc.Tx.GetTxActualSize()
-		totalSize += size
+	for _, p := range tp.partitions {
+		totalSize += p.Size()
 	}
 	return totalSize
 }
 
+// MonitorPool removes txs which live in pool longer than tx life time of
+// their shard, a partition is locked only while its txs are checked
 func (tp *TxPool) MonitorPool() {
-	if tp.config.TxLifeTime == 0 {
+	hasTxLifeTime := false
+	for _, p := range tp.partitions {
+		if p.limit.TxLifeTime > 0 {
+			hasTxLifeTime = true
+		}
+	}
+	if !hasTxLifeTime {
 		return
 	}
 	for {
 		<-time.Tick(tp.Scantime)
-		tp.mtx.Lock()
-		ttl := time.Duration(tp.config.TxLifeTime) * time.Second
-		txsToBeRemoved := []*TxDesc{}
-		for _, txDesc := range tp.pool {
-			if time.Since(txDesc.StartTime) > ttl {
-				txsToBeRemoved = append(txsToBeRemoved, txDesc)
+		for _, p := range tp.partitions {
+			p.mtx.Lock()
+			for _, txDesc := range p.expired() {
+				txHash := *txDesc.Desc.Tx.Hash()
+				startTime := txDesc.StartTime
+				// descendants of an expired tx spend coins which won't be in chain
+				descendants := p.descendants(txDesc)
+				if _, ok := p.remove(txHash); !ok {
+					continue
+				}
+				tp.RecordTxEvent(txHash, TxEvent{Type: TxEventEvicted, Detail: fmt.Sprintf("lived in pool longer than tx life time %+v seconds", p.limit.TxLifeTime)})
+				for _, descendant := range descendants {
+					tp.removeFromPool(descendant, fmt.Sprintf("ancestor %+v is evicted by tx life time", txHash.String()))
+				}
+				tp.RemoveTxCoinHashH(txHash)
+				tp.candidateMtx.Lock()
+				delete(tp.CandidatePool, txHash)
+				tp.candidateMtx.Unlock()
+				tp.tokenIDMtx.Lock()
+				delete(tp.TokenIDPool, txHash)
+				tp.tokenIDMtx.Unlock()
+				go common.AnalyzeTimeSeriesTxSizeMetric(fmt.Sprintf("%d", txDesc.Desc.Tx.GetTxActualSize()), common.TxPoolRemoveAfterLifeTime, float64(time.Since(startTime).Seconds()))
+				size := tp.CalPoolSize()
+				go common.AnalyzeTimeSeriesPoolSizeMetric(fmt.Sprintf("%d", tp.Count()), float64(size))
 			}
+			p.mtx.Unlock()
 		}
-		for _, txDesc := range txsToBeRemoved {
-			txHash := *txDesc.Desc.Tx.Hash()
-			startTime := txDesc.StartTime
-			delete(tp.pool, txHash)
-			delete(tp.poolSerialNumbers, txHash)
-			delete(tp.txCoinHashHPool, txHash)
-			delete(tp.CandidatePool, txHash)
-			delete(tp.TokenIDPool, txHash)
-			go common.AnalyzeTimeSeriesTxSizeMetric(fmt.Sprintf("%d", txDesc.Desc.Tx.GetTxActualSize()), common.TxPoolRemoveAfterLifeTime, float64(time.Since(startTime).Seconds()))
-			size := tp.CalPoolSize()
-			go common.AnalyzeTimeSeriesPoolSizeMetric(fmt.Sprintf("%d", len(tp.pool)), float64(size))
-		}
-		tp.mtx.Unlock()
 	}
 }
 
 func (tp *TxPool) Start(cQuit chan struct{}) {
 	go tp.MonitorPool()
+	// txs which are accepted are also written one by one, snapshots drop txs
+	// which leave pool without being removed from database
+	var cSnapshot <-chan time.Time
+	if tp.config.PersistMempool && tp.config.SnapshotInterval > 0 {
+		snapshotTicker := time.NewTicker(tp.config.SnapshotInterval)
+		defer snapshotTicker.Stop()
+		cSnapshot = snapshotTicker.C
+	}
 	for {
 		select {
 		case <-cQuit:
 			return
+		case <-cSnapshot:
+			count, err := tp.SaveDatabaseMP()
+			if err != nil {
+				Logger.log.Errorf("Fail to save snapshot of mempool, error: %+v", err)
+			} else {
+				Logger.log.Debugf("Save snapshot of %+v transactions of mempool", count)
+			}
 		case shardID := <-tp.CRoleInCommittees:
 			{
 				go func() {
@@ -2,24 +2,50 @@ package mempool
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/databasemp"
 	"github.com/constant-money/constant-chain/databasemp/lvdb"
 	"github.com/constant-money/constant-chain/transaction"
 )
 
+// mempoolDBVersion is version of format of txs in mempool database, txs of a
+// database without version have the same format
+const mempoolDBVersion = 1
+
+// LoadReport tells number of txs which are loaded from mempool database and
+// why the other txs aren't loaded
+type LoadReport struct {
+	Loaded   int
+	Rejected map[common.Hash]string // reason by tx hash
+}
+
 type TempDesc struct {
 	StartTime     time.Time
 	IsPushMessage bool
 	Height        uint64
 	Fee           uint64
-	FeePerKB      int32
+	FeePerKB      uint64
 }
 
-func (tp *TxPool) AddTransactionToDatabaseMP(txHash *common.Hash, txDesc TxDesc) error {
+// encodeTxDesc returns tx of txDesc as it is stored in mempool database, nil
+// if its type isn't stored
+func encodeTxDesc(txHash *common.Hash, txDesc TxDesc) (*databasemp.Transaction, error) {
 	tx := txDesc.Desc.Tx
+	switch tx.GetType() {
+	case common.TxNormalType, common.TxCustomTokenType, common.TxCustomTokenPrivacyType:
+	default:
+		return nil, nil
+	}
+	valueTx, err := json.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
 	tempDesc := TempDesc{
 		StartTime:     txDesc.StartTime,
 		IsPushMessage: txDesc.IsFowardMessage,
@@ -27,59 +53,24 @@ func (tp *TxPool) AddTransactionToDatabaseMP(txHash *common.Hash, txDesc TxDesc)
 		Fee:           txDesc.Desc.Fee,
 		FeePerKB:      txDesc.Desc.FeePerKB,
 	}
-	switch tx.GetType() {
-	//==================For Constant Transfer Only
-	case common.TxNormalType:
-		{
-			normalTx := tx.(*transaction.Tx)
-			valueTx, err := json.Marshal(normalTx)
-			if err != nil {
-				return err
-			}
-			valueDesc, err := json.Marshal(tempDesc)
-			if err != nil {
-				return err
-			}
-			err = tp.config.DataBaseMempool.AddTransaction(txHash, common.TxNormalType, valueTx, valueDesc)
-			if err != nil {
-				return err
-			}
-		}
-	//==================For Constant & TxCustomToken Transfer
-	case common.TxCustomTokenType:
-		{
-			customTokenTx := tx.(*transaction.TxCustomToken)
-			valueTx, err := json.Marshal(customTokenTx)
-			if err != nil {
-				return err
-			}
-			valueDesc, err := json.Marshal(tempDesc)
-			if err != nil {
-				return err
-			}
-			err = tp.config.DataBaseMempool.AddTransaction(txHash, common.TxCustomTokenType, valueTx, valueDesc)
-			if err != nil {
-				return err
-			}
-		}
-	case common.TxCustomTokenPrivacyType:
-		{
-			customTokenPrivacyTx := tx.(*transaction.TxCustomTokenPrivacy)
-			valueTx, err := json.Marshal(customTokenPrivacyTx)
-			if err != nil {
-				return err
-			}
-			valueDesc, err := json.Marshal(tempDesc)
-			if err != nil {
-				return err
-			}
-			err = tp.config.DataBaseMempool.AddTransaction(txHash, common.TxCustomTokenPrivacyType, valueTx, valueDesc)
-			if err != nil {
-				return err
-			}
-		}
+	valueDesc, err := json.Marshal(tempDesc)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &databasemp.Transaction{
+		Hash:      txHash,
+		Type:      tx.GetType(),
+		ValueTx:   valueTx,
+		ValueDesc: valueDesc,
+	}, nil
+}
+
+func (tp *TxPool) AddTransactionToDatabaseMP(txHash *common.Hash, txDesc TxDesc) error {
+	dbTx, err := encodeTxDesc(txHash, txDesc)
+	if err != nil || dbTx == nil {
+		return err
+	}
+	return tp.config.DataBaseMempool.AddTransaction(dbTx.Hash, dbTx.Type, dbTx.ValueTx, dbTx.ValueDesc)
 }
 
 func (tp *TxPool) GetTransactionFromDatabaseMP(txHash *common.Hash) (TxDesc, error) {
@@ -94,41 +85,124 @@ func (tp *TxPool) GetTransactionFromDatabaseMP(txHash *common.Hash) (TxDesc, err
 func (tp *TxPool) ResetDatabaseMP() error {
 	return tp.config.DataBaseMempool.Reset()
 }
-func (tp *TxPool) LoadDatabaseMP() ([]TxDesc, error) {
-	txDescs := []TxDesc{}
+
+/*
+SaveDatabaseMP writes a snapshot of txs in pool to mempool database with
+version of its format in one atomic write, txs in database which aren't in
+pool are removed. It returns number of saved txs
+*/
+func (tp *TxPool) SaveDatabaseMP() (int, error) {
+	if tp.config.DataBaseMempool == nil {
+		return 0, errors.New("mempool database is not set")
+	}
+	dbTxs := []databasemp.Transaction{}
+	for txHash, txDesc := range tp.GetPool() {
+		txHash := txHash
+		dbTx, err := encodeTxDesc(&txHash, *txDesc)
+		if err != nil {
+			return 0, err
+		}
+		if dbTx != nil {
+			dbTxs = append(dbTxs, *dbTx)
+		}
+	}
+	err := tp.config.DataBaseMempool.ReplaceTransactions(dbTxs, mempoolDBVersion)
+	if err != nil {
+		return 0, err
+	}
+	return len(dbTxs), nil
+}
+
+/*
+LoadDatabaseMP loads txs of mempool database into pool. Txs are validated
+again with current best state, a tx which can't be read, expired or became
+invalid while node was down is removed from database and reported with the
+reason. A tx which is in pool already is skipped
+*/
+func (tp *TxPool) LoadDatabaseMP() (*LoadReport, error) {
+	report := &LoadReport{Rejected: make(map[common.Hash]string)}
+	if tp.config.DataBaseMempool == nil {
+		return report, errors.New("mempool database is not set")
+	}
+	version, ok, err := tp.config.DataBaseMempool.GetVersion()
+	if err != nil {
+		return report, err
+	}
+	if ok && version > mempoolDBVersion {
+		return report, fmt.Errorf("mempool database has version %d, newer than version %d of node", version, mempoolDBVersion)
+	}
 	allTxHashes, allTxs, err := tp.config.DataBaseMempool.Load()
-	ttl := time.Duration(tp.config.TxLifeTime) * time.Second
 	if err != nil {
-		return txDescs, err
+		return report, err
 	}
-	for index, tx := range allTxs {
-		values := strings.Split(string(tx), string(lvdb.Splitter))
+	reject := func(txHash *common.Hash, reason string) {
+		tp.RemoveTransactionFromDatabaseMP(txHash)
+		report.Rejected[*txHash] = reason
+	}
+	loadedTxDescs := []TxDesc{}
+	for index, value := range allTxs {
+		txHash, err := common.NewHash(allTxHashes[index][3:])
+		if err != nil {
+			continue
+		}
+		values := strings.Split(string(value), string(lvdb.Splitter))
+		if len(values) != 3 {
+			reject(txHash, "malformed transaction in database")
+			continue
+		}
 		txDesc, err := UmmarshallTxDescFromDatabase(values[0], []byte(values[1]), []byte(values[2]))
 		if err != nil {
-			txHash, err := common.NewHash(allTxHashes[index][3:])
-			if err != nil {
-				continue
-			}
 			// fail to ummarshall transaction then remove
-			tp.RemoveTransactionFromDatabaseMP(txHash)
+			reject(txHash, err.Error())
+			continue
+		}
+		loadedTxDescs = append(loadedTxDescs, txDesc)
+	}
+	// txs are added in order they entered pool, so a tx comes after parents
+	// whose output coins it spends
+	sort.SliceStable(loadedTxDescs, func(i, j int) bool {
+		return loadedTxDescs[i].StartTime.Before(loadedTxDescs[j].StartTime)
+	})
+	for _, txDesc := range loadedTxDescs {
+		txDesc := txDesc
+		tx := txDesc.Desc.Tx
+		if tp.HaveTransaction(tx.Hash()) {
 			continue
 		}
 		//if transaction is timeout then remove
-		if time.Since(txDesc.StartTime) > ttl {
-			tp.RemoveTransactionFromDatabaseMP(txDesc.Desc.Tx.Hash())
+		partition := tp.partitionOf(tx)
+		ttl := time.Duration(partition.limit.TxLifeTime) * time.Second
+		if ttl > 0 && time.Since(txDesc.StartTime) > ttl {
+			reject(tx.Hash(), "transaction expired")
+			continue
+		}
+		//if not validated by current blockchain db then remove, a valid tx
+		//replaces or evicts txs of pool like a new tx
+		partition.mtx.Lock()
+		err := tp.ValidateTransaction(tx)
+		var evictedTxs []*TxDesc
+		if err == nil {
+			evictedTxs, err = tp.evictionsFor(partition, tx)
+		}
+		if err == nil {
+			err = tp.acceptTxDesc(&txDesc, false)
 		}
-		//if not validated by current blockchain db then remove
-		err = tp.ValidateTransaction(txDesc.Desc.Tx)
+		if err == nil {
+			for _, evictedTx := range evictedTxs {
+				tp.removeFromPool(evictedTx, fmt.Sprintf("evicted from full pool by transaction %+v", tx.Hash().String()))
+			}
+		}
+		partition.mtx.Unlock()
 		if err != nil {
-			tp.RemoveTransactionFromDatabaseMP(txDesc.Desc.Tx.Hash())
+			tp.addRecentReject(tx, err)
+			reject(tx.Hash(), err.Error())
 			continue
 		}
-
-		tp.addTx(&txDesc, false)
-		txDescs = append(txDescs, txDesc)
+		report.Loaded++
 	}
-	return txDescs, nil
+	return report, nil
 }
+
 func (tp *TxPool) RemoveTransactionFromDatabaseMP(txHash *common.Hash) error {
 	if has, _ := tp.config.DataBaseMempool.HasTransaction(txHash); has {
 		err := tp.config.DataBaseMempool.RemoveTransaction(txHash)
@@ -168,6 +242,8 @@ func UmmarshallTxDescFromDatabase(txType string, valueTx []byte, valueDesc []byt
 			}
 			txDesc.Desc.Tx = &customTokenPrivacyTx
 		}
+	default:
+		return txDesc, fmt.Errorf("transaction type %+v is not stored in mempool database", txType)
 	}
 	tempDesc := TempDesc{}
 	err := json.Unmarshal(valueDesc, &tempDesc)
@@ -0,0 +1,90 @@
+package mempool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/databasemp/lvdb"
+)
+
+func TestSaveDatabaseMP(t *testing.T) {
+	tp := newTestPool(t, Config{})
+	txDesc := addTestTx(tp, newTestTx("saved", 100, 1, []int64{1}, []int64{2}))
+	db := tp.config.DataBaseMempool
+	// a tx which isn't in pool is removed by the snapshot
+	if err := db.AddTransaction(&common.Hash{1}, common.TxNormalType, []byte("{}"), []byte("{}")); err != nil {
+		t.Fatalf("db.AddTransaction returns %+v", err)
+	}
+	if saved, err := tp.SaveDatabaseMP(); err != nil || saved != 1 {
+		t.Fatalf("tp.SaveDatabaseMP saves %d txs, %+v", saved, err)
+	}
+	if version, ok, err := db.GetVersion(); err != nil || !ok || version != mempoolDBVersion {
+		t.Fatalf("db.GetVersion returns %d, %t, %+v", version, ok, err)
+	}
+	_, values, err := db.Load()
+	if err != nil || len(values) != 1 {
+		t.Fatalf("db.Load returns %d txs, %+v", len(values), err)
+	}
+	fields := strings.Split(string(values[0]), string(lvdb.Splitter))
+	if len(fields) != 3 {
+		t.Fatalf("saved tx has %d fields instead of 3", len(fields))
+	}
+	loaded, err := UmmarshallTxDescFromDatabase(fields[0], []byte(fields[1]), []byte(fields[2]))
+	if err != nil {
+		t.Fatalf("UmmarshallTxDescFromDatabase returns %+v", err)
+	}
+	if loaded.Desc.Fee != txDesc.Desc.Fee || loaded.Desc.FeePerKB != txDesc.Desc.FeePerKB || !loaded.StartTime.Equal(txDesc.StartTime) {
+		t.Fatalf("loaded tx desc %+v doesn't match saved %+v", loaded.Desc, txDesc.Desc)
+	}
+}
+
+func TestLoadDatabaseMPVersion(t *testing.T) {
+	tp := newTestPool(t, Config{})
+	db := tp.config.DataBaseMempool
+	if err := db.ReplaceTransactions(nil, mempoolDBVersion+1); err != nil {
+		t.Fatalf("db.ReplaceTransactions returns %+v", err)
+	}
+	if _, err := tp.LoadDatabaseMP(); err == nil {
+		t.Fatalf("database of a newer version should not be loaded")
+	}
+
+	// a tx which can't be read is removed from database and reported
+	if err := db.ReplaceTransactions(nil, mempoolDBVersion); err != nil {
+		t.Fatalf("db.ReplaceTransactions returns %+v", err)
+	}
+	txHash := common.Hash{1}
+	if err := db.AddTransaction(&txHash, common.TxNormalType, []byte("{"), []byte("{}")); err != nil {
+		t.Fatalf("db.AddTransaction returns %+v", err)
+	}
+	report, err := tp.LoadDatabaseMP()
+	if err != nil {
+		t.Fatalf("tp.LoadDatabaseMP returns %+v", err)
+	}
+	if _, ok := report.Rejected[txHash]; !ok || report.Loaded != 0 {
+		t.Fatalf("malformed tx should be rejected, report %+v", report)
+	}
+	if has, _ := db.HasTransaction(&txHash); has {
+		t.Fatalf("rejected tx should be removed from database")
+	}
+}
+
+// TestAcceptTxDesc adds a loaded tx in place of the tx in pool which it
+// replaces by fee
+func TestAcceptTxDesc(t *testing.T) {
+	tp := newTestPool(t, Config{ReplaceByFee: true})
+	original := newTestTx("original", 100, 1, []int64{1}, []int64{2})
+	addTestTx(tp, original)
+	replacement := newTestTx("replacement", 300, 1, []int64{1}, []int64{3})
+	txDesc := createTxDescMempool(replacement, 1, replacement.Fee)
+	partition := tp.partitionOf(replacement)
+	partition.mtx.Lock()
+	err := tp.acceptTxDesc(txDesc, false)
+	partition.mtx.Unlock()
+	if err != nil {
+		t.Fatalf("tp.acceptTxDesc returns %+v", err)
+	}
+	if tp.HaveTransaction(&original.hash) || !tp.HaveTransaction(&replacement.hash) {
+		t.Fatalf("replacement should be in pool instead of original tx")
+	}
+}
@@ -52,59 +52,73 @@ func (tp *TxPool) ValidateTxList(txs []metadata.Transaction) error {
 	}
 	//validate txs list
 	for _, tx := range txs {
-		txHash := tx.Hash()
-		// Don't accept the transaction if it already exists in the pool.
-		if tp.isTxInPool(txHash) {
-			str := fmt.Sprintf("already have transaction %+v", txHash.String())
-			err := MempoolTxError{}
-			err.Init(RejectDuplicateTx, errors.New(str))
-			return err
-		}
-
-		// check tx with all txs in current mempool
-		err := tx.ValidateTxWithCurrentMempool(tp)
+		err := tp.validateTxInList(tx)
 		if err != nil {
 			return err
 		}
-		if tx.GetType() == common.TxCustomTokenType {
-			customTokenTx := tx.(*transaction.TxCustomToken)
-			if customTokenTx.TxTokenData.Type == transaction.CustomTokenInit {
-				tokenID := customTokenTx.TxTokenData.PropertyID.String()
-				tp.tokenIDMtx.Lock()
-				found := common.IndexOfStrInHashMap(tokenID, tp.TokenIDPool)
-				tp.tokenIDMtx.Unlock()
-				if found > 0 {
-					str := fmt.Sprintf("Init Transaction of this Token is in pool already %+v", tokenID)
-					err := MempoolTxError{}
-					err.Init(RejectDuplicateInitTokenTx, errors.New(str))
-					return err
-				}
+	}
+
+	return nil
+}
+
+// validateTxInList validates tx of a block with txs before it in the list and
+// adds it to pool, partition of tx is locked while it is checked
+func (tp *TxPool) validateTxInList(tx metadata.Transaction) error {
+	partition := tp.partitionOf(tx)
+	partition.mtx.Lock()
+	defer partition.mtx.Unlock()
+	txHash := tx.Hash()
+	// Don't accept the transaction if it already exists in the pool.
+	if partition.isTxInPool(txHash) {
+		str := fmt.Sprintf("already have transaction %+v", txHash.String())
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, errors.New(str))
+		return err
+	}
+
+	// check tx with all txs in current mempool
+	err := tx.ValidateTxWithCurrentMempool(partition)
+	if err != nil {
+		return err
+	}
+	if tx.GetType() == common.TxCustomTokenType {
+		customTokenTx := tx.(*transaction.TxCustomToken)
+		if customTokenTx.TxTokenData.Type == transaction.CustomTokenInit {
+			tokenID := customTokenTx.TxTokenData.PropertyID.String()
+			tp.tokenIDMtx.RLock()
+			found := common.IndexOfStrInHashMap(tokenID, tp.TokenIDPool)
+			tp.tokenIDMtx.RUnlock()
+			if found > 0 {
+				str := fmt.Sprintf("Init Transaction of this Token is in pool already %+v", tokenID)
+				err := MempoolTxError{}
+				err.Init(RejectDuplicateInitTokenTx, errors.New(str))
+				return err
 			}
 		}
+	}
 
-		// check duplicate stake public key ONLY with staking transaction
-		if tx.GetMetadata() != nil {
-			if tx.GetMetadata().GetType() == metadata.ShardStakingMeta || tx.GetMetadata().GetType() == metadata.BeaconStakingMeta {
-				pubkey := base58.Base58Check{}.Encode(tx.GetSigPubKey(), common.ZeroByte)
-				tp.tokenIDMtx.Lock()
-				found := common.IndexOfStrInHashMap(pubkey, tp.CandidatePool)
-				tp.tokenIDMtx.Unlock()
-				if found > 0 {
-					str := fmt.Sprintf("This public key already stake and still in pool %+v", pubkey)
-					err := MempoolTxError{}
-					err.Init(RejectDuplicateStakeTx, errors.New(str))
-					return err
-				}
+	// check duplicate stake public key ONLY with staking transaction
+	if tx.GetMetadata() != nil {
+		if tx.GetMetadata().GetType() == metadata.ShardStakingMeta || tx.GetMetadata().GetType() == metadata.BeaconStakingMeta {
+			pubkey := base58.Base58Check{}.Encode(tx.GetSigPubKey(), common.ZeroByte)
+			tp.candidateMtx.RLock()
+			found := common.IndexOfStrInHashMap(pubkey, tp.CandidatePool)
+			tp.candidateMtx.RUnlock()
+			if found > 0 {
+				str := fmt.Sprintf("This public key already stake and still in pool %+v", pubkey)
+				err := MempoolTxError{}
+				err.Init(RejectDuplicateStakeTx, errors.New(str))
+				return err
 			}
 		}
-
-		shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
-		bestHeight := tp.config.BlockChain.BestState.Shard[shardID].BestBlock.Header.Height
-		txFee := tx.GetTxFee()
-		txD := createTxDescMempool(tx, bestHeight, txFee)
-		tp.addTx(txD, false)
 	}
 
+	shardID := common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte())
+	bestHeight := tp.config.BlockChain.BestState.Shard[shardID].BestBlock.Header.Height
+	txFee := tx.GetTxFee()
+	txD := createTxDescMempool(tx, bestHeight, txFee)
+	tp.addTx(txD, false)
+
 	return nil
 }
 
@@ -0,0 +1,131 @@
+package mempool
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/databasemp"
+	_ "github.com/constant-money/constant-chain/databasemp/lvdb"
+	"github.com/constant-money/constant-chain/metadata"
+	"github.com/constant-money/constant-chain/privacy"
+	zkp "github.com/constant-money/constant-chain/privacy/zeroknowledge"
+	"github.com/constant-money/constant-chain/transaction"
+)
+
+func init() {
+	Logger.Init(common.NewBackend(nil).Logger("test", true))
+}
+
+// testTx is a tx without privacy of shard 0 which spends input coins and
+// creates output coins given by numbers, each number is a distinct coin
+type testTx struct {
+	transaction.Tx
+	hash common.Hash
+	size uint64
+}
+
+func (tx *testTx) Hash() *common.Hash {
+	return &tx.hash
+}
+
+func (tx *testTx) GetTxActualSize() uint64 {
+	return tx.size
+}
+
+// testPoint returns a distinct point for each n, it is commitment or serial
+// number of coin n
+func testPoint(n int64) *privacy.EllipticPoint {
+	return privacy.PedCom.G[0].ScalarMult(big.NewInt(n))
+}
+
+func newTestTx(name string, fee, size uint64, inputs, outputs []int64) *testTx {
+	tx := &testTx{hash: common.HashH([]byte(name)), size: size}
+	tx.Type = common.TxNormalType
+	tx.Fee = fee
+	tx.Proof = &zkp.PaymentProof{}
+	for _, n := range inputs {
+		coin := &privacy.InputCoin{CoinDetails: &privacy.Coin{}}
+		coin.CoinDetails.CoinCommitment = testPoint(n)
+		coin.CoinDetails.SerialNumber = testPoint(n + 1000000)
+		tx.Proof.InputCoins = append(tx.Proof.InputCoins, coin)
+	}
+	for _, n := range outputs {
+		coin := &privacy.OutputCoin{CoinDetails: &privacy.Coin{}}
+		coin.CoinDetails.CoinCommitment = testPoint(n)
+		tx.Proof.OutputCoins = append(tx.Proof.OutputCoins, coin)
+	}
+	return tx
+}
+
+func newTestPool(t *testing.T, cfg Config) *TxPool {
+	db, err := databasemp.Open("memdbmempool")
+	if err != nil {
+		t.Fatalf("could not open memdbmempool: %+v", err)
+	}
+	cfg.DataBaseMempool = db
+	tp := &TxPool{}
+	tp.Init(&cfg)
+	return tp
+}
+
+// addTestTx adds tx to pool without validation
+func addTestTx(tp *TxPool, tx metadata.Transaction) *TxDesc {
+	txDesc := &TxDesc{Desc: metadata.TxDesc{Tx: tx, Fee: tx.GetTxFee()}, StartTime: time.Now()}
+	partition := tp.partitionOf(tx)
+	partition.mtx.Lock()
+	tp.addTx(txDesc, false)
+	partition.mtx.Unlock()
+	return txDesc
+}
+
+func newTestTokenInitTx(fee uint64) *transaction.TxCustomToken {
+	tx := &transaction.TxCustomToken{}
+	tx.Type = common.TxCustomTokenType
+	tx.Fee = fee
+	tx.TxTokenData.Type = transaction.CustomTokenInit
+	tx.TxTokenData.PropertyID = common.HashH([]byte("token"))
+	tx.TxTokenData.Vouts = []transaction.TxTokenVout{}
+	return tx
+}
+
+// TestClaimPoolIndexes claims the same token ID for txs of different
+// partitions at the same time, only one of them gets it
+func TestClaimPoolIndexes(t *testing.T) {
+	tp := newTestPool(t, Config{})
+	txs := make([]*transaction.TxCustomToken, 10)
+	errs := make([]error, len(txs))
+	var wg sync.WaitGroup
+	for i := range txs {
+		txs[i] = newTestTokenInitTx(uint64(i + 1))
+		txs[i].PubKeyLastByteSender = byte(i)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = tp.claimPoolIndexes(txs[i], nil)
+		}(i)
+	}
+	wg.Wait()
+	var holder *transaction.TxCustomToken
+	for i, err := range errs {
+		if err != nil {
+			continue
+		}
+		if holder != nil {
+			t.Fatalf("token ID is claimed by more than one tx")
+		}
+		holder = txs[i]
+	}
+	if holder == nil || len(tp.TokenIDPool) != 1 {
+		t.Fatalf("token ID should be claimed by one tx, pool has %d token IDs", len(tp.TokenIDPool))
+	}
+
+	// a tx which replaces the holder claims its token ID
+	replacement := newTestTokenInitTx(100)
+	excluded := map[common.Hash]*TxDesc{*holder.Hash(): {}}
+	if err := tp.claimPoolIndexes(replacement, excluded); err != nil {
+		t.Fatalf("tp.claimPoolIndexes of replacement returns err: %+v", err)
+	}
+}
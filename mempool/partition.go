@@ -0,0 +1,187 @@
+package mempool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+)
+
+// PoolLimit is the limit of txs which pool holds for one sender shard
+type PoolLimit struct {
+	MaxTx      uint64 // max number of transaction
+	MaxSize    uint64 // max sum of actual size of transaction in KB, 0 is no limit
+	TxLifeTime uint   // transaction life time in second, 0 keeps transaction until it is in block
+}
+
+/*
+txPartition holds txs of pool which are sent from one shard (shard of sender
+by GetShardIDFromLastByte). Each partition has its own lock, limit and fee
+priority index, so a burst of txs of a shard can't fill pool or block txs of
+other shards. Txs of different shards never spend the same serial numbers, so
+double spend is checked inside a partition.
+Number and size of txs are updated atomically, they can be read without lock
+*/
+type txPartition struct {
+	mtx               sync.RWMutex
+	shardID           byte
+	limit             PoolLimit
+	pool              map[common.Hash]*TxDesc
+	poolSerialNumbers map[common.Hash][][]byte
+	priority          txPriorityQueue // fee priority index of partition
+	count             int64
+	size              uint64
+
+	// outputs maps commitments of output coins of txs to the txs. It is
+	// changed with partition lock held and also read without partition lock
+	// when a tx is verified
+	outputs    map[string]*TxDesc
+	outputsMtx sync.RWMutex
+}
+
+func newTxPartition(shardID byte, limit PoolLimit) *txPartition {
+	return &txPartition{
+		shardID:           shardID,
+		limit:             limit,
+		pool:              make(map[common.Hash]*TxDesc),
+		poolSerialNumbers: make(map[common.Hash][][]byte),
+		priority:          txPriorityQueue{},
+		outputs:           make(map[string]*TxDesc),
+	}
+}
+
+// ----------- transaction.MempoolRetriever's implementation -----------------
+func (p *txPartition) GetSerialNumbers() map[common.Hash][][]byte {
+	return p.poolSerialNumbers
+}
+
+func (p *txPartition) GetTxsInMem() map[common.Hash]metadata.TxDesc {
+	txsInMem := make(map[common.Hash]metadata.TxDesc)
+	for hash, txDesc := range p.pool {
+		txsInMem[hash] = txDesc.Desc
+	}
+	return txsInMem
+}
+
+// ----------- end of transaction.MempoolRetriever's implementation -----------------
+
+// add puts txDesc in partition, partition lock must be held
+func (p *txPartition) add(txDesc *TxDesc) {
+	txHash := *txDesc.Desc.Tx.Hash()
+	p.remove(txHash)
+	p.pool[txHash] = txDesc
+	p.priority.add(txDesc)
+	p.poolSerialNumbers[txHash] = txDesc.Desc.Tx.ListNullifiers()
+	p.link(txDesc)
+	atomic.AddInt64(&p.count, 1)
+	atomic.AddUint64(&p.size, txDesc.Desc.Tx.GetTxActualSize())
+}
+
+// remove takes tx out of partition, partition lock must be held
+func (p *txPartition) remove(txHash common.Hash) (*TxDesc, bool) {
+	txDesc, ok := p.pool[txHash]
+	if !ok {
+		return nil, false
+	}
+	p.priority.remove(txDesc)
+	p.unlink(txDesc)
+	delete(p.pool, txHash)
+	delete(p.poolSerialNumbers, txHash)
+	atomic.AddInt64(&p.count, -1)
+	atomic.AddUint64(&p.size, ^(txDesc.Desc.Tx.GetTxActualSize() - 1))
+	return txDesc, true
+}
+
+// reset removes all txs of partition, partition lock must be held
+func (p *txPartition) reset() {
+	p.pool = make(map[common.Hash]*TxDesc)
+	p.poolSerialNumbers = make(map[common.Hash][][]byte)
+	p.priority = txPriorityQueue{}
+	p.outputsMtx.Lock()
+	p.outputs = make(map[string]*TxDesc)
+	p.outputsMtx.Unlock()
+	atomic.StoreInt64(&p.count, 0)
+	atomic.StoreUint64(&p.size, 0)
+}
+
+// Count returns number of txs in partition
+func (p *txPartition) Count() int {
+	return int(atomic.LoadInt64(&p.count))
+}
+
+// Size returns sum of actual size of txs in partition
+func (p *txPartition) Size() uint64 {
+	return atomic.LoadUint64(&p.size)
+}
+
+/*
+evictionsFor returns txs which are evicted for txDesc to fit limit of
+partition, from the lowest priority. A tx is evicted with its descendants.
+It returns false if partition is full and txDesc doesn't have higher
+priority than the txs it would evict.
+Partition lock must be held
+*/
+func (p *txPartition) evictionsFor(txDesc *TxDesc) ([]*TxDesc, bool) {
+	txSize := txDesc.Desc.Tx.GetTxActualSize()
+	count, size := uint64(len(p.pool)), p.Size()
+	isFull := func() bool {
+		return count >= p.limit.MaxTx || (p.limit.MaxSize > 0 && size+txSize > p.limit.MaxSize)
+	}
+	if !isFull() {
+		return nil, true
+	}
+	evicted := []*TxDesc{}
+	isEvicted := make(map[*TxDesc]bool)
+	sorted := p.priority.sorted()
+	for i := len(sorted) - 1; i >= 0 && isFull(); i-- {
+		if isEvicted[sorted[i]] {
+			continue
+		}
+		if !hasHigherPriority(txDesc, sorted[i]) {
+			return nil, false
+		}
+		for _, evictedTx := range append([]*TxDesc{sorted[i]}, p.descendants(sorted[i])...) {
+			if isEvicted[evictedTx] {
+				continue
+			}
+			isEvicted[evictedTx] = true
+			evicted = append(evicted, evictedTx)
+			count--
+			size -= evictedTx.Desc.Tx.GetTxActualSize()
+		}
+	}
+	if isFull() {
+		return nil, false
+	}
+	return evicted, true
+}
+
+// expiredAt returns txs of partition which can't be in a block at height by
+// their expiry height, partition lock must be held
+func (p *txPartition) expiredAt(height uint64) []*TxDesc {
+	txDescs := []*TxDesc{}
+	for _, txDesc := range p.pool {
+		if isExpired(txDesc.Desc.Tx, height) {
+			txDescs = append(txDescs, txDesc)
+		}
+	}
+	return txDescs
+}
+
+// expired returns txs which live in partition longer than its tx life time,
+// partition lock must be held
+func (p *txPartition) expired() []*TxDesc {
+	txDescs := []*TxDesc{}
+	if p.limit.TxLifeTime == 0 {
+		return txDescs
+	}
+	ttl := time.Duration(p.limit.TxLifeTime) * time.Second
+	for _, txDesc := range p.pool {
+		if time.Since(txDesc.StartTime) > ttl {
+			txDescs = append(txDescs, txDesc)
+		}
+	}
+	return txDescs
+}
@@ -0,0 +1,99 @@
+package mempool
+
+import (
+	"container/heap"
+	"sort"
+)
+
+/*
+txPriorityQueue is the fee priority index of pool, a min heap of txs whose
+top is the tx with the lowest priority, so it is the first tx to be evicted
+when pool is full. A tx has higher priority if it pays more fee per KB, or
+its package with descendants in pool does, txs with the same fee per KB are
+ordered by lock time like transaction.SortTxsByLockTime, then by time they
+enter pool
+*/
+type txPriorityQueue []*TxDesc
+
+// hasHigherPriority tells if a goes before b in a block
+func hasHigherPriority(a, b *TxDesc) bool {
+	if priorityFeePerKB(a) != priorityFeePerKB(b) {
+		return priorityFeePerKB(a) > priorityFeePerKB(b)
+	}
+	if a.Desc.Tx.GetLockTime() != b.Desc.Tx.GetLockTime() {
+		return a.Desc.Tx.GetLockTime() < b.Desc.Tx.GetLockTime()
+	}
+	return a.StartTime.Before(b.StartTime)
+}
+
+// priorityFeePerKB is fee per KB of txDesc or of its package with its
+// descendants in pool if the package pays more
+func priorityFeePerKB(txDesc *TxDesc) uint64 {
+	if txDesc.packageFeePerKB > txDesc.Desc.FeePerKB {
+		return txDesc.packageFeePerKB
+	}
+	return txDesc.Desc.FeePerKB
+}
+
+func (pq txPriorityQueue) Len() int {
+	return len(pq)
+}
+
+func (pq txPriorityQueue) Less(i, j int) bool {
+	return hasHigherPriority(pq[j], pq[i])
+}
+
+func (pq txPriorityQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].priorityIndex = i
+	pq[j].priorityIndex = j
+}
+
+func (pq *txPriorityQueue) Push(x interface{}) {
+	txDesc := x.(*TxDesc)
+	txDesc.priorityIndex = len(*pq)
+	*pq = append(*pq, txDesc)
+}
+
+func (pq *txPriorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	txDesc := old[n-1]
+	old[n-1] = nil
+	txDesc.priorityIndex = -1
+	*pq = old[:n-1]
+	return txDesc
+}
+
+// add puts txDesc in the index
+func (pq *txPriorityQueue) add(txDesc *TxDesc) {
+	heap.Push(pq, txDesc)
+}
+
+// remove takes txDesc out of the index if it is in
+func (pq *txPriorityQueue) remove(txDesc *TxDesc) {
+	index := txDesc.priorityIndex
+	if index < 0 || index >= len(*pq) || (*pq)[index] != txDesc {
+		return
+	}
+	heap.Remove(pq, index)
+}
+
+// update moves txDesc in the index after its priority changes
+func (pq *txPriorityQueue) update(txDesc *TxDesc) {
+	index := txDesc.priorityIndex
+	if index < 0 || index >= len(*pq) || (*pq)[index] != txDesc {
+		return
+	}
+	heap.Fix(pq, index)
+}
+
+// sorted returns txs of the index from the highest priority
+func (pq txPriorityQueue) sorted() []*TxDesc {
+	txDescs := make([]*TxDesc, len(pq))
+	copy(txDescs, pq)
+	sort.Slice(txDescs, func(i, j int) bool {
+		return hasHigherPriority(txDescs[i], txDescs[j])
+	})
+	return txDescs
+}
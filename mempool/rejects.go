@@ -0,0 +1,84 @@
+package mempool
+
+import (
+	"time"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// defaultMaxRecentRejects is the number of rejected txs which pool remembers
+// when Config.MaxRecentRejects isn't set
+const defaultMaxRecentRejects = 1000
+
+// RejectedTx is the reason why a tx is rejected by pool
+type RejectedTx struct {
+	Code    int    // code of MempoolTxError
+	Message string // message of MempoolTxError
+	Err     string // detail of error
+	Time    time.Time
+}
+
+func (tp *TxPool) initRecentRejects() {
+	maxRejects := tp.config.MaxRecentRejects
+	if maxRejects <= 0 {
+		maxRejects = defaultMaxRecentRejects
+	}
+	tp.recentRejects, _ = lru.New(maxRejects)
+}
+
+/*
+rejectError turns err of validation of tx into MempoolTxError, so a reject
+always has a code. Errors of transaction package from checks with pool or
+chain are wrapped with key
+*/
+func rejectError(key int, err error) error {
+	if _, ok := err.(MempoolTxError); ok {
+		return err
+	}
+	mempoolErr := MempoolTxError{}
+	mempoolErr.Init(key, err)
+	return mempoolErr
+}
+
+/*
+addRecentReject remembers why tx is rejected, the oldest reject is dropped
+when the cache is full. A tx which is rejected because it is in pool or pool
+is busy isn't remembered, it isn't invalid
+*/
+func (tp *TxPool) addRecentReject(tx metadata.Transaction, err error) {
+	mempoolErr, ok := err.(MempoolTxError)
+	if !ok {
+		mempoolErr = MempoolTxError{}
+		mempoolErr.Init(RejectInvalidTx, err)
+	}
+	switch mempoolErr.Code {
+	case ErrCodeMessage[RejectDuplicateTx].Code, ErrCodeMessage[AdmissionBusyError].Code:
+		return
+	}
+	rejectedTx := RejectedTx{
+		Code:    mempoolErr.Code,
+		Message: mempoolErr.Message,
+		Time:    time.Now(),
+	}
+	if mempoolErr.Err != nil {
+		rejectedTx.Err = mempoolErr.Err.Error()
+	}
+	tp.recentRejects.Add(*tx.Hash(), rejectedTx)
+}
+
+// GetRecentReject returns why tx of txHash is rejected if pool still
+// remembers it
+func (tp *TxPool) GetRecentReject(txHash common.Hash) (RejectedTx, bool) {
+	value, ok := tp.recentRejects.Get(txHash)
+	if !ok {
+		return RejectedTx{}, false
+	}
+	return value.(RejectedTx), true
+}
+
+// removeRecentReject forgets reject of tx when it is accepted later
+func (tp *TxPool) removeRecentReject(txHash common.Hash) {
+	tp.recentRejects.Remove(txHash)
+}
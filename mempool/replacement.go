@@ -0,0 +1,194 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+	"github.com/constant-money/constant-chain/transaction"
+)
+
+/*
+replacedTxs returns txs of partition which tx replaces by fee with their
+descendants, which are evicted with them. With ReplaceByFee on, a tx which
+spends serial numbers of txs in pool replaces them if it spends all of their
+serial numbers and pays a strictly higher fee and fee per KB than each of
+them. Its fee must cover fees of all evicted txs plus ReplaceFeePerKB for its
+own size, so replacements can't relay txs for free. It returns error if tx
+spends serial numbers of a tx in pool but can't replace it.
+Without ReplaceByFee nothing is replaced, so the double spend check of pool
+rejects the tx
+*/
+func (tp *TxPool) replacedTxs(partition *txPartition, tx metadata.Transaction) (map[common.Hash]*TxDesc, error) {
+	if !tp.config.ReplaceByFee {
+		return nil, nil
+	}
+	serialNumbers := make(map[string]bool)
+	for _, serialNumber := range tx.ListNullifiers() {
+		serialNumbers[string(serialNumber)] = true
+	}
+	if len(serialNumbers) == 0 {
+		return nil, nil
+	}
+	replaced := make(map[common.Hash]*TxDesc)
+	for txHash, poolSerialNumbers := range partition.poolSerialNumbers {
+		isConflict, isSubset := false, true
+		for _, serialNumber := range poolSerialNumbers {
+			if serialNumbers[string(serialNumber)] {
+				isConflict = true
+			} else {
+				isSubset = false
+			}
+		}
+		txDesc, ok := partition.pool[txHash]
+		if !isConflict || !ok {
+			continue
+		}
+		if !isSubset {
+			err := MempoolTxError{}
+			err.Init(RejectReplacementTx, fmt.Errorf("transaction %+v doesn't spend all serial numbers of transaction %+v", tx.Hash().String(), txHash.String()))
+			return nil, err
+		}
+		replaced[txHash] = txDesc
+	}
+	if len(replaced) == 0 {
+		return nil, nil
+	}
+	fee := tx.GetTxFee()
+	feePerKB := transaction.FeePerKB(tx)
+	for txHash, txDesc := range replaced {
+		if fee <= txDesc.Desc.Fee || feePerKB <= txDesc.Desc.FeePerKB {
+			err := MempoolTxError{}
+			err.Init(RejectReplacementTx, fmt.Errorf("transaction %+v with fee %d and fee per KB %d doesn't pay more than transaction %+v with fee %d and fee per KB %d", tx.Hash().String(), fee, feePerKB, txHash.String(), txDesc.Desc.Fee, txDesc.Desc.FeePerKB))
+			return nil, err
+		}
+	}
+	descendants := []*TxDesc{}
+	for _, txDesc := range replaced {
+		descendants = append(descendants, partition.descendants(txDesc)...)
+	}
+	for _, descendant := range descendants {
+		replaced[*descendant.Desc.Tx.Hash()] = descendant
+	}
+	// tx can't replace the txs whose outputs it spends
+	for _, parent := range partition.parentsOf(tx) {
+		if _, ok := replaced[*parent.Desc.Tx.Hash()]; ok {
+			err := MempoolTxError{}
+			err.Init(RejectReplacementTx, fmt.Errorf("transaction %+v spends outputs of transaction %+v which it replaces", tx.Hash().String(), parent.Desc.Tx.Hash().String()))
+			return nil, err
+		}
+	}
+	totalFee := uint64(0)
+	for _, txDesc := range replaced {
+		totalFee += txDesc.Desc.Fee
+	}
+	minFee := totalFee + tp.config.ReplaceFeePerKB*tx.GetTxActualSize()
+	if fee <= totalFee || fee < minFee {
+		err := MempoolTxError{}
+		err.Init(RejectReplacementTx, fmt.Errorf("transaction %+v with fee %d doesn't pay %d fee of %d transactions it evicts plus %d fee per KB", tx.Hash().String(), fee, totalFee, len(replaced), tp.config.ReplaceFeePerKB))
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// poolView is MempoolRetriever of a partition without some of its txs, txs
+// which are replaced by a new tx are left out when the new tx is validated
+type poolView struct {
+	partition *txPartition
+	excluded  map[common.Hash]*TxDesc
+}
+
+func (view poolView) GetSerialNumbers() map[common.Hash][][]byte {
+	serialNumbers := make(map[common.Hash][][]byte)
+	for txHash, poolSerialNumbers := range view.partition.poolSerialNumbers {
+		if _, ok := view.excluded[txHash]; !ok {
+			serialNumbers[txHash] = poolSerialNumbers
+		}
+	}
+	return serialNumbers
+}
+
+func (view poolView) GetTxsInMem() map[common.Hash]metadata.TxDesc {
+	txsInMem := make(map[common.Hash]metadata.TxDesc)
+	for txHash, txDesc := range view.partition.pool {
+		if _, ok := view.excluded[txHash]; !ok {
+			txsInMem[txHash] = txDesc.Desc
+		}
+	}
+	return txsInMem
+}
+
+// retrieverWithout returns partition without excluded txs
+func (p *txPartition) retrieverWithout(excluded map[common.Hash]*TxDesc) metadata.MempoolRetriever {
+	if len(excluded) == 0 {
+		return p
+	}
+	return poolView{partition: p, excluded: excluded}
+}
+
+// hasValueOutside returns true if value is in m for a tx which isn't
+// excluded, m is CandidatePool or TokenIDPool
+func hasValueOutside(value string, m map[common.Hash]string, excluded map[common.Hash]*TxDesc) bool {
+	for txHash, v := range m {
+		if _, ok := excluded[txHash]; !ok && v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceTxs removes txs which a new tx replaces by fee, partition lock of
+// new tx must be held. Descendants in replaced are removed with their
+// ancestors
+func (tp *TxPool) replaceTxs(newTxHash *common.Hash, replaced map[common.Hash]*TxDesc) {
+	roots := make(map[common.Hash]*TxDesc)
+	for txHash, txDesc := range replaced {
+		isRoot := true
+		for parentHash := range txDesc.parents {
+			if _, ok := replaced[parentHash]; ok {
+				isRoot = false
+				break
+			}
+		}
+		if isRoot {
+			roots[txHash] = txDesc
+		}
+	}
+	for txHash, txDesc := range roots {
+		Logger.log.Infof("Replace tx %+v with fee %+v by tx %+v", txHash.String(), txDesc.Desc.Fee, newTxHash.String())
+		tp.removeFromPool(txDesc, fmt.Sprintf("replaced by transaction %+v", newTxHash.String()))
+	}
+}
+
+/*
+MinReplacementFee returns the lowest fee which a tx of size KB pays to replace
+tx txHash of pool, it covers fees of the tx and its descendants plus
+ReplaceFeePerKB for size
+*/
+func (tp *TxPool) MinReplacementFee(txHash *common.Hash, size uint64) (uint64, error) {
+	for _, p := range tp.partitions {
+		p.mtx.RLock()
+		txDesc, ok := p.pool[*txHash]
+		if !ok {
+			p.mtx.RUnlock()
+			continue
+		}
+		totalFee := txDesc.Desc.Fee
+		for _, descendant := range p.descendants(txDesc) {
+			totalFee += descendant.Desc.Fee
+		}
+		p.mtx.RUnlock()
+		minFee := totalFee + tp.config.ReplaceFeePerKB*size
+		if minFee <= totalFee {
+			minFee = totalFee + 1
+		}
+		return minFee, nil
+	}
+	return 0, errors.New("transaction is not in the pool")
+}
+
+// IsReplaceByFeeEnabled returns true if txs in pool can be replaced by fee
+func (tp *TxPool) IsReplaceByFeeEnabled() bool {
+	return tp.config.ReplaceByFee
+}
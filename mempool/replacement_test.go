@@ -0,0 +1,64 @@
+package mempool
+
+import (
+	"testing"
+)
+
+func TestReplacedTxs(t *testing.T) {
+	tp := newTestPool(t, Config{ReplaceByFee: true, ReplaceFeePerKB: 10})
+	partition := tp.partitions[0]
+	// parent spends coin 1, child spends output coin 2 of parent
+	parent := newTestTx("parent", 100, 1, []int64{1}, []int64{2})
+	child := newTestTx("child", 300, 1, []int64{2}, []int64{3})
+	addTestTx(tp, parent)
+	addTestTx(tp, child)
+	if len(partition.pool[child.hash].parents) != 1 {
+		t.Fatalf("child should depend on parent")
+	}
+
+	// a replacement pays for parent and the child which is evicted with it
+	cheap := newTestTx("cheap", 150, 1, []int64{1}, []int64{4})
+	if _, err := tp.replacedTxs(partition, cheap); err == nil {
+		t.Fatalf("replacement which doesn't pay for descendants should be rejected")
+	}
+	noIncrement := newTestTx("noincrement", 405, 1, []int64{1}, []int64{4})
+	if _, err := tp.replacedTxs(partition, noIncrement); err == nil {
+		t.Fatalf("replacement which doesn't pay the increment should be rejected")
+	}
+	addTestTx(tp, newTestTx("twoinputs", 100, 1, []int64{6, 7}, nil))
+	partial := newTestTx("partial", 1000, 1, []int64{6}, nil)
+	if _, err := tp.replacedTxs(partition, partial); err == nil {
+		t.Fatalf("replacement which doesn't spend all serial numbers of a tx should be rejected")
+	}
+	unrelated := newTestTx("unrelated", 1, 1, []int64{8}, nil)
+	if replaced, err := tp.replacedTxs(partition, unrelated); err != nil || len(replaced) != 0 {
+		t.Fatalf("tx which doesn't spend coins of pool replaces %d txs, %+v", len(replaced), err)
+	}
+
+	replacement := newTestTx("replacement", 410, 1, []int64{1}, []int64{4})
+	if fee, err := tp.MinReplacementFee(&parent.hash, 1); err != nil || fee != 410 {
+		t.Fatalf("tp.MinReplacementFee returns %d, %+v", fee, err)
+	}
+	replaced, err := tp.replacedTxs(partition, replacement)
+	if err != nil {
+		t.Fatalf("tp.replacedTxs returns err: %+v", err)
+	}
+	if len(replaced) != 2 || replaced[parent.hash] == nil || replaced[child.hash] == nil {
+		t.Fatalf("replacement should evict parent and child, it evicts %d txs", len(replaced))
+	}
+	partition.mtx.Lock()
+	tp.replaceTxs(replacement.Hash(), replaced)
+	partition.mtx.Unlock()
+	if partition.isTxInPool(&parent.hash) || partition.isTxInPool(&child.hash) {
+		t.Fatalf("replaced txs should be removed from pool")
+	}
+
+	// a tx can't replace the parent whose outputs it spends
+	tp = newTestPool(t, Config{ReplaceByFee: true})
+	partition = tp.partitions[0]
+	addTestTx(tp, parent)
+	spender := newTestTx("spender", 1000, 1, []int64{1, 2}, []int64{4})
+	if _, err := tp.replacedTxs(partition, spender); err == nil {
+		t.Fatalf("tx which spends outputs of the tx it replaces should be rejected")
+	}
+}
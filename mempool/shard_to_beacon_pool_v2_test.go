@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/common"
 )
 
 var (
@@ -102,7 +102,7 @@ type TxDesc struct {
 	Fee uint64
 
 	// FeePerKB is the fee the transaction pays in coin per 1000 bytes.
-	FeePerKB int32
+	FeePerKB uint64
 }
 
 // Interface for mempool which is used in metadata
@@ -149,6 +149,7 @@ type Transaction interface {
 	GetMetadataType() int
 	GetType() string
 	GetLockTime() int64
+	GetExpiryHeight() uint64
 	GetTxActualSize() uint64
 	GetSenderAddrLastByte() byte
 	GetTxFee() uint64
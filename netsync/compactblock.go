@@ -0,0 +1,154 @@
+package netsync
+
+import (
+	"time"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/wire"
+	libp2p "github.com/libp2p/go-libp2p-peer"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// CompactBlockLiveTime is the time a compact block waits for its missing
+	// txs, the full block is requested after it
+	CompactBlockLiveTime = 10 * time.Second
+)
+
+// pendingCompactBlock is a rebuilt block which waits for its missing txs from
+// the peer which sent its compact block
+type pendingCompactBlock struct {
+	block  *blockchain.ShardBlock
+	peerID libp2p.ID
+}
+
+// HandleMessageShardBlockCompact rebuilds shard block from compact block and
+// mempool, missing txs are requested from sender of compact block
+func (netSync *NetSync) HandleMessageShardBlockCompact(msg *wire.MessageBlockShardCompact) {
+	Logger.log.Info("Handling new message BlockShardCompact")
+	blkHash := msg.Block.Hash()
+	if _, ok := netSync.Cache.blockCache.Get(blkHash.String()); ok {
+		return
+	}
+	if _, ok := netSync.Cache.compactBlockCache.Get(blkHash.String()); ok {
+		return
+	}
+	peerID, err := libp2p.IDB58Decode(msg.SenderID)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	block, missing, err := netSync.config.BlockChain.ReconstructShardBlock(&msg.Block)
+	if err != nil {
+		Logger.log.Error(err)
+		netSync.requestFullShardBlock(msg.Block.Header.ShardID, *blkHash, peerID)
+		return
+	}
+	if len(missing) == 0 {
+		netSync.handleReconstructedShardBlock(block)
+		return
+	}
+	Logger.log.Infof("Compact block %+v misses %+v of %+v txs", blkHash.String(), len(missing), len(block.Body.Transactions))
+	netSync.Cache.compactBlockCache.Add(blkHash.String(), &pendingCompactBlock{block: block, peerID: peerID}, cache.NoExpiration)
+	// the full block is requested if missing txs don't come in time
+	time.AfterFunc(CompactBlockLiveTime, func() {
+		if pending, ok := netSync.takePendingCompactBlock(*blkHash); ok {
+			Logger.log.Infof("Missing txs of compact block %+v don't come in time, request full block", blkHash.String())
+			netSync.requestFullShardBlock(pending.block.Header.ShardID, *blkHash, pending.peerID)
+		}
+	})
+	msgGetTxs, err := wire.MakeEmptyMessage(wire.CmdGetBlockTxs)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	msgGetTxs.(*wire.MessageGetBlockTxs).ShardID = msg.Block.Header.ShardID
+	msgGetTxs.(*wire.MessageGetBlockTxs).BlockHash = *blkHash
+	msgGetTxs.(*wire.MessageGetBlockTxs).Indexes = missing
+	err = netSync.config.Server.PushMessageToPeer(msgGetTxs, peerID)
+	if err != nil {
+		Logger.log.Error(err)
+	}
+}
+
+// HandleMessageGetBlockTxs sends txs of a shard block which a peer misses when
+// rebuilding the block from compact block
+func (netSync *NetSync) HandleMessageGetBlockTxs(msg *wire.MessageGetBlockTxs) {
+	Logger.log.Info("Handling new message - " + wire.CmdGetBlockTxs)
+	peerID, err := libp2p.IDB58Decode(msg.SenderID)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	blockTxs, err := netSync.config.BlockChain.GetShardBlockTxs(msg.BlockHash, msg.Indexes)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	msgTxs, err := wire.MakeEmptyMessage(wire.CmdBlockTxs)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	msgTxs.(*wire.MessageBlockTxs).ShardID = msg.ShardID
+	msgTxs.(*wire.MessageBlockTxs).Txs = *blockTxs
+	err = netSync.config.Server.PushMessageToPeer(msgTxs, peerID)
+	if err != nil {
+		Logger.log.Error(err)
+	}
+}
+
+// HandleMessageBlockTxs completes a pending compact block with the missing
+// txs, the full block is requested if they don't complete it
+func (netSync *NetSync) HandleMessageBlockTxs(msg *wire.MessageBlockTxs) {
+	Logger.log.Info("Handling new message BlockTxs")
+	blkHash := msg.Txs.BlockHash
+	pending, ok := netSync.takePendingCompactBlock(blkHash)
+	if !ok {
+		return
+	}
+	err := pending.block.FillMissingTxs(&msg.Txs)
+	if err != nil {
+		Logger.log.Error(err)
+		netSync.requestFullShardBlock(pending.block.Header.ShardID, blkHash, pending.peerID)
+		return
+	}
+	netSync.handleReconstructedShardBlock(pending.block)
+}
+
+// takePendingCompactBlock removes compact block of blkHash which waits for
+// its missing txs and returns it, only one caller gets it
+func (netSync *NetSync) takePendingCompactBlock(blkHash common.Hash) (*pendingCompactBlock, bool) {
+	netSync.Cache.compactBlockMtx.Lock()
+	defer netSync.Cache.compactBlockMtx.Unlock()
+	pending, ok := netSync.Cache.compactBlockCache.Get(blkHash.String())
+	if !ok {
+		return nil, false
+	}
+	netSync.Cache.compactBlockCache.Delete(blkHash.String())
+	return pending.(*pendingCompactBlock), true
+}
+
+func (netSync *NetSync) handleReconstructedShardBlock(block *blockchain.ShardBlock) {
+	if isAdded := netSync.HandleCacheBlock(*block.Hash()); !isAdded {
+		netSync.config.BlockChain.OnBlockShardReceived(block)
+	}
+}
+
+// requestFullShardBlock falls back to getblkshard when a compact block can not
+// be rebuilt
+func (netSync *NetSync) requestFullShardBlock(shardID byte, blkHash common.Hash, peerID libp2p.ID) {
+	msg, err := wire.MakeEmptyMessage(wire.CmdGetBlockShard)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	msg.(*wire.MessageGetBlockShard).ByHash = true
+	msg.(*wire.MessageGetBlockShard).BlksHash = []common.Hash{blkHash}
+	msg.(*wire.MessageGetBlockShard).ShardID = shardID
+	err = netSync.config.Server.PushMessageToPeer(msg, peerID)
+	if err != nil {
+		Logger.log.Error(err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/constant-money/constant-chain/blockchain"
 	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
 	"github.com/constant-money/constant-chain/wire"
 	libp2p "github.com/libp2p/go-libp2p-peer"
 )
@@ -102,7 +103,12 @@ func (netSync *NetSync) GetBlkShardByHeightAndSend(peerID libp2p.ID, fromPool bo
 		} else {
 			blk, err := netSync.config.BlockChain.GetShardBlockByHeight(blkHeight, shardID)
 			if err != nil {
-				Logger.log.Error(err)
+				if database.IsBlockPruned(err) {
+					// peer selects archive nodes for pruned blocks
+					Logger.log.Debugf("Block %d of shard %d is pruned, it isn't sent to peer %s", blkHeight, shardID, peerID.Pretty())
+				} else {
+					Logger.log.Error(err)
+				}
 				continue
 			}
 			blkMsg, err = netSync.CreateBlkShardMsgByType(blk, blkType, crossShardID)
@@ -0,0 +1,78 @@
+package netsync
+
+import (
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/common/base58"
+	"github.com/constant-money/constant-chain/metadata"
+	"github.com/constant-money/constant-chain/privacy"
+	"github.com/constant-money/constant-chain/wire"
+)
+
+/*
+ValidateGossipMessage is the validation hook of gossip topics, a message which
+is not valid is dropped and not relayed to other peers of topic.
+Only cheap checks are done here: message must belong to topic, txs must pass
+sanity check and blocks must be signed by their producer, who must be a member
+of committee of the block. Full validation is done later when message is
+processed
+*/
+func (netSync *NetSync) ValidateGossipMessage(topic string, msg wire.Message) bool {
+	msgTopic, ok := wire.GetTopic(msg)
+	if !ok || msgTopic != topic {
+		Logger.log.Errorf("Gossip message %s does not belong to topic %s", msg.MessageType(), topic)
+		return false
+	}
+	switch msg := msg.(type) {
+	case *wire.MessageTx:
+		return netSync.validateGossipTx(msg.Transaction)
+	case *wire.MessageTxToken:
+		return netSync.validateGossipTx(msg.Transaction)
+	case *wire.MessageTxPrivacyToken:
+		return netSync.validateGossipTx(msg.Transaction)
+	case *wire.MessageBlockShard:
+		return netSync.validateShardProducer(msg.Block.Header, msg.Block.ProducerSig)
+	case *wire.MessageCrossShard:
+		return netSync.validateShardProducer(msg.Block.Header, msg.Block.ProducerSig)
+	case *wire.MessageShardToBeacon:
+		return netSync.validateShardProducer(msg.Block.Header, msg.Block.ProducerSig)
+	case *wire.MessageBlockBeacon:
+		committee := netSync.config.BlockChain.BestState.Beacon.GetBeaconCommittee()
+		return validateProducerSig(committee, msg.Block.Header.ProducerAddress, msg.Block.ProducerSig, msg.Block.Header.Hash())
+	}
+	return false
+}
+
+func (netSync *NetSync) validateGossipTx(tx metadata.Transaction) bool {
+	if tx == nil {
+		return false
+	}
+	ok, err := tx.ValidateSanityData(netSync.config.BlockChain)
+	if err != nil || !ok {
+		Logger.log.Errorf("Gossip tx %+v is not valid: %+v", tx.Hash(), err)
+		return false
+	}
+	return true
+}
+
+// validateShardProducer checks that a block of shard is signed by a member of
+// committee of the shard at beacon height of the block
+func (netSync *NetSync) validateShardProducer(header blockchain.ShardHeader, producerSig string) bool {
+	committee := netSync.config.BlockChain.GetShardCommitteeByBeaconHeight(header.ShardID, header.BeaconHeight)
+	return validateProducerSig(committee, header.ProducerAddress, producerSig, header.Hash())
+}
+
+func validateProducerSig(committee []string, producer privacy.PaymentAddress, producerSig string, blkHash common.Hash) bool {
+	producerPk := base58.Base58Check{}.Encode(producer.Pk, common.ZeroByte)
+	if common.IndexOfStr(producerPk, committee) < 0 {
+		Logger.log.Errorf("Producer %+v of block %+v is not in committee", producerPk, blkHash)
+		return false
+	}
+	err := cashec.ValidateDataB58(producerPk, producerSig, blkHash.GetBytes())
+	if err != nil {
+		Logger.log.Error(err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package netsync
+
+import (
+	"testing"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/wire"
+)
+
+func init() {
+	Logger.Init(common.NewBackend(nil).Logger("test", true))
+}
+
+func newTestGossipNetSync(beaconCommittee []string) *NetSync {
+	bc := &blockchain.BlockChain{
+		BestState: &blockchain.BestState{
+			Beacon: &blockchain.BestStateBeacon{BeaconCommittee: beaconCommittee},
+		},
+	}
+	return &NetSync{config: &NetSyncConfig{BlockChain: bc}}
+}
+
+func newTestBeaconBlockMsg(t *testing.T, producer *cashec.KeySet) *wire.MessageBlockBeacon {
+	msg := &wire.MessageBlockBeacon{}
+	msg.Block.Header.Height = 2
+	msg.Block.Header.ProducerAddress = producer.PaymentAddress
+	blkHash := msg.Block.Header.Hash()
+	sig, err := producer.SignDataB58(blkHash.GetBytes())
+	if err != nil {
+		t.Fatalf("failed to sign block: %+v", err)
+	}
+	msg.Block.ProducerSig = sig
+	return msg
+}
+
+func TestValidateGossipBeaconBlock(t *testing.T) {
+	member := (&cashec.KeySet{}).GenerateKey([]byte("member"))
+	outsider := (&cashec.KeySet{}).GenerateKey([]byte("outsider"))
+	netSync := newTestGossipNetSync([]string{member.GetPublicKeyB58()})
+
+	if !netSync.ValidateGossipMessage(wire.TopicBeacon, newTestBeaconBlockMsg(t, member)) {
+		t.Fatalf("block signed by committee member should be valid")
+	}
+	// a valid signature of a key which isn't in committee is not enough
+	if netSync.ValidateGossipMessage(wire.TopicBeacon, newTestBeaconBlockMsg(t, outsider)) {
+		t.Fatalf("block signed by node outside of committee should be rejected")
+	}
+	msg := newTestBeaconBlockMsg(t, member)
+	msg.Block.Header.Height = 3
+	if netSync.ValidateGossipMessage(wire.TopicBeacon, msg) {
+		t.Fatalf("block changed after signing should be rejected")
+	}
+	if netSync.ValidateGossipMessage(wire.TopicShardBlock(0), newTestBeaconBlockMsg(t, member)) {
+		t.Fatalf("block of another topic should be rejected")
+	}
+}
+
+func TestValidateGossipTxWithoutTx(t *testing.T) {
+	netSync := newTestGossipNetSync(nil)
+	msgs := []wire.Message{&wire.MessageTx{}, &wire.MessageTxToken{}, &wire.MessageTxPrivacyToken{}}
+	for _, msg := range msgs {
+		if _, ok := wire.GetTopic(msg); ok {
+			t.Fatalf("%s without tx should have no topic", msg.MessageType())
+		}
+		if netSync.ValidateGossipMessage(wire.TopicTx(0), msg) {
+			t.Fatalf("%s without tx should be rejected", msg.MessageType())
+		}
+	}
+}
@@ -59,6 +59,8 @@ type NetSyncConfig struct {
 type NetSyncCache struct {
 	blockCache              *cache.Cache
 	txCache                 *lru.Cache
+	compactBlockCache       *cache.Cache
+	compactBlockMtx         sync.Mutex
 	CTxCache                chan common.Hash
 }
 
@@ -69,9 +71,11 @@ func (netSync NetSync) New(cfg *NetSyncConfig, cTxCache chan common.Hash, cfgSha
 	netSync.cMessage = make(chan interface{})
 	blockCache := cache.New(MsgLiveTime, MsgsCleanupInterval)
 	txCache, _ := lru.New(txCache)
+	compactBlockCache := cache.New(CompactBlockLiveTime, MsgsCleanupInterval)
 	netSync.Cache = &NetSyncCache{
 		txCache:                 txCache,
 		blockCache: blockCache,
+		compactBlockCache: compactBlockCache,
 	}
 	netSync.Cache.CTxCache = cTxCache
 	return &netSync
@@ -158,6 +162,18 @@ out:
 						{
 							netSync.HandleMessageShardBlock(msg)
 						}
+					case *wire.MessageBlockShardCompact:
+						{
+							netSync.HandleMessageShardBlockCompact(msg)
+						}
+					case *wire.MessageGetBlockTxs:
+						{
+							netSync.HandleMessageGetBlockTxs(msg)
+						}
+					case *wire.MessageBlockTxs:
+						{
+							netSync.HandleMessageBlockTxs(msg)
+						}
 					case *wire.MessageGetCrossShard:
 						{
 							netSync.HandleMessageGetCrossShard(msg)
@@ -215,10 +231,25 @@ out:
 	netSync.cMessage <- msg
 }*/
 
+// signalDone tells the caller of a queue function that a message which
+// isn't queued is handled, a nil done isn't waited on
+func signalDone(done chan struct{}) {
+	if done != nil {
+		done <- struct{}{}
+	}
+}
+
 func (netSync *NetSync) QueueTx(peer *peer.Peer, msg *wire.MessageTx, done chan struct{}) {
 	// Don't accept more transactions if we're shutting down.
 	if atomic.LoadInt32(&netSync.shutdown) != 0 {
-		done <- struct{}{}
+		signalDone(done)
+		return
+	}
+	netSync.recordTxReceived(peer, msg.Transaction)
+	// Drop transaction if mempool can't take more to verify
+	if netSync.isTxPoolBusy() {
+		netSync.config.TxMemPool.RecordTxEvent(*msg.Transaction.Hash(), mempool.TxEvent{Type: mempool.TxEventRejected, Detail: "dropped while mempool is busy"})
+		signalDone(done)
 		return
 	}
 	netSync.cMessage <- msg
@@ -227,7 +258,14 @@ func (netSync *NetSync) QueueTx(peer *peer.Peer, msg *wire.MessageTx, done chan
 func (netSync *NetSync) QueueTxToken(peer *peer.Peer, msg *wire.MessageTxToken, done chan struct{}) {
 	// Don't accept more transactions if we're shutting down.
 	if atomic.LoadInt32(&netSync.shutdown) != 0 {
-		done <- struct{}{}
+		signalDone(done)
+		return
+	}
+	netSync.recordTxReceived(peer, msg.Transaction)
+	// Drop transaction if mempool can't take more to verify
+	if netSync.isTxPoolBusy() {
+		netSync.config.TxMemPool.RecordTxEvent(*msg.Transaction.Hash(), mempool.TxEvent{Type: mempool.TxEventRejected, Detail: "dropped while mempool is busy"})
+		signalDone(done)
 		return
 	}
 	netSync.cMessage <- msg
@@ -236,7 +274,14 @@ func (netSync *NetSync) QueueTxToken(peer *peer.Peer, msg *wire.MessageTxToken,
 func (netSync *NetSync) QueueTxPrivacyToken(peer *peer.Peer, msg *wire.MessageTxPrivacyToken, done chan struct{}) {
 	// Don't accept more transactions if we're shutting down.
 	if atomic.LoadInt32(&netSync.shutdown) != 0 {
-		done <- struct{}{}
+		signalDone(done)
+		return
+	}
+	netSync.recordTxReceived(peer, msg.Transaction)
+	// Drop transaction if mempool can't take more to verify
+	if netSync.isTxPoolBusy() {
+		netSync.config.TxMemPool.RecordTxEvent(*msg.Transaction.Hash(), mempool.TxEvent{Type: mempool.TxEventRejected, Detail: "dropped while mempool is busy"})
+		signalDone(done)
 		return
 	}
 	netSync.cMessage <- msg
@@ -250,6 +295,7 @@ func (netSync *NetSync) HandleMessageTx(msg *wire.MessageTx) {
 	}
 	if isAdded := netSync.HandleCacheTx(msg.Transaction); !isAdded {
 		hash, _, err := netSync.config.TxMemPool.MaybeAcceptTransaction(msg.Transaction)
+		netSync.forgetBusyTx(msg.Transaction, err)
 		if err != nil {
 			Logger.log.Error(err)
 
@@ -274,6 +320,7 @@ func (netSync *NetSync) HandleMessageTxToken(msg *wire.MessageTxToken) {
 	}
 	if isAdded := netSync.HandleCacheTx(msg.Transaction); !isAdded {
 		hash, _, err := netSync.config.TxMemPool.MaybeAcceptTransaction(msg.Transaction)
+		netSync.forgetBusyTx(msg.Transaction, err)
 
 		if err != nil {
 			Logger.log.Error(err)
@@ -298,6 +345,7 @@ func (netSync *NetSync) HandleMessageTxPrivacyToken(msg *wire.MessageTxPrivacyTo
 	}
 	if isAdded := netSync.HandleCacheTx(msg.Transaction); !isAdded {
 		hash, _, err := netSync.config.TxMemPool.MaybeAcceptTransaction(msg.Transaction)
+		netSync.forgetBusyTx(msg.Transaction, err)
 		if err != nil {
 			Logger.log.Error(err)
 		} else {
@@ -476,6 +524,34 @@ func (netSync *NetSync) HandleCacheTx(transaction metadata.Transaction) bool {
 	return false
 }
 
+// isTxPoolBusy tells if mempool doesn't take more transactions to verify,
+// transactions of peers are dropped until it catches up
+func (netSync *NetSync) isTxPoolBusy() bool {
+	if netSync.config.TxMemPool.IsAdmissionBusy() {
+		Logger.log.Debug("Mempool admission is busy, drop transaction")
+		return true
+	}
+	return false
+}
+
+// recordTxReceived adds receipt of transaction from peer to tx history of
+// mempool, peer may be unknown
+func (netSync *NetSync) recordTxReceived(peer *peer.Peer, transaction metadata.Transaction) {
+	event := mempool.TxEvent{Type: mempool.TxEventReceived}
+	if peer != nil {
+		event.PeerID = peer.PeerID.Pretty()
+	}
+	netSync.config.TxMemPool.RecordTxEvent(*transaction.Hash(), event)
+}
+
+// forgetBusyTx removes transaction which mempool rejects because admission
+// is busy from cache, so it is handled when a peer sends it again
+func (netSync *NetSync) forgetBusyTx(transaction metadata.Transaction, err error) {
+	if mempoolErr, ok := err.(mempool.MempoolTxError); ok && mempoolErr.Code == mempool.ErrCodeMessage[mempool.AdmissionBusyError].Code {
+		netSync.Cache.txCache.Remove(*transaction.Hash())
+	}
+}
+
 func (netSync *NetSync) HandleCacheTxHash(txHash common.Hash) {
 	netSync.Cache.txCache.Add(txHash, true)
 }
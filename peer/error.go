@@ -12,6 +12,8 @@ const (
 	CreateP2PAddressErr
 	GetPeerIdFromProtocolErr
 	OpeningStreamP2PErr
+	CreateGossipErr
+	GossipErr
 
 	// PeerConn err
 )
@@ -26,6 +28,8 @@ var ErrCodeMessage = map[int]struct {
 	CreateP2PAddressErr:      {-1002, "Can not create libp2p address for node"},
 	GetPeerIdFromProtocolErr: {-1003, "Can not get peer id from protocol"},
 	OpeningStreamP2PErr:      {-1004, "Fail in opening stream "},
+	CreateGossipErr:          {-1005, "Can not create gossip pubsub"},
+	GossipErr:                {-1006, "Gossip pubsub error"},
 
 	// -2xxx for peer connection
 }
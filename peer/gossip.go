@@ -0,0 +1,186 @@
+package peer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/wire"
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/libp2p/go-libp2p-pubsub"
+)
+
+/*
+StartGossip creates gossipsub router on host of peer. After that shard and
+beacon messages are published and received by topics (see wire.GetTopic), so a
+node only receives traffic of the topics it subscribes
+*/
+func (peerObj *Peer) StartGossip() error {
+	pubSub, err := pubsub.NewGossipSub(context.Background(), peerObj.Host)
+	if err != nil {
+		return NewPeerError(CreateGossipErr, err, peerObj)
+	}
+	peerObj.subscriptionsMtx.Lock()
+	defer peerObj.subscriptionsMtx.Unlock()
+	peerObj.pubSub = pubSub
+	peerObj.subscriptions = make(map[string]*pubsub.Subscription)
+	return nil
+}
+
+// GossipEnabled returns true when gossip is started
+func (peerObj *Peer) GossipEnabled() bool {
+	peerObj.subscriptionsMtx.Lock()
+	defer peerObj.subscriptionsMtx.Unlock()
+	return peerObj.pubSub != nil
+}
+
+// PublishMessage publishes a message to a gossip topic
+func (peerObj *Peer) PublishMessage(topic string, msg wire.Message) error {
+	if !peerObj.GossipEnabled() {
+		return NewPeerError(GossipErr, errors.New("gossip is not started"), peerObj)
+	}
+	data, err := encodeGossipMessage(msg)
+	if err != nil {
+		return NewPeerError(GossipErr, err, peerObj)
+	}
+	err = peerObj.pubSub.Publish(topic, data)
+	if err != nil {
+		return NewPeerError(GossipErr, err, peerObj)
+	}
+	return nil
+}
+
+/*
+Subscribe subscribes a gossip topic. Messages of topic are checked by
+ValidateGossipMessage listener before they are relayed to other peers, valid
+messages are passed to OnGossipMessage listener
+*/
+func (peerObj *Peer) Subscribe(topic string) error {
+	peerObj.subscriptionsMtx.Lock()
+	defer peerObj.subscriptionsMtx.Unlock()
+	if peerObj.pubSub == nil {
+		return NewPeerError(GossipErr, errors.New("gossip is not started"), peerObj)
+	}
+	if _, ok := peerObj.subscriptions[topic]; ok {
+		return nil
+	}
+	validator := peerObj.Config.MessageListeners.ValidateGossipMessage
+	if validator != nil {
+		err := peerObj.pubSub.RegisterTopicValidator(topic, func(ctx context.Context, gossipMsg *pubsub.Message) bool {
+			msg, err := decodeGossipMessage(gossipMsg.GetData())
+			if err != nil {
+				Logger.log.Error(err)
+				return false
+			}
+			return validator(topic, msg)
+		})
+		if err != nil {
+			return NewPeerError(GossipErr, err, peerObj)
+		}
+	}
+	sub, err := peerObj.pubSub.Subscribe(topic)
+	if err != nil {
+		if validator != nil {
+			peerObj.pubSub.UnregisterTopicValidator(topic)
+		}
+		return NewPeerError(GossipErr, err, peerObj)
+	}
+	peerObj.subscriptions[topic] = sub
+	go peerObj.handleSubscription(topic, sub)
+	Logger.log.Infof("Subscribed gossip topic %s", topic)
+	return nil
+}
+
+// Unsubscribe cancels subscription of a gossip topic
+func (peerObj *Peer) Unsubscribe(topic string) {
+	peerObj.subscriptionsMtx.Lock()
+	defer peerObj.subscriptionsMtx.Unlock()
+	sub, ok := peerObj.subscriptions[topic]
+	if !ok {
+		return
+	}
+	sub.Cancel()
+	if peerObj.Config.MessageListeners.ValidateGossipMessage != nil {
+		peerObj.pubSub.UnregisterTopicValidator(topic)
+	}
+	delete(peerObj.subscriptions, topic)
+	Logger.log.Infof("Unsubscribed gossip topic %s", topic)
+}
+
+// GetSubscribedTopics returns the gossip topics which peer subscribes
+func (peerObj *Peer) GetSubscribedTopics() []string {
+	peerObj.subscriptionsMtx.Lock()
+	defer peerObj.subscriptionsMtx.Unlock()
+	topics := make([]string, 0, len(peerObj.subscriptions))
+	for topic := range peerObj.subscriptions {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+func (peerObj *Peer) handleSubscription(topic string, sub *pubsub.Subscription) {
+	for {
+		gossipMsg, err := sub.Next(context.Background())
+		if err != nil {
+			// subscription is canceled
+			Logger.log.Infof("Stop handling gossip topic %s: %+v", topic, err)
+			return
+		}
+		if peer.ID(gossipMsg.GetFrom()) == peerObj.PeerID {
+			continue
+		}
+		msg, err := decodeGossipMessage(gossipMsg.GetData())
+		if err != nil {
+			Logger.log.Error(err)
+			continue
+		}
+		if peerObj.Config.MessageListeners.OnGossipMessage != nil {
+			peerObj.Config.MessageListeners.OnGossipMessage(topic, msg)
+		}
+	}
+}
+
+// encodeGossipMessage encodes message as stream messages: json body with 24
+// bytes header, then gzip. Forward bytes of header are not used by gossip
+func encodeGossipMessage(msg wire.Message) ([]byte, error) {
+	messageBytes, err := msg.JsonSerialize()
+	if err != nil {
+		return nil, err
+	}
+	headerBytes := make([]byte, wire.MessageHeaderSize)
+	cmdType, err := wire.GetCmdType(reflect.TypeOf(msg))
+	if err != nil {
+		return nil, err
+	}
+	copy(headerBytes[:], []byte(cmdType))
+	messageBytes = append(messageBytes, headerBytes...)
+	return common.GZipToBytes(messageBytes)
+}
+
+func decodeGossipMessage(data []byte) (wire.Message, error) {
+	jsonDecodeBytes, err := common.GZipFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(jsonDecodeBytes) < wire.MessageHeaderSize {
+		return nil, errors.New("gossip message is too short")
+	}
+	messageBody := jsonDecodeBytes[:len(jsonDecodeBytes)-wire.MessageHeaderSize]
+	messageHeader := jsonDecodeBytes[len(jsonDecodeBytes)-wire.MessageHeaderSize:]
+	commandType := string(bytes.Trim(messageHeader[:wire.MessageCmdTypeSize], "\x00"))
+	message, err := wire.MakeEmptyMessage(commandType)
+	if err != nil {
+		return nil, err
+	}
+	if len(jsonDecodeBytes) > message.MaxPayloadLength(1) {
+		return nil, errors.New("gossip message exceeds max payload of " + commandType)
+	}
+	err = json.Unmarshal(messageBody, &message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
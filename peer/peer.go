@@ -20,6 +20,7 @@ import (
 	"github.com/libp2p/go-libp2p-host"
 	"github.com/libp2p/go-libp2p-net"
 	"github.com/libp2p/go-libp2p-peer"
+	"github.com/libp2p/go-libp2p-pubsub"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/patrickmn/go-cache"
 )
@@ -46,6 +47,7 @@ type Peer struct {
 	RawAddress       string
 	ListeningAddress common.SimpleAddr
 	PublicKey        string
+	AddrSignature    string // signature of PublicKey over wire.AddrSignData of RawAddress
 
 	Seed   int64
 	Config Config
@@ -56,6 +58,11 @@ type Peer struct {
 	PendingPeers    map[string]*Peer
 	pendingPeersMtx sync.Mutex
 
+	// gossip pubsub, nil if gossip is not started
+	pubSub           *pubsub.PubSub
+	subscriptions    map[string]*pubsub.Subscription
+	subscriptionsMtx sync.Mutex
+
 	HandleConnected    func(peerConn *PeerConn)
 	HandleDisconnected func(peerConn *PeerConn)
 	HandleFailed       func(peerConn *PeerConn)
@@ -108,12 +115,21 @@ type MessageListeners struct {
 	OnGetAddr          func(p *PeerConn, msg *wire.MessageGetAddr)
 	OnAddr             func(p *PeerConn, msg *wire.MessageAddr)
 
+	// compact block relay
+	OnBlockShardCompact func(p *PeerConn, msg *wire.MessageBlockShardCompact)
+	OnGetBlockTxs       func(p *PeerConn, msg *wire.MessageGetBlockTxs)
+	OnBlockTxs          func(p *PeerConn, msg *wire.MessageBlockTxs)
+
 	//PBFT
 	OnBFTMsg             func(p *PeerConn, msg wire.Message)
 	OnPeerState          func(p *PeerConn, msg *wire.MessagePeerState)
 	PushRawBytesToShard  func(p *PeerConn, msgBytes *[]byte, shard byte) error
 	PushRawBytesToBeacon func(p *PeerConn, msgBytes *[]byte) error
 	GetCurrentRoleShard  func() (string, *byte)
+
+	// gossip pubsub
+	OnGossipMessage       func(topic string, msg wire.Message)
+	ValidateGossipMessage func(topic string, msg wire.Message) bool
 }
 
 // outMsg is used to house a message to be sent along with a channel to signal
@@ -173,9 +189,13 @@ func (peerObj Peer) NewPeer() (*Peer, error) {
 	port := strings.Split(peerObj.ListeningAddress.String(), ":")[1]
 	net := peerObj.ListeningAddress.Network()
 	listeningAddressString := fmt.Sprintf("/%s/%s/tcp/%s", net, ip, port)
+	// secio encrypts streams and authenticates peer id of remote side by its
+	// libp2p key, committee public key is bound to this peer id by version
+	// handshake
 	opts := []libp2p.Option{
 		libp2p.ListenAddrStrings(listeningAddressString),
 		libp2p.Identity(priv),
+		libp2p.DefaultSecurity,
 	}
 
 	basicHost, err := libp2p.New(context.Background(), opts...)
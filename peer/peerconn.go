@@ -3,6 +3,7 @@ package peer
 import (
 	"bufio"
 	"bytes"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -38,6 +39,21 @@ type PeerConn struct {
 	isOutboundMtx    sync.Mutex
 	isForceClose     bool
 	isForceCloseMtx  sync.Mutex
+	// compactBlock is set when remote peer accepts compact shard blocks, it
+	// is negotiated by version message
+	compactBlock    bool
+	compactBlockMtx sync.Mutex
+	// archive is set when remote peer keeps full history of blocks
+	archive    bool
+	archiveMtx sync.Mutex
+	// gossip is set when both sides use gossip topics, then messages which
+	// are published to topics are not pushed to remote peer directly
+	gossip    bool
+	gossipMtx sync.Mutex
+	// challenge is a random nonce which is sent in version message, remote
+	// peer proves its public key by signing it in verack message
+	challenge    string
+	challengeMtx sync.Mutex
 
 	RWStream       *bufio.ReadWriter
 	VerValid       bool
@@ -65,6 +81,58 @@ func (peerConn *PeerConn) SetIsOutbound(v bool) {
 	peerConn.isOutbound = v
 }
 
+// GetChallenge returns the handshake challenge of connection, it is created
+// on first call
+func (peerConn *PeerConn) GetChallenge() (string, error) {
+	peerConn.challengeMtx.Lock()
+	defer peerConn.challengeMtx.Unlock()
+	if peerConn.challenge == "" {
+		nonce := make([]byte, common.HashSize)
+		_, err := rand.Read(nonce)
+		if err != nil {
+			return "", err
+		}
+		peerConn.challenge = hex.EncodeToString(nonce)
+	}
+	return peerConn.challenge, nil
+}
+
+func (peerConn *PeerConn) GetCompactBlock() bool {
+	peerConn.compactBlockMtx.Lock()
+	defer peerConn.compactBlockMtx.Unlock()
+	return peerConn.compactBlock
+}
+
+func (peerConn *PeerConn) SetCompactBlock(v bool) {
+	peerConn.compactBlockMtx.Lock()
+	defer peerConn.compactBlockMtx.Unlock()
+	peerConn.compactBlock = v
+}
+
+func (peerConn *PeerConn) GetArchive() bool {
+	peerConn.archiveMtx.Lock()
+	defer peerConn.archiveMtx.Unlock()
+	return peerConn.archive
+}
+
+func (peerConn *PeerConn) SetArchive(v bool) {
+	peerConn.archiveMtx.Lock()
+	defer peerConn.archiveMtx.Unlock()
+	peerConn.archive = v
+}
+
+func (peerConn *PeerConn) GetGossip() bool {
+	peerConn.gossipMtx.Lock()
+	defer peerConn.gossipMtx.Unlock()
+	return peerConn.gossip
+}
+
+func (peerConn *PeerConn) SetGossip(v bool) {
+	peerConn.gossipMtx.Lock()
+	defer peerConn.gossipMtx.Unlock()
+	peerConn.gossip = v
+}
+
 func (peerConn *PeerConn) GetIsForceClose() bool {
 	peerConn.isForceCloseMtx.Lock()
 	defer peerConn.isForceCloseMtx.Unlock()
@@ -274,9 +342,21 @@ func (peerConn *PeerConn) InMessageHandler(rw *bufio.ReadWriter) {
 						peerConn.Config.MessageListeners.OnGetAddr(peerConn, message.(*wire.MessageGetAddr))
 					}
 				case reflect.TypeOf(&wire.MessageAddr{}):
-					if peerConn.Config.MessageListeners.OnGetAddr != nil {
+					if peerConn.Config.MessageListeners.OnAddr != nil {
 						peerConn.Config.MessageListeners.OnAddr(peerConn, message.(*wire.MessageAddr))
 					}
+				case reflect.TypeOf(&wire.MessageBlockShardCompact{}):
+					if peerConn.Config.MessageListeners.OnBlockShardCompact != nil {
+						peerConn.Config.MessageListeners.OnBlockShardCompact(peerConn, message.(*wire.MessageBlockShardCompact))
+					}
+				case reflect.TypeOf(&wire.MessageGetBlockTxs{}):
+					if peerConn.Config.MessageListeners.OnGetBlockTxs != nil {
+						peerConn.Config.MessageListeners.OnGetBlockTxs(peerConn, message.(*wire.MessageGetBlockTxs))
+					}
+				case reflect.TypeOf(&wire.MessageBlockTxs{}):
+					if peerConn.Config.MessageListeners.OnBlockTxs != nil {
+						peerConn.Config.MessageListeners.OnBlockTxs(peerConn, message.(*wire.MessageBlockTxs))
+					}
 				case reflect.TypeOf(&wire.MessageBFTPropose{}):
 					if peerConn.Config.MessageListeners.OnBFTMsg != nil {
 						peerConn.Config.MessageListeners.OnBFTMsg(peerConn, message.(*wire.MessageBFTPropose))
@@ -7,6 +7,7 @@ import (
 	"github.com/constant-money/constant-chain/cashec"
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/common/base58"
+	"github.com/constant-money/constant-chain/mempool"
 	"github.com/constant-money/constant-chain/metadata"
 	"github.com/constant-money/constant-chain/privacy"
 	"github.com/constant-money/constant-chain/transaction"
@@ -92,7 +93,9 @@ func (rpcServer RpcServer) makeArrayInputCoinHashHs(inputCoins []*privacy.InputC
 	return inCoinHs
 }
 
-func (rpcServer RpcServer) buildRawTransaction(params interface{}, meta metadata.Metadata) (*transaction.Tx, *RPCError) {
+// buildRawTransaction builds a tx which may be in blocks up to expiryHeight,
+// 0 is no expiry
+func (rpcServer RpcServer) buildRawTransaction(params interface{}, meta metadata.Metadata, expiryHeight uint64) (*transaction.Tx, *RPCError) {
 	Logger.log.Infof("Params: \n%+v\n\n\n", params)
 
 	/******* START Fetch all component to ******/
@@ -149,7 +152,7 @@ func (rpcServer RpcServer) buildRawTransaction(params interface{}, meta metadata
 	// missing flag for privacy
 	// false by default
 	//fmt.Printf("#inputCoins: %d\n", len(inputCoins))
-	tx := transaction.Tx{}
+	tx := transaction.Tx{ExpiryHeight: expiryHeight}
 	err = tx.Init(
 		&senderKeySet.PrivateKey,
 		paymentInfos,
@@ -494,12 +497,12 @@ func (rpcServer RpcServer) buildRawPrivacyCustomTokenTransaction(
 	return tx, nil
 }
 
-// estimateFeeWithEstimator - only estimate fee by estimator and return fee per kb
-func (rpcServer RpcServer) estimateFeeWithEstimator(defaultFee int64, shardID byte, numBlock uint64) uint64 {
+// estimateFeeWithEstimator - only estimate fee of txs of fee model by estimator and return fee per kb
+func (rpcServer RpcServer) estimateFeeWithEstimator(defaultFee int64, shardID byte, numBlock uint64, feeModel mempool.FeeModel) uint64 {
 	estimateFeeCoinPerKb := uint64(0)
 	if defaultFee == -1 {
 		if _, ok := rpcServer.config.FeeEstimator[shardID]; ok {
-			temp, _ := rpcServer.config.FeeEstimator[shardID].EstimateFee(numBlock)
+			temp, _ := rpcServer.config.FeeEstimator[shardID].EstimateFee(feeModel, numBlock)
 			estimateFeeCoinPerKb = uint64(temp)
 		}
 		if estimateFeeCoinPerKb == 0 {
@@ -526,7 +529,16 @@ func (rpcServer RpcServer) estimateFee(defaultFee int64, candidateOutputCoins []
 	estimateFeeCoinPerKb := uint64(0)
 	estimateTxSizeInKb := uint64(0)
 
-	estimateFeeCoinPerKb = rpcServer.estimateFeeWithEstimator(defaultFee, shardID, numBlock)
+	// txs with privacy or token data are much bigger, their fee rates are
+	// estimated apart
+	txType := common.TxNormalType
+	if customTokenParams != nil {
+		txType = common.TxCustomTokenType
+	} else if privacyCustomTokenParams != nil {
+		txType = common.TxCustomTokenPrivacyType
+	}
+	feeModel := mempool.NewFeeModel(txType, hasPrivacy)
+	estimateFeeCoinPerKb = rpcServer.estimateFeeWithEstimator(defaultFee, shardID, numBlock, feeModel)
 
 	if rpcServer.config.Wallet != nil {
 		estimateFeeCoinPerKb += uint64(rpcServer.config.Wallet.GetConfig().IncrementalFee)
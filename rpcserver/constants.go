@@ -11,6 +11,7 @@ const (
 
 	EstimateFee              = "estimatefee"
 	EstimateFeeWithEstimator = "estimatefeewithestimator"
+	EstimateSmartFee         = "estimatesmartfee"
 
 	GetActiveShards    = "getactiveshards"
 	GetMaxShardsNumber = "getmaxshardsnumber"
@@ -19,6 +20,9 @@ const (
 	GetRawMempool                 = "getrawmempool"
 	GetNumberOfTxsInMempool       = "getnumberoftxsinmempool"
 	GetMempoolEntry               = "getmempoolentry"
+	TestMempoolAccept             = "testmempoolaccept"
+	RemoveTxFromMempool           = "removetxfrommempool"
+	GetTxHistory                  = "gettxhistory"
 	GetBeaconPoolState            = "getbeaconpoolstate"
 	GetShardPoolState             = "getshardpoolstate"
 	GetShardPoolLatestValidHeight = "getshardpoollatestvalidheight"
@@ -43,6 +47,7 @@ const (
 	CreateRawTransaction                       = "createtransaction"
 	SendRawTransaction                         = "sendtransaction"
 	CreateAndSendTransaction                   = "createandsendtransaction"
+	BumpFee                                    = "bumpfee"
 	CreateAndSendCustomTokenTransaction        = "createandsendcustomtokentransaction"
 	SendRawCustomTokenTransaction              = "sendrawcustomtokentransaction"
 	CreateRawCustomTokenTransaction            = "createrawcustomtokentransaction"
@@ -115,4 +120,13 @@ const (
 	CreateAndSendIssuingRequest     = "createandsendissuingrequest"
 	CreateAndSendContractingRequest = "createandsendcontractingrequest"
 	GetBridgeTokensAmounts          = "getbridgetokensamounts"
+
+	// database
+	BackupDB        = "backupdb"
+	ExportBlocks    = "exportblocks"
+	ImportBlocks    = "importblocks"
+	RollbackChain   = "rollbackchain"
+	GetDBCacheStats = "getdbcachestats"
+	SaveMempool     = "savemempool"
+	LoadMempool     = "loadmempool"
 )
@@ -27,6 +27,7 @@ const (
 	ErrTxTypeInvalid
 	ErrRejectInvalidFee
 	ErrTxNotExistedInMemAndBLock
+	ErrDatabase
 )
 
 // Standard JSON-RPC 2.0 errors.
@@ -60,6 +61,7 @@ var ErrCodeMessage = map[int]struct {
 	// processing -2xxx
 	ErrCreateTxData: {-2001, "Can not create tx"},
 	ErrSendTxData:   {-2002, "Can not send tx"},
+	ErrDatabase:     {-2003, "Database error"},
 }
 
 // RPCError represents an error that is used as a part of a JSON-RPC Response
@@ -0,0 +1,6 @@
+package jsonresult
+
+type BlockFileResult struct {
+	Path   string `json:"Path"`
+	Blocks uint64 `json:"Blocks"`
+}
@@ -4,3 +4,22 @@ type EstimateFeeResult struct {
 	EstimateFeeCoinPerKb uint64
 	EstimateTxSizeInKb   uint64
 }
+
+// EstimateSmartFeeResult is fee estimates of every fee model of txs of a
+// shard to be confirmed in NumBlocks blocks
+type EstimateSmartFeeResult struct {
+	ShardID    byte
+	NumBlocks  uint64
+	Confidence float64
+	Estimates  []FeeModelEstimate
+}
+
+// FeeModelEstimate is the median fee per kb of txs of a fee model which are
+// confirmed in the target number of blocks, and the lowest fee per kb which
+// is confirmed in them with confidence
+type FeeModelEstimate struct {
+	FeeModel              string
+	FeeCoinPerKb          uint64
+	ConfidentFeeCoinPerKb uint64
+	Errors                []string `json:",omitempty"`
+}
@@ -0,0 +1,13 @@
+package jsonresult
+
+type DBCacheKindStats struct {
+	Hits    uint64  `json:"Hits"`
+	Misses  uint64  `json:"Misses"`
+	HitRate float64 `json:"HitRate"`
+}
+
+type GetDBCacheStatsResult struct {
+	Size     int                         `json:"Size"`
+	Capacity int                         `json:"Capacity"`
+	Kinds    map[string]DBCacheKindStats `json:"Kinds"`
+}
@@ -3,13 +3,25 @@ package jsonresult
 import "github.com/constant-money/constant-chain/metadata"
 
 type GetMempoolInfo struct {
-	Size          int                `json:"Size"`
-	Bytes         uint64             `json:"Bytes"`
-	Usage         uint64             `json:"Usage"`
-	MaxMempool    uint64             `json:"MaxMempool"`
-	MempoolMinFee uint64             `json:"MempoolMinFee"`
-	MempoolMaxFee uint64             `json:"MempoolMaxFee"`
-	ListTxs       []GetMempoolInfoTx `json:"ListTxs"`
+	Size          int                   `json:"Size"`
+	Bytes         uint64                `json:"Bytes"`
+	Usage         uint64                `json:"Usage"`
+	MaxMempool    uint64                `json:"MaxMempool"`
+	MempoolMinFee uint64                `json:"MempoolMinFee"`
+	MempoolMaxFee uint64                `json:"MempoolMaxFee"`
+	ListTxs       []GetMempoolInfoTx    `json:"ListTxs"`
+	Shards        []GetMempoolInfoShard `json:"Shards"`
+}
+
+// GetMempoolInfoShard is number and size of txs of a sender shard in pool
+// with limits of pool for the shard
+type GetMempoolInfoShard struct {
+	ShardID    byte   `json:"ShardID"`
+	Size       int    `json:"Size"`
+	Bytes      uint64 `json:"Bytes"`
+	MaxTx      uint64 `json:"MaxTx"`
+	MaxBytes   uint64 `json:"MaxBytes"`
+	TxLifeTime uint   `json:"TxLifeTime"`
 }
 
 type GetMempoolInfoTx struct {
@@ -17,10 +29,74 @@ type GetMempoolInfoTx struct {
 	LockTime int64  `json:"LockTime"`
 }
 
+// RemoveTxFromMempoolResult is ids of txs which are removed from mempool, the
+// requested tx and txs which spend its output coins
+type RemoveTxFromMempoolResult struct {
+	Removed []string `json:"Removed"`
+}
+
+// GetTxHistoryResult is lifecycle history of a tx which mempool keeps, from
+// the oldest event
+type GetTxHistoryResult struct {
+	TxID      string          `json:"TxID"`
+	InMempool bool            `json:"InMempool"`
+	Events    []TxEventResult `json:"Events"`
+}
+
+// TxEventResult is a step of lifecycle of a tx
+type TxEventResult struct {
+	Type   string `json:"Type"`
+	Time   int64  `json:"Time"`
+	PeerID string `json:"PeerID,omitempty"`
+	Height uint64 `json:"Height,omitempty"`
+	Detail string `json:"Detail,omitempty"`
+}
+
+// SaveMempoolResult is number of txs of mempool which are saved to mempool
+// database
+type SaveMempoolResult struct {
+	Saved int `json:"Saved"`
+}
+
+// LoadMempoolResult is number of txs which are loaded from mempool database
+// and reasons of txs which are rejected by tx id
+type LoadMempoolResult struct {
+	Loaded   int               `json:"Loaded"`
+	Rejected map[string]string `json:"Rejected"`
+}
+
 type GetRawMempoolResult struct {
 	TxHashes []string
 }
 
 type GetMempoolEntryResult struct {
-	Tx metadata.Transaction
+	Tx     metadata.Transaction
+	Reject *MempoolRejectResult `json:",omitempty"` // reason why tx was rejected if it isn't in pool
+}
+
+// MempoolRejectResult is the reason why mempool rejects a tx
+type MempoolRejectResult struct {
+	Code       int    `json:"Code"`
+	Message    string `json:"Message"`
+	Reason     string `json:"Reason"`
+	RejectTime int64  `json:"RejectTime,omitempty"`
+}
+
+func NewMempoolRejectResult(code int, message string, reason string) *MempoolRejectResult {
+	return &MempoolRejectResult{
+		Code:    code,
+		Message: message,
+		Reason:  reason,
+	}
+}
+
+// TestMempoolAcceptResult tells if mempool accepts a tx without adding it to
+// mempool
+type TestMempoolAcceptResult struct {
+	TxID     string               `json:"TxID"`
+	ShardID  byte                 `json:"ShardID"`
+	Allowed  bool                 `json:"Allowed"`
+	Fee      uint64               `json:"Fee"`
+	FeePerKB uint64               `json:"FeePerKB"`
+	Reject   *MempoolRejectResult `json:"Reject,omitempty"`
 }
@@ -30,6 +30,8 @@ type TransactionDetail struct {
 
 	IsInMempool bool `json:"IsInMempool"`
 	IsInBlock   bool `json:"IsInBlock"`
+
+	ExpiryHeight uint64 `json:"ExpiryHeight,omitempty"`
 }
 
 type ProofDetail struct {
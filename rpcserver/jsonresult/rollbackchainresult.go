@@ -0,0 +1,7 @@
+package jsonresult
+
+type RollbackChainResult struct {
+	ShardID int    `json:"ShardID"`
+	Height  uint64 `json:"Height"`
+	Hash    string `json:"Hash"`
+}
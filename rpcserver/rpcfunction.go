@@ -1,12 +1,14 @@
 package rpcserver
 
 import (
+	"fmt"
 	"log"
 	"net"
 	"os"
 
 	"github.com/constant-money/constant-chain/common"
 	"github.com/constant-money/constant-chain/common/base58"
+	"github.com/constant-money/constant-chain/mempool"
 	"github.com/constant-money/constant-chain/metadata"
 	"github.com/constant-money/constant-chain/privacy"
 	"github.com/constant-money/constant-chain/rpcserver/jsonresult"
@@ -28,6 +30,7 @@ var RpcHandler = map[string]commandHandler{
 	GetAllPeers:              RpcServer.handleGetAllPeers,
 	EstimateFee:              RpcServer.handleEstimateFee,
 	EstimateFeeWithEstimator: RpcServer.handleEstimateFeeWithEstimator,
+	EstimateSmartFee:         RpcServer.handleEstimateSmartFee,
 	GetActiveShards:          RpcServer.handleGetActiveShards,
 	GetMaxShardsNumber:       RpcServer.handleGetMaxShardsNumber,
 
@@ -36,6 +39,8 @@ var RpcHandler = map[string]commandHandler{
 	GetRawMempool:               RpcServer.handleGetRawMempool,
 	GetNumberOfTxsInMempool:     RpcServer.handleGetNumberOfTxsInMempool,
 	GetMempoolEntry:             RpcServer.handleMempoolEntry,
+	TestMempoolAccept:           RpcServer.handleTestMempoolAccept,
+	GetTxHistory:                RpcServer.handleGetTxHistory,
 	GetShardToBeaconPoolStateV2: RpcServer.handleGetShardToBeaconPoolStateV2,
 	GetCrossShardPoolStateV2:    RpcServer.handleGetCrossShardPoolStateV2,
 	GetShardPoolStateV2:         RpcServer.handleGetShardPoolStateV2,
@@ -61,6 +66,7 @@ var RpcHandler = map[string]commandHandler{
 	CreateRawTransaction:            RpcServer.handleCreateRawTransaction,
 	SendRawTransaction:              RpcServer.handleSendRawTransaction,
 	CreateAndSendTransaction:        RpcServer.handleCreateAndSendTx,
+	BumpFee:                         RpcServer.handleBumpFee,
 	GetMempoolInfo:                  RpcServer.handleGetMempoolInfo,
 	GetTransactionByHash:            RpcServer.handleGetTransactionByHash,
 	CreateAndSendStakingTransaction: RpcServer.handleCreateAndSendStakingTx,
@@ -117,6 +123,9 @@ var RpcHandler = map[string]commandHandler{
 	GetStackingAmount: RpcServer.handleGetStakingAmount,
 
 	HashToIdenticon: RpcServer.handleHashToIdenticon,
+
+	// database
+	GetDBCacheStats: RpcServer.handleGetDBCacheStats,
 }
 
 // Commands that are available to a limited user
@@ -136,6 +145,19 @@ var RpcLimited = map[string]commandHandler{
 	GetReceivedByAccount:               RpcServer.handleGetReceivedByAccount,
 	SetTxFee:                           RpcServer.handleSetTxFee,
 	GetRecentTransactionsByBlockNumber: RpcServer.handleGetRecentTransactionsByBlockNumber,
+
+	// pool, txs of node are removed
+	RemoveTxFromMempool: RpcServer.handleRemoveTxFromMempool,
+
+	// database, blocks of node are reverted
+	RollbackChain: RpcServer.handleRollbackChain,
+
+	// database, files are read and written on node
+	BackupDB:     RpcServer.handleBackupDB,
+	ExportBlocks: RpcServer.handleExportBlocks,
+	ImportBlocks: RpcServer.handleImportBlocks,
+	SaveMempool:  RpcServer.handleSaveMempool,
+	LoadMempool:  RpcServer.handleLoadMempool,
 }
 
 /*
@@ -400,6 +422,7 @@ func (rpcServer RpcServer) handleGetNumberOfTxsInMempool(params interface{}, clo
 
 /*
 handleMempoolEntry - RPC fetch a specific transaction from the mempool
+If the transaction is not in mempool but was rejected recently, the reason of reject is returned
 */
 func (rpcServer RpcServer) handleMempoolEntry(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
 	Logger.log.Infof("handleMempoolEntry params: %+v", params)
@@ -416,8 +439,13 @@ func (rpcServer RpcServer) handleMempoolEntry(params interface{}, closeChan <-ch
 	result := jsonresult.GetMempoolEntryResult{}
 	result.Tx, err = rpcServer.config.TxMemPool.GetTx(txID)
 	if err != nil {
-		Logger.log.Infof("handleMempoolEntry result: nil %+v", err)
-		return nil, NewRPCError(ErrUnexpected, err)
+		rejectedTx, ok := rpcServer.config.TxMemPool.GetRecentReject(*txID)
+		if !ok {
+			Logger.log.Infof("handleMempoolEntry result: nil %+v", err)
+			return nil, NewRPCError(ErrUnexpected, err)
+		}
+		result.Reject = jsonresult.NewMempoolRejectResult(rejectedTx.Code, rejectedTx.Message, rejectedTx.Err)
+		result.Reject.RejectTime = rejectedTx.Time.Unix()
 	}
 	Logger.log.Infof("handleMempoolEntry result: %+v", result)
 	return result, nil
@@ -550,8 +578,20 @@ func (rpcServer RpcServer) handleEstimateFeeWithEstimator(params interface{}, cl
 	lastByte := senderKeySet.PaymentAddress.Pk[len(senderKeySet.PaymentAddress.Pk)-1]
 	shardIDSender := common.GetShardIDFromLastByte(lastByte)
 
-	// param #2: numblocl
-	estimateFeeCoinPerKb := rpcServer.estimateFeeWithEstimator(defaultFeeCoinPerKb, shardIDSender, 8)
+	// param #3: fee model of tx, optional
+	feeModel := mempool.FeeModelNormal
+	if len(arrayParams) > 2 {
+		feeModelParam, ok := arrayParams[2].(string)
+		if !ok {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("fee model is invalid"))
+		}
+		feeModel, err = mempool.ParseFeeModel(feeModelParam)
+		if err != nil {
+			return nil, NewRPCError(ErrRPCInvalidParams, err)
+		}
+	}
+
+	estimateFeeCoinPerKb := rpcServer.estimateFeeWithEstimator(defaultFeeCoinPerKb, shardIDSender, 8, feeModel)
 
 	result := jsonresult.EstimateFeeResult{
 		EstimateFeeCoinPerKb: estimateFeeCoinPerKb,
@@ -560,6 +600,78 @@ func (rpcServer RpcServer) handleEstimateFeeWithEstimator(params interface{}, cl
 	return result, nil
 }
 
+/*
+handleEstimateSmartFee - RPC estimates fee per kilobyte of every fee model of
+txs in shard of an address to be included within a certain number of blocks,
+as a median and as the lowest fee rate which is confirmed with confidence
+*/
+func (rpcServer RpcServer) handleEstimateSmartFee(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleEstimateSmartFee params: %+v", params)
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Not enough params"))
+	}
+	// param #1: payment address or private key of sender
+	senderKeyParam, ok := arrayParams[0].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Sender key is invalid"))
+	}
+	senderKeySet, err := rpcServer.GetKeySetFromKeyParams(senderKeyParam)
+	if err != nil {
+		return nil, NewRPCError(ErrInvalidSenderPrivateKey, err)
+	}
+	lastByte := senderKeySet.PaymentAddress.Pk[len(senderKeySet.PaymentAddress.Pk)-1]
+	shardIDSender := common.GetShardIDFromLastByte(lastByte)
+
+	// param #2: number of blocks, optional
+	numBlocks := uint64(8)
+	if len(arrayParams) > 1 {
+		numBlocksParam, ok := arrayParams[1].(float64)
+		if !ok || numBlocksParam < 1 {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("number of blocks is invalid"))
+		}
+		numBlocks = uint64(numBlocksParam)
+	}
+
+	// param #3: confidence in (0, 1], optional
+	confidence := mempool.DefaultEstimateFeeConfidence
+	if len(arrayParams) > 2 {
+		confidence, ok = arrayParams[2].(float64)
+		if !ok || confidence <= 0 || confidence > 1 {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("confidence is invalid"))
+		}
+	}
+
+	feeEstimator, ok := rpcServer.config.FeeEstimator[shardIDSender]
+	if !ok {
+		return nil, NewRPCError(ErrUnexpected, fmt.Errorf("shard %d has no fee estimator", shardIDSender))
+	}
+	result := jsonresult.EstimateSmartFeeResult{
+		ShardID:    shardIDSender,
+		NumBlocks:  numBlocks,
+		Confidence: confidence,
+		Estimates:  []jsonresult.FeeModelEstimate{},
+	}
+	for _, feeModel := range mempool.FeeModels() {
+		estimate := jsonresult.FeeModelEstimate{FeeModel: feeModel.String()}
+		feeCoinPerKb, err := feeEstimator.EstimateFee(feeModel, numBlocks)
+		if err != nil {
+			estimate.Errors = append(estimate.Errors, err.Error())
+		} else {
+			estimate.FeeCoinPerKb = uint64(feeCoinPerKb)
+		}
+		confidentFeeCoinPerKb, err := feeEstimator.EstimateFeeWithConfidence(feeModel, numBlocks, confidence)
+		if err != nil {
+			estimate.Errors = append(estimate.Errors, err.Error())
+		} else {
+			estimate.ConfidentFeeCoinPerKb = uint64(confidentFeeCoinPerKb)
+		}
+		result.Estimates = append(result.Estimates, estimate)
+	}
+	Logger.log.Infof("handleEstimateSmartFee result: %+v", result)
+	return result, nil
+}
+
 // handleGetActiveShards - return active shard num
 func (rpcServer RpcServer) handleGetActiveShards(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
 	Logger.log.Infof("handleGetActiveShards params: %+v", params)
@@ -0,0 +1,196 @@
+package rpcserver
+
+import (
+	"errors"
+	"os"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/database"
+	"github.com/constant-money/constant-chain/rpcserver/jsonresult"
+)
+
+func pathParam(params interface{}) (string, bool) {
+	paramsArray := common.InterfaceSlice(params)
+	if len(paramsArray) < 1 {
+		return "", false
+	}
+	path, ok := paramsArray[0].(string)
+	return path, ok && path != ""
+}
+
+/*
+handleBackupDB - RPC writes a consistent copy of chain database to a
+directory or a .tar.gz file on node
+*/
+func (rpcServer RpcServer) handleBackupDB(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleBackupDB params: %+v", params)
+	path, ok := pathParam(params)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("backup path is invalid"))
+	}
+	err := (*rpcServer.config.Database).Backup(path)
+	if err != nil {
+		Logger.log.Infof("handleBackupDB result: %+v", err)
+		return nil, NewRPCError(ErrDatabase, err)
+	}
+	Logger.log.Infof("handleBackupDB result: %+v", path)
+	return path, nil
+}
+
+/*
+handleExportBlocks - RPC writes all beacon and shard blocks to a block file
+on node
+*/
+func (rpcServer RpcServer) handleExportBlocks(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleExportBlocks params: %+v", params)
+	path, ok := pathParam(params)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("block file path is invalid"))
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	defer file.Close()
+	count, err := blockchain.ExportBlocks(*rpcServer.config.Database, rpcServer.config.ChainParams.Name, file)
+	if err != nil {
+		Logger.log.Infof("handleExportBlocks result: %+v", err)
+		return nil, NewRPCError(ErrDatabase, err)
+	}
+	result := jsonresult.BlockFileResult{Path: path, Blocks: count}
+	Logger.log.Infof("handleExportBlocks result: %+v", result)
+	return result, nil
+}
+
+/*
+handleImportBlocks - RPC inserts blocks of a block file on node, blocks are
+verified before they are inserted
+*/
+func (rpcServer RpcServer) handleImportBlocks(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleImportBlocks params: %+v", params)
+	path, ok := pathParam(params)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("block file path is invalid"))
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	defer file.Close()
+	count, err := rpcServer.config.BlockChain.ImportBlocks(file)
+	result := jsonresult.BlockFileResult{Path: path, Blocks: count}
+	if err != nil {
+		Logger.log.Infof("handleImportBlocks result: %+v, %+v", result, err)
+		return nil, NewRPCError(ErrDatabase, err)
+	}
+	Logger.log.Infof("handleImportBlocks result: %+v", result)
+	return result, nil
+}
+
+/*
+handleRollbackChain - RPC reverts best blocks of beacon chain or of a shard
+with their undo logs, params are shard id (-1 for beacon) and number of
+blocks
+*/
+func (rpcServer RpcServer) handleRollbackChain(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleRollbackChain params: %+v", params)
+	paramsArray := common.InterfaceSlice(params)
+	if len(paramsArray) != 2 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("params must be shard id and number of blocks"))
+	}
+	shardIDParam, ok := paramsArray[0].(float64)
+	if !ok || shardIDParam < -1 || shardIDParam >= common.MAX_SHARD_NUMBER {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("shardID is invalid"))
+	}
+	countParam, ok := paramsArray[1].(float64)
+	if !ok || countParam < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("number of blocks is invalid"))
+	}
+	shardID := int(shardIDParam)
+	count := uint64(countParam)
+
+	chain := rpcServer.config.BlockChain
+	var err error
+	result := jsonresult.RollbackChainResult{ShardID: shardID}
+	if shardID == -1 {
+		err = chain.RevertBeaconBlocks(count)
+		result.Height = chain.BestState.Beacon.BeaconHeight
+		result.Hash = chain.BestState.Beacon.BestBlockHash.String()
+	} else {
+		err = chain.RevertShardBlocks(byte(shardID), count)
+		if bestState, ok := chain.BestState.Shard[byte(shardID)]; ok {
+			result.Height = bestState.ShardHeight
+			result.Hash = bestState.BestBlockHash.String()
+		}
+	}
+	if err != nil {
+		Logger.log.Infof("handleRollbackChain result: %+v, %+v", result, err)
+		return nil, NewRPCError(ErrDatabase, err)
+	}
+	Logger.log.Infof("handleRollbackChain result: %+v", result)
+	return result, nil
+}
+
+/*
+handleGetDBCacheStats - RPC returns size of database cache and its hits and
+misses of every kind of lookup
+*/
+func (rpcServer RpcServer) handleGetDBCacheStats(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	cache, ok := (*rpcServer.config.Database).(*database.CacheDB)
+	if !ok {
+		return nil, NewRPCError(ErrDatabase, errors.New("database cache is disabled"))
+	}
+	stats := cache.Stats()
+	result := jsonresult.GetDBCacheStatsResult{
+		Size:     stats.Size,
+		Capacity: stats.Capacity,
+		Kinds:    make(map[string]jsonresult.DBCacheKindStats, len(stats.Kinds)),
+	}
+	for kind, kindStats := range stats.Kinds {
+		hitRate := float64(0)
+		if lookups := kindStats.Hits + kindStats.Misses; lookups > 0 {
+			hitRate = float64(kindStats.Hits) / float64(lookups)
+		}
+		result.Kinds[kind] = jsonresult.DBCacheKindStats{
+			Hits:    kindStats.Hits,
+			Misses:  kindStats.Misses,
+			HitRate: hitRate,
+		}
+	}
+	return result, nil
+}
+
+/*
+handleSaveMempool - RPC saves a snapshot of txs of mempool to mempool
+database, txs in database which aren't in mempool are removed
+*/
+func (rpcServer RpcServer) handleSaveMempool(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	count, err := rpcServer.config.TxMemPool.SaveDatabaseMP()
+	if err != nil {
+		return nil, NewRPCError(ErrDatabase, err)
+	}
+	Logger.log.Infof("Save %d transactions of mempool", count)
+	return jsonresult.SaveMempoolResult{Saved: count}, nil
+}
+
+/*
+handleLoadMempool - RPC loads txs of mempool database into mempool, txs are
+validated with current best state and the reasons of rejected txs are
+returned
+*/
+func (rpcServer RpcServer) handleLoadMempool(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	report, err := rpcServer.config.TxMemPool.LoadDatabaseMP()
+	if err != nil {
+		return nil, NewRPCError(ErrDatabase, err)
+	}
+	result := jsonresult.LoadMempoolResult{
+		Loaded:   report.Loaded,
+		Rejected: make(map[string]string, len(report.Rejected)),
+	}
+	for txHash, reason := range report.Rejected {
+		result.Rejected[txHash.String()] = reason
+	}
+	Logger.log.Infof("Load %d transactions into mempool, %d rejected", result.Loaded, len(result.Rejected))
+	return result, nil
+}
@@ -123,11 +123,24 @@ func (rpcServer RpcServer) handleListOutputCoins(params interface{}, closeChan <
 
 /*
 // handleCreateTransaction handles createtransaction commands.
+Parameter #5—shard height of the last block which tx may be in, optional,
+0 is no expiry
 */
 func (rpcServer RpcServer) handleCreateRawTransaction(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
 	Logger.log.Infof("handleCreateRawTransaction params: %+v", params)
 	var err error
-	tx, err := rpcServer.buildRawTransaction(params, nil)
+	expiryHeight := uint64(0)
+	if arrayParams := common.InterfaceSlice(params); len(arrayParams) > 4 && arrayParams[4] != nil {
+		expiryHeightParam, ok := arrayParams[4].(float64)
+		if !ok || expiryHeightParam < 0 {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("expiry height is invalid"))
+		}
+		expiryHeight = uint64(expiryHeightParam)
+		if expiryHeight > 0 && !rpcServer.config.ChainParams.IsExpiryHeightActive(expiryHeight) {
+			return nil, NewRPCError(ErrRPCInvalidParams, fmt.Errorf("expiry height is active from height %d", rpcServer.config.ChainParams.ExpiryHeightActivation))
+		}
+	}
+	tx, err := rpcServer.buildRawTransaction(params, nil, expiryHeight)
 	if err.(*RPCError) != nil {
 		Logger.log.Critical(err)
 		return nil, NewRPCError(ErrCreateTxData, err)
@@ -207,6 +220,53 @@ func (rpcServer RpcServer) handleSendRawTransaction(params interface{}, closeCha
 	return result, nil
 }
 
+/*
+handleTestMempoolAccept - RPC checks if mempool accepts a raw transaction without adding it to mempool or sending it to network,
+the reject code and reason are returned for a transaction which mempool rejects
+*/
+func (rpcServer RpcServer) handleTestMempoolAccept(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleTestMempoolAccept params: %+v", params)
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("raw transaction is missing"))
+	}
+	base58CheckData, ok := arrayParams[0].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("raw transaction is invalid"))
+	}
+	rawTxBytes, _, err := base58.Base58Check{}.Decode(base58CheckData)
+	if err != nil {
+		Logger.log.Infof("handleTestMempoolAccept result: %+v, err: %+v", nil, err)
+		return nil, NewRPCError(ErrSendTxData, err)
+	}
+	var tx transaction.Tx
+	err = json.Unmarshal(rawTxBytes, &tx)
+	if err != nil {
+		Logger.log.Infof("handleTestMempoolAccept result: %+v, err: %+v", nil, err)
+		return nil, NewRPCError(ErrSendTxData, err)
+	}
+
+	result := jsonresult.TestMempoolAcceptResult{
+		TxID:    tx.Hash().String(),
+		ShardID: common.GetShardIDFromLastByte(tx.GetSenderAddrLastByte()),
+	}
+	txDesc, err := rpcServer.config.TxMemPool.TestMempoolAccept(&tx)
+	if err != nil {
+		mempoolErr, ok := err.(mempool.MempoolTxError)
+		if !ok {
+			mempoolErr = mempool.MempoolTxError{}
+			mempoolErr.Init(mempool.RejectInvalidTx, err)
+		}
+		result.Reject = jsonresult.NewMempoolRejectResult(mempoolErr.Code, mempoolErr.Message, err.Error())
+	} else {
+		result.Allowed = true
+		result.Fee = txDesc.Desc.Fee
+		result.FeePerKB = txDesc.Desc.FeePerKB
+	}
+	Logger.log.Infof("handleTestMempoolAccept result: %+v", result)
+	return result, nil
+}
+
 /*
 handleCreateAndSendTx - RPC creates transaction and send to network
 */
@@ -235,6 +295,275 @@ func (rpcServer RpcServer) handleCreateAndSendTx(params interface{}, closeChan <
 	return result, nil
 }
 
+/*
+handleRemoveTxFromMempool - RPC removes a tx and txs which spend its output
+coins from mempool and mempool database, so its input coins can be spent by
+another tx. The tx may still be in a block if another node has it.
+Parameter #1—hash of the tx
+*/
+func (rpcServer RpcServer) handleRemoveTxFromMempool(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleRemoveTxFromMempool params: %+v", params)
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("tx id is missing"))
+	}
+	txIDParam, ok := arrayParams[0].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("tx id is invalid"))
+	}
+	txHash, err := common.Hash{}.NewHashFromStr(txIDParam)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	removedTxs, err := rpcServer.config.TxMemPool.CancelTx(*txHash)
+	if err != nil {
+		return nil, NewRPCError(ErrTxNotExistedInMemAndBLock, err)
+	}
+	result := jsonresult.RemoveTxFromMempoolResult{Removed: []string{}}
+	for _, removedTx := range removedTxs {
+		result.Removed = append(result.Removed, removedTx.String())
+	}
+	Logger.log.Infof("handleRemoveTxFromMempool result: %+v", result)
+	return result, nil
+}
+
+/*
+handleGetTxHistory - RPC returns lifecycle history of a tx which mempool
+keeps: when it is received and from which peer, validated or rejected,
+forwarded, included in a block or removed from mempool.
+Parameter #1—hash of the tx
+*/
+func (rpcServer RpcServer) handleGetTxHistory(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleGetTxHistory params: %+v", params)
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("tx id is missing"))
+	}
+	txIDParam, ok := arrayParams[0].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("tx id is invalid"))
+	}
+	txHash, err := common.Hash{}.NewHashFromStr(txIDParam)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	events, ok := rpcServer.config.TxMemPool.GetTxHistory(*txHash)
+	if !ok {
+		return nil, NewRPCError(ErrTxNotExistedInMemAndBLock, fmt.Errorf("mempool has no history of transaction %+v", txHash.String()))
+	}
+	result := jsonresult.GetTxHistoryResult{
+		TxID:      txHash.String(),
+		InMempool: rpcServer.config.TxMemPool.HaveTransaction(txHash),
+		Events:    []jsonresult.TxEventResult{},
+	}
+	for _, event := range events {
+		result.Events = append(result.Events, jsonresult.TxEventResult{
+			Type:   string(event.Type),
+			Time:   event.Time.Unix(),
+			PeerID: event.PeerID,
+			Height: event.Height,
+			Detail: event.Detail,
+		})
+	}
+	Logger.log.Infof("handleGetTxHistory result: %+v", result)
+	return result, nil
+}
+
+/*
+handleBumpFee - RPC replaces a tx in mempool, which is stuck by a low fee, by a
+tx which spends the same input coins and pays more fee. Mempool must run with
+replace by fee on.
+Parameter #1—private key of sender
+Parameter #2—list of receivers
+Parameter #3—estimation fee nano constant per kb, -1 to use the fee estimator
+Parameter #4—hasPrivacy flag: 1 or -1
+Parameter #5—hash of the tx to replace
+*/
+func (rpcServer RpcServer) handleBumpFee(params interface{}, closeChan <-chan struct{}) (interface{}, *RPCError) {
+	Logger.log.Infof("handleBumpFee params: %+v", params)
+	if !rpcServer.config.TxMemPool.IsReplaceByFeeEnabled() {
+		return nil, NewRPCError(ErrSendTxData, errors.New("replace by fee is disabled in mempool"))
+	}
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 5 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("bumpfee needs 5 params"))
+	}
+
+	// param #1: private key of sender
+	senderKeyParam, ok := arrayParams[0].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("private key is invalid"))
+	}
+	senderKeySet, err := rpcServer.GetKeySetFromPrivateKeyParams(senderKeyParam)
+	if err != nil {
+		return nil, NewRPCError(ErrInvalidSenderPrivateKey, err)
+	}
+	lastByte := senderKeySet.PaymentAddress.Pk[len(senderKeySet.PaymentAddress.Pk)-1]
+	shardIDSender := common.GetShardIDFromLastByte(lastByte)
+
+	// param #2: list receiver
+	receiversPaymentAddressStrParam := make(map[string]interface{})
+	if arrayParams[1] != nil {
+		receiversPaymentAddressStrParam, ok = arrayParams[1].(map[string]interface{})
+		if !ok {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("receivers are invalid"))
+		}
+	}
+	paymentInfos := make([]*privacy.PaymentInfo, 0)
+	totalAmount := uint64(0)
+	for paymentAddressStr, amount := range receiversPaymentAddressStrParam {
+		keyWalletReceiver, err := wallet.Base58CheckDeserialize(paymentAddressStr)
+		if err != nil {
+			return nil, NewRPCError(ErrInvalidReceiverPaymentAddress, err)
+		}
+		amountValue, ok := amount.(float64)
+		if !ok {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("amount is invalid"))
+		}
+		paymentInfos = append(paymentInfos, &privacy.PaymentInfo{
+			Amount:         uint64(amountValue),
+			PaymentAddress: keyWalletReceiver.KeySet.PaymentAddress,
+		})
+		totalAmount += uint64(amountValue)
+	}
+
+	// param #3: estimation fee nano constant per kb
+	estimateFeeCoinPerKbParam, ok := arrayParams[2].(float64)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("fee per kb is invalid"))
+	}
+	estimateFeeCoinPerKb := int64(estimateFeeCoinPerKbParam)
+
+	// param #4: hasPrivacy flag: 1 or -1
+	hasPrivacyParam, ok := arrayParams[3].(float64)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("privacy flag is invalid"))
+	}
+	hasPrivacy := int(hasPrivacyParam) > 0
+
+	// param #5: hash of the tx to replace
+	txHashParam, ok := arrayParams[4].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("tx hash is invalid"))
+	}
+	oldTxHash, err := common.Hash{}.NewHashFromStr(txHashParam)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	oldTx, err := rpcServer.config.TxMemPool.GetTx(oldTxHash)
+	if err != nil {
+		return nil, NewRPCError(ErrTxNotExistedInMemAndBLock, err)
+	}
+	if _, ok := oldTx.(*transaction.Tx); !ok || oldTx.GetType() != common.TxNormalType || oldTx.GetMetadata() != nil {
+		return nil, NewRPCError(ErrTxTypeInvalid, errors.New("only normal tx without metadata can be replaced"))
+	}
+
+	// input coins of the old tx are still unspent coins of sender, they are
+	// marked as spent in mempool so they are picked by serial number before
+	// coins in mempool are filtered out
+	serialNumbers := make(map[string]bool)
+	for _, serialNumber := range oldTx.ListNullifiers() {
+		serialNumbers[string(serialNumber)] = true
+	}
+	constantTokenID := &common.Hash{}
+	constantTokenID.SetBytes(common.ConstantID[:])
+	outCoins, err := rpcServer.config.BlockChain.GetListOutputCoinsByKeyset(senderKeySet, shardIDSender, constantTokenID)
+	if err != nil {
+		return nil, NewRPCError(ErrGetOutputCoin, err)
+	}
+	candidateOutputCoins := make([]*privacy.OutputCoin, 0)
+	otherOutputCoins := make([]*privacy.OutputCoin, 0)
+	candidateOutputCoinAmount := uint64(0)
+	for _, outCoin := range outCoins {
+		if outCoin.CoinDetails.SerialNumber != nil && serialNumbers[string(outCoin.CoinDetails.SerialNumber.Compress())] {
+			candidateOutputCoins = append(candidateOutputCoins, outCoin)
+			candidateOutputCoinAmount += outCoin.CoinDetails.Value
+		} else {
+			otherOutputCoins = append(otherOutputCoins, outCoin)
+		}
+	}
+	if len(candidateOutputCoins) != len(serialNumbers) {
+		return nil, NewRPCError(ErrGetOutputCoin, fmt.Errorf("input coins of tx %+v are not unspent coins of sender", oldTxHash.String()))
+	}
+	otherOutputCoins, err = rpcServer.filterMemPoolOutCoinsToSpent(otherOutputCoins)
+	if err != nil {
+		return nil, NewRPCError(ErrGetOutputCoin, err)
+	}
+
+	// new tx has to pay more than the old one and its descendants in pool
+	realFee, _, estimateTxSizeInKb := rpcServer.estimateFee(estimateFeeCoinPerKb, candidateOutputCoins,
+		paymentInfos, shardIDSender, 0, hasPrivacy, nil, nil, nil)
+	minFee, err := rpcServer.config.TxMemPool.MinReplacementFee(oldTxHash, estimateTxSizeInKb)
+	if err != nil {
+		return nil, NewRPCError(ErrTxNotExistedInMemAndBLock, err)
+	}
+	if realFee < minFee {
+		realFee = minFee
+	}
+	if candidateOutputCoinAmount < totalAmount+realFee {
+		needToPay := totalAmount + realFee - candidateOutputCoinAmount
+		if len(otherOutputCoins) == 0 {
+			return nil, NewRPCError(ErrGetOutputCoin, errors.New("not enough output coin"))
+		}
+		candidateOutputCoinsForFee, _, amount, err := rpcServer.chooseBestOutCoinsToSpent(otherOutputCoins, needToPay)
+		if err != nil {
+			return nil, NewRPCError(ErrGetOutputCoin, err)
+		}
+		if amount < needToPay {
+			return nil, NewRPCError(ErrGetOutputCoin, errors.New("not enough output coin"))
+		}
+		candidateOutputCoins = append(candidateOutputCoins, candidateOutputCoinsForFee...)
+	}
+	inputCoins := transaction.ConvertOutputCoinToInputCoin(candidateOutputCoins)
+
+	tx := transaction.Tx{}
+	errTx := tx.Init(
+		&senderKeySet.PrivateKey,
+		paymentInfos,
+		inputCoins,
+		realFee,
+		hasPrivacy,
+		*rpcServer.config.Database,
+		nil, // use for constant coin -> nil is valid
+		nil,
+	)
+	if errTx != nil {
+		return nil, NewRPCError(ErrCreateTxData, errTx)
+	}
+	rpcServer.config.TxMemPool.PrePoolTxCoinHashH(*tx.Hash(), rpcServer.makeArrayInputCoinHashHs(inputCoins))
+
+	hash, _, err := rpcServer.config.TxMemPool.MaybeAcceptTransaction(&tx)
+	if err != nil {
+		mempoolErr, ok := err.(mempool.MempoolTxError)
+		if ok {
+			if mempoolErr.Code == mempool.ErrCodeMessage[mempool.RejectInvalidFee].Code {
+				return nil, NewRPCError(ErrRejectInvalidFee, mempoolErr)
+			}
+		}
+		Logger.log.Infof("handleBumpFee result: %+v, err: %+v", nil, err)
+		return nil, NewRPCError(ErrSendTxData, err)
+	}
+	Logger.log.Infof("Tx %+v replaces tx %+v", *hash, *oldTxHash)
+
+	// broadcast Message
+	txMsg, err := wire.MakeEmptyMessage(wire.CmdTx)
+	if err != nil {
+		return nil, NewRPCError(ErrSendTxData, err)
+	}
+	txMsg.(*wire.MessageTx).Transaction = &tx
+	err = rpcServer.config.Server.PushMessageToAll(txMsg)
+	if err == nil {
+		rpcServer.config.TxMemPool.MarkFowardedTransaction(*tx.Hash())
+	}
+
+	result := jsonresult.CreateTransactionResult{
+		TxID:    tx.Hash().String(),
+		ShardID: shardIDSender,
+	}
+	Logger.log.Infof("handleBumpFee result: %+v", result)
+	return result, nil
+}
+
 /*
 handleGetMempoolInfo - RPC returns information about the node's current txs memory pool
 */
@@ -244,6 +573,16 @@ func (rpcServer RpcServer) handleGetMempoolInfo(params interface{}, closeChan <-
 	result.Size = rpcServer.config.TxMemPool.Count()
 	result.Bytes = rpcServer.config.TxMemPool.Size()
 	result.MempoolMaxFee = rpcServer.config.TxMemPool.MaxFee()
+	for _, state := range rpcServer.config.TxMemPool.PartitionStates() {
+		result.Shards = append(result.Shards, jsonresult.GetMempoolInfoShard{
+			ShardID:    state.ShardID,
+			Size:       state.Count,
+			Bytes:      state.Size,
+			MaxTx:      state.Limit.MaxTx,
+			MaxBytes:   state.Limit.MaxSize,
+			TxLifeTime: state.Limit.TxLifeTime,
+		})
+	}
 	listTxsDetail := rpcServer.config.TxMemPool.ListTxsDetail()
 	if len(listTxsDetail) > 0 {
 		result.ListTxs = make([]jsonresult.GetMempoolInfoTx, 0)
@@ -354,6 +693,7 @@ func (rpcServer RpcServer) revertTxToResponseObject(tx metadata.Transaction, blo
 			return nil, NewRPCError(ErrTxTypeInvalid, errors.New("Tx type is invalid"))
 		}
 	}
+	result.ExpiryHeight = tx.GetExpiryHeight()
 	return result, nil
 }
 
@@ -1127,7 +1467,7 @@ func (rpcServer RpcServer) handleCreateRawStakingTransaction(params interface{},
 
 	metadata, err := metadata.NewStakingMetadata(int(stakingType), base58.Base58Check{}.Encode(paymentAddress, common.ZeroByte))
 
-	tx, err := rpcServer.buildRawTransaction(params, metadata)
+	tx, err := rpcServer.buildRawTransaction(params, metadata, 0)
 	if err.(*RPCError) != nil {
 		Logger.log.Critical(err)
 		Logger.log.Infof("handleCreateRawStakingTransaction result: %+v, err: %+v", nil, err)
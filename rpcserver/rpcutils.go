@@ -33,7 +33,7 @@ func (rpcServer RpcServer) createRawTxWithMetadata(params interface{}, closeChan
 		return nil, NewRPCError(ErrUnexpected, err)
 	}
 
-	tx, err := rpcServer.buildRawTransaction(params, meta)
+	tx, err := rpcServer.buildRawTransaction(params, meta, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +109,6 @@ func (rpcServer RpcServer) sendRawTxWithMetadata(params interface{}, closeChan <
 	if err == nil {
 		rpcServer.config.TxMemPool.MarkFowardedTransaction(*tx.Hash())
 	}
-	rpcServer.config.TxMemPool.MarkFowardedTransaction(*tx.Hash())
 	result := jsonresult.CreateTransactionResult{
 		TxID: tx.Hash().String(),
 	}
@@ -150,7 +149,6 @@ func (rpcServer RpcServer) sendRawCustomTokenTxWithMetadata(params interface{},
 	if err == nil {
 		rpcServer.config.TxMemPool.MarkFowardedTransaction(*tx.Hash())
 	}
-	rpcServer.config.TxMemPool.MarkFowardedTransaction(*tx.Hash())
 	result := jsonresult.CreateTransactionResult{
 		TxID: tx.Hash().String(),
 	}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/constant-money/constant-chain/databasemp"
-	"github.com/constant-money/constant-chain/metadata"
 	"github.com/constant-money/constant-chain/transaction"
 	"log"
 	"net"
@@ -59,6 +58,9 @@ type Server struct {
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	feeEstimator map[byte]*mempool.FeeEstimator
+	// relayShards are the shards which node relays, node subscribes gossip
+	// topics of these shards and of its current shard
+	relayShards []byte
 
 	cQuit     chan struct{}
 	cNewPeers chan *peer.Peer
@@ -128,8 +130,6 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 	serverObj.dataBase = db
 
 	//Init channel
-	cPendingTxs := make(chan metadata.Transaction, 100)
-	cRemovedTxs := make(chan metadata.Transaction)
 	cRoleInCommitteesMempool := make(chan int)
 	cRoleInCommitteesNetSync := make(chan int)
 	cTxCache := make(chan common.Hash, 100)
@@ -169,6 +169,8 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 		}
 	}
 
+	serverObj.relayShards = relayShards
+
 	err = serverObj.blockChain.Init(&blockchain.Config{
 		ChainParams:       serverObj.chainParams,
 		DataBase:          serverObj.dataBase,
@@ -181,8 +183,8 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 		Server:            serverObj,
 		UserKeySet:        serverObj.userKeySet,
 		NodeMode:          cfg.NodeMode,
+		PruneDepth:        cfg.PruneDepth,
 	})
-	serverObj.blockChain.InitChannelBlockchain(cRemovedTxs)
 	if err != nil {
 		return err
 	}
@@ -235,7 +237,20 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 
 		serverObj.feeEstimator = make(map[byte]*mempool.FeeEstimator)
 	}
+	// every shard has a fee estimator, which learns from its blocks
+	for shardID := range serverObj.blockChain.BestState.Shard {
+		if _, ok := serverObj.feeEstimator[shardID]; !ok {
+			serverObj.feeEstimator[shardID] = mempool.NewFeeEstimator(
+				mempool.DefaultEstimateFeeMaxRollback,
+				mempool.DefaultEstimateFeeMinRegisteredBlocks)
+		}
+		serverObj.blockChain.AddFeeEstimator(shardID, serverObj.feeEstimator[shardID])
+	}
 	// create mempool tx
+	txPoolShardLimits, err := parseTxPoolShardLimits(cfg.TxPoolShardLimits)
+	if err != nil {
+		return err
+	}
 	serverObj.memPool = &mempool.TxPool{}
 	serverObj.memPool.Init(&mempool.Config{
 		BlockChain:        serverObj.blockChain,
@@ -244,16 +259,28 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 		FeeEstimator:      serverObj.feeEstimator,
 		TxLifeTime:        cfg.TxPoolTTL,
 		MaxTx:             cfg.TxPoolMaxTx,
+		MaxSize:           cfg.TxPoolMaxSize,
+		ShardLimits:       txPoolShardLimits,
 		DataBaseMempool:   dbmp,
 		IsLoadFromMempool: cfg.LoadMempool,
+		SnapshotInterval:  time.Duration(cfg.MempoolSnapshot) * time.Second,
 		PersistMempool:    cfg.PersistMempool,
+		ReplaceByFee:      cfg.TxPoolReplaceByFee,
+		ReplaceFeePerKB:   cfg.TxPoolRBFIncrement,
+		AdmissionWorkers:  cfg.TxPoolWorkers,
+		MaxPendingTxs:     cfg.TxPoolMaxPending,
+		MaxRecentRejects:  cfg.TxPoolMaxRejects,
+		AcceptChildTxs:    cfg.TxPoolCPFP,
+		MaxTxHistory:      cfg.TxHistory,
+		TxHistoryFile:     cfg.TxHistoryFile,
+		TxHistoryFileSize: int64(cfg.TxHistoryFileSize) * 1024 * 1024,
 		RelayShards:       relayShards,
 		UserKeyset:        serverObj.userKeySet,
 	})
 	serverObj.memPool.AnnouncePersisDatabaseMempool()
 	//add tx pool
 	serverObj.blockChain.AddTxPool(serverObj.memPool)
-	serverObj.memPool.InitChannelMempool(cTxCache, cRoleInCommitteesMempool, cPendingTxs)
+	serverObj.memPool.InitChannelMempool(cTxCache, cRoleInCommitteesMempool)
 	//==============Temp mem pool only used for validation
 	serverObj.tempMemPool = &mempool.TxPool{}
 	serverObj.tempMemPool.Init(&mempool.Config{
@@ -267,7 +294,7 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 	//===============
 	serverObj.addrManager = addrmanager.New(cfg.DataDir)
 	// Init block template generator
-	serverObj.blockgen, err = blockchain.BlkTmplGenerator{}.Init(serverObj.memPool, serverObj.blockChain, serverObj.shardToBeaconPool, serverObj.crossShardPool, cPendingTxs, cRemovedTxs)
+	serverObj.blockgen, err = blockchain.BlkTmplGenerator{}.Init(serverObj.memPool, serverObj.blockChain, serverObj.shardToBeaconPool, serverObj.crossShardPool)
 	if err != nil {
 		return err
 	}
@@ -314,6 +341,13 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 			Logger.log.Error(err)
 			return err
 		}
+		if !cfg.DisableGossip {
+			err = peer.StartGossip()
+			if err != nil {
+				Logger.log.Error(err)
+				return err
+			}
+		}
 	}
 	connManager := connmanager.ConnManager{}.New(&connmanager.Config{
 		OnInboundAccept:      serverObj.InboundPeerConnected,
@@ -328,8 +362,10 @@ func (serverObj *Server) NewServer(listenAddrs string, db database.DatabaseInter
 		MaxPeersOther:      cfg.MaxPeersOther,
 		MaxPeersNoShard:    cfg.MaxPeersNoShard,
 		MaxPeersBeacon:     cfg.MaxPeersBeacon,
+		AddrManager:        serverObj.addrManager,
 	})
 	serverObj.connManager = connManager
+	serverObj.updateGossipTopics()
 
 	// Start up persistent peers.
 	permanentPeers := cfg.ConnectPeers
@@ -449,6 +485,22 @@ func (serverObj *Server) Stop() error {
 		}
 	}
 
+	// Save txs of mempool, they are loaded with --loadmempool
+	if cfg.PersistMempool && serverObj.memPool != nil {
+		count, err := serverObj.memPool.SaveDatabaseMP()
+		if err != nil {
+			Logger.log.Errorf("Can't save mempool: %v", err)
+		} else {
+			Logger.log.Infof("Save %d transactions of mempool", count)
+		}
+	}
+
+	if serverObj.memPool != nil {
+		if err := serverObj.memPool.CloseTxHistory(); err != nil {
+			Logger.log.Errorf("Can't close tx history file: %v", err)
+		}
+	}
+
 	serverObj.consensusEngine.Stop()
 	serverObj.blockChain.StopSync()
 	// Signal the remaining goroutines to cQuit.
@@ -550,7 +602,6 @@ func (serverObj Server) Start() {
 }
 func (serverObj *Server) TransactionPoolBroadcastLoop() {
 	<-time.Tick(serverObj.memPool.Scantime)
-	serverObj.memPool.LockPool()
 	txDescs := serverObj.memPool.GetPool()
 	for _, txDesc := range txDescs {
 		<-time.Tick(50 * time.Millisecond)
@@ -599,7 +650,6 @@ func (serverObj *Server) TransactionPoolBroadcastLoop() {
 			}
 		}
 	}
-	serverObj.memPool.UnlockPool()
 }
 
 func (serverObject Server) CheckForceUpdateSourceCode() {
@@ -701,6 +751,15 @@ func (serverObj *Server) NewPeerConfig() *peer.Config {
 			OnGetAddr:          serverObj.OnGetAddr,
 			OnAddr:             serverObj.OnAddr,
 
+			// compact block relay
+			OnBlockShardCompact: serverObj.OnBlockShardCompact,
+			OnGetBlockTxs:       serverObj.OnGetBlockTxs,
+			OnBlockTxs:          serverObj.OnBlockTxs,
+
+			// gossip pubsub
+			OnGossipMessage:       serverObj.OnGossipMessage,
+			ValidateGossipMessage: serverObj.ValidateGossipMessage,
+
 			//constantbft
 			OnBFTMsg: serverObj.OnBFTMsg,
 			// OnInvalidBlock:  serverObj.OnInvalidBlock,
@@ -730,6 +789,30 @@ func (serverObj *Server) OnBlockShard(p *peer.PeerConn,
 	Logger.log.Debug("Receive a new blockshard END")
 }
 
+// OnBlockShardCompact is invoked when a peer receives a compact shard block
+func (serverObj *Server) OnBlockShardCompact(p *peer.PeerConn, msg *wire.MessageBlockShardCompact) {
+	Logger.log.Debug("Receive a new blockshardcompact START")
+	var txProcessed chan struct{}
+	serverObj.netSync.QueueMessage(nil, msg, txProcessed)
+	Logger.log.Debug("Receive a new blockshardcompact END")
+}
+
+// OnGetBlockTxs is invoked when a peer asks for missing txs of a compact block
+func (serverObj *Server) OnGetBlockTxs(p *peer.PeerConn, msg *wire.MessageGetBlockTxs) {
+	Logger.log.Debug("Receive a getblocktxs START")
+	var txProcessed chan struct{}
+	serverObj.netSync.QueueMessage(nil, msg, txProcessed)
+	Logger.log.Debug("Receive a getblocktxs END")
+}
+
+// OnBlockTxs is invoked when a peer receives missing txs of a compact block
+func (serverObj *Server) OnBlockTxs(p *peer.PeerConn, msg *wire.MessageBlockTxs) {
+	Logger.log.Debug("Receive a blocktxs START")
+	var txProcessed chan struct{}
+	serverObj.netSync.QueueMessage(nil, msg, txProcessed)
+	Logger.log.Debug("Receive a blocktxs END")
+}
+
 func (serverObj *Server) OnBlockBeacon(p *peer.PeerConn,
 	msg *wire.MessageBlockBeacon) {
 	Logger.log.Debug("Receive a new blockbeacon START")
@@ -801,7 +884,7 @@ func (serverObj *Server) OnGetShardToBeacon(_ *peer.PeerConn, msg *wire.MessageG
 func (serverObj *Server) OnTx(peer *peer.PeerConn, msg *wire.MessageTx) {
 	Logger.log.Debug("Receive a new transaction START")
 	var txProcessed chan struct{}
-	serverObj.netSync.QueueTx(nil, msg, txProcessed)
+	serverObj.netSync.QueueTx(peer.RemotePeer, msg, txProcessed)
 	//<-txProcessed
 
 	Logger.log.Debug("Receive a new transaction END")
@@ -810,7 +893,7 @@ func (serverObj *Server) OnTx(peer *peer.PeerConn, msg *wire.MessageTx) {
 func (serverObj *Server) OnTxToken(peer *peer.PeerConn, msg *wire.MessageTxToken) {
 	Logger.log.Debug("Receive a new transaction(normal token) START")
 	var txProcessed chan struct{}
-	serverObj.netSync.QueueTxToken(nil, msg, txProcessed)
+	serverObj.netSync.QueueTxToken(peer.RemotePeer, msg, txProcessed)
 	//<-txProcessed
 
 	Logger.log.Debug("Receive a new transaction(normal token) END")
@@ -819,7 +902,7 @@ func (serverObj *Server) OnTxToken(peer *peer.PeerConn, msg *wire.MessageTxToken
 func (serverObj *Server) OnTxPrivacyToken(peer *peer.PeerConn, msg *wire.MessageTxPrivacyToken) {
 	Logger.log.Debug("Receive a new transaction(privacy token) START")
 	var txProcessed chan struct{}
-	serverObj.netSync.QueueTxPrivacyToken(nil, msg, txProcessed)
+	serverObj.netSync.QueueTxPrivacyToken(peer.RemotePeer, msg, txProcessed)
 	//<-txProcessed
 
 	Logger.log.Debug("Receive a new transaction(privacy token) END")
@@ -833,24 +916,25 @@ func (serverObj *Server) OnTxPrivacyToken(peer *peer.PeerConn, msg *wire.Message
 func (serverObj *Server) OnVersion(peerConn *peer.PeerConn, msg *wire.MessageVersion) {
 	Logger.log.Debug("Receive version message START")
 
-	pbk := ""
-	if msg.PublicKey != "" {
-		err := cashec.ValidateDataB58(msg.PublicKey, msg.SignDataB58, []byte(peerConn.ListenerPeer.PeerID.Pretty()))
-		if err == nil {
-			pbk = msg.PublicKey
-		} else {
-			peerConn.ForceClose()
-			return
-		}
+	// peer id in message must be the peer id which is authenticated by
+	// transport
+	if msg.LocalPeerId != peerConn.RemotePeerID {
+		Logger.log.Errorf("Version message of peer %s has peer id %s", peerConn.RemotePeerID.Pretty(), msg.LocalPeerId.Pretty())
+		peerConn.ForceClose()
+		return
 	}
 
+	// public key of remote peer is set when it signs our challenge in verack
 	remotePeer := &peer.Peer{
 		ListeningAddress: msg.LocalAddress,
 		RawAddress:       msg.RawLocalAddress,
 		PeerID:           msg.LocalPeerId,
-		PublicKey:        pbk,
 	}
-	peerConn.RemotePeer.PublicKey = pbk
+	// compact block is used only if both sides support it
+	peerConn.SetCompactBlock(msg.CompactBlock && !cfg.DisableCompactBlock)
+	peerConn.SetArchive(msg.Archive)
+	// gossip is used only if both sides started it
+	peerConn.SetGossip(msg.Gossip && peerConn.ListenerPeer.GossipEnabled())
 
 	serverObj.cNewPeers <- remotePeer
 	valid := false
@@ -858,12 +942,6 @@ func (serverObj *Server) OnVersion(peerConn *peer.PeerConn, msg *wire.MessageVer
 		valid = true
 	}
 
-	// check for accept connection
-	if !serverObj.connManager.CheckForAcceptConn(peerConn) {
-		peerConn.ForceClose()
-		return
-	}
-
 	msgV, err := wire.MakeEmptyMessage(wire.CmdVerack)
 	if err != nil {
 		return
@@ -871,6 +949,23 @@ func (serverObj *Server) OnVersion(peerConn *peer.PeerConn, msg *wire.MessageVer
 
 	msgV.(*wire.MessageVerAck).Valid = valid
 	msgV.(*wire.MessageVerAck).Timestamp = time.Now()
+	// prove our public key by signing challenge of remote peer
+	if peerConn.ListenerPeer.Config.UserKeySet != nil {
+		signDataB58, err := peerConn.ListenerPeer.Config.UserKeySet.SignDataB58(wire.VerAckSignData(msg.Challenge, peerConn.ListenerPeer.PeerID, peerConn.RemotePeerID))
+		if err != nil {
+			Logger.log.Error(err)
+		} else {
+			msgV.(*wire.MessageVerAck).PublicKey = peerConn.ListenerPeer.Config.UserKeySet.GetPublicKeyB58()
+			msgV.(*wire.MessageVerAck).SignDataB58 = signDataB58
+		}
+		// sign our address record, so other nodes can relay it
+		addrSignDataB58, err := peerConn.ListenerPeer.Config.UserKeySet.SignDataB58(wire.AddrSignData(peerConn.ListenerPeer.RawAddress, peerConn.ListenerPeer.Config.UserKeySet.GetPublicKeyB58()))
+		if err != nil {
+			Logger.log.Error(err)
+		} else {
+			msgV.(*wire.MessageVerAck).AddrSignDataB58 = addrSignDataB58
+		}
+	}
 
 	peerConn.QueueMessageWithEncoding(msgV, nil, peer.MESSAGE_TO_PEER, nil)
 
@@ -891,6 +986,38 @@ OnVerAck is invoked when a peer receives a version acknowlege message
 func (serverObj *Server) OnVerAck(peerConn *peer.PeerConn, msg *wire.MessageVerAck) {
 	Logger.log.Debug("Receive verack message START")
 
+	// public key is bound to connection only when remote peer signs our
+	// challenge with it, so a peer can't claim public key of another node
+	// and take its messages
+	peerConn.RemotePeer.PublicKey = ""
+	peerConn.RemotePeer.AddrSignature = ""
+	if msg.PublicKey != "" {
+		challenge, err := peerConn.GetChallenge()
+		if err != nil {
+			Logger.log.Error(err)
+			peerConn.ForceClose()
+			return
+		}
+		err = cashec.ValidateDataB58(msg.PublicKey, msg.SignDataB58, wire.VerAckSignData(challenge, peerConn.RemotePeerID, peerConn.ListenerPeer.PeerID))
+		if err != nil {
+			Logger.log.Errorf("Peer %s can't prove public key %s: %+v", peerConn.RemotePeerID.Pretty(), msg.PublicKey, err)
+			peerConn.ForceClose()
+			return
+		}
+		peerConn.RemotePeer.PublicKey = msg.PublicKey
+		// address record of peer is relayed in addr messages only if it is
+		// signed for the address which we know peer by
+		if cashec.ValidateDataB58(msg.PublicKey, msg.AddrSignDataB58, wire.AddrSignData(peerConn.RemotePeer.RawAddress, msg.PublicKey)) == nil {
+			peerConn.RemotePeer.AddrSignature = msg.AddrSignDataB58
+		}
+	}
+
+	// check for accept connection
+	if !serverObj.connManager.CheckForAcceptConn(peerConn) {
+		peerConn.ForceClose()
+		return
+	}
+
 	if msg.Valid {
 		peerConn.VerValid = true
 
@@ -916,8 +1043,11 @@ func (serverObj *Server) OnVerAck(peerConn *peer.PeerConn, msg *wire.MessageVerA
 		rawPeers := []wire.RawPeer{}
 		peers := serverObj.addrManager.AddressCache()
 		for _, peer := range peers {
-			if peerConn.RemotePeerID.Pretty() != serverObj.connManager.GetPeerId(peer.RawAddress) {
-				rawPeers = append(rawPeers, wire.RawPeer{peer.RawAddress, peer.PublicKey})
+			if peerConn.RemotePeerID.Pretty() != serverObj.connManager.GetPeerId(peer.RawAddress) && peer.AddrSignature != "" {
+				rawPeers = append(rawPeers, wire.RawPeer{RawAddress: peer.RawAddress, PublicKey: peer.PublicKey, Signature: peer.AddrSignature})
+				if len(rawPeers) >= wire.MaxAddrPerMsg {
+					break
+				}
 			}
 		}
 		msgSA.(*wire.MessageAddr).RawPeers = rawPeers
@@ -955,8 +1085,12 @@ func (serverObj *Server) OnGetAddr(peerConn *peer.PeerConn, msg *wire.MessageGet
 	peers := serverObj.addrManager.AddressCache()
 	rawPeers := []wire.RawPeer{}
 	for _, peer := range peers {
-		if peerConn.RemotePeerID.Pretty() != serverObj.connManager.GetPeerId(peer.RawAddress) {
-			rawPeers = append(rawPeers, wire.RawPeer{peer.RawAddress, peer.PublicKey})
+		// only address records which are signed by their node are relayed
+		if peerConn.RemotePeerID.Pretty() != serverObj.connManager.GetPeerId(peer.RawAddress) && peer.AddrSignature != "" {
+			rawPeers = append(rawPeers, wire.RawPeer{RawAddress: peer.RawAddress, PublicKey: peer.PublicKey, Signature: peer.AddrSignature})
+			if len(rawPeers) >= wire.MaxAddrPerMsg {
+				break
+			}
 		}
 	}
 	msgS.(*wire.MessageAddr).RawPeers = rawPeers
@@ -968,6 +1102,37 @@ func (serverObj *Server) OnGetAddr(peerConn *peer.PeerConn, msg *wire.MessageGet
 
 func (serverObj *Server) OnAddr(peerConn *peer.PeerConn, msg *wire.MessageAddr) {
 	Logger.log.Debugf("Receive addr message %v", msg.RawPeers)
+	if err := msg.VerifyMsgSanity(); err != nil {
+		Logger.log.Error(err)
+		return
+	}
+
+	// save addresses into addr manager, connection manager uses them to
+	// discover peers without bootnode
+	listener := serverObj.connManager.Config.ListenerPeer
+	for _, rawPeer := range msg.RawPeers {
+		if rawPeer.RawAddress == "" || rawPeer.PublicKey == "" {
+			continue
+		}
+		// a relayed address record is trusted only if its node signs it
+		if err := cashec.ValidateDataB58(rawPeer.PublicKey, rawPeer.Signature, wire.AddrSignData(rawPeer.RawAddress, rawPeer.PublicKey)); err != nil {
+			Logger.log.Debugf("Drop address %s of public key %s which isn't signed: %+v", rawPeer.RawAddress, rawPeer.PublicKey, err)
+			continue
+		}
+		if listener != nil && rawPeer.RawAddress == listener.RawAddress {
+			continue
+		}
+		peerID, err := libp2p.IDB58Decode(serverObj.connManager.GetPeerId(rawPeer.RawAddress))
+		if err != nil {
+			continue
+		}
+		serverObj.addrManager.AddAddress(&peer.Peer{
+			RawAddress:    rawPeer.RawAddress,
+			PublicKey:     rawPeer.PublicKey,
+			AddrSignature: rawPeer.Signature,
+			PeerID:        peerID,
+		})
+	}
 }
 
 func (serverObj *Server) OnBFTMsg(_ *peer.PeerConn, msg wire.Message) {
@@ -1007,11 +1172,96 @@ func (serverObj *Server) GetPeerIDsFromPublicKey(pubKey string) []libp2p.ID {
 	return result
 }
 
+// OnGossipMessage is invoked when a message is received from a gossip topic
+func (serverObj *Server) OnGossipMessage(topic string, msg wire.Message) {
+	Logger.log.Debugf("Receive gossip message %s of topic %s", msg.MessageType(), topic)
+	serverObj.netSync.QueueMessage(nil, msg, nil)
+}
+
+// ValidateGossipMessage is invoked before a gossip message is relayed
+func (serverObj *Server) ValidateGossipMessage(topic string, msg wire.Message) bool {
+	return serverObj.netSync.ValidateGossipMessage(topic, msg)
+}
+
+/*
+publishMessage publishes msg to its gossip topic, it returns false when gossip
+is disabled or msg has no topic, then msg must be pushed to peers directly.
+When it returns true, msg still must be pushed directly to peers which don't
+use gossip, see pushToNonGossipPeers
+*/
+func (serverObj *Server) publishMessage(msg wire.Message) bool {
+	listener := serverObj.connManager.Config.ListenerPeer
+	if listener == nil || !listener.GossipEnabled() {
+		return false
+	}
+	topic, ok := wire.GetTopic(msg)
+	if !ok {
+		return false
+	}
+	msg.SetSenderID(listener.PeerID)
+	err := listener.PublishMessage(topic, msg)
+	if err != nil {
+		Logger.log.Error(err)
+		return false
+	}
+	Logger.log.Debugf("Published msg %s to topic %s", msg.MessageType(), topic)
+	return true
+}
+
+// pushToNonGossipPeers pushes msg directly to peers of peerConns which don't
+// receive it from gossip topics
+func (serverObj *Server) pushToNonGossipPeers(msg wire.Message, peerConns []*peer.PeerConn) {
+	for _, peerConn := range peerConns {
+		if !peerConn.GetGossip() {
+			msg.SetSenderID(peerConn.ListenerPeer.PeerID)
+			peerConn.QueueMessageWithEncoding(msg, nil, peer.MESSAGE_TO_PEER, nil)
+		}
+	}
+}
+
+/*
+updateGossipTopics subscribes gossip topics of beacon, relay shards and current
+shard of node, and unsubscribes topics which node doesn't follow any more
+*/
+func (serverObj *Server) updateGossipTopics() {
+	listener := serverObj.connManager.Config.ListenerPeer
+	if listener == nil || !listener.GossipEnabled() {
+		return
+	}
+	topics := map[string]bool{wire.TopicBeacon: true}
+	for _, shardID := range serverObj.relayShards {
+		for _, topic := range wire.TopicsOfShard(shardID) {
+			topics[topic] = true
+		}
+	}
+	_, currentShard := serverObj.connManager.GetCurrentRoleShard()
+	if currentShard != nil {
+		for _, topic := range wire.TopicsOfShard(*currentShard) {
+			topics[topic] = true
+		}
+	}
+	for _, topic := range listener.GetSubscribedTopics() {
+		if !topics[topic] {
+			listener.Unsubscribe(topic)
+		}
+	}
+	for topic := range topics {
+		err := listener.Subscribe(topic)
+		if err != nil {
+			Logger.log.Error(err)
+		}
+	}
+}
+
 /*
 PushMessageToAll broadcast msg
 */
 func (serverObj *Server) PushMessageToAll(msg wire.Message) error {
 	Logger.log.Debug("Push msg to all peers")
+	if serverObj.publishMessage(msg) {
+		serverObj.pushToNonGossipPeers(msg, serverObj.connManager.GetPeerConnOfAll())
+		return nil
+	}
 	var dc chan<- struct{}
 	msg.SetSenderID(serverObj.connManager.Config.ListenerPeer.PeerID)
 	serverObj.connManager.Config.ListenerPeer.QueueMessageWithEncoding(msg, dc, peer.MESSAGE_TO_ALL, nil)
@@ -1060,6 +1310,10 @@ PushMessageToPeer push msg to pbk
 */
 func (serverObj *Server) PushMessageToShard(msg wire.Message, shard byte) error {
 	Logger.log.Debugf("Push msg to shard %d", shard)
+	if serverObj.publishMessage(msg) {
+		serverObj.pushToNonGossipPeers(msg, serverObj.connManager.GetPeerConnOfShard(shard))
+		return nil
+	}
 	peerConns := serverObj.connManager.GetPeerConnOfShard(shard)
 	if len(peerConns) > 0 {
 		for _, peerConn := range peerConns {
@@ -1075,6 +1329,56 @@ func (serverObj *Server) PushMessageToShard(msg wire.Message, shard byte) error
 	return nil
 }
 
+/*
+PushShardBlockToShard push a new shard block to peers of shard, peers which
+support compact block receive compact block, other peers receive full block
+*/
+func (serverObj *Server) PushShardBlockToShard(block *blockchain.ShardBlock) error {
+	shard := block.Header.ShardID
+	Logger.log.Debugf("Push shard block to shard %d", shard)
+	msgFull, err := wire.MakeEmptyMessage(wire.CmdBlockShard)
+	if err != nil {
+		return err
+	}
+	msgFull.(*wire.MessageBlockShard).Block = *block
+	msgCompact, err := wire.MakeEmptyMessage(wire.CmdBlockShardCompact)
+	if err != nil {
+		return err
+	}
+	msgCompact.(*wire.MessageBlockShardCompact).Block = *blockchain.NewCompactShardBlock(block)
+	// full block is published once to shard topic, then only peers which
+	// support compact block or don't use gossip are pushed directly
+	published := serverObj.publishMessage(msgFull)
+	peerConns := serverObj.connManager.GetPeerConnOfShard(shard)
+	if published {
+		for _, peerConn := range peerConns {
+			if !cfg.DisableCompactBlock && peerConn.GetCompactBlock() {
+				msgCompact.SetSenderID(peerConn.ListenerPeer.PeerID)
+				peerConn.QueueMessageWithEncoding(msgCompact, nil, peer.MESSAGE_TO_PEER, nil)
+			} else if !peerConn.GetGossip() {
+				peerConn.QueueMessageWithEncoding(msgFull, nil, peer.MESSAGE_TO_PEER, nil)
+			}
+		}
+		return nil
+	}
+	if len(peerConns) == 0 {
+		Logger.log.Error("RemotePeer of shard not exist!")
+		listener := serverObj.connManager.Config.ListenerPeer
+		listener.QueueMessageWithEncoding(msgFull, nil, peer.MESSAGE_TO_SHARD, &shard)
+		return nil
+	}
+	for _, peerConn := range peerConns {
+		if !cfg.DisableCompactBlock && peerConn.GetCompactBlock() {
+			msgCompact.SetSenderID(peerConn.ListenerPeer.PeerID)
+			peerConn.QueueMessageWithEncoding(msgCompact, nil, peer.MESSAGE_TO_PEER, nil)
+		} else {
+			peerConn.QueueMessageWithEncoding(msgFull, nil, peer.MESSAGE_TO_PEER, nil)
+		}
+	}
+	Logger.log.Debugf("Pushed shard block to shard %d", shard)
+	return nil
+}
+
 func (serverObj *Server) PushRawBytesToShard(p *peer.PeerConn, msgBytes *[]byte, shard byte) error {
 	Logger.log.Debugf("Push raw bytes to shard %d", shard)
 	peerConns := serverObj.connManager.GetPeerConnOfShard(shard)
@@ -1102,6 +1406,10 @@ PushMessageToPeer push msg to beacon node
 */
 func (serverObj *Server) PushMessageToBeacon(msg wire.Message) error {
 	Logger.log.Debugf("Push msg to beacon")
+	if serverObj.publishMessage(msg) {
+		serverObj.pushToNonGossipPeers(msg, serverObj.connManager.GetPeerConnOfBeacon())
+		return nil
+	}
 	peerConns := serverObj.connManager.GetPeerConnOfBeacon()
 	if len(peerConns) > 0 {
 		fmt.Println("BFT:", len(peerConns))
@@ -1163,18 +1471,22 @@ func (serverObj *Server) PushVersionMessage(peerConn *peer.PeerConn) error {
 	msg.(*wire.MessageVersion).RawRemoteAddress = peerConn.ListenerPeer.RawAddress
 	msg.(*wire.MessageVersion).RemotePeerId = peerConn.ListenerPeer.PeerID
 	msg.(*wire.MessageVersion).ProtocolVersion = serverObj.protocolVersion
+	msg.(*wire.MessageVersion).CompactBlock = !cfg.DisableCompactBlock
+	msg.(*wire.MessageVersion).Archive = cfg.Archive
+	msg.(*wire.MessageVersion).Gossip = peerConn.ListenerPeer.GossipEnabled()
 
-	// ValidateTransaction Public Key from ProducerPrvKey
-	if peerConn.ListenerPeer.Config.UserKeySet != nil {
-		msg.(*wire.MessageVersion).PublicKey = peerConn.ListenerPeer.Config.UserKeySet.GetPublicKeyB58()
-		signDataB58, err := peerConn.ListenerPeer.Config.UserKeySet.SignDataB58([]byte(peerConn.RemotePeer.PeerID.Pretty()))
-		if err == nil {
-			msg.(*wire.MessageVersion).SignDataB58 = signDataB58
-		}
+	if err != nil {
+		return err
 	}
+	// remote peer signs challenge in verack to prove its public key
+	challenge, err := peerConn.GetChallenge()
 	if err != nil {
 		return err
 	}
+	msg.(*wire.MessageVersion).Challenge = challenge
+	if peerConn.ListenerPeer.Config.UserKeySet != nil {
+		msg.(*wire.MessageVersion).PublicKey = peerConn.ListenerPeer.Config.UserKeySet.GetPublicKeyB58()
+	}
 	peerConn.QueueMessageWithEncoding(msg, nil, peer.MESSAGE_TO_PEER, nil)
 	return nil
 }
@@ -1185,6 +1497,7 @@ func (serverObj *Server) GetCurrentRoleShard() (string, *byte) {
 
 func (serverObj *Server) UpdateConsensusState(role string, userPbk string, currentShard *byte, beaconCommittee []string, shardCommittee map[byte][]string) {
 	serverObj.connManager.UpdateConsensusState(role, userPbk, currentShard, beaconCommittee, shardCommittee)
+	serverObj.updateGossipTopics()
 }
 
 func (serverObj *Server) PushMessageGetBlockBeaconByHeight(from uint64, to uint64, peerID libp2p.ID) error {
@@ -1348,6 +1661,13 @@ func (serverObj *Server) PushMessageGetBlockCrossShardBySpecificHeight(fromShard
 	return serverObj.PushMessageToPeer(msg, peerID)
 }
 
+// IsArchivePeer returns true if peer of peerID advertises full history of
+// blocks in its version message
+func (serverObj *Server) IsArchivePeer(peerID libp2p.ID) bool {
+	peerConn := serverObj.connManager.Config.ListenerPeer.GetPeerConnByPeerID(peerID.Pretty())
+	return peerConn != nil && peerConn.GetArchive()
+}
+
 func (serverObj *Server) BoardcastNodeState() error {
 	listener := serverObj.connManager.Config.ListenerPeer
 	msg, err := wire.MakeEmptyMessage(wire.CmdPeerState)
@@ -204,6 +204,52 @@ func EstimateTxSize(inputCoins []*privacy.OutputCoin, payments []*privacy.Paymen
 	return uint64(math.Ceil(float64(sizeTx) / 1024))
 }
 
+/*
+FeePerKB returns constant fee of tx per KB of its actual size. Fee paid in a
+privacy token isn't included, tokens have no price in constant so txs are
+ordered by the constant fee they pay, see TokenFee
+*/
+func FeePerKB(tx metadata.Transaction) uint64 {
+	size := tx.GetTxActualSize()
+	if size == 0 {
+		size = 1
+	}
+	return tx.GetTxFee() / size
+}
+
+// TokenFee returns fee which tx pays in its privacy token, txs of other types
+// pay no token fee
+func TokenFee(tx metadata.Transaction) uint64 {
+	if tokenTx, ok := tx.(*TxCustomTokenPrivacy); ok {
+		return tokenTx.TxTokenPrivacyData.TxNormal.Fee
+	}
+	return 0
+}
+
+// SortTxsByFeePerKB sorts txs by fee per KB from the highest, txs with the
+// same fee per KB are sorted by lock time like SortTxsByLockTime
+func SortTxsByFeePerKB(txs []metadata.Transaction) []metadata.Transaction {
+	feePerKBs := make([]uint64, len(txs))
+	sorted := make([]int, len(txs))
+	for i, tx := range txs {
+		feePerKBs[i] = FeePerKB(tx)
+		sorted[i] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if feePerKBs[a] != feePerKBs[b] {
+			return feePerKBs[a] > feePerKBs[b]
+		}
+		return txs[a].GetLockTime() < txs[b].GetLockTime()
+	})
+	result := make([]metadata.Transaction, len(txs))
+	for i, index := range sorted {
+		result[i] = txs[index]
+	}
+	copy(txs, result)
+	return txs
+}
+
 // SortTxsByLockTime sorts txs by lock time
 func SortTxsByLockTime(txs []metadata.Transaction, isDesc bool) []metadata.Transaction {
 	sort.Slice(txs, func(i, j int) bool {
@@ -0,0 +1,49 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/constant-money/constant-chain/common"
+	"github.com/constant-money/constant-chain/metadata"
+)
+
+func newTestTokenTx(fee, tokenFee uint64, lockTime int64) *TxCustomTokenPrivacy {
+	tx := &TxCustomTokenPrivacy{}
+	tx.Type = common.TxCustomTokenPrivacyType
+	tx.Fee = fee
+	tx.LockTime = lockTime
+	tx.TxTokenPrivacyData.TxNormal.Fee = tokenFee
+	return tx
+}
+
+// TestSortTxsByFeePerKBIgnoresTokenFee checks that txs are ordered by the
+// constant fee they pay, a high token fee doesn't raise priority of a tx
+func TestSortTxsByFeePerKBIgnoresTokenFee(t *testing.T) {
+	highFee := newTestTokenTx(300, 0, 3)
+	highTokenFee := newTestTokenTx(10, 1000000, 1)
+	normal := &Tx{Type: common.TxNormalType, Fee: 50, LockTime: 2}
+	if fee := TokenFee(highTokenFee); fee != 1000000 {
+		t.Fatalf("TokenFee returns %d", fee)
+	}
+	if fee := TokenFee(normal); fee != 0 {
+		t.Fatalf("TokenFee of normal tx returns %d", fee)
+	}
+	if FeePerKB(highTokenFee) >= FeePerKB(normal) {
+		t.Fatalf("token fee should not be in fee per KB %d", FeePerKB(highTokenFee))
+	}
+
+	txs := SortTxsByFeePerKB([]metadata.Transaction{highTokenFee, normal, highFee})
+	expected := []metadata.Transaction{highFee, normal, highTokenFee}
+	for i := range expected {
+		if txs[i] != expected[i] {
+			t.Fatalf("tx %d has fee per KB %d, expected %d", i, FeePerKB(txs[i]), FeePerKB(expected[i]))
+		}
+	}
+
+	// txs with the same fee per KB are sorted by lock time
+	late, early := newTestTokenTx(10, 0, 5), newTestTokenTx(10, 500, 4)
+	txs = SortTxsByFeePerKB([]metadata.Transaction{late, early})
+	if txs[0] != early || txs[1] != late {
+		t.Fatalf("txs with the same fee per KB should be sorted by lock time")
+	}
+}
@@ -26,6 +26,9 @@ type Tx struct {
 	Type     string `json:"Type"` // Transaction type
 	LockTime int64  `json:"LockTime"`
 
+	// Shard height of the last block which tx may be in, 0 is no expiry
+	ExpiryHeight uint64 `json:"ExpiryHeight,omitempty"`
+
 	Fee  uint64 `json:"Fee"` // Fee applies: always consant
 	Info []byte // 512 bytes
 
@@ -478,6 +481,12 @@ func (tx Tx) String() string {
 		record += metadata
 	}
 
+	// expiry height is only hashed when it is set, so hashes of txs without
+	// it don't change
+	if tx.ExpiryHeight > 0 {
+		record += "expiry" + strconv.FormatUint(tx.ExpiryHeight, 10)
+	}
+
 	//TODO: To be uncomment
 	// record += string(tx.Info)
 	return record
@@ -518,6 +527,10 @@ func (tx *Tx) GetTxActualSize() uint64 {
 	sizeTx += uint64(1)
 	sizeTx += uint64(len(tx.Info))
 
+	if tx.ExpiryHeight > 0 {
+		sizeTx += uint64(8)
+	}
+
 	meta := tx.Metadata
 	if meta != nil {
 		sizeTx += meta.CalculateSize()
@@ -951,6 +964,12 @@ func (tx *Tx) GetLockTime() int64 {
 	return tx.LockTime
 }
 
+// GetExpiryHeight returns shard height of the last block which tx may be in,
+// 0 if tx doesn't expire
+func (tx *Tx) GetExpiryHeight() uint64 {
+	return tx.ExpiryHeight
+}
+
 func (tx *Tx) GetSigPubKey() []byte {
 	return tx.SigPubKey
 }
@@ -34,6 +34,11 @@ const (
 	CmdAddr               = "addr"
 	CmdPing               = "ping"
 
+	// compact block relay Cmd
+	CmdBlockShardCompact = "blkshdcmpct"
+	CmdGetBlockTxs       = "getblocktxs"
+	CmdBlockTxs          = "blocktxs"
+
 	// POS Cmd
 	CmdBFTPropose = "bftpropose"
 	CmdBFTPrepare = "bftprepare"
@@ -159,6 +164,15 @@ func MakeEmptyMessage(messageType string) (Message, error) {
 	case CmdPing:
 		msg = &MessagePing{}
 		break
+	case CmdBlockShardCompact:
+		msg = &MessageBlockShardCompact{}
+		break
+	case CmdGetBlockTxs:
+		msg = &MessageGetBlockTxs{}
+		break
+	case CmdBlockTxs:
+		msg = &MessageBlockTxs{}
+		break
 	case CmdMsgCheck:
 		msg = &MessageMsgCheck{
 			Timestamp: time.Now().UnixNano(),
@@ -221,6 +235,12 @@ func GetCmdType(msgType reflect.Type) (string, error) {
 		return CmdBFTReq, nil
 	case reflect.TypeOf(&MessagePeerState{}):
 		return CmdPeerState, nil
+	case reflect.TypeOf(&MessageBlockShardCompact{}):
+		return CmdBlockShardCompact, nil
+	case reflect.TypeOf(&MessageGetBlockTxs{}):
+		return CmdGetBlockTxs, nil
+	case reflect.TypeOf(&MessageBlockTxs{}):
+		return CmdBlockTxs, nil
 	case reflect.TypeOf(&MessageMsgCheck{}):
 		return CmdMsgCheck, nil
 	case reflect.TypeOf(&MessageMsgCheckResp{}):
@@ -2,6 +2,7 @@ package wire
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/constant-money/constant-chain/cashec"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -13,11 +14,23 @@ import (
 
 const (
 	MaxGetAddressPayload = 100000 // 1 Kb
+	// MaxAddrPerMsg is the max number of address records in an addr message,
+	// so a full message fits in MaxGetAddressPayload
+	MaxAddrPerMsg = 250
 )
 
 type RawPeer struct {
 	RawAddress string
 	PublicKey  string
+	// Signature is the signature of PublicKey over AddrSignData, a node
+	// signs its own address record and other nodes relay it as is
+	Signature string `json:",omitempty"`
+}
+
+// AddrSignData returns the data which is signed in an address record, so a
+// peer can't relay public key of a node with another address
+func AddrSignData(rawAddress string, publicKey string) []byte {
+	return []byte(fmt.Sprintf("%s|%s", rawAddress, publicKey))
 }
 
 type MessageAddr struct {
@@ -60,5 +73,8 @@ func (msg *MessageAddr) SignMsg(_ *cashec.KeySet) error {
 }
 
 func (msg *MessageAddr) VerifyMsgSanity() error {
+	if len(msg.RawPeers) > MaxAddrPerMsg {
+		return fmt.Errorf("addr message has %d address records, max is %d", len(msg.RawPeers), MaxAddrPerMsg)
+	}
 	return nil
 }
@@ -0,0 +1,16 @@
+package wire
+
+import (
+	"testing"
+)
+
+func TestMessageAddrSanityLimit(t *testing.T) {
+	msg := &MessageAddr{RawPeers: make([]RawPeer, MaxAddrPerMsg)}
+	if err := msg.VerifyMsgSanity(); err != nil {
+		t.Fatalf("addr message with %d records should be valid: %+v", MaxAddrPerMsg, err)
+	}
+	msg.RawPeers = append(msg.RawPeers, RawPeer{})
+	if err := msg.VerifyMsgSanity(); err == nil {
+		t.Fatalf("addr message with more than %d records should be rejected", MaxAddrPerMsg)
+	}
+}
@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"encoding/json"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/libp2p/go-libp2p-peer"
+)
+
+// MessageBlockShardCompact announces a new shard block to peers of the same
+// shard which support compact block, receiver rebuilds the block from its
+// mempool and asks SenderID for missing txs with getblocktxs
+type MessageBlockShardCompact struct {
+	Block    blockchain.CompactShardBlock
+	SenderID string
+}
+
+func (msg *MessageBlockShardCompact) Hash() string {
+	rawBytes, err := msg.JsonSerialize()
+	if err != nil {
+		return ""
+	}
+	return common.HashH(rawBytes).String()
+}
+
+func (msg *MessageBlockShardCompact) MessageType() string {
+	return CmdBlockShardCompact
+}
+
+func (msg *MessageBlockShardCompact) MaxPayloadLength(pver int) int {
+	return MaxBlockPayload
+}
+
+func (msg *MessageBlockShardCompact) JsonSerialize() ([]byte, error) {
+	jsonBytes, err := json.Marshal(msg)
+	return jsonBytes, err
+}
+
+func (msg *MessageBlockShardCompact) JsonDeserialize(jsonStr string) error {
+	err := json.Unmarshal([]byte(jsonStr), msg)
+	return err
+}
+
+func (msg *MessageBlockShardCompact) SetSenderID(senderID peer.ID) error {
+	msg.SenderID = senderID.Pretty()
+	return nil
+}
+
+func (msg *MessageBlockShardCompact) SignMsg(_ *cashec.KeySet) error {
+	return nil
+}
+
+func (msg *MessageBlockShardCompact) VerifyMsgSanity() error {
+	return nil
+}
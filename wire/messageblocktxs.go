@@ -0,0 +1,54 @@
+package wire
+
+import (
+	"encoding/json"
+
+	"github.com/constant-money/constant-chain/blockchain"
+	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/libp2p/go-libp2p-peer"
+)
+
+// MessageBlockTxs is the answer of getblocktxs
+type MessageBlockTxs struct {
+	ShardID byte
+	Txs     blockchain.ShardBlockTxs
+}
+
+func (msg *MessageBlockTxs) Hash() string {
+	rawBytes, err := msg.JsonSerialize()
+	if err != nil {
+		return ""
+	}
+	return common.HashH(rawBytes).String()
+}
+
+func (msg *MessageBlockTxs) MessageType() string {
+	return CmdBlockTxs
+}
+
+func (msg *MessageBlockTxs) MaxPayloadLength(pver int) int {
+	return MaxBlockPayload
+}
+
+func (msg *MessageBlockTxs) JsonSerialize() ([]byte, error) {
+	jsonBytes, err := json.Marshal(msg)
+	return jsonBytes, err
+}
+
+func (msg *MessageBlockTxs) JsonDeserialize(jsonStr string) error {
+	err := json.Unmarshal([]byte(jsonStr), msg)
+	return err
+}
+
+func (msg *MessageBlockTxs) SetSenderID(senderID peer.ID) error {
+	return nil
+}
+
+func (msg *MessageBlockTxs) SignMsg(_ *cashec.KeySet) error {
+	return nil
+}
+
+func (msg *MessageBlockTxs) VerifyMsgSanity() error {
+	return nil
+}
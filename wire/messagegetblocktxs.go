@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"encoding/json"
+
+	"github.com/constant-money/constant-chain/cashec"
+	"github.com/constant-money/constant-chain/common"
+	"github.com/libp2p/go-libp2p-peer"
+)
+
+// MessageGetBlockTxs requests the txs at Indexes of a shard block which are
+// not found in mempool when rebuilding it from a compact block
+type MessageGetBlockTxs struct {
+	ShardID   byte
+	BlockHash common.Hash
+	Indexes   []int
+	SenderID  string
+}
+
+func (msg *MessageGetBlockTxs) Hash() string {
+	rawBytes, err := msg.JsonSerialize()
+	if err != nil {
+		return ""
+	}
+	return common.HashH(rawBytes).String()
+}
+
+func (msg *MessageGetBlockTxs) MessageType() string {
+	return CmdGetBlockTxs
+}
+
+func (msg *MessageGetBlockTxs) MaxPayloadLength(pver int) int {
+	return MaxBlockPayload
+}
+
+func (msg *MessageGetBlockTxs) JsonSerialize() ([]byte, error) {
+	jsonBytes, err := json.Marshal(msg)
+	return jsonBytes, err
+}
+
+func (msg *MessageGetBlockTxs) JsonDeserialize(jsonStr string) error {
+	err := json.Unmarshal([]byte(jsonStr), msg)
+	return err
+}
+
+func (msg *MessageGetBlockTxs) SetSenderID(senderID peer.ID) error {
+	msg.SenderID = senderID.Pretty()
+	return nil
+}
+
+func (msg *MessageGetBlockTxs) SignMsg(_ *cashec.KeySet) error {
+	return nil
+}
+
+func (msg *MessageGetBlockTxs) VerifyMsgSanity() error {
+	return nil
+}
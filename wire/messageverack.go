@@ -3,7 +3,7 @@ package wire
 import (
 	"encoding/hex"
 	"encoding/json"
-
+	"fmt"
 	"time"
 
 	"github.com/constant-money/constant-chain/cashec"
@@ -14,6 +14,23 @@ import (
 type MessageVerAck struct {
 	Valid     bool
 	Timestamp time.Time
+	// PublicKey and SignDataB58 prove that sender owns the key: SignDataB58
+	// is the signature of PublicKey over VerAckSignData
+	PublicKey   string
+	SignDataB58 string
+	// AddrSignDataB58 is the signature of PublicKey over AddrSignData of
+	// listening address of sender, its signed address record
+	AddrSignDataB58 string
+}
+
+/*
+VerAckSignData returns the data which is signed in verack message: challenge
+of version message which is acknowledged, peer id of signer and of receiver.
+Peer ids are authenticated by transport, so a signature can not be replayed
+on another connection
+*/
+func VerAckSignData(challenge string, signer peer.ID, receiver peer.ID) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", challenge, signer.Pretty(), receiver.Pretty()))
 }
 
 func (msg *MessageVerAck) Hash() string {
@@ -22,8 +22,19 @@ type MessageVersion struct {
 	LocalAddress     common.SimpleAddr
 	RawLocalAddress  string
 	LocalPeerId      peer.ID
-	PublicKey        string
-	SignDataB58      string
+	// PublicKey is the committee public key which sender claims, it is
+	// trusted only after sender signs Challenge in verack message
+	PublicKey string
+	// Challenge is a random nonce which receiver must sign
+	Challenge string
+	// CompactBlock is true when node accepts compact shard blocks
+	CompactBlock bool
+	// Archive is true when node keeps full history, so it can serve blocks
+	// which are pruned by other nodes
+	Archive bool
+	// Gossip is true when node receives shard and beacon messages by gossip
+	// topics, other nodes get them pushed directly
+	Gossip bool
 }
 
 func (msg *MessageVersion) Hash() string {
@@ -0,0 +1,68 @@
+package wire
+
+import (
+	"fmt"
+
+	"github.com/constant-money/constant-chain/common"
+)
+
+// gossip pubsub topics, shard topics are suffixed by shard id
+const (
+	TopicTxPrefix         = "tx-"
+	TopicShardBlockPrefix = "blockshard-"
+	TopicCrossShardPrefix = "crossshard-"
+	TopicBeacon           = "beacon"
+)
+
+// TopicTx is the topic of txs which are sent from shard shardID
+func TopicTx(shardID byte) string {
+	return fmt.Sprintf("%s%d", TopicTxPrefix, shardID)
+}
+
+// TopicShardBlock is the topic of blocks of shard shardID
+func TopicShardBlock(shardID byte) string {
+	return fmt.Sprintf("%s%d", TopicShardBlockPrefix, shardID)
+}
+
+// TopicCrossShard is the topic of cross shard blocks which are sent to shard
+// shardID
+func TopicCrossShard(shardID byte) string {
+	return fmt.Sprintf("%s%d", TopicCrossShardPrefix, shardID)
+}
+
+// TopicsOfShard returns all topics of a shard
+func TopicsOfShard(shardID byte) []string {
+	return []string{TopicTx(shardID), TopicShardBlock(shardID), TopicCrossShard(shardID)}
+}
+
+/*
+GetTopic returns the gossip topic of a message, messages which are not
+published by gossip (version, getaddr, bft...) and tx messages without tx have
+no topic
+*/
+func GetTopic(msg Message) (string, bool) {
+	switch msg := msg.(type) {
+	case *MessageTx:
+		if msg.Transaction == nil {
+			return "", false
+		}
+		return TopicTx(common.GetShardIDFromLastByte(msg.Transaction.GetSenderAddrLastByte())), true
+	case *MessageTxToken:
+		if msg.Transaction == nil {
+			return "", false
+		}
+		return TopicTx(common.GetShardIDFromLastByte(msg.Transaction.GetSenderAddrLastByte())), true
+	case *MessageTxPrivacyToken:
+		if msg.Transaction == nil {
+			return "", false
+		}
+		return TopicTx(common.GetShardIDFromLastByte(msg.Transaction.GetSenderAddrLastByte())), true
+	case *MessageBlockShard:
+		return TopicShardBlock(msg.Block.Header.ShardID), true
+	case *MessageCrossShard:
+		return TopicCrossShard(msg.Block.ToShardID), true
+	case *MessageBlockBeacon, *MessageShardToBeacon:
+		return TopicBeacon, true
+	}
+	return "", false
+}